@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/mevdschee/p2pquic-go/pkg/p2pquic"
+)
+
+// defaultMaxRoomCandidates bounds how many addresses a room may make us dial
+const defaultMaxRoomCandidates = 20
+
+// globalMaxRoomCandidates is the cap set with -max-room-candidates (0 = none)
+var globalMaxRoomCandidates = defaultMaxRoomCandidates
+
+// defaultRestunAfter is how old our own candidates may get before a punch
+// rediscovers them, since the NAT may have rebound the mapping meanwhile
+const defaultRestunAfter = 15 * time.Second
+
+// globalRestunAfter is the age set with -restun-after (0 = never refresh)
+var globalRestunAfter = defaultRestunAfter
+
+// candidateDiscoverer is the part of *p2pquic.Peer used to gather candidates
+type candidateDiscoverer interface {
+	DiscoverCandidates() ([]p2pquic.Candidate, error)
+}
+
+// localCandidates remembers our discovered candidates and when they were
+// gathered, rediscovering them once they are older than maxAge
+type localCandidates struct {
+	peer         candidateDiscoverer
+	maxAge       time.Duration
+	now          func() time.Time
+	candidates   []p2pquic.Candidate
+	discoveredAt time.Time
+}
+
+func newLocalCandidates(peer candidateDiscoverer, maxAge time.Duration) *localCandidates {
+	return &localCandidates{peer: peer, maxAge: maxAge, now: time.Now}
+}
+
+// get returns our candidates, running discovery first if there are none yet
+// or they went stale. refreshed reports whether discovery ran.
+func (l *localCandidates) get() (candidates []p2pquic.Candidate, refreshed bool, err error) {
+	stale := l.maxAge > 0 && l.now().Sub(l.discoveredAt) >= l.maxAge
+	if l.candidates != nil && !stale {
+		return l.candidates, false, nil
+	}
+	candidates, err = l.peer.DiscoverCandidates()
+	if err != nil {
+		return nil, false, err
+	}
+	l.candidates = candidates
+	l.discoveredAt = l.now()
+	return candidates, true, nil
+}
+
+// limitRoomCandidates drops duplicate and malformed candidates from a room
+// and caps the rest at max (no cap if max <= 0), so a hostile room cannot
+// keep us dialing. Candidates on one of our own subnets come first, then
+// public (reflexive) ones, then the rest.
+func limitRoomCandidates(candidates []p2pquic.Candidate, localNets []*net.IPNet, max int) []p2pquic.Candidate {
+	rank := func(ip net.IP) int {
+		for _, n := range localNets {
+			if n.Contains(ip) {
+				return 0
+			}
+		}
+		if !ip.IsPrivate() && !ip.IsLoopback() {
+			return 1
+		}
+		return 2
+	}
+
+	type ranked struct {
+		c    p2pquic.Candidate
+		rank int
+	}
+	seen := make(map[string]bool)
+	list := make([]ranked, 0, len(candidates))
+	for _, c := range candidates {
+		ip := net.ParseIP(c.IP)
+		if ip == nil || ip.IsUnspecified() || c.Port <= 0 || c.Port > 65535 {
+			continue
+		}
+		key := fmt.Sprintf("%s:%d", c.IP, c.Port)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		list = append(list, ranked{c, rank(ip)})
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].rank < list[j].rank
+	})
+
+	if max > 0 && len(list) > max {
+		list = list[:max]
+	}
+	limited := make([]p2pquic.Candidate, len(list))
+	for i, r := range list {
+		limited[i] = r.c
+	}
+	return limited
+}
+
+// localNetworks returns the subnets of this machine's interfaces
+func localNetworks() []*net.IPNet {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	var nets []*net.IPNet
+	for _, a := range addrs {
+		if n, ok := a.(*net.IPNet); ok && !n.IP.IsLoopback() {
+			nets = append(nets, n)
+		}
+	}
+	return nets
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mevdschee/p2pquic-go/pkg/p2pquic"
+)
+
+func TestLimitRoomCandidates(t *testing.T) {
+	_, lan, _ := net.ParseCIDR("192.168.1.0/24")
+	local := []*net.IPNet{lan}
+
+	t.Run("over-large list is truncated to the cap", func(t *testing.T) {
+		var flood []p2pquic.Candidate
+		for i := 0; i < 5000; i++ {
+			flood = append(flood, p2pquic.Candidate{IP: fmt.Sprintf("10.%d.%d.1", i/256, i%256), Port: 4000})
+		}
+		got := limitRoomCandidates(flood, local, 20)
+		if len(got) != 20 {
+			t.Fatalf("Expected 20 candidates, got %d", len(got))
+		}
+	})
+
+	t.Run("same subnet and public first", func(t *testing.T) {
+		cands := []p2pquic.Candidate{
+			{IP: "10.0.0.5", Port: 4000},
+			{IP: "203.0.113.7", Port: 4000},
+			{IP: "192.168.1.20", Port: 4000},
+		}
+		got := limitRoomCandidates(cands, local, 2)
+		if len(got) != 2 || got[0].IP != "192.168.1.20" || got[1].IP != "203.0.113.7" {
+			t.Errorf("Unexpected order: %v", got)
+		}
+	})
+
+	t.Run("duplicates and malformed entries are dropped", func(t *testing.T) {
+		cands := []p2pquic.Candidate{
+			{IP: "203.0.113.7", Port: 4000},
+			{IP: "203.0.113.7", Port: 4000},
+			{IP: "not-an-ip", Port: 4000},
+			{IP: "0.0.0.0", Port: 4000},
+			{IP: "203.0.113.8", Port: 0},
+			{IP: "203.0.113.9", Port: 70000},
+		}
+		got := limitRoomCandidates(cands, local, 20)
+		if len(got) != 1 {
+			t.Errorf("Expected 1 usable candidate, got %v", got)
+		}
+	})
+
+	t.Run("zero means no cap", func(t *testing.T) {
+		cands := []p2pquic.Candidate{{IP: "203.0.113.1", Port: 1}, {IP: "203.0.113.2", Port: 1}, {IP: "203.0.113.3", Port: 1}}
+		if got := limitRoomCandidates(cands, nil, 0); len(got) != 3 {
+			t.Errorf("Expected all 3 candidates, got %d", len(got))
+		}
+	})
+}
+
+type fakeDiscoverer struct {
+	calls int
+}
+
+func (f *fakeDiscoverer) DiscoverCandidates() ([]p2pquic.Candidate, error) {
+	f.calls++
+	return []p2pquic.Candidate{{IP: "203.0.113.1", Port: 40000 + f.calls}}, nil
+}
+
+func TestLocalCandidatesRefresh(t *testing.T) {
+	now := time.Unix(1000, 0)
+	clock := func() time.Time { return now }
+
+	t.Run("fresh candidates are reused", func(t *testing.T) {
+		d := &fakeDiscoverer{}
+		l := newLocalCandidates(d, 15*time.Second)
+		l.now = clock
+		l.get()
+		now = now.Add(5 * time.Second)
+		_, refreshed, _ := l.get()
+		if refreshed || d.calls != 1 {
+			t.Errorf("Expected cached candidates, got refreshed=%v after %d discoveries", refreshed, d.calls)
+		}
+	})
+
+	t.Run("stale candidates are rediscovered", func(t *testing.T) {
+		d := &fakeDiscoverer{}
+		l := newLocalCandidates(d, 15*time.Second)
+		l.now = clock
+		first, _, _ := l.get()
+		now = now.Add(20 * time.Second)
+		second, refreshed, _ := l.get()
+		if !refreshed || d.calls != 2 {
+			t.Fatalf("Expected rediscovery, got refreshed=%v after %d discoveries", refreshed, d.calls)
+		}
+		if first[0].Port == second[0].Port {
+			t.Errorf("Expected the new mapping, got %v", second)
+		}
+	})
+
+	t.Run("zero max age never refreshes", func(t *testing.T) {
+		d := &fakeDiscoverer{}
+		l := newLocalCandidates(d, 0)
+		l.now = clock
+		l.get()
+		now = now.Add(time.Hour)
+		if _, refreshed, _ := l.get(); refreshed {
+			t.Error("Expected no refresh with refreshing disabled")
+		}
+	})
+}
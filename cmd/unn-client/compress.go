@@ -0,0 +1,33 @@
+package main
+
+import (
+	"io"
+	"log"
+
+	"github.com/mevdschee/underground-node-network/internal/sshcompress"
+	"golang.org/x/crypto/ssh"
+)
+
+// globalCompressSSH is set with -compress-ssh: ask rooms to compress the
+// session output
+var globalCompressSSH bool
+
+// requestCompression asks the room to compress the session output when
+// -compress-ssh is set, before the pty is requested, and returns the reader
+// for the session's stdout. Rooms that do not compress are read as is.
+func requestCompression(session *ssh.Session, stdout io.Reader, verbose bool) io.Reader {
+	if !globalCompressSSH {
+		return stdout
+	}
+	ok, err := sshcompress.Request(session)
+	if err != nil || !ok {
+		if verbose {
+			log.Printf("Room does not compress, continuing uncompressed")
+		}
+		return stdout
+	}
+	if verbose {
+		log.Printf("Room output is compressed")
+	}
+	return sshcompress.NewReader(stdout)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/mevdschee/p2pquic-go/pkg/p2pquic"
+	"golang.org/x/crypto/ssh"
+)
+
+// globalAllowDirectSSH is set with -allow-direct-ssh: when the QUIC path to a
+// room fails, dial the room's SSH port over TCP on its advertised addresses
+var globalAllowDirectSSH bool
+
+// roomHostKeyCallback accepts only the given host keys (authorized_keys format)
+func roomHostKeyCallback(hostKeys []string) ssh.HostKeyCallback {
+	parsed := make([]ssh.PublicKey, 0, len(hostKeys))
+	for _, keyStr := range hostKeys {
+		if pubKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(keyStr)); err == nil {
+			parsed = append(parsed, pubKey)
+		}
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		keyBytes := key.Marshal()
+		for _, hk := range parsed {
+			if bytes.Equal(hk.Marshal(), keyBytes) {
+				return nil
+			}
+		}
+		return fmt.Errorf("host key mismatch")
+	}
+}
+
+// directSSHTargets turns a room's advertised candidates into host:port
+// addresses for its SSH port, one per host, ordered and capped like the QUIC
+// candidates
+func directSSHTargets(candidates []string, sshPort int) []string {
+	if sshPort <= 0 {
+		return nil
+	}
+	parsed := make([]p2pquic.Candidate, 0, len(candidates))
+	for _, c := range candidates {
+		host, portStr, err := net.SplitHostPort(c)
+		if err != nil {
+			host, portStr = c, strconv.Itoa(sshPort)
+		}
+		port, _ := strconv.Atoi(portStr)
+		parsed = append(parsed, p2pquic.Candidate{IP: host, Port: port})
+	}
+	parsed = limitRoomCandidates(parsed, localNetworks(), globalMaxRoomCandidates)
+
+	seen := make(map[string]bool)
+	var targets []string
+	for _, c := range parsed {
+		if seen[c.IP] {
+			continue
+		}
+		seen[c.IP] = true
+		targets = append(targets, net.JoinHostPort(c.IP, strconv.Itoa(sshPort)))
+	}
+	return targets
+}
+
+// directFallback returns the addresses to dial directly after the QUIC path
+// failed with quicErr. Without -allow-direct-ssh, or when the room gave no
+// SSH port, addresses or host keys to check, it returns quicErr instead.
+func directFallback(quicErr error, allow bool, teleportData *TeleportData) ([]string, error) {
+	if !allow || len(teleportData.PublicKeys) == 0 {
+		return nil, quicErr
+	}
+	targets := directSSHTargets(teleportData.Candidates, teleportData.SSHPort)
+	if len(targets) == 0 {
+		return nil, quicErr
+	}
+	return targets, nil
+}
+
+// dialRoomDirect connects to the first target that accepts an SSH session
+// over TCP, checking the room's host keys from the teleport data
+func dialRoomDirect(targets []string, entrypointConfig *ssh.ClientConfig, teleportData *TeleportData, verbose bool) (*ssh.Client, error) {
+	config := &ssh.ClientConfig{
+		User:            entrypointConfig.User,
+		Auth:            entrypointConfig.Auth,
+		HostKeyCallback: roomHostKeyCallback(teleportData.PublicKeys),
+		Timeout:         10 * time.Second,
+		ClientVersion:   "SSH-2.0-UNN-CLIENT",
+	}
+	var lastErr error
+	for _, target := range targets {
+		if verbose {
+			log.Printf("Attempting direct SSH connection to %s", target)
+		}
+		client, err := ssh.Dial("tcp", target, config)
+		if err == nil {
+			return client, nil
+		}
+		lastErr = err
+		if verbose {
+			log.Printf("Direct SSH connection to %s failed: %v", target, err)
+		}
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mevdschee/underground-node-network/internal/sshtest"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestDirectFallback(t *testing.T) {
+	quicErr := errors.New("failed to connect via p2pquic: timeout")
+	hostSigner, err := sshtest.NewSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(hostSigner.PublicKey())))
+	data := &TeleportData{
+		RoomName:   "lounge",
+		Candidates: []string{"203.0.113.7:44323", "203.0.113.7:50000", "192.168.1.20:44323", "bogus"},
+		SSHPort:    2222,
+		PublicKeys: []string{hostKey},
+	}
+
+	t.Run("disabled keeps the QUIC error", func(t *testing.T) {
+		if _, err := directFallback(quicErr, false, data); err != quicErr {
+			t.Errorf("Expected the QUIC error, got %v", err)
+		}
+	})
+
+	t.Run("needs host keys and an SSH port", func(t *testing.T) {
+		noKeys := *data
+		noKeys.PublicKeys = nil
+		if _, err := directFallback(quicErr, true, &noKeys); err != quicErr {
+			t.Errorf("Expected no fallback without host keys, got %v", err)
+		}
+		noPort := *data
+		noPort.SSHPort = 0
+		if _, err := directFallback(quicErr, true, &noPort); err != quicErr {
+			t.Errorf("Expected no fallback without an SSH port, got %v", err)
+		}
+	})
+
+	t.Run("one SSH address per host", func(t *testing.T) {
+		targets, err := directFallback(quicErr, true, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(targets)
+		want := []string{"192.168.1.20:2222", "203.0.113.7:2222"}
+		if !slices.Equal(targets, want) {
+			t.Errorf("Expected %v, got %v", want, targets)
+		}
+	})
+
+	t.Run("dials over TCP and checks the host key", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		serverConfig := &ssh.ServerConfig{NoClientAuth: true}
+		serverConfig.AddHostKey(hostSigner)
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				go func() {
+					if sconn, chans, reqs, err := ssh.NewServerConn(conn, serverConfig); err == nil {
+						go ssh.DiscardRequests(reqs)
+						go func() {
+							for ch := range chans {
+								ch.Reject(ssh.Prohibited, "test")
+							}
+						}()
+						sconn.Wait()
+					}
+				}()
+			}
+		}()
+
+		clientConfig := &ssh.ClientConfig{User: "alice"}
+		client, err := dialRoomDirect([]string{"127.0.0.1:1", ln.Addr().String()}, clientConfig, data, false)
+		if err != nil {
+			t.Fatalf("Expected to connect to the second target, got %v", err)
+		}
+		client.Close()
+
+		otherSigner, _ := sshtest.NewSigner()
+		wrongKeys := *data
+		wrongKeys.PublicKeys = []string{string(ssh.MarshalAuthorizedKey(otherSigner.PublicKey()))}
+		if _, err := dialRoomDirect([]string{ln.Addr().String()}, clientConfig, &wrongKeys, false); err == nil || !strings.Contains(err.Error(), "host key mismatch") {
+			t.Errorf("Expected a host key mismatch, got %v", err)
+		}
+	})
+}
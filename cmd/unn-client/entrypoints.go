@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// defaultEntrypointPort is used for entrypoints given without a port
+const defaultEntrypointPort = "44322"
+
+// globalEntrypoints are the fallback entrypoints from -entrypoints, tried
+// after the one in the URL
+var globalEntrypoints []string
+
+// splitURLEntrypoints takes a comma-separated host list out of a unn:// URL,
+// as in unn://a:44322,b:44322/room, which url.Parse would reject. It
+// returns the URL with only the first host and the other hosts.
+func splitURLEntrypoints(rawURL string) (string, []string) {
+	rest, ok := strings.CutPrefix(rawURL, "unn://")
+	if !ok {
+		return rawURL, nil
+	}
+	end := strings.IndexAny(rest, "/?#")
+	if end < 0 {
+		end = len(rest)
+	}
+	authority, tail := rest[:end], rest[end:]
+	userinfo := ""
+	if at := strings.LastIndex(authority, "@"); at >= 0 {
+		userinfo, authority = authority[:at+1], authority[at+1:]
+	}
+	hosts := strings.Split(authority, ",")
+	return "unn://" + userinfo + hosts[0] + tail, hosts[1:]
+}
+
+// parseEntrypoints splits a comma-separated -entrypoints value
+func parseEntrypoints(value string) []string {
+	var entrypoints []string
+	for _, e := range strings.Split(value, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			entrypoints = append(entrypoints, e)
+		}
+	}
+	return entrypoints
+}
+
+// entrypointCandidates lists the entrypoints to try in order, each with a
+// port and without duplicates
+func entrypointCandidates(entrypoints ...string) []string {
+	var candidates []string
+	seen := make(map[string]bool)
+	for _, e := range entrypoints {
+		if e == "" {
+			continue
+		}
+		if !strings.Contains(e, ":") {
+			e = net.JoinHostPort(e, defaultEntrypointPort)
+		}
+		if !seen[e] {
+			seen[e] = true
+			candidates = append(candidates, e)
+		}
+	}
+	return candidates
+}
+
+// resolveEntrypoint resolves an entrypoint to an IPv4 address and port
+func resolveEntrypoint(entrypoint string) (string, error) {
+	host, port, err := net.SplitHostPort(entrypoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid entrypoint address: %w", err)
+	}
+
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			return net.JoinHostPort(ip.String(), port), nil
+		}
+	}
+	return "", fmt.Errorf("no IPv4 address found for %s", host)
+}
+
+// dialEntrypoint connects to the first entrypoint that can be resolved and
+// accepts the SSH connection, and returns its index in entrypoints
+func dialEntrypoint(entrypoints []string, config *ssh.ClientConfig, verbose bool) (*ssh.Client, int, error) {
+	var lastErr error
+	for i, entrypoint := range entrypoints {
+		address, err := resolveEntrypoint(entrypoint)
+		if err == nil {
+			var client *ssh.Client
+			if client, err = ssh.Dial("tcp", address, config); err == nil {
+				return client, i, nil
+			}
+		}
+		lastErr = err
+		if verbose && i < len(entrypoints)-1 {
+			log.Printf("Entrypoint %s failed, trying the next one: %v", entrypoint, err)
+		}
+	}
+	return nil, -1, lastErr
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net"
+	"slices"
+	"testing"
+
+	"github.com/mevdschee/underground-node-network/internal/sshtest"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestEntrypoints(t *testing.T) {
+	t.Run("hosts from the URL", func(t *testing.T) {
+		tests := []struct {
+			in, url string
+			hosts   []string
+		}{
+			{"unn://a.example/room", "unn://a.example/room", nil},
+			{"unn://bob@a.example:44322,b.example:2222/room#direct", "unn://bob@a.example:44322/room#direct", []string{"b.example:2222"}},
+			{"unn://a.example,b.example,c.example", "unn://a.example", []string{"b.example", "c.example"}},
+			{"https://a.example,b.example/", "https://a.example,b.example/", nil},
+		}
+		for _, tt := range tests {
+			url, hosts := splitURLEntrypoints(tt.in)
+			if url != tt.url || !slices.Equal(hosts, tt.hosts) {
+				t.Errorf("splitURLEntrypoints(%q) = %q, %v, want %q, %v", tt.in, url, hosts, tt.url, tt.hosts)
+			}
+		}
+	})
+
+	t.Run("candidates get the default port once", func(t *testing.T) {
+		got := entrypointCandidates(append([]string{"a.example", "b.example:2222"}, parseEntrypoints(" a.example:44322, ,c.example")...)...)
+		want := []string{"a.example:44322", "b.example:2222", "c.example:44322"}
+		if !slices.Equal(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("fails over to the next entrypoint", func(t *testing.T) {
+		// Take a free port and close it, so the first entrypoint refuses
+		closed, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		refused := closed.Addr().String()
+		closed.Close()
+
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		hostSigner, err := sshtest.NewSigner()
+		if err != nil {
+			t.Fatal(err)
+		}
+		serverConfig := &ssh.ServerConfig{NoClientAuth: true}
+		serverConfig.AddHostKey(hostSigner)
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				go func() {
+					if sconn, chans, reqs, err := ssh.NewServerConn(conn, serverConfig); err == nil {
+						go ssh.DiscardRequests(reqs)
+						go func() {
+							for ch := range chans {
+								ch.Reject(ssh.Prohibited, "test")
+							}
+						}()
+						sconn.Wait()
+					}
+				}()
+			}
+		}()
+
+		config := &ssh.ClientConfig{User: "alice", HostKeyCallback: ssh.InsecureIgnoreHostKey()}
+		client, used, err := dialEntrypoint([]string{refused, ln.Addr().String()}, config, false)
+		if err != nil {
+			t.Fatalf("Expected to connect to the second entrypoint, got %v", err)
+		}
+		client.Close()
+		if used != 1 {
+			t.Errorf("Expected the second entrypoint to be used, got %d", used)
+		}
+
+		if _, _, err := dialEntrypoint([]string{refused}, config, false); err == nil {
+			t.Error("Expected an error when no entrypoint accepts")
+		}
+	})
+}
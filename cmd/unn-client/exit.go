@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// exitKind classifies how a remote SSH session ended
+type exitKind int
+
+const (
+	exitClean    exitKind = iota // exited with a status, or closed without one
+	exitSignaled                 // terminated by a signal
+	exitAbnormal                 // transport or protocol failure
+)
+
+// classifyExit inspects the error returned by ssh.Session.Wait. The entrypoint
+// and rooms close sessions without sending an exit status when the user
+// leaves, which x/crypto reports as *ssh.ExitMissingError.
+func classifyExit(err error) exitKind {
+	if err == nil {
+		return exitClean
+	}
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		if exitErr.Signal() != "" {
+			return exitSignaled
+		}
+		return exitClean
+	}
+	var missingErr *ssh.ExitMissingError
+	if errors.As(err, &missingErr) {
+		return exitClean
+	}
+	return exitAbnormal
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestClassifyExit(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want exitKind
+	}{
+		{"nil", nil, exitClean},
+		{"exit status", &ssh.ExitError{Waitmsg: ssh.Waitmsg{}}, exitClean},
+		{"missing status", &ssh.ExitMissingError{}, exitClean},
+		{"wrapped missing status", fmt.Errorf("room: %w", &ssh.ExitMissingError{}), exitClean},
+		{"eof", io.EOF, exitAbnormal},
+		{"other", errors.New("connection reset"), exitAbnormal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyExit(tt.err); got != tt.want {
+				t.Errorf("classifyExit(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// globalDownloadHistory is the downloads log path, or "" when recording is disabled
+var globalDownloadHistory string
+
+// downloadRecord is one line of the local downloads log
+type downloadRecord struct {
+	Time     time.Time
+	Filename string
+	Size     int64
+	Checksum string
+	Path     string
+}
+
+// downloadHistoryPath returns the file used to record completed downloads
+func downloadHistoryPath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".unn", "downloads.log")
+}
+
+// appendDownload adds a record to the downloads log as a tab-separated line
+func appendDownload(path string, rec downloadRecord) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "%s\t%s\t%d\t%s\t%s\n",
+		rec.Time.UTC().Format(time.RFC3339), rec.Filename, rec.Size, rec.Checksum, rec.Path)
+	return err
+}
+
+// loadDownloads reads the downloads log, skipping malformed lines. A missing
+// log is not an error.
+func loadDownloads(path string) ([]downloadRecord, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []downloadRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 5 {
+			continue
+		}
+		ts, err := time.Parse(time.RFC3339, fields[0])
+		if err != nil {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		records = append(records, downloadRecord{
+			Time:     ts,
+			Filename: fields[1],
+			Size:     size,
+			Checksum: fields[3],
+			Path:     fields[4],
+		})
+	}
+	return records, scanner.Err()
+}
+
+// findDownload returns the most recent record with the given checksum
+func findDownload(records []downloadRecord, checksum string) (downloadRecord, bool) {
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].Checksum == checksum {
+			return records[i], true
+		}
+	}
+	return downloadRecord{}, false
+}
+
+// printDownloads writes the downloads log in a human readable form
+func printDownloads(w io.Writer, records []downloadRecord) {
+	if len(records) == 0 {
+		fmt.Fprintln(w, "No downloads recorded.")
+		return
+	}
+	for _, r := range records {
+		checksum := r.Checksum
+		if len(checksum) > 12 {
+			checksum = checksum[:12]
+		}
+		fmt.Fprintf(w, "%s  %-30s %10d  %s  %s\n",
+			r.Time.Local().Format("2006-01-02 15:04"), r.Filename, r.Size, checksum, r.Path)
+	}
+}
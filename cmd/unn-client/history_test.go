@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownloadHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, ".unn", "downloads.log")
+
+	records, err := loadDownloads(path)
+	if err != nil || len(records) != 0 {
+		t.Fatalf("expected empty history, got %v (%v)", records, err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	first := downloadRecord{Time: now, Filename: "a.txt", Size: 12, Checksum: "abc123", Path: "/tmp/a.txt"}
+	second := downloadRecord{Time: now.Add(time.Minute), Filename: "a.txt", Size: 12, Checksum: "abc123", Path: "/tmp/a (1).txt"}
+	for _, rec := range []downloadRecord{first, second} {
+		if err := appendDownload(path, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A malformed line must not break reading
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString("garbage\n")
+	f.Close()
+
+	records, err = loadDownloads(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	if !records[0].Time.Equal(first.Time) || records[0].Filename != first.Filename || records[0].Size != first.Size ||
+		records[0].Checksum != first.Checksum || records[0].Path != first.Path {
+		t.Errorf("record mismatch: got %+v, want %+v", records[0], first)
+	}
+
+	if prev, ok := findDownload(records, "abc123"); !ok || prev.Path != second.Path {
+		t.Errorf("expected most recent duplicate %s, got %+v", second.Path, prev)
+	}
+	if _, ok := findDownload(records, "other"); ok {
+		t.Error("expected no match for unknown checksum")
+	}
+
+	var buf bytes.Buffer
+	printDownloads(&buf, records)
+	if !strings.Contains(buf.String(), "/tmp/a (1).txt") {
+		t.Errorf("expected destination in output, got %q", buf.String())
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"io"
@@ -8,9 +10,13 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/nat"
+	"github.com/mevdschee/underground-node-network/internal/ui/common"
 )
 
 type StdinManager struct {
@@ -96,25 +102,114 @@ func main() {
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
 		fmt.Fprintf(os.Stderr, "  %s unn://localhost/myroom\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s unn://localhost (interactive mode)\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s unn://localhost/myroom#direct (exit when leaving the room)\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s unn://a.example,b.example/myroom (try b.example if a.example is down)\n", os.Args[0])
 	}
 
 	verbose := flag.Bool("v", false, "Verbose output")
 	identity := flag.String("identity", "", "Path to private key for authentication")
 	batch := flag.Bool("batch", false, "Non-interactive batch mode")
+	quicIdleTimeout := flag.Duration("quic-idle-timeout", nat.DefaultQUICTuning.MaxIdleTimeout, "Close the QUIC connection to a room after this long without any packet")
+	quicKeepalive := flag.Duration("quic-keepalive", 0, "Send a QUIC keepalive at this interval (0 for the -nat-type interval)")
+	quicStreamWindow := flag.String("quic-stream-window", "", "Initial QUIC stream receive window, e.g. 2M, for faster downloads on high-latency links (empty for the quic-go default of 512K)")
 	homeDir, _ := os.UserHomeDir()
 	defaultDownloads := filepath.Join(homeDir, "Downloads")
 	downloads := flag.String("downloads", defaultDownloads, "Directory for file downloads")
+	dumpTeleportData := flag.Bool("dump-teleport", false, "Print the connection data the entrypoint sends for a room before joining it")
+	redact := flag.Bool("redact", false, "Mask candidate addresses in verbose log output")
+	direct := flag.Bool("direct", false, "Exit when leaving the room instead of returning to the entrypoint lobby (same as a #direct URL)")
+	rememberRoom := flag.Bool("remember-room", false, "Persist the last joined room to ~/.unn/last_room")
+	history := flag.Bool("history", false, "Print the downloads history from ~/.unn/downloads.log and exit")
+	clearHistory := flag.Bool("clear-history", false, "Clear the downloads history and exit")
+	noHistory := flag.Bool("no-history", false, "Do not record downloads in ~/.unn/downloads.log")
+	timestamp := flag.Bool("timestamp", false, "Add .YYYYMMDD-HHMMSS to download names instead of numbering them on collision")
+	expect := flag.String("expect", "", "Expected SHA-256 of the first download, checked instead of trusting the room's checksum")
+	verify := flag.String("verify", "", "Check a file already on disk against the -expect checksum and exit")
+	maxRoomCandidates := flag.Int("max-room-candidates", defaultMaxRoomCandidates, "Dial at most this many of a room's advertised addresses (0 for no limit)")
+	restunAfter := flag.Duration("restun-after", defaultRestunAfter, "Rediscover our own candidates before a punch if they are older than this (0 to never refresh)")
+	natType := flag.String("nat-type", "unknown", "NAT in front of this client, sets the QUIC keepalive interval: none, full-cone, restricted, port-restricted, symmetric or unknown")
+	quicResume := flag.Bool("quic-resume", false, "Resume the TLS session when reconnecting to a room in this process, skipping the certificate exchange (rooms do not accept 0-RTT early data)")
+	ascii := flag.Bool("ascii", false, "Draw message boxes with ASCII only (default when the locale is not UTF-8)")
+	noTitle := flag.Bool("no-title", false, "Do not let rooms set the terminal title")
+	allowDirectSSH := flag.Bool("allow-direct-ssh", false, "If the QUIC connection to a room fails, dial the room's SSH port over TCP on its advertised addresses")
+	entrypoints := flag.String("entrypoints", "", "Comma-separated fallback entrypoints (host[:port]) to try when the one in the URL is unreachable")
+	compressSSH := flag.Bool("compress-ssh", false, "Ask rooms to deflate-compress their output, for rooms started with -compress-ssh (saves bandwidth on slow links, costs some CPU)")
 	flag.Parse()
 
+	if *expect != "" {
+		if _, err := hex.DecodeString(*expect); err != nil || len(*expect) != sha256.Size*2 {
+			log.Fatalf("Error: -expect must be a hex SHA-256 checksum")
+		}
+		globalExpectedChecksum = strings.ToLower(*expect)
+	}
+	if *verify != "" {
+		if *expect == "" {
+			log.Fatalf("Error: -verify needs the checksum to compare against in -expect")
+		}
+		actual, err := verifyFile(*verify, globalExpectedChecksum)
+		if err != nil {
+			log.Fatalf("Error: %s: %v", *verify, err)
+		}
+		fmt.Printf("%s: OK (sha256 %s)\n", *verify, actual)
+		return
+	}
+	globalMaxRoomCandidates = *maxRoomCandidates
+	globalRestunAfter = *restunAfter
+	globalQUICResume = *quicResume
+	globalAllowDirectSSH = *allowDirectSSH
+	globalCompressSSH = *compressSSH
+	globalEntrypoints = parseEntrypoints(*entrypoints)
+	parsedNAT, natErr := nat.ParseNATType(*natType)
+	if natErr != nil {
+		log.Fatalf("Invalid -nat-type: %v", natErr)
+	}
+	globalTimestampNames = *timestamp
+	globalDumpTeleport = *dumpTeleportData
+	globalDirect = *direct
+	globalSetTitle = !*noTitle && !*batch
+	common.SetASCII(*ascii || !common.LocaleIsUTF8())
+
+	if *clearHistory {
+		if err := os.Remove(downloadHistoryPath()); err != nil && !os.IsNotExist(err) {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+	if *history {
+		records, err := loadDownloads(downloadHistoryPath())
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		printDownloads(os.Stdout, records)
+		return
+	}
+	if !*noHistory {
+		globalDownloadHistory = downloadHistoryPath()
+	}
+
 	if flag.NArg() < 1 {
 		flag.Usage()
 		os.Exit(1)
 	}
 
 	unnUrl := flag.Arg(0)
+	nat.SetRedaction(*redact)
+	streamWindow, windowErr := nat.ParseWindow(*quicStreamWindow)
+	if windowErr != nil {
+		log.Fatalf("Invalid -quic-stream-window: %v", windowErr)
+	}
+	globalQUICTuning = nat.QUICTuning{MaxIdleTimeout: *quicIdleTimeout, KeepAlivePeriod: *quicKeepalive, StreamWindow: streamWindow}
+	if globalQUICTuning.KeepAlivePeriod == 0 {
+		globalQUICTuning.KeepAlivePeriod = nat.KeepaliveInterval(parsedNAT)
+	}
+	if err := globalQUICTuning.Validate(); err != nil {
+		log.Fatalf("Invalid -quic-idle-timeout or -quic-keepalive: %v", err)
+	}
 	// Ignore SIGINT so it's passed as a byte to the SSH sessions
 	signal.Ignore(os.Interrupt)
-	if err := teleport(unnUrl, *identity, *verbose, *batch, *downloads); err != nil {
+	err := teleport(unnUrl, *identity, *verbose, *batch, *downloads, *rememberRoom)
+	resetTerminalTitle(os.Stdout)
+	if err != nil {
 		log.Fatalf("Error: %v", err)
 	}
 }
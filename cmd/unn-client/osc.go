@@ -2,21 +2,21 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
-	"regexp"
 	"strings"
 
+	"github.com/gdamore/tcell/v2"
 	"github.com/mevdschee/underground-node-network/internal/protocol"
+	"github.com/mevdschee/underground-node-network/internal/ui/common"
 	"golang.org/x/term"
 )
 
-var ansiRegex = regexp.MustCompile(`\x1b(\[([0-9;]*[a-zA-Z])|c|\[\?[0-9]+[hl])`)
-
-func stripANSI(s string) string {
-	return ansiRegex.ReplaceAllString(s, "")
-}
-
 func handleOSCPopup(p protocol.PopupPayload) {
+	// The popup text comes from the remote side and is printed raw
+	p.Title = common.StripANSI(p.Title)
+	p.Message = common.StripANSI(p.Message)
+
 	// 1. Clear screen and move to top
 	fmt.Print("\033[H\033[2J")
 
@@ -51,13 +51,15 @@ func handleOSCPopup(p protocol.PopupPayload) {
 
 	// 5. Advanced Colors (256-color fallback)
 	borderColor := "38;5;39" // Information blue
-	icon := "ⓘ"
+	icon := common.Glyphs.IconInfo
 	if p.Type == "error" {
 		borderColor = "38;5;196" // Error red
-		icon = "✖"
+		icon = common.Glyphs.IconError
 	} else if p.Type == "warning" {
 		borderColor = "38;5;214" // Warning orange
-		icon = "⚠"
+		icon = common.Glyphs.IconWarning
+	} else if accent := themeAccentSGR(globalRoomTheme.Accent); accent != "" {
+		borderColor = accent // Room branding, never overrides warning/error colors
 	}
 
 	titleColor := "\033[1;48;5;235;38;5;255m" // Dark bg, white text
@@ -70,16 +72,17 @@ func handleOSCPopup(p protocol.PopupPayload) {
 
 	// 7. Print Box with Shadow
 	// Header line
-	fmt.Printf("%s%s▛%s▜%s\r\n", leftPaddingStr, primary, strings.Repeat("▀", boxWidth-2), reset)
+	g := common.Glyphs
+	fmt.Printf("%s%s%s%s%s%s\r\n", leftPaddingStr, primary, g.BlockTopLeft, strings.Repeat(g.BlockTop, boxWidth-2), g.BlockTopRight, reset)
 
 	// Title line
 	titleText := fmt.Sprintf("%s %s", icon, strings.ToUpper(p.Title))
 	tPadLeft := (boxWidth - 4 - len(titleText)) / 2
 	tPadRight := boxWidth - 4 - len(titleText) - tPadLeft
-	fmt.Printf("%s%s▌ %s%s%s%s %s▐%s█%s\r\n", leftPaddingStr, primary, titleColor, strings.Repeat(" ", tPadLeft), titleText, strings.Repeat(" ", tPadRight), reset+primary, shadow, reset)
+	fmt.Printf("%s%s%s %s%s%s%s %s%s%s%s%s\r\n", leftPaddingStr, primary, g.BlockLeft, titleColor, strings.Repeat(" ", tPadLeft), titleText, strings.Repeat(" ", tPadRight), reset+primary, g.BlockRight, shadow, g.Shadow, reset)
 
 	// Separator
-	fmt.Printf("%s%s▙%s▟%s█%s\r\n", leftPaddingStr, primary, strings.Repeat("▄", boxWidth-2), shadow, reset)
+	fmt.Printf("%s%s%s%s%s%s%s%s\r\n", leftPaddingStr, primary, g.BlockBottomLeft, strings.Repeat(g.BlockBottom, boxWidth-2), g.BlockBottomRight, shadow, g.Shadow, reset)
 
 	// Message body
 	for _, l := range lines {
@@ -88,13 +91,56 @@ func handleOSCPopup(p protocol.PopupPayload) {
 		}
 		lPadLeft := (boxWidth - 4 - len(l)) / 2
 		lPadRight := boxWidth - 4 - len(l) - lPadLeft
-		fmt.Printf("%s%s▌ %s%s%s %s▐%s█%s\r\n", leftPaddingStr, primary, strings.Repeat(" ", lPadLeft), l, strings.Repeat(" ", lPadRight), primary, shadow, reset)
+		fmt.Printf("%s%s%s %s%s%s %s%s%s%s%s\r\n", leftPaddingStr, primary, g.BlockLeft, strings.Repeat(" ", lPadLeft), l, strings.Repeat(" ", lPadRight), primary, g.BlockRight, shadow, g.Shadow, reset)
 	}
 
 	// Bottom border
-	fmt.Printf("%s%s▙%s▟%s█%s\r\n", leftPaddingStr, primary, strings.Repeat("▀", boxWidth-2), shadow, reset)
-	fmt.Printf("%s  %s%s%s\r\n", leftPaddingStr, shadow, strings.Repeat("▀", boxWidth-1), reset)
+	fmt.Printf("%s%s%s%s%s%s%s%s\r\n", leftPaddingStr, primary, g.BlockBottomLeft, strings.Repeat(g.BlockTop, boxWidth-2), g.BlockBottomRight, shadow, g.Shadow, reset)
+	fmt.Printf("%s  %s%s%s\r\n", leftPaddingStr, shadow, strings.Repeat(g.ShadowBottom, boxWidth-1), reset)
 
 	// 8. Space for prompt
 	fmt.Print("\r\n\r\n")
 }
+
+// themeAccentSGR converts a room theme accent (color name or #rrggbb) to a
+// 24-bit foreground SGR parameter, or "" if it is unset or unknown
+func themeAccentSGR(accent string) string {
+	if accent == "" {
+		return ""
+	}
+	color := tcell.GetColor(strings.ToLower(accent))
+	if color == tcell.ColorDefault {
+		return ""
+	}
+	r, g, b := color.RGB()
+	return fmt.Sprintf("38;2;%d;%d;%d", r, g, b)
+}
+
+// globalSetTitle lets rooms and the entrypoint set the terminal title
+// (disabled with -no-title)
+var globalSetTitle = true
+
+// titleSet records that the title was changed, so it is reset on exit
+var titleSet bool
+
+// setTerminalTitle writes OSC 2 with the title. The title comes from the
+// remote side, so escapes and control characters are removed first.
+func setTerminalTitle(w io.Writer, title string) {
+	if !globalSetTitle {
+		return
+	}
+	title = strings.ReplaceAll(common.StripANSI(title), "\n", " ")
+	if r := []rune(title); len(r) > 80 {
+		title = string(r[:80])
+	}
+	fmt.Fprintf(w, "\033]2;%s\007", title)
+	titleSet = true
+}
+
+// resetTerminalTitle clears a title set during the session
+func resetTerminalTitle(w io.Writer) {
+	if titleSet {
+		fmt.Fprint(w, "\033]2;\007")
+		titleSet = false
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/mevdschee/p2pquic-go/pkg/p2pquic"
+	"github.com/mevdschee/underground-node-network/internal/nat"
+	"github.com/quic-go/quic-go"
+)
+
+// globalQUICTuning holds the QUIC transport parameters of room connections,
+// set with the -quic-* flags (the keepalive follows -nat-type by default)
+var globalQUICTuning = nat.DefaultQUICTuning
+
+// Hole-punching sends a few packets to every candidate before dialing, like
+// p2pquic's Connect, and then gives the room's punches time to arrive
+const (
+	punchRounds   = 5
+	punchInterval = 100 * time.Millisecond
+)
+
+var punchSettle = 2 * time.Second
+
+// All candidates share one deadline, so a long list cannot stall the
+// teleport; a single attempt still gets at most candidateDialTimeout
+var roomDialTimeout = 15 * time.Second
+
+const candidateDialTimeout = 5 * time.Second
+
+// roomTLSConfig returns the client config for a room's QUIC listener
+func roomTLSConfig() *tls.Config {
+	return &tls.Config{
+		InsecureSkipVerify: true, // the room is verified by its SSH host key
+		NextProtos:         []string{"p2pquic"},
+	}
+}
+
+// punchCandidates opens our NAT towards the room's candidates
+func punchCandidates(conn net.PacketConn, candidates []p2pquic.Candidate) {
+	var addrs []*net.UDPAddr
+	for _, c := range candidates {
+		addr, err := net.ResolveUDPAddr("udp4", net.JoinHostPort(c.IP, fmt.Sprint(c.Port)))
+		if err != nil {
+			continue
+		}
+		addrs = append(addrs, addr)
+	}
+	for i := 0; i < punchRounds; i++ {
+		for _, addr := range addrs {
+			conn.WriteTo([]byte("PUNCH"), addr)
+		}
+		time.Sleep(punchInterval)
+	}
+}
+
+// dialRoom hole-punches the room's candidates from conn and connects to the
+// first one that answers, with the tuned QUIC config
+func dialRoom(conn net.PacketConn, candidates []p2pquic.Candidate, tlsConf *tls.Config) (*quic.Conn, error) {
+	punchCandidates(conn, candidates)
+	time.Sleep(punchSettle)
+
+	quicConf := globalQUICTuning.Config()
+	deadline := time.Now().Add(roomDialTimeout)
+	for _, c := range candidates {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, fmt.Errorf("no candidate answered within %s", roomDialTimeout)
+		}
+		addr, err := net.ResolveUDPAddr("udp4", net.JoinHostPort(c.IP, fmt.Sprint(c.Port)))
+		if err != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), min(remaining, candidateDialTimeout))
+		quicConn, err := quic.Dial(ctx, conn, addr, tlsConf, quicConf)
+		cancel()
+		if err != nil {
+			log.Printf("Failed to connect to %s: %v", nat.RedactCandidate(addr.String()), err)
+			continue
+		}
+		return quicConn, nil
+	}
+	return nil, fmt.Errorf("failed to connect to any candidate")
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mevdschee/p2pquic-go/pkg/p2pquic"
+	"github.com/mevdschee/underground-node-network/internal/nat"
+	"github.com/quic-go/quic-go"
+)
+
+func testRoomTLSConfig(t *testing.T) *tls.Config {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := x509.Certificate{SerialNumber: big.NewInt(1), NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		NextProtos:   []string{"p2pquic"},
+	}
+}
+
+func TestDialRoomTuning(t *testing.T) {
+	oldSettle, oldTuning := punchSettle, globalQUICTuning
+	punchSettle = 0
+	defer func() { punchSettle, globalQUICTuning = oldSettle, oldTuning }()
+
+	// The room writes to a stream the client never reads, so it is only
+	// blocked by the client's stream window
+	ln, err := quic.ListenAddr("127.0.0.1:0", testRoomTLSConfig(t), nil)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+	written := make(chan int, 1)
+	go func() {
+		conn, err := ln.Accept(context.Background())
+		if err != nil {
+			return
+		}
+		stream, err := conn.OpenStream()
+		if err != nil {
+			return
+		}
+		stream.SetWriteDeadline(time.Now().Add(time.Second))
+		buf := make([]byte, 16<<10)
+		n := 0
+		for n < 4<<20 {
+			m, err := stream.Write(buf)
+			n += m
+			if err != nil {
+				break
+			}
+		}
+		written <- n
+	}()
+
+	const window = 2 << 20
+	globalQUICTuning = nat.QUICTuning{MaxIdleTimeout: time.Second, StreamWindow: window}
+	udpConn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Failed to bind: %v", err)
+	}
+	defer udpConn.Close()
+	addr := ln.Addr().(*net.UDPAddr)
+	conn, err := dialRoom(udpConn, []p2pquic.Candidate{{IP: addr.IP.String(), Port: addr.Port}}, roomTLSConfig())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.CloseWithError(0, "")
+
+	t.Run("stream window", func(t *testing.T) {
+		if n := <-written; n < window || n >= 2*window {
+			t.Errorf("Expected the room to write about %d unread bytes, got %d", window, n)
+		}
+	})
+
+	t.Run("idle timeout", func(t *testing.T) {
+		// Without a keepalive the connection closes after the idle timeout
+		// instead of the 5 minute default
+		select {
+		case <-conn.Context().Done():
+		case <-time.After(5 * time.Second):
+			t.Error("Connection did not close after the 1s idle timeout")
+		}
+	})
+}
+
+func TestDialRoomDeadline(t *testing.T) {
+	oldSettle, oldTimeout := punchSettle, roomDialTimeout
+	punchSettle, roomDialTimeout = 0, 300*time.Millisecond
+	defer func() { punchSettle, roomDialTimeout = oldSettle, oldTimeout }()
+
+	// Candidates that swallow every packet, like a room behind a closed NAT
+	var candidates []p2pquic.Candidate
+	for i := 0; i < 10; i++ {
+		silent, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		if err != nil {
+			t.Fatalf("Failed to bind: %v", err)
+		}
+		defer silent.Close()
+		addr := silent.LocalAddr().(*net.UDPAddr)
+		candidates = append(candidates, p2pquic.Candidate{IP: addr.IP.String(), Port: addr.Port})
+	}
+	udpConn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Failed to bind: %v", err)
+	}
+	defer udpConn.Close()
+
+	start := time.Now()
+	if _, err := dialRoom(udpConn, candidates, roomTLSConfig()); err == nil {
+		t.Fatal("Expected the dial to fail")
+	}
+	// Punching takes punchRounds*punchInterval, the dials share the deadline
+	if elapsed := time.Since(start) - punchRounds*punchInterval; elapsed > time.Second {
+		t.Errorf("Expected all candidates to give up within %s, took %s", roomDialTimeout, elapsed)
+	}
+}
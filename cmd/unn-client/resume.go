@@ -0,0 +1,35 @@
+package main
+
+import (
+	"crypto/tls"
+	"sync"
+)
+
+// globalQUICResume is set with -quic-resume. Rooms do not accept 0-RTT early
+// data, so this only resumes the TLS session.
+var globalQUICResume bool
+
+// roomSessions holds the TLS session tickets of the rooms we connected to in
+// this process, keyed by room peer id, so reconnecting resumes the session
+type roomSessions struct {
+	mu     sync.Mutex
+	caches map[string]tls.ClientSessionCache
+}
+
+var globalRoomSessions = &roomSessions{caches: make(map[string]tls.ClientSessionCache)}
+
+// tlsConfig returns a client config that stores and reuses the room's tickets
+func (r *roomSessions) tlsConfig(roomPeerID string) *tls.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cache, ok := r.caches[roomPeerID]
+	if !ok {
+		cache = tls.NewLRUClientSessionCache(1)
+		r.caches[roomPeerID] = cache
+	}
+	return &tls.Config{
+		InsecureSkipVerify: true, // the room is verified by its SSH host key
+		NextProtos:         []string{"p2pquic"},
+		ClientSessionCache: cache,
+	}
+}
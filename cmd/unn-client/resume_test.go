@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mevdschee/p2pquic-go/pkg/p2pquic"
+	"github.com/quic-go/quic-go"
+)
+
+func testRoomListener(t *testing.T) *quic.Listener {
+	ln, err := quic.ListenAddr("127.0.0.1:0", testRoomTLSConfig(t), nil)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	// Echo one byte per stream so the client knows the handshake completed
+	go func() {
+		for {
+			conn, err := ln.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			go func() {
+				stream, err := conn.AcceptStream(context.Background())
+				if err != nil {
+					return
+				}
+				buf := make([]byte, 1)
+				io.ReadFull(stream, buf)
+				stream.Write(buf)
+				stream.Close()
+			}()
+		}
+	}()
+	return ln
+}
+
+func TestRoomSessionResumption(t *testing.T) {
+	ln := testRoomListener(t)
+	addr := ln.Addr().(*net.UDPAddr)
+	candidates := []p2pquic.Candidate{{IP: addr.IP.String(), Port: addr.Port}}
+	sessions := &roomSessions{caches: make(map[string]tls.ClientSessionCache)}
+	oldSettle := punchSettle
+	punchSettle = 0
+	defer func() { punchSettle = oldSettle }()
+
+	connect := func(roomPeerID string) bool {
+		t.Helper()
+		udpConn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		if err != nil {
+			t.Fatalf("Failed to bind: %v", err)
+		}
+		defer udpConn.Close()
+		conn, err := dialRoom(udpConn, candidates, sessions.tlsConfig(roomPeerID))
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer conn.CloseWithError(0, "")
+		stream, err := conn.OpenStreamSync(context.Background())
+		if err != nil {
+			t.Fatalf("Failed to open stream: %v", err)
+		}
+		stream.Write([]byte{1})
+		if _, err := io.ReadFull(stream, make([]byte, 1)); err != nil {
+			t.Fatalf("Failed to read echo: %v", err)
+		}
+		// The ticket arrives after the handshake; give it a moment
+		time.Sleep(100 * time.Millisecond)
+		return conn.ConnectionState().TLS.DidResume
+	}
+
+	t.Run("first connect does a full handshake", func(t *testing.T) {
+		if connect("room-a") {
+			t.Error("Expected no resumption without a cached ticket")
+		}
+	})
+
+	t.Run("reconnect resumes", func(t *testing.T) {
+		if !connect("room-a") {
+			t.Error("Expected the cached ticket to be reused")
+		}
+	})
+
+	t.Run("tickets are per room", func(t *testing.T) {
+		if connect("room-b") {
+			t.Error("Expected another room not to reuse room-a's ticket")
+		}
+	})
+}
@@ -1,9 +1,9 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -19,43 +19,29 @@ import (
 	"github.com/mevdschee/p2pquic-go/pkg/p2pquic"
 	"github.com/mevdschee/underground-node-network/internal/nat"
 	"github.com/mevdschee/underground-node-network/internal/protocol"
+	"github.com/mevdschee/underground-node-network/internal/ui/password"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/term"
 )
 
 func runRoomSSH(candidates []string, sshPort int, hostKeys []string, entrypointConfig *ssh.ClientConfig, identPath string, verbose bool, normalState *term.State, batch bool) (bool, *protocol.PopupPayload, error) {
 	if verbose {
-		log.Printf("Got connection info: candidates=%v port=%d keys=%d", candidates, sshPort, len(hostKeys))
-	}
-
-	// Prepare host key callback
-	parsedHostKeys := make([]ssh.PublicKey, 0, len(hostKeys))
-	for _, keyStr := range hostKeys {
-		pubKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(keyStr))
-		if err == nil {
-			parsedHostKeys = append(parsedHostKeys, pubKey)
-		}
-	}
-
-	hostKeyCallback := func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-		keyBytes := key.Marshal()
-		for _, hk := range parsedHostKeys {
-			if bytes.Equal(hk.Marshal(), keyBytes) {
-				return nil
-			}
-		}
-		return fmt.Errorf("host key mismatch")
+		log.Printf("Got connection info: candidates=%v port=%d keys=%d", nat.RedactCandidates(candidates), sshPort, len(hostKeys))
 	}
 
 	// Use the same auth as the entrypoint
 	config := &ssh.ClientConfig{
 		User:            entrypointConfig.User,
 		Auth:            entrypointConfig.Auth,
-		HostKeyCallback: hostKeyCallback,
+		HostKeyCallback: roomHostKeyCallback(hostKeys),
 		Timeout:         10 * time.Second,
 		ClientVersion:   "SSH-2.0-UNN-CLIENT",
 	}
 
+	if globalMaxRoomCandidates > 0 && len(candidates) > globalMaxRoomCandidates {
+		candidates = candidates[:globalMaxRoomCandidates]
+	}
+
 	// Try each candidate
 	var lastErr error
 	for _, candidate := range candidates {
@@ -84,7 +70,7 @@ func runRoomSSH(candidates []string, sshPort int, hostKeys []string, entrypointC
 
 		if roomPeerID == "" {
 			if verbose {
-				log.Printf("Could not extract room peer ID from candidate: %s", candidate)
+				log.Printf("Could not extract room peer ID from candidate: %s", nat.RedactCandidate(candidate))
 			}
 			continue
 		}
@@ -151,8 +137,17 @@ func runRoomSSH(candidates []string, sshPort int, hostKeys []string, entrypointC
 			log.Printf("Registered client with signaling server")
 		}
 
-		// Connect to room via p2pquic
-		quicConn, err := p2pPeer.Connect(roomPeerID)
+		// Connect to room via p2pquic, with the tuned QUIC config
+		roomPeer, err := p2pquic.NewSignalingClient(signalingURL).GetPeer(roomPeerID)
+		if err != nil {
+			p2pPeer.Close()
+			lastErr = err
+			if verbose {
+				log.Printf("Failed to get room peer info for %s: %v", roomPeerID, err)
+			}
+			continue
+		}
+		quicConn, err := dialRoom(p2pPeer.GetUDPConn(), roomPeer.Candidates, roomTLSConfig())
 		if err != nil {
 			p2pPeer.Close()
 			lastErr = err
@@ -200,6 +195,7 @@ func runRoomSSH(candidates []string, sshPort int, hostKeys []string, entrypointC
 
 		stdout, _ := session.StdoutPipe()
 		stderr, _ := session.StderrPipe()
+		roomOutput := requestCompression(session, stdout, verbose)
 
 		fd := int(os.Stdin.Fd())
 		w, h, err := term.GetSize(fd)
@@ -215,18 +211,7 @@ func runRoomSSH(candidates []string, sshPort int, hostKeys []string, entrypointC
 			return false, nil, fmt.Errorf("failed to start session: %w", err)
 		}
 		// Handle window changes
-		winch := make(chan os.Signal, 1)
-		signal.Notify(winch, syscall.SIGWINCH)
-		defer signal.Stop(winch)
-
-		go func() {
-			for range winch {
-				w, h, err := term.GetSize(fd)
-				if err == nil {
-					session.WindowChange(h, w)
-				}
-			}
-		}()
+		defer forwardWindowChanges(session, fd)()
 
 		// Track session state
 		var (
@@ -242,7 +227,7 @@ func runRoomSSH(candidates []string, sshPort int, hostKeys []string, entrypointC
 			var inOSC bool
 
 			for {
-				n, err := stdout.Read(buf)
+				n, err := roomOutput.Read(buf)
 				if err != nil {
 					return
 				}
@@ -311,10 +296,68 @@ func runRoomSSH(candidates []string, sshPort int, hostKeys []string, entrypointC
 	return false, nil, fmt.Errorf("no candidates available")
 }
 
-func loadKey(path string) (ssh.Signer, error) {
+// loadKey reads a private key. An encrypted key asks for its passphrase on
+// the terminal when prompt is set.
+func loadKey(path string, prompt bool) (ssh.Signer, error) {
 	keyBytes, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	return ssh.ParsePrivateKey(keyBytes)
+	signer, err := ssh.ParsePrivateKey(keyBytes)
+	var missing *ssh.PassphraseMissingError
+	if !errors.As(err, &missing) || !prompt {
+		return signer, err
+	}
+	passphrase, err := readPassphrase(fmt.Sprintf("Passphrase for %s: ", path))
+	if err != nil {
+		return nil, err
+	}
+	return ssh.ParsePrivateKeyWithPassphrase(keyBytes, []byte(passphrase))
+}
+
+// readPassphrase reads a masked line from the local terminal
+func readPassphrase(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("key is encrypted and stdin is not a terminal")
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return "", err
+	}
+	defer term.Restore(fd, state)
+	return password.ReadLine(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stderr}, prompt)
+}
+
+// forwardWindowChanges relays terminal resizes to the remote PTY until the
+// returned stop function is called. Resizes keep flowing while stdin is
+// handed elsewhere, so a TUI redrawn mid-download gets the current size.
+func forwardWindowChanges(session *ssh.Session, fd int) func() {
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-winch:
+				if w, h, err := term.GetSize(fd); err == nil {
+					session.WindowChange(h, w)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(winch)
+			close(done)
+		})
+	}
 }
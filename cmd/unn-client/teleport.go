@@ -6,10 +6,10 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"net"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -23,6 +23,67 @@ import (
 
 var globalDownloadsDir string
 
+// globalRoomTheme is the branding pushed by the current room, if any
+var globalRoomTheme protocol.ThemePayload
+
+// Room host keys used to verify signed chat messages, and verification
+// tallies. The OSC parser goroutine updates them while connectToRoom resets
+// and reports them, so they are guarded by roomKeysMu.
+var (
+	roomKeysMu         sync.Mutex
+	globalRoomKeys     []ssh.PublicKey
+	signedVerified     int
+	signedVerifyFailed int
+	rotatedHostKey     ssh.PublicKey // set when the room rotated its key during the visit
+)
+
+// reconnectReason is set when the room asked us to reconnect before closing
+// the connection, so the client joins it again right away
+var reconnectReason string
+
+// globalDumpTeleport is set with -dump-teleport
+var globalDumpTeleport bool
+
+// globalDirect is set with -direct: leaving the room exits the client
+// instead of returning to the entrypoint lobby
+var globalDirect bool
+
+// isDirect reports whether the URL asks for direct mode, either with -direct
+// or with a #direct fragment as in unn://entrypoint/room#direct
+func isDirect(u *url.URL) bool {
+	return globalDirect || u.Fragment == "direct"
+}
+
+// returnToLobby decides whether to reconnect to the entrypoint after a room
+// connection ended. In direct mode the client exits, unless the room asked
+// everyone to reconnect, in which case it goes back to rejoin the room.
+func returnToLobby(direct, rejoin bool) bool {
+	return !direct || rejoin
+}
+
+// dumpTeleport prints the connection data the entrypoint sent for a room.
+// Host keys are shown as fingerprints unless showKeys is set, and candidates
+// follow -redact.
+func dumpTeleport(w io.Writer, data *TeleportData, showKeys bool) {
+	fmt.Fprintf(w, "\r\nTeleport to %s:\r\n", data.RoomName)
+	fmt.Fprintf(w, "  SSH port: %d\r\n", data.SSHPort)
+	fmt.Fprintf(w, "  Candidates (%d):\r\n", len(data.Candidates))
+	for _, c := range nat.RedactCandidates(data.Candidates) {
+		fmt.Fprintf(w, "    %s\r\n", c)
+	}
+	fmt.Fprintf(w, "  Host keys (%d):\r\n", len(data.PublicKeys))
+	for _, k := range data.PublicKeys {
+		switch key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(k)); {
+		case err != nil:
+			fmt.Fprintf(w, "    invalid key: %v\r\n", err)
+		case showKeys:
+			fmt.Fprintf(w, "    %s\r\n", strings.TrimSpace(k))
+		default:
+			fmt.Fprintf(w, "    %s %s\r\n", key.Type(), ssh.FingerprintSHA256(key))
+		}
+	}
+}
+
 // TeleportData received via OSC from server
 type TeleportData struct {
 	RoomName   string   `json:"room_name"`
@@ -31,9 +92,10 @@ type TeleportData struct {
 	PublicKeys []string `json:"public_keys,omitempty"`
 }
 
-func teleport(unnUrl string, identPath string, verbose bool, batch bool, downloadsDir string) error {
+func teleport(unnUrl string, identPath string, verbose bool, batch bool, downloadsDir string, rememberRoom bool) error {
 	globalDownloadsDir = downloadsDir
-	// Parse the SSH URL
+	// Parse the SSH URL, after taking out any fallback hosts
+	unnUrl, urlFallbacks := splitURLEntrypoints(unnUrl)
 	u, err := url.Parse(unnUrl)
 	if err != nil {
 		return fmt.Errorf("invalid URL: %w", err)
@@ -44,15 +106,12 @@ func teleport(unnUrl string, identPath string, verbose bool, batch bool, downloa
 	}
 
 	// Extract components
-	entrypoint := u.Host
-	if entrypoint == "" {
+	if u.Host == "" {
 		return fmt.Errorf("no entrypoint hostname specified")
 	}
 
-	// Default port if not specified
-	if !strings.Contains(entrypoint, ":") {
-		entrypoint += ":44322"
-	}
+	// The URL's entrypoint comes first, then the fallbacks in order
+	entrypoints := entrypointCandidates(append(append([]string{u.Host}, urlFallbacks...), globalEntrypoints...)...)
 
 	username := u.User.Username()
 	if username == "" {
@@ -63,21 +122,35 @@ func teleport(unnUrl string, identPath string, verbose bool, batch bool, downloa
 	}
 
 	roomName := strings.TrimPrefix(u.Path, "/")
+	direct := isDirect(u)
+
+	// Remember the last joined room so it can be offered again at the entrypoint
+	lastRoom := ""
+	rejoin := false
+	if rememberRoom {
+		lastRoom = loadLastRoom(lastRoomPath())
+	}
 
 	if verbose {
-		log.Printf("Connecting to entry point: %s@%s", username, entrypoint)
+		log.Printf("Connecting to entry point: %s@%s", username, entrypoints[0])
+		if len(entrypoints) > 1 {
+			log.Printf("Fallback entry points: %s", strings.Join(entrypoints[1:], ", "))
+		}
 		if roomName != "" {
 			log.Printf("Target room: %s", roomName)
 		} else {
 			log.Printf("Interactive selection mode")
 		}
+		if direct {
+			log.Printf("Direct mode: exiting when leaving the room")
+		}
 	}
 
 	// Load identity key
 	var authMethods []ssh.AuthMethod
 
 	if identPath != "" {
-		signer, err := loadKey(identPath)
+		signer, err := loadKey(identPath, !batch)
 		if err != nil {
 			return fmt.Errorf("failed to load identity key: %w", err)
 		}
@@ -92,7 +165,7 @@ func teleport(unnUrl string, identPath string, verbose bool, batch bool, downloa
 		}
 
 		for _, keyPath := range possibleKeys {
-			signer, err := loadKey(keyPath)
+			signer, err := loadKey(keyPath, !batch)
 			if err == nil {
 				authMethods = append(authMethods, ssh.PublicKeys(signer))
 				if verbose {
@@ -127,31 +200,6 @@ func teleport(unnUrl string, identPath string, verbose bool, batch bool, downloa
 		}
 	}
 
-	// Resolve to IPv4 only
-	host, port, err := net.SplitHostPort(entrypoint)
-	if err != nil {
-		return fmt.Errorf("invalid entrypoint address: %w", err)
-	}
-
-	ips, err := net.LookupIP(host)
-	if err != nil {
-		return fmt.Errorf("failed to resolve %s: %w", host, err)
-	}
-
-	var ipv4Addr string
-	for _, ip := range ips {
-		if ip.To4() != nil {
-			ipv4Addr = ip.String()
-			break
-		}
-	}
-
-	if ipv4Addr == "" {
-		return fmt.Errorf("no IPv4 address found for %s", host)
-	}
-
-	ipv4Address := net.JoinHostPort(ipv4Addr, port)
-
 	// Mutex-protected current stdin destination
 	var stdinMu sync.Mutex
 	var currentStdin io.Writer
@@ -176,13 +224,17 @@ func teleport(unnUrl string, identPath string, verbose bool, batch bool, downloa
 
 	// Main loop - reconnect to entrypoint after disconnecting from room
 	for {
-		entrypointSSH, err := ssh.Dial("tcp", ipv4Address, config)
+		entrypointSSH, used, err := dialEntrypoint(entrypoints, config, verbose)
 		if err != nil {
 			return fmt.Errorf("failed to connect to entrypoint: %w", err)
 		}
 
 		if verbose {
-			log.Printf("Connected to entrypoint")
+			log.Printf("Connected to entrypoint %s", entrypoints[used])
+		}
+		// Reconnect to the entrypoint that worked before trying the others
+		if used > 0 {
+			entrypoints = append([]string{entrypoints[used]}, slices.Delete(entrypoints, used, used+1)...)
 		}
 
 		// Create a new session for the interactive TUI
@@ -239,6 +291,11 @@ func teleport(unnUrl string, identPath string, verbose bool, batch bool, downloa
 			return fmt.Errorf("failed to start shell: %w", err)
 		}
 
+		stopResize := func() {}
+		if !batch {
+			stopResize = forwardWindowChanges(session, fd)
+		}
+
 		// If user specified a room on first connection, send join command
 		if roomName != "" {
 			go func(room string) {
@@ -246,6 +303,16 @@ func teleport(unnUrl string, identPath string, verbose bool, batch bool, downloa
 				stdin.Write([]byte("/join " + room + "\r"))
 			}(roomName)
 			roomName = "" // Only auto-join on first connection
+		} else if rejoin && lastRoom != "" {
+			go func(room string) {
+				stdin.Write([]byte("/join " + room + "\r"))
+			}(lastRoom)
+			rejoin = false
+		} else if lastRoom != "" && !batch {
+			// Pre-fill the input with a join to the previous room, Enter to rejoin
+			go func(room string) {
+				stdin.Write([]byte("/join " + room))
+			}(lastRoom)
 		}
 
 		// Set current stdin destination
@@ -274,12 +341,26 @@ func teleport(unnUrl string, identPath string, verbose bool, batch bool, downloa
 		select {
 		case teleportData = <-teleportChan:
 			// We received teleport data - connect to room via p2pquic
+			stopResize()
 			stdinMu.Lock()
 			currentStdin = nil
 			stdinMu.Unlock()
 			session.Close()
 
+			if globalDumpTeleport {
+				dumpTeleport(os.Stderr, teleportData, verbose)
+			}
+
+			lastRoom = teleportData.RoomName
+			if rememberRoom {
+				if err := saveLastRoom(lastRoomPath(), lastRoom); err != nil && verbose {
+					log.Printf("Failed to save last room: %v", err)
+				}
+			}
+
+			reconnectReason = ""
 			err := connectToRoom(entrypointSSH, config, teleportData, verbose, batch, &stdinMu, &currentStdin)
+			rejoin = reconnectReason != ""
 			entrypointSSH.Close()
 
 			// Clear stdin destination and allow buffered input to be discarded
@@ -287,31 +368,28 @@ func teleport(unnUrl string, identPath string, verbose bool, batch bool, downloa
 			currentStdin = nil
 			stdinMu.Unlock()
 
+			// After room disconnect, reconnect to entrypoint
+			shouldReconnect = returnToLobby(direct, rejoin)
+
 			if err != nil {
+				if !shouldReconnect {
+					return fmt.Errorf("room connection error: %w", err)
+				}
 				log.Printf("Room connection error: %v", err)
 			}
 
-			// After room disconnect, reconnect to entrypoint
-			shouldReconnect = true
-
 		case err := <-sessionDone:
+			stopResize()
 			stdinMu.Lock()
 			currentStdin = nil
 			stdinMu.Unlock()
 			session.Close()
 			entrypointSSH.Close()
 
-			if err != nil {
-				if _, ok := err.(*ssh.ExitError); ok {
-					// Normal exit - user quit
-					return nil
-				}
-				if err.Error() == "wait: remote command exited without exit status or exit signal" {
-					return nil
-				}
+			// Clean and signaled exits mean the user quit the entrypoint UI
+			if classifyExit(err) == exitAbnormal {
 				return fmt.Errorf("session error: %w", err)
 			}
-			// Clean exit from entrypoint UI
 			return nil
 		}
 
@@ -343,14 +421,22 @@ func parseOSCOutput(r io.Reader, w io.Writer, onTeleport func(*TeleportData)) {
 				if inOSC {
 					if data[i] == 0x07 { // BEL - end of OSC
 						oscBuffer = append(oscBuffer, data[writeStart:i]...)
-						handleOSC(oscBuffer, onTeleport)
+						if isClipboardSet(oscBuffer) {
+							fmt.Fprintf(w, "\x1b]%s\x07", oscBuffer)
+						} else {
+							handleOSC(oscBuffer, onTeleport)
+						}
 						oscBuffer = oscBuffer[:0]
 						inOSC = false
 						writeStart = i + 1
 					} else if data[i] == 0x1b && i+1 < len(data) && data[i+1] == '\\' {
 						// ST (\x1b\\) - alternative end of OSC
 						oscBuffer = append(oscBuffer, data[writeStart:i]...)
-						handleOSC(oscBuffer, onTeleport)
+						if isClipboardSet(oscBuffer) {
+							fmt.Fprintf(w, "\x1b]%s\x07", oscBuffer)
+						} else {
+							handleOSC(oscBuffer, onTeleport)
+						}
 						oscBuffer = oscBuffer[:0]
 						inOSC = false
 						writeStart = i + 2
@@ -383,6 +469,18 @@ func parseOSCOutput(r io.Reader, w io.Writer, onTeleport func(*TeleportData)) {
 	}
 }
 
+// isClipboardSet reports whether an OSC body is an OSC 52 clipboard write.
+// Those are passed through to the terminal; clipboard queries ("?") are not,
+// so a room can never read the user's clipboard.
+func isClipboardSet(data []byte) bool {
+	content := string(data)
+	if !strings.HasPrefix(content, "52;") {
+		return false
+	}
+	_, text, ok := strings.Cut(content[3:], ";")
+	return ok && text != "?"
+}
+
 func handleOSC(data []byte, onTeleport func(*TeleportData)) {
 	// OSC format: 31337;{"action":"teleport",...}
 	content := string(data)
@@ -421,6 +519,30 @@ func handleOSC(data []byte, onTeleport func(*TeleportData)) {
 			}
 		}
 		onTeleport(teleportData)
+	} else if action == "theme" {
+		var theme protocol.ThemePayload
+		if err := json.Unmarshal([]byte(jsonData), &theme); err == nil {
+			globalRoomTheme = theme
+		}
+	} else if action == "signed_message" {
+		var msg protocol.SignedMessagePayload
+		if err := json.Unmarshal([]byte(jsonData), &msg); err == nil {
+			verifySignedMessage(&msg)
+		}
+	} else if action == "host_key" {
+		var msg protocol.HostKeyPayload
+		if err := json.Unmarshal([]byte(jsonData), &msg); err == nil {
+			acceptHostKeyRotation(&msg)
+		}
+	} else if action == "reconnect" {
+		reconnectReason, _ = payload["reason"].(string)
+		if reconnectReason == "" {
+			reconnectReason = "no reason given"
+		}
+	} else if action == "title" {
+		if title, ok := payload["title"].(string); ok {
+			setTerminalTitle(os.Stdout, title)
+		}
 	} else if action == "transfer_block" {
 		// Handle file download blocks
 		var blockPayload protocol.FileBlockPayload
@@ -430,6 +552,63 @@ func handleOSC(data []byte, onTeleport func(*TeleportData)) {
 	}
 }
 
+// resetRoomKeys trusts the room host keys from the entrypoint for a new
+// visit and clears the tallies of the previous one
+func resetRoomKeys(publicKeys []string) {
+	roomKeysMu.Lock()
+	defer roomKeysMu.Unlock()
+	globalRoomKeys = nil
+	signedVerified, signedVerifyFailed = 0, 0
+	rotatedHostKey = nil
+	for _, k := range publicKeys {
+		if key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(k)); err == nil {
+			globalRoomKeys = append(globalRoomKeys, key)
+		}
+	}
+}
+
+// roomKeyTallies returns how many signed messages verified and failed during
+// the visit, and the new host key if the room rotated it
+func roomKeyTallies() (verified, failed int, rotated ssh.PublicKey) {
+	roomKeysMu.Lock()
+	defer roomKeysMu.Unlock()
+	return signedVerified, signedVerifyFailed, rotatedHostKey
+}
+
+// verifySignedMessage checks a room-signed chat message against the room's registered host keys
+func verifySignedMessage(msg *protocol.SignedMessagePayload) {
+	roomKeysMu.Lock()
+	defer roomKeysMu.Unlock()
+	for _, key := range globalRoomKeys {
+		if msg.Verify(key) == nil {
+			signedVerified++
+			return
+		}
+	}
+	signedVerifyFailed++
+	log.Printf("Warning: message from %s failed signature verification", msg.Sender)
+}
+
+// acceptHostKeyRotation trusts a new room host key for the rest of the visit,
+// but only if the announcement is signed by a key the entrypoint vouched for
+func acceptHostKeyRotation(msg *protocol.HostKeyPayload) bool {
+	newKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(msg.PublicKey))
+	if err != nil {
+		return false
+	}
+	roomKeysMu.Lock()
+	defer roomKeysMu.Unlock()
+	for _, key := range globalRoomKeys {
+		if msg.Verify(key) == nil {
+			globalRoomKeys = append(globalRoomKeys, newKey)
+			rotatedHostKey = newKey
+			return true
+		}
+	}
+	log.Printf("Warning: ignoring host key rotation that is not signed by the room key")
+	return false
+}
+
 func connectToRoom(entrypointSSH *ssh.Client, config *ssh.ClientConfig, teleportData *TeleportData, verbose, batch bool, stdinMu *sync.Mutex, currentStdin *io.Writer) error {
 	// Suppress log output during connection unless verbose
 	if !verbose {
@@ -437,6 +616,129 @@ func connectToRoom(entrypointSSH *ssh.Client, config *ssh.ClientConfig, teleport
 		defer log.SetOutput(os.Stderr)
 	}
 
+	roomSSHClient, cleanup, err := dialRoomQUIC(entrypointSSH, config, teleportData, verbose)
+	if err != nil {
+		targets, fallbackErr := directFallback(err, globalAllowDirectSSH, teleportData)
+		if fallbackErr != nil {
+			return fallbackErr
+		}
+		if verbose {
+			log.Printf("QUIC connection failed (%v), trying direct SSH", err)
+		}
+		roomSSHClient, err = dialRoomDirect(targets, config, teleportData, verbose)
+		if err != nil {
+			return fmt.Errorf("failed to connect via p2pquic or direct SSH: %w", err)
+		}
+		cleanup = func() {}
+	}
+	defer cleanup()
+	defer roomSSHClient.Close()
+
+	// Open a session
+	session, err := roomSSHClient.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to create room session: %w", err)
+	}
+	defer session.Close()
+
+	// Get stdin pipe for room session
+	roomStdin, err := session.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to get room stdin pipe: %w", err)
+	}
+
+	roomStdout, err := session.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to get room stdout pipe: %w", err)
+	}
+
+	session.Stderr = os.Stderr
+
+	// Room keys from the entrypoint, used to verify signed messages
+	globalRoomTheme = protocol.ThemePayload{}
+	resetRoomKeys(teleportData.PublicKeys)
+
+	// Parse OSC output from room for file transfers (no teleport handler for rooms)
+	go parseOSCOutput(requestCompression(session, roomStdout, verbose), os.Stdout, func(data *TeleportData) {})
+
+	// Request PTY
+	fd := int(os.Stdin.Fd())
+	width, height := 80, 24
+	if !batch {
+		var w, h int
+		w, h, err = term.GetSize(fd)
+		if err == nil {
+			width, height = w, h
+		}
+	}
+
+	if err := session.RequestPty("xterm-256color", height, width, ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
+	}); err != nil {
+		return fmt.Errorf("failed to request PTY: %w", err)
+	}
+
+	if err := session.Shell(); err != nil {
+		return fmt.Errorf("failed to start shell: %w", err)
+	}
+
+	if !batch {
+		defer forwardWindowChanges(session, fd)()
+	}
+
+	// Set room stdin as current destination
+	stdinMu.Lock()
+	*currentStdin = roomStdin
+	stdinMu.Unlock()
+
+	// Wait for session to end
+	err = session.Wait()
+
+	verified, failed, rotated := roomKeyTallies()
+	if verbose && verified > 0 {
+		log.Printf("Verified %d signed messages", verified)
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "\r\nWarning: %d room messages failed signature verification\r\n", failed)
+	}
+	for _, msg := range takeFailedDownloads() {
+		fmt.Fprintf(os.Stderr, "\r\nWarning: %s\r\n", msg)
+	}
+	if rotated != nil {
+		fmt.Fprintf(os.Stderr, "\r\nNote: the room rotated its host key, new fingerprint %s\r\n", ssh.FingerprintSHA256(rotated))
+	}
+
+	if reconnectReason != "" {
+		fmt.Fprintf(os.Stderr, "\r\nThe room asked everyone to reconnect (%s), rejoining...\r\n", reconnectReason)
+		return nil
+	}
+
+	// Leaving the room, or being kicked, ends the session without an error
+	if classifyExit(err) == exitAbnormal {
+		return fmt.Errorf("session error: %w", err)
+	}
+
+	return nil
+}
+
+// dialRoomQUIC punches through to the room with p2pquic and starts SSH over a
+// QUIC stream. cleanup releases the peer, signaling and QUIC connection once
+// the returned client is done.
+func dialRoomQUIC(entrypointSSH *ssh.Client, config *ssh.ClientConfig, teleportData *TeleportData, verbose bool) (client *ssh.Client, cleanup func(), err error) {
+	var closers []func()
+	cleanup = func() {
+		for i := len(closers) - 1; i >= 0; i-- {
+			closers[i]()
+		}
+	}
+	defer func() {
+		if err != nil {
+			cleanup()
+		}
+	}()
+
 	// Create p2pquic peer for client
 	clientID := fmt.Sprintf("client-%d", time.Now().UnixNano())
 	p2pConfig := p2pquic.Config{
@@ -447,13 +749,13 @@ func connectToRoom(entrypointSSH *ssh.Client, config *ssh.ClientConfig, teleport
 	}
 	p2pPeer, err := p2pquic.NewPeer(p2pConfig)
 	if err != nil {
-		return fmt.Errorf("failed to create p2pquic peer: %w", err)
+		return nil, nil, fmt.Errorf("failed to create p2pquic peer: %w", err)
 	}
-	defer p2pPeer.Close()
+	closers = append(closers, func() { p2pPeer.Close() })
 
 	// Bind the UDP socket first to get the actual port assigned by the OS
 	if err := p2pPeer.Bind(); err != nil {
-		return fmt.Errorf("failed to bind p2pquic socket: %w", err)
+		return nil, nil, fmt.Errorf("failed to bind p2pquic socket: %w", err)
 	}
 
 	if verbose {
@@ -461,9 +763,10 @@ func connectToRoom(entrypointSSH *ssh.Client, config *ssh.ClientConfig, teleport
 	}
 
 	// Discover client candidates (now with actual port)
-	clientCandidates, err := p2pPeer.DiscoverCandidates()
+	ownCandidates := newLocalCandidates(p2pPeer, globalRestunAfter)
+	clientCandidates, _, err := ownCandidates.get()
 	if err != nil {
-		return fmt.Errorf("failed to discover candidates: %w", err)
+		return nil, nil, fmt.Errorf("failed to discover candidates: %w", err)
 	}
 
 	if verbose {
@@ -473,18 +776,41 @@ func connectToRoom(entrypointSSH *ssh.Client, config *ssh.ClientConfig, teleport
 	// Register client with signaling via SSH
 	signalingClient, err := nat.NewSSHSignalingClient(entrypointSSH)
 	if err != nil {
-		return fmt.Errorf("failed to create signaling client: %w", err)
+		return nil, nil, fmt.Errorf("failed to create signaling client: %w", err)
 	}
-	defer signalingClient.Close()
+	closers = append(closers, func() { signalingClient.Close() })
 
 	if err := signalingClient.Register(clientID, clientCandidates); err != nil {
-		return fmt.Errorf("failed to register with signaling: %w", err)
+		return nil, nil, fmt.Errorf("failed to register with signaling: %w", err)
 	}
 
 	if verbose {
 		log.Printf("Registered client with signaling (30s TTL)")
 	}
 
+	// Create entrypoint API client for punch request
+	epClient, err := NewEntrypointClient(entrypointSSH)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create entrypoint client for punch coordination: %w", err)
+	}
+	closers = append(closers, func() { epClient.Close() })
+
+	// Signaling setup can be slow; right before the punch, rediscover if our
+	// mappings may have gone stale so the room punches towards our current
+	// address
+	clientCandidates, refreshed, err := ownCandidates.get()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to rediscover candidates: %w", err)
+	}
+	if refreshed {
+		if verbose {
+			log.Printf("Rediscovered %d stale candidates", len(clientCandidates))
+		}
+		if err := signalingClient.Register(clientID, clientCandidates); err != nil {
+			return nil, nil, fmt.Errorf("failed to re-register with signaling: %w", err)
+		}
+	}
+
 	// Request coordinated hole-punching via entrypoint (triggers room registration)
 	clientCandidateStrs := make([]string, len(clientCandidates))
 	for i, c := range clientCandidates {
@@ -495,15 +821,8 @@ func connectToRoom(entrypointSSH *ssh.Client, config *ssh.ClientConfig, teleport
 		log.Printf("Requesting coordinated punch to room %s", teleportData.RoomName)
 	}
 
-	// Create entrypoint API client for punch request
-	epClient, err := NewEntrypointClient(entrypointSSH)
-	if err != nil {
-		return fmt.Errorf("failed to create entrypoint client for punch coordination: %w", err)
-	}
-	defer epClient.Close()
-
 	if err := epClient.RequestPreparePunch(teleportData.RoomName, clientID, clientCandidateStrs); err != nil {
-		return fmt.Errorf("coordinated punch request failed: %w", err)
+		return nil, nil, fmt.Errorf("coordinated punch request failed: %w", err)
 	}
 
 	if verbose {
@@ -517,7 +836,7 @@ func connectToRoom(entrypointSSH *ssh.Client, config *ssh.ClientConfig, teleport
 	roomPeerID := fmt.Sprintf("room-%s", teleportData.RoomName)
 	roomPeerInfo, err := signalingClient.GetPeer(roomPeerID)
 	if err != nil {
-		return fmt.Errorf("failed to get room peer info: %w", err)
+		return nil, nil, fmt.Errorf("failed to get room peer info: %w", err)
 	}
 
 	if verbose {
@@ -532,6 +851,10 @@ func connectToRoom(entrypointSSH *ssh.Client, config *ssh.ClientConfig, teleport
 			Port: c.Port,
 		}
 	}
+	p2pRoomCandidates = limitRoomCandidates(p2pRoomCandidates, localNetworks(), globalMaxRoomCandidates)
+	if len(p2pRoomCandidates) == 0 {
+		return nil, nil, fmt.Errorf("room advertised no usable candidates")
+	}
 
 	// Connect via p2pquic using the peer info we got from SSH signaling
 	if verbose {
@@ -540,11 +863,18 @@ func connectToRoom(entrypointSSH *ssh.Client, config *ssh.ClientConfig, teleport
 
 	// Connect and get the underlying QUIC connection using peer info
 	ctx := context.Background()
-	quicConn, err := p2pPeer.Connect(roomPeerID, p2pquic.WithCandidates(p2pRoomCandidates...))
+	tlsConf := roomTLSConfig()
+	if globalQUICResume {
+		tlsConf = globalRoomSessions.tlsConfig(roomPeerID)
+	}
+	quicConn, err := dialRoom(p2pPeer.GetUDPConn(), p2pRoomCandidates, tlsConf)
 	if err != nil {
-		return fmt.Errorf("failed to connect via p2pquic: %w", err)
+		return nil, nil, fmt.Errorf("failed to connect via p2pquic: %w", err)
 	}
-	defer quicConn.CloseWithError(0, "client disconnecting")
+	if verbose && globalQUICResume {
+		log.Printf("Resumed TLS session: %v", quicConn.ConnectionState().TLS.DidResume)
+	}
+	closers = append(closers, func() { quicConn.CloseWithError(0, "client disconnecting") })
 
 	if verbose {
 		log.Printf("p2pquic connection established")
@@ -553,7 +883,7 @@ func connectToRoom(entrypointSSH *ssh.Client, config *ssh.ClientConfig, teleport
 	// Open a stream for SSH
 	stream, err := quicConn.OpenStreamSync(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to open stream: %w", err)
+		return nil, nil, fmt.Errorf("failed to open stream: %w", err)
 	}
 
 	// Wrap stream as net.Conn for SSH
@@ -562,74 +892,8 @@ func connectToRoom(entrypointSSH *ssh.Client, config *ssh.ClientConfig, teleport
 	// Connect SSH client over the QUIC stream
 	sshConnWrapper, chans, reqs, err := ssh.NewClientConn(sshConn, teleportData.RoomName, config)
 	if err != nil {
-		return fmt.Errorf("failed to establish SSH over p2pquic: %w", err)
-	}
-
-	roomSSHClient := ssh.NewClient(sshConnWrapper, chans, reqs)
-	defer roomSSHClient.Close()
-
-	// Open a session
-	session, err := roomSSHClient.NewSession()
-	if err != nil {
-		return fmt.Errorf("failed to create room session: %w", err)
-	}
-	defer session.Close()
-
-	// Get stdin pipe for room session
-	roomStdin, err := session.StdinPipe()
-	if err != nil {
-		return fmt.Errorf("failed to get room stdin pipe: %w", err)
-	}
-
-	roomStdout, err := session.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("failed to get room stdout pipe: %w", err)
+		return nil, nil, fmt.Errorf("failed to establish SSH over p2pquic: %w", err)
 	}
 
-	session.Stderr = os.Stderr
-
-	// Parse OSC output from room for file transfers (no teleport handler for rooms)
-	go parseOSCOutput(roomStdout, os.Stdout, func(data *TeleportData) {})
-
-	// Request PTY
-	fd := int(os.Stdin.Fd())
-	width, height := 80, 24
-	if !batch {
-		var w, h int
-		w, h, err = term.GetSize(fd)
-		if err == nil {
-			width, height = w, h
-		}
-	}
-
-	if err := session.RequestPty("xterm-256color", height, width, ssh.TerminalModes{
-		ssh.ECHO:          1,
-		ssh.TTY_OP_ISPEED: 14400,
-		ssh.TTY_OP_OSPEED: 14400,
-	}); err != nil {
-		return fmt.Errorf("failed to request PTY: %w", err)
-	}
-
-	if err := session.Shell(); err != nil {
-		return fmt.Errorf("failed to start shell: %w", err)
-	}
-
-	// Set room stdin as current destination
-	stdinMu.Lock()
-	*currentStdin = roomStdin
-	stdinMu.Unlock()
-
-	// Wait for session to end
-	if err := session.Wait(); err != nil {
-		// Exit status errors are normal when user disconnects
-		if _, ok := err.(*ssh.ExitError); ok {
-			// Normal exit
-		} else if err.Error() == "wait: remote command exited without exit status or exit signal" {
-			// Connection closed without clean exit - still normal
-		} else {
-			return fmt.Errorf("session error: %w", err)
-		}
-	}
-
-	return nil
+	return ssh.NewClient(sshConnWrapper, chans, reqs), cleanup, nil
 }
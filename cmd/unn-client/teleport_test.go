@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mevdschee/underground-node-network/internal/protocol"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestParseOSCOutputClipboard(t *testing.T) {
+	t.Run("clipboard set passes through", func(t *testing.T) {
+		var out bytes.Buffer
+		parseOSCOutput(strings.NewReader("a\x1b]52;c;aGVsbG8=\x07b"), &out, nil)
+		if got, want := out.String(), "a\x1b]52;c;aGVsbG8=\x07b"; got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	})
+
+	t.Run("clipboard query is dropped", func(t *testing.T) {
+		var out bytes.Buffer
+		parseOSCOutput(strings.NewReader("a\x1b]52;c;?\x1b\\b"), &out, nil)
+		if got := out.String(); got != "ab" {
+			t.Errorf("Expected clipboard query to be dropped, got %q", got)
+		}
+	})
+
+	t.Run("other sequences are swallowed", func(t *testing.T) {
+		var out bytes.Buffer
+		parseOSCOutput(strings.NewReader("a\x1b]0;title\x07b"), &out, nil)
+		if got := out.String(); got != "ab" {
+			t.Errorf("Expected title sequence to be swallowed, got %q", got)
+		}
+	})
+}
+
+func TestAcceptHostKeyRotation(t *testing.T) {
+	_, oldPriv, _ := ed25519.GenerateKey(rand.Reader)
+	oldSigner, _ := ssh.NewSignerFromKey(oldPriv)
+	_, otherPriv, _ := ed25519.GenerateKey(rand.Reader)
+	otherSigner, _ := ssh.NewSignerFromKey(otherPriv)
+	newPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	newKey, _ := ssh.NewPublicKey(newPub)
+
+	globalRoomKeys = []ssh.PublicKey{oldSigner.PublicKey()}
+	rotatedHostKey = nil
+	defer func() { globalRoomKeys, rotatedHostKey = nil, nil }()
+
+	msg := &protocol.HostKeyPayload{PublicKey: string(ssh.MarshalAuthorizedKey(newKey)), Timestamp: 1700000000}
+
+	msg.Sign(otherSigner)
+	if acceptHostKeyRotation(msg) {
+		t.Errorf("Accepted a rotation signed by an unknown key")
+	}
+
+	msg.Sign(oldSigner)
+	if !acceptHostKeyRotation(msg) {
+		t.Fatalf("Rejected a rotation signed by the room key")
+	}
+	if len(globalRoomKeys) != 2 || rotatedHostKey == nil {
+		t.Errorf("New key was not trusted after rotation")
+	}
+}
+
+func TestSignedMessageTallies(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := ssh.NewSignerFromKey(priv)
+	defer resetRoomKeys(nil)
+
+	good := &protocol.SignedMessagePayload{Sender: "alice", Text: "hi", Timestamp: 1700000000}
+	good.Sign(signer)
+	forged := *good
+	forged.Text = "bye"
+
+	// The OSC parser goroutines verify messages while the tallies are read
+	resetRoomKeys([]string{string(ssh.MarshalAuthorizedKey(signer.PublicKey()))})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				verifySignedMessage(good)
+				verifySignedMessage(&forged)
+				roomKeyTallies()
+			}
+		}()
+	}
+	wg.Wait()
+
+	verified, failed, rotated := roomKeyTallies()
+	if verified != 200 || failed != 200 || rotated != nil {
+		t.Errorf("Expected 200 verified and 200 failed, got %d and %d", verified, failed)
+	}
+	resetRoomKeys(nil)
+	if verified, failed, _ := roomKeyTallies(); verified != 0 || failed != 0 {
+		t.Errorf("Expected the tallies to be cleared for the next visit")
+	}
+}
+
+func TestSetTerminalTitle(t *testing.T) {
+	t.Run("writes OSC 2", func(t *testing.T) {
+		var out bytes.Buffer
+		setTerminalTitle(&out, "UNN: room den")
+		if got, want := out.String(), "\x1b]2;UNN: room den\x07"; got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+		out.Reset()
+		resetTerminalTitle(&out)
+		if got, want := out.String(), "\x1b]2;\x07"; got != want {
+			t.Errorf("Expected reset %q, got %q", want, got)
+		}
+	})
+
+	t.Run("escapes from the room are removed", func(t *testing.T) {
+		var out bytes.Buffer
+		setTerminalTitle(&out, "evil\x07\x1b]52;c;aGk=\x07\nroom")
+		if got, want := out.String(), "\x1b]2;evil room\x07"; got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+		resetTerminalTitle(&bytes.Buffer{})
+	})
+
+	t.Run("disabled with -no-title", func(t *testing.T) {
+		globalSetTitle = false
+		defer func() { globalSetTitle = true }()
+		var out bytes.Buffer
+		setTerminalTitle(&out, "UNN: lobby")
+		resetTerminalTitle(&out)
+		if out.Len() != 0 {
+			t.Errorf("Expected nothing written, got %q", out.String())
+		}
+	})
+}
+
+func TestReconnectRequest(t *testing.T) {
+	reconnectReason = ""
+	defer func() { reconnectReason = "" }()
+
+	handleOSC([]byte(`31337;{"action":"reconnect","room_name":"lounge","reason":"new host key"}`), func(*TeleportData) {})
+	if reconnectReason != "new host key" {
+		t.Errorf("Expected the reconnect reason to be recorded, got %q", reconnectReason)
+	}
+}
+
+func TestDumpTeleport(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	key, _ := ssh.NewPublicKey(pub)
+	authorized := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+	data := &TeleportData{
+		RoomName:   "lounge",
+		Candidates: []string{"203.0.113.7:44323", "192.168.1.20:44323"},
+		SSHPort:    44323,
+		PublicKeys: []string{authorized},
+	}
+
+	t.Run("keys as fingerprints", func(t *testing.T) {
+		var buf bytes.Buffer
+		dumpTeleport(&buf, data, false)
+		out := buf.String()
+		for _, want := range []string{"Teleport to lounge", "SSH port: 44323", "Candidates (2)", "203.0.113.7:44323", ssh.FingerprintSHA256(key)} {
+			if !strings.Contains(out, want) {
+				t.Errorf("Expected %q in dump:\n%s", want, out)
+			}
+		}
+		if strings.Contains(out, authorized) {
+			t.Error("Expected the full key to be redacted")
+		}
+	})
+
+	t.Run("full keys when verbose", func(t *testing.T) {
+		var buf bytes.Buffer
+		dumpTeleport(&buf, data, true)
+		if !strings.Contains(buf.String(), authorized) {
+			t.Errorf("Expected the full key in verbose dump:\n%s", buf.String())
+		}
+	})
+}
+
+func TestDirectMode(t *testing.T) {
+	t.Run("url fragment", func(t *testing.T) {
+		for raw, want := range map[string]bool{
+			"unn://localhost/myroom#direct": true,
+			"unn://localhost/myroom":        false,
+			"unn://localhost/myroom#other":  false,
+		} {
+			u, _ := url.Parse(raw)
+			if got := isDirect(u); got != want {
+				t.Errorf("isDirect(%s) = %v, want %v", raw, got, want)
+			}
+		}
+	})
+
+	t.Run("flag", func(t *testing.T) {
+		globalDirect = true
+		defer func() { globalDirect = false }()
+		u, _ := url.Parse("unn://localhost/myroom")
+		if !isDirect(u) {
+			t.Error("Expected -direct to enable direct mode")
+		}
+	})
+
+	t.Run("leaving the room", func(t *testing.T) {
+		if returnToLobby(true, false) {
+			t.Error("Expected direct mode to exit after the room")
+		}
+		if !returnToLobby(true, true) {
+			t.Error("Expected direct mode to reconnect when the room asks everyone to rejoin")
+		}
+		if !returnToLobby(false, false) {
+			t.Error("Expected normal mode to return to the lobby")
+		}
+	})
+}
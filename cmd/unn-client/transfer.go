@@ -6,11 +6,13 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/mevdschee/underground-node-network/internal/protocol"
 )
@@ -21,14 +23,68 @@ type oscTransferState struct {
 	received  int
 	total     int
 	checksum  string
+	expected  string // from -expect, for the first download only
 	indices   map[int]bool
 }
 
 var (
 	activeTransfers = make(map[string]*oscTransferState)
+	failedDownloads []string // verification failures not yet shown to the user
 	transfersMu     sync.Mutex
 )
 
+// globalExpectedChecksum is a SHA-256 the user obtained out of band (-expect).
+// When set it is trusted over the checksum the room reports. It names one
+// file, so the first download takes it and later ones use the room's.
+var globalExpectedChecksum string
+
+// takeFailedDownloads returns the downloads that failed verification since
+// the last call. Logging is off in rooms unless -v is given, so the caller
+// prints them once the session ends.
+func takeFailedDownloads() []string {
+	transfersMu.Lock()
+	defer transfersMu.Unlock()
+	failed := failedDownloads
+	failedDownloads = nil
+	return failed
+}
+
+// verifyChecksum checks a downloaded file's SHA-256 against the room's
+// reported checksum and, if given, the user's expected one. A room that
+// reports a different checksum than the user expects is rejected even if the
+// content matches its own claim.
+func verifyChecksum(actual, reported, expected string) error {
+	if expected != "" {
+		if reported != "" && !strings.EqualFold(reported, expected) {
+			return fmt.Errorf("room reports checksum %s, but %s was expected", reported, expected)
+		}
+		if !strings.EqualFold(actual, expected) {
+			return fmt.Errorf("checksum mismatch: expected %s, got %s", expected, actual)
+		}
+		return nil
+	}
+	if reported != "" && actual != reported {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", reported, actual)
+	}
+	return nil
+}
+
+// verifyFile checks a file already on disk against an expected SHA-256, for
+// content received out of band, and returns the checksum it computed
+func verifyFile(path, expected string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+	actual := hex.EncodeToString(hasher.Sum(nil))
+	return actual, verifyChecksum(actual, "", expected)
+}
+
 func handleOSCBlockTransfer(p protocol.FileBlockPayload, verbose bool) {
 	transfersMu.Lock()
 	state, ok := activeTransfers[p.ID]
@@ -44,8 +100,10 @@ func handleOSCBlockTransfer(p protocol.FileBlockPayload, verbose bool) {
 			filename:  p.Filename,
 			total:     p.Count,
 			checksum:  p.Checksum,
+			expected:  globalExpectedChecksum,
 			indices:   make(map[int]bool),
 		}
+		globalExpectedChecksum = ""
 		activeTransfers[p.ID] = state
 	}
 	transfersMu.Unlock()
@@ -110,7 +168,12 @@ func assembleFile(state *oscTransferState, transferID string, verbose bool) {
 	}
 
 	// 2. Determine unique final path
-	finalPath := getUniquePath(filepath.Join(globalDownloadsDir, state.filename))
+	finalPath := filepath.Join(globalDownloadsDir, state.filename)
+	if globalTimestampNames {
+		finalPath = getTimestampedPath(finalPath, time.Now())
+	} else {
+		finalPath = getUniquePath(finalPath)
+	}
 
 	// 3. Write and hash
 	hasher := sha256.New()
@@ -121,6 +184,7 @@ func assembleFile(state *oscTransferState, transferID string, verbose bool) {
 	}
 	defer out.Close()
 
+	var size int64
 	for _, b := range blocks {
 		if b == nil {
 			log.Printf("Missing block in reassembly!")
@@ -128,22 +192,51 @@ func assembleFile(state *oscTransferState, transferID string, verbose bool) {
 		}
 		out.Write(b)
 		hasher.Write(b)
+		size += int64(len(b))
 	}
 
 	// 4. Verify checksum
 	actualChecksum := hex.EncodeToString(hasher.Sum(nil))
-	if state.checksum != "" && actualChecksum != state.checksum {
-		log.Printf("Checksum mismatch for %s!", state.filename)
-		log.Printf("Expected: %s", state.checksum)
-		log.Printf("Actual:   %s", actualChecksum)
+	if err := verifyChecksum(actualChecksum, state.checksum, state.expected); err != nil {
+		log.Printf("Verification failed for %s: %v", state.filename, err)
+		// Don't leave unverified content where it looks like a finished download
+		out.Close()
+		os.Remove(finalPath)
+		transfersMu.Lock()
+		failedDownloads = append(failedDownloads, fmt.Sprintf("download of %s failed verification and was deleted: %v", state.filename, err))
+		transfersMu.Unlock()
 	} else {
 		if verbose {
 			log.Printf("Saved %s to %s", state.filename, finalPath)
 		}
 		os.Remove(state.partsPath)
+		recordDownload(downloadRecord{
+			Time:     time.Now(),
+			Filename: state.filename,
+			Size:     size,
+			Checksum: actualChecksum,
+			Path:     finalPath,
+		}, verbose)
 	}
 
 	transfersMu.Lock()
 	delete(activeTransfers, transferID)
 	transfersMu.Unlock()
 }
+
+// recordDownload appends a completed download to the local history, noting
+// when the same content was saved before
+func recordDownload(rec downloadRecord, verbose bool) {
+	if globalDownloadHistory == "" {
+		return
+	}
+	if verbose {
+		records, _ := loadDownloads(globalDownloadHistory)
+		if prev, ok := findDownload(records, rec.Checksum); ok {
+			log.Printf("%s was already downloaded to %s on %s", rec.Filename, prev.Path, prev.Time.Local().Format("2006-01-02 15:04"))
+		}
+	}
+	if err := appendDownload(globalDownloadHistory, rec); err != nil {
+		log.Printf("Failed to record download: %v", err)
+	}
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/mevdschee/underground-node-network/internal/protocol"
@@ -86,3 +89,128 @@ func TestAssembleFile(t *testing.T) {
 		t.Error("expected state to be removed from activeTransfers")
 	}
 }
+
+func TestVerifyChecksum(t *testing.T) {
+	// sha256 of "hello" and of "world"
+	hello := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	world := "486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7"
+
+	tests := []struct {
+		name     string
+		actual   string
+		reported string
+		expected string
+		ok       bool
+	}{
+		{"room checksum matches", hello, hello, "", true},
+		{"room checksum differs", world, hello, "", false},
+		{"expected matches room", hello, hello, hello, true},
+		{"expected overrides missing room checksum", hello, "", hello, true},
+		// A compromised room serves other content and reports its matching checksum
+		{"room and expected disagree", world, world, hello, false},
+		{"content differs from expected", world, "", hello, false},
+		{"case insensitive", hello, "", strings.ToUpper(hello), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyChecksum(tt.actual, tt.reported, tt.expected)
+			if (err == nil) != tt.ok {
+				t.Errorf("verifyChecksum() error = %v, want ok=%v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestAssembleFileExpectMismatch(t *testing.T) {
+	tmpDir := t.TempDir()
+	globalDownloadsDir = tmpDir
+	partsPath := filepath.Join(tmpDir, "evil.txt.x.parts")
+	data, _ := json.Marshal(protocol.FileBlockPayload{Filename: "evil.txt", ID: "x", Count: 1, Index: 0,
+		Data: base64.StdEncoding.EncodeToString([]byte("world"))})
+	os.WriteFile(partsPath, append(data, '\n'), 0644)
+
+	// The room's checksum matches what it sent, but not what the user expects
+	state := &oscTransferState{
+		partsPath: partsPath,
+		filename:  "evil.txt",
+		total:     1,
+		checksum:  "486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7",
+		expected:  "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		indices:   map[int]bool{0: true},
+	}
+	activeTransfers["x"] = state
+	assembleFile(state, "x", false)
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "evil.txt")); !os.IsNotExist(err) {
+		t.Errorf("Unverified download was kept")
+	}
+	if failed := takeFailedDownloads(); len(failed) != 1 || !strings.Contains(failed[0], "evil.txt") {
+		t.Errorf("Expected the failure to be reported, got %q", failed)
+	}
+	if failed := takeFailedDownloads(); len(failed) != 0 {
+		t.Errorf("Expected failures to be reported once, got %q", failed)
+	}
+}
+
+func TestExpectFirstDownloadOnly(t *testing.T) {
+	tmpDir := t.TempDir()
+	globalDownloadsDir = tmpDir
+	globalExpectedChecksum = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	defer func() { globalExpectedChecksum = "" }()
+
+	// Both files are intact, but only the first is the one the user expects
+	for _, name := range []string{"hello.txt", "other.txt"} {
+		content := []byte(name)
+		sum := sha256.Sum256(content)
+		handleOSCBlockTransfer(protocol.FileBlockPayload{Filename: name, ID: name, Count: 1, Index: 0,
+			Checksum: hex.EncodeToString(sum[:]), Data: base64.StdEncoding.EncodeToString(content)}, false)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "hello.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected the first download to be checked against -expect")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "other.txt")); err != nil {
+		t.Errorf("Expected later downloads to use the room's checksum: %v", err)
+	}
+	if globalExpectedChecksum != "" {
+		t.Errorf("Expected the checksum to be used up")
+	}
+	takeFailedDownloads()
+}
+
+func TestVerifyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hello.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hello := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+	t.Run("match", func(t *testing.T) {
+		actual, err := verifyFile(path, hello)
+		if err != nil {
+			t.Fatalf("Expected a match, got %v", err)
+		}
+		if actual != hello {
+			t.Errorf("Expected checksum %s, got %s", hello, actual)
+		}
+	})
+
+	t.Run("uppercase", func(t *testing.T) {
+		if _, err := verifyFile(path, strings.ToUpper(hello)); err != nil {
+			t.Errorf("Expected the comparison to ignore case, got %v", err)
+		}
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		world := "486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7"
+		if _, err := verifyFile(path, world); err == nil || !strings.Contains(err.Error(), "mismatch") {
+			t.Errorf("Expected a checksum mismatch, got %v", err)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		if _, err := verifyFile(filepath.Join(t.TempDir(), "nope"), hello); err == nil {
+			t.Error("Expected an error for a missing file")
+		}
+	})
+}
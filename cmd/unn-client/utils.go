@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 func getUniquePath(path string) string {
@@ -25,6 +26,19 @@ func getUniquePath(path string) string {
 	}
 }
 
+// globalTimestampNames is set with -timestamp to name downloads by time
+// instead of numbering them on collision
+var globalTimestampNames bool
+
+// getTimestampedPath inserts .YYYYMMDD-HHMMSS before the extension, so
+// repeated downloads of the same file sort by time. Two downloads within the
+// same second still get numbered rather than overwrite each other.
+func getTimestampedPath(path string, now time.Time) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	return getUniquePath(fmt.Sprintf("%s.%s%s", base, now.Format("20060102-150405"), ext))
+}
+
 func copyFile(src, dst string) error {
 	in, err := os.Open(src)
 	if err != nil {
@@ -41,3 +55,26 @@ func copyFile(src, dst string) error {
 	_, err = io.Copy(out, in)
 	return err
 }
+
+// lastRoomPath returns the file used to persist the last joined room
+func lastRoomPath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".unn", "last_room")
+}
+
+// loadLastRoom reads the persisted last room name, or "" if none
+func loadLastRoom(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// saveLastRoom persists the last joined room name
+func saveLastRoom(path, room string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(room+"\n"), 0600)
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestGetUniquePath(t *testing.T) {
@@ -46,3 +47,44 @@ func TestGetUniquePath(t *testing.T) {
 		t.Errorf("expected %s, got %s", expected2, unique)
 	}
 }
+
+func TestGetTimestampedPath(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "nightly.zip")
+	now := time.Date(2024, 3, 9, 7, 5, 2, 0, time.UTC)
+
+	first := getTimestampedPath(path, now)
+	if expected := filepath.Join(tmpDir, "nightly.20240309-070502.zip"); first != expected {
+		t.Errorf("expected %s, got %s", expected, first)
+	}
+	if err := os.WriteFile(first, []byte("first"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second download within the same second must not clobber the first
+	second := getTimestampedPath(path, now)
+	if expected := filepath.Join(tmpDir, "nightly.20240309-070502 (1).zip"); second != expected {
+		t.Errorf("expected %s, got %s", expected, second)
+	}
+
+	if later := getTimestampedPath(path, now.Add(time.Second)); later != filepath.Join(tmpDir, "nightly.20240309-070503.zip") {
+		t.Errorf("unexpected path %s", later)
+	}
+}
+
+func TestLastRoom(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, ".unn", "last_room")
+
+	if room := loadLastRoom(path); room != "" {
+		t.Errorf("expected empty room, got %q", room)
+	}
+
+	if err := saveLastRoom(path, "lobby"); err != nil {
+		t.Fatal(err)
+	}
+
+	if room := loadLastRoom(path); room != "lobby" {
+		t.Errorf("expected lobby, got %q", room)
+	}
+}
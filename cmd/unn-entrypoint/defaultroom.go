@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mevdschee/p2pquic-go/pkg/p2pquic"
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/entrypoint"
+	"github.com/mevdschee/underground-node-network/internal/nat"
+	"github.com/mevdschee/underground-node-network/internal/protocol"
+	"github.com/mevdschee/underground-node-network/internal/sshserver"
+	"golang.org/x/crypto/ssh"
+)
+
+// defaultRoom is a room run inside the entrypoint process so a fresh
+// entrypoint is not empty. It advertises the machine's addresses and
+// registers like any other room, reconnecting until it is stopped. It has no
+// operator: nobody can change its settings or kick people from it.
+type defaultRoom struct {
+	name   string
+	server *sshserver.Server
+	doors  []string
+	mu     sync.Mutex
+	client *entrypoint.Client // current connection, guarded by mu
+	done   chan struct{}      // closed by stop, guarded by mu
+	wg     sync.WaitGroup
+}
+
+// startDefaultRoom starts the room, keeping its host key and doors in dir,
+// and registers it with the entrypoint at epAddr
+func startDefaultRoom(name string, epAddr net.Addr, dir string) (*defaultRoom, error) {
+	doorsDir := filepath.Join(dir, "doors")
+	if err := os.MkdirAll(doorsDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", doorsDir, err)
+	}
+	doorManager := doors.NewManager(doorsDir)
+	if err := doorManager.Scan(); err != nil {
+		log.Printf("Warning: failed to scan default room doors: %v", err)
+	}
+	server, err := sshserver.NewServer("127.0.0.1:0", filepath.Join(dir, "host_key"), name, doorManager)
+	if err != nil {
+		return nil, err
+	}
+	server.SetAutoOperator(false)
+	if err := server.Start(); err != nil {
+		return nil, err
+	}
+
+	// Rooms reach the entrypoint like visitors do, so dial it on localhost
+	// when it listens on all interfaces
+	_, port, _ := net.SplitHostPort(epAddr.String())
+	r := &defaultRoom{name: name, server: server, doors: doorManager.List(), done: make(chan struct{})}
+	r.wg.Add(1)
+	go r.run(net.JoinHostPort("127.0.0.1", port))
+	return r, nil
+}
+
+// run keeps the room registered until stop is called
+func (r *defaultRoom) run(epAddr string) {
+	defer r.wg.Done()
+	for {
+		client := entrypoint.NewClient(epAddr, "default", r.server.GetHostKey())
+		if err := client.Connect(); err != nil {
+			log.Printf("Default room %s failed to connect: %v", r.name, err)
+		} else {
+			r.mu.Lock()
+			select {
+			case <-r.done:
+				r.mu.Unlock()
+				client.Close()
+				return
+			default:
+			}
+			r.client = client
+			r.mu.Unlock()
+			if err := r.serve(client); err != nil {
+				log.Printf("Default room %s lost the entrypoint: %v", r.name, err)
+			}
+			client.Close()
+		}
+		select {
+		case <-r.done:
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// serve registers the room and answers punch offers until the connection ends
+func (r *defaultRoom) serve(client *entrypoint.Client) error {
+	port := r.server.GetPort()
+	// The entrypoint sees the room on localhost, so it cannot add a
+	// server-reflexive address; advertise the interfaces and localhost
+	local := nat.FilterCandidates(nat.GetLocalCandidates(port, nil, nil), 0)
+	local = append(local, nat.Candidate{Type: "host", IP: "127.0.0.1", Port: port})
+	candidates := nat.CandidatesToStrings(local)
+	publicKeys := []string{string(ssh.MarshalAuthorizedKey(r.server.GetHostKey().PublicKey()))}
+	if err := client.Register(r.name, r.doors, port, publicKeys, len(r.server.GetPeople())); err != nil {
+		return err
+	}
+	log.Printf("Default room %s registered on port %d", r.name, port)
+	r.server.OnPeopleChange = func(count int) {
+		client.Register(r.name, r.doors, port, publicKeys, count)
+	}
+	client.SetPreviewHandler(r.server.GetBanner)
+
+	return client.ListenForMessages(nil, func(offer protocol.PunchOfferPayload) {
+		if pubKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(offer.PersonKey)); err == nil {
+			r.server.AuthorizeKey(pubKey, offer.Username)
+			if offer.Verified {
+				r.server.VerifyKey(pubKey)
+			}
+		}
+		// Visitors look the room up in signaling before dialing it
+		signalingClient, err := nat.NewSSHSignalingClient(client.Connection())
+		if err != nil {
+			log.Printf("Default room %s failed to create signaling client: %v", r.name, err)
+			return
+		}
+		defer signalingClient.Close()
+		peerCandidates := make([]p2pquic.Candidate, len(local))
+		for i, c := range local {
+			peerCandidates[i] = p2pquic.Candidate{IP: c.IP, Port: c.Port}
+		}
+		if err := signalingClient.Register(fmt.Sprintf("room-%s", r.name), peerCandidates); err != nil {
+			log.Printf("Default room %s failed to register with signaling: %v", r.name, err)
+		}
+		// Open our NAT towards the visitor, like unn-room does
+		if udpConn := r.server.GetUDPConn(); udpConn != nil {
+			for _, candidate := range offer.Candidates {
+				addr, err := net.ResolveUDPAddr("udp4", candidate)
+				if err != nil {
+					continue
+				}
+				for i := 0; i < 5; i++ {
+					udpConn.WriteToUDP([]byte("PUNCH"), addr)
+					time.Sleep(100 * time.Millisecond)
+				}
+			}
+		}
+	}, nil, port, func() []string { return candidates })
+}
+
+// stop unregisters the room and shuts it down
+func (r *defaultRoom) stop() {
+	r.mu.Lock()
+	close(r.done)
+	if r.client != nil {
+		r.client.Close()
+	}
+	r.mu.Unlock()
+	r.wg.Wait()
+	r.server.Stop()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/entrypoint"
+)
+
+func TestDefaultRoom(t *testing.T) {
+	tmpDir := t.TempDir()
+	server, err := entrypoint.NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create entrypoint: %v", err)
+	}
+	server.SetRoomGrace(0)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start entrypoint: %v", err)
+	}
+	defer server.Stop()
+
+	listed := func() bool {
+		for _, room := range server.GetRooms() {
+			if room.Name == "lobby" {
+				return true
+			}
+		}
+		return false
+	}
+	waitFor := func(want bool) bool {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if listed() == want {
+				return true
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		return false
+	}
+
+	room, err := startDefaultRoom("lobby", server.Addr(), filepath.Join(tmpDir, "default_room"))
+	if err != nil {
+		t.Fatalf("Failed to start default room: %v", err)
+	}
+	t.Run("listed after startup", func(t *testing.T) {
+		if !waitFor(true) {
+			t.Fatalf("Expected the default room in GetRooms, got %+v", server.GetRooms())
+		}
+	})
+
+	t.Run("gone after stop", func(t *testing.T) {
+		done := make(chan struct{})
+		go func() {
+			room.stop()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Stopping the default room hung")
+		}
+		if !waitFor(false) {
+			t.Errorf("Expected the default room to go offline, got %+v", server.GetRooms())
+		}
+	})
+}
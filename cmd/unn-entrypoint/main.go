@@ -7,9 +7,14 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
 
 	"github.com/mevdschee/underground-node-network/internal/entrypoint"
+	"github.com/mevdschee/underground-node-network/internal/keystrength"
+	"github.com/mevdschee/underground-node-network/internal/logfile"
+	"github.com/mevdschee/underground-node-network/internal/ui/banner"
+	"github.com/mevdschee/underground-node-network/internal/ui/common"
 )
 
 func main() {
@@ -17,8 +22,39 @@ func main() {
 	bind := flag.String("bind", "0.0.0.0", "Address to bind to")
 	hostKey := flag.String("hostkey", "", "Path to SSH host key")
 	usersDir := flag.String("users", "", "Path to users directory (defaults to <hostkey_dir>)")
+	userRetention := flag.Int("user-retention", 0, "Prune identities not seen for this many days (0 = keep forever)")
+	roomGrace := flag.Duration("room-grace", entrypoint.DefaultRoomGrace, "How long a room that lost its connection stays listed before it goes offline (0 = immediately)")
+	joinRetry := flag.Duration("join-retry", entrypoint.DefaultJoinRetryWindow, "How long /join waits for a room that is not online yet (0 = fail immediately)")
+	idleTimeout := flag.Duration("idle-timeout", 0, "Disconnect people idle in the lobby for this long, e.g. 30m (0 = never)")
+	connLimit := flag.Int("conn-limit", entrypoint.DefaultConnLimit, "Block an IP that opens more than this many connections per -conn-window (0 = no limit)")
+	connWindow := flag.Duration("conn-window", entrypoint.DefaultConnWindow, "Window for -conn-limit, also how long an IP stays blocked")
+	logFile := flag.String("log-file", "", "Write logs to this file instead of stderr")
+	logMaxSize := flag.Int64("log-max-size", 10, "Roll over the log file at this size in MiB")
+	bannerMaxLines := flag.Int("banner-max-lines", banner.DefaultMaxLines, "Truncate banner.asc to this many lines (0 for no limit)")
+	bannerMaxBytes := flag.Int("banner-max-bytes", banner.DefaultMaxBytes, "Truncate banner.asc to this many bytes (0 for no limit)")
+	ascii := flag.Bool("ascii", false, "Draw the lobby UI with ASCII only, for terminals without box-drawing glyphs")
+	platformTimeout := flag.Duration("platform-timeout", entrypoint.DefaultPlatformTimeout, "How long one request to GitHub or another identity platform may take")
+	platformRetries := flag.Int("platform-retries", entrypoint.DefaultPlatformRetries, "Retry a key fetch this often after a network or server error, with backoff (0 = no retries)")
+	admins := flag.String("admins", "", "Comma-separated verified UNN usernames allowed to run admin commands such as /metrics")
+	metricsLog := flag.Duration("metrics-log", 0, "Log connection, verification and punch counters as JSON at this interval, e.g. 1h (0 = never)")
+	writeDelay := flag.Duration("write-delay", 0, "Collect small writes to a terminal for up to this long and send them as one, e.g. 5ms (0 = send right away)")
+	maxRoomsPerOwner := flag.Int("max-rooms-per-owner", 0, "Reject a room when its owner already has this many rooms online (0 = no limit)")
+	reclaim := flag.String("reclaim", "add", "When a new key is verified against the platform account that owns a taken username: add (keep the old keys too), rebind (drop the old keys) or off (refuse)")
+	bannerEncoding := flag.String("banner-encoding", "auto", "Encoding of banner.asc: auto (UTF-8, else CP437), utf-8 or cp437")
+	defaultRoomName := flag.String("default-room", "", "Run a room with this name inside the entrypoint, without an operator, so the entrypoint is not empty")
+	minKeyStrength := flag.String("min-key-strength", "off", "How to handle client keys weaker than the minimum (DSA, or RSA below 2048 bits): off, warn or refuse, optionally with the RSA minimum, e.g. refuse:3072")
 	flag.Parse()
 
+	// Redirect logging to a rotating file if requested
+	if *logFile != "" {
+		w, err := logfile.Open(*logFile, *logMaxSize*1024*1024)
+		if err != nil {
+			log.Fatalf("Failed to open log file: %v", err)
+		}
+		defer w.Close()
+		log.SetOutput(w)
+	}
+
 	// Set default host key path
 	if *hostKey == "" {
 		homeDir, err := os.UserHomeDir()
@@ -42,6 +78,35 @@ func main() {
 		log.Fatalf("Failed to create entry point: %v", err)
 	}
 
+	server.SetUserRetention(*userRetention)
+	server.SetIdleTimeout(*idleTimeout)
+	server.SetJoinRetryWindow(*joinRetry)
+	server.SetRoomGrace(*roomGrace)
+	server.SetBannerLimits(*bannerMaxLines, *bannerMaxBytes)
+	enc, err := banner.ParseEncoding(*bannerEncoding)
+	if err != nil {
+		log.Fatalf("Invalid -banner-encoding: %v", err)
+	}
+	server.SetBannerEncoding(enc)
+	common.SetASCII(*ascii)
+	server.SetConnectionLimit(*connLimit, *connWindow)
+	server.SetWriteDelay(*writeDelay)
+	server.SetPlatformTimeout(*platformTimeout)
+	server.SetPlatformRetries(*platformRetries)
+	server.SetAdmins(strings.Split(*admins, ","))
+	server.SetMetricsLog(*metricsLog)
+	server.SetMaxRoomsPerOwner(*maxRoomsPerOwner)
+	reclaimMode, err := entrypoint.ParseReclaimMode(*reclaim)
+	if err != nil {
+		log.Fatalf("Invalid -reclaim: %v", err)
+	}
+	server.SetReclaimMode(reclaimMode)
+	keyPolicy, err := keystrength.Parse(*minKeyStrength)
+	if err != nil {
+		log.Fatalf("Invalid -min-key-strength: %v", err)
+	}
+	server.SetKeyPolicy(keyPolicy)
+
 	if err := server.Start(); err != nil {
 		log.Fatalf("Failed to start entry point: %v", err)
 	}
@@ -50,10 +115,21 @@ func main() {
 	log.Printf("Connect with: ssh -p %d %s", *port, *bind)
 	log.Printf("Available subsystems: unn-control (rooms), unn-api (clients), unn-signaling (p2p)")
 
+	var room *defaultRoom
+	if *defaultRoomName != "" {
+		room, err = startDefaultRoom(*defaultRoomName, server.Addr(), filepath.Join(*usersDir, "default_room"))
+		if err != nil {
+			log.Fatalf("Failed to start default room: %v", err)
+		}
+	}
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	<-sigChan
 
 	log.Printf("Shutting down...")
+	if room != nil {
+		room.stop()
+	}
 	server.Stop()
 }
@@ -224,7 +224,7 @@ func main() {
 	}
 	defer s.Fini()
 
-	sw, sh := s.Size()
+	sw, sh := common.ClampSize(s.Size())
 	baseStyle := tcell.StyleDefault.Background(tcell.ColorBlack).Foreground(tcell.ColorGreen)
 	brightStyle := baseStyle.Foreground(tcell.ColorLime)
 
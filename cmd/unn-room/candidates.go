@@ -0,0 +1,46 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// candidateCache holds the candidates the room advertises in punch answers.
+// Discovery sends STUN traffic, so refresh runs it again at most once per
+// interval.
+type candidateCache struct {
+	mu          sync.Mutex
+	discover    func() []string
+	interval    time.Duration // 0 = never refresh
+	now         func() time.Time
+	current     []string
+	refreshedAt time.Time
+}
+
+// newCandidateCache discovers the candidates once
+func newCandidateCache(discover func() []string, interval time.Duration) *candidateCache {
+	c := &candidateCache{discover: discover, interval: interval, now: time.Now}
+	c.current = discover()
+	c.refreshedAt = c.now()
+	return c
+}
+
+// get returns the current candidates
+func (c *candidateCache) get() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.current
+}
+
+// refresh rediscovers the candidates unless refreshing is off or the last
+// discovery was less than interval ago, and reports whether it ran
+func (c *candidateCache) refresh() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.interval <= 0 || c.now().Sub(c.refreshedAt) < c.interval {
+		return false
+	}
+	c.current = c.discover()
+	c.refreshedAt = c.now()
+	return true
+}
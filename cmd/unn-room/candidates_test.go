@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCandidateCache(t *testing.T) {
+	newTestCache := func(interval time.Duration) (*candidateCache, *int, *time.Time) {
+		calls := 0
+		now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		discover := func() []string {
+			calls++
+			return []string{fmt.Sprintf("203.0.113.%d:2222", calls)}
+		}
+		c := newCandidateCache(discover, interval)
+		c.now = func() time.Time { return now }
+		c.refreshedAt = now
+		return c, &calls, &now
+	}
+
+	t.Run("off keeps the first candidates", func(t *testing.T) {
+		c, calls, now := newTestCache(0)
+		for i := 0; i < 3; i++ {
+			*now = now.Add(time.Hour)
+			if c.refresh() {
+				t.Error("Expected no refresh when refreshing is off")
+			}
+		}
+		if *calls != 1 || !slices.Equal(c.get(), []string{"203.0.113.1:2222"}) {
+			t.Errorf("Expected only the initial discovery, got %d calls and %v", *calls, c.get())
+		}
+	})
+
+	t.Run("refreshes at most once per interval", func(t *testing.T) {
+		c, calls, now := newTestCache(time.Minute)
+		// A burst of people-count changes within the interval
+		for i := 0; i < 5; i++ {
+			*now = now.Add(10 * time.Second)
+			if c.refresh() {
+				t.Errorf("Update %d refreshed within the interval", i)
+			}
+		}
+		*now = now.Add(10 * time.Second)
+		if !c.refresh() {
+			t.Error("Expected a refresh once the interval passed")
+		}
+		if c.refresh() {
+			t.Error("Expected the next update to be throttled")
+		}
+		if *calls != 2 || !slices.Equal(c.get(), []string{"203.0.113.2:2222"}) {
+			t.Errorf("Expected 2 discoveries and the new candidates, got %d and %v", *calls, c.get())
+		}
+	})
+}
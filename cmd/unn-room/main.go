@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"os"
@@ -14,9 +15,14 @@ import (
 
 	"github.com/mevdschee/underground-node-network/internal/doors"
 	"github.com/mevdschee/underground-node-network/internal/entrypoint"
+	"github.com/mevdschee/underground-node-network/internal/keystrength"
+	"github.com/mevdschee/underground-node-network/internal/logfile"
 	"github.com/mevdschee/underground-node-network/internal/nat"
 	"github.com/mevdschee/underground-node-network/internal/protocol"
 	"github.com/mevdschee/underground-node-network/internal/sshserver"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"github.com/mevdschee/underground-node-network/internal/ui/banner"
+	"github.com/mevdschee/underground-node-network/internal/ui/common"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -24,21 +30,105 @@ func main() {
 	// Parse command-line flags
 	port := flag.Int("port", 2222, "SSH server port")
 	bind := flag.String("bind", "127.0.0.1", "Address to bind to")
+	quicIdleTimeout := flag.Duration("quic-idle-timeout", nat.DefaultQUICTuning.MaxIdleTimeout, "Close QUIC connections after this long without any packet")
+	quicKeepalive := flag.Duration("quic-keepalive", 0, "Send a QUIC keepalive at this interval to keep the connection and NAT mapping open (0 for the -nat-type interval)")
+	natType := flag.String("nat-type", "unknown", "NAT in front of this room, sets the QUIC keepalive interval: none, full-cone, restricted, port-restricted, symmetric or unknown")
+	quicStreamWindow := flag.String("quic-stream-window", "", "Initial QUIC stream receive window, e.g. 2M, for faster uploads to the room on high-latency links (empty for the quic-go default of 512K)")
 	doorsDir := flag.String("doors", "./doors", "Directory containing door executables")
+	doorMaxInstances := flag.Int("door-max-instances", 0, "Maximum concurrent processes per door (0 for no limit)")
+	doorsMaxTotal := flag.Int("doors-max-total", 0, "Maximum concurrent door processes over all doors (0 for no limit)")
+	createDirs := flag.Bool("create-dirs", false, "Create the doors directory if it does not exist")
 	roomName := flag.String("room", "anonymous", "Name of your room")
 	hostKey := flag.String("hostkey", "", "Path to SSH host key (auto-generated if not specified)")
 	entryPointAddr := flag.String("entrypoint", "", "Entry point address (e.g., localhost:44322)")
 	identity := flag.String("identity", "", "Path to private key for entrypoint registration")
-	roomFiles := flag.String("files", "", "Directory containing files for download")
+	requireEntrypoint := flag.Bool("require-entrypoint", false, "Exit with an error if the room cannot register with the entrypoint at startup, instead of running local-only")
+	var roomFiles stringList
+	flag.Var(&roomFiles, "files", "Directory containing files for download (repeat for multiple folders, shown under their base names, which must differ)")
 	headless := flag.Bool("headless", false, "Disable TUI (headless mode)")
+	ascii := flag.Bool("ascii", false, "Draw the UI with ASCII only, for terminals without box-drawing glyphs")
+	candidateInclude := flag.String("candidate-include", "", "Comma-separated interfaces to take candidates from, ignoring all others (e.g. eth0,wlan0; empty for all)")
+	candidateExclude := flag.String("candidate-exclude", "", "Comma-separated interfaces to exclude from candidates (e.g. docker0,tun0)")
+	signMessages := flag.Bool("sign-messages", false, "Sign chat messages with the room host key for UNN-aware clients")
+	clipboard := flag.Bool("clipboard", false, "Copy /geturl download details to the clipboard of UNN-aware clients (OSC 52)")
+	maxUsername := flag.Int("max-username", sshserver.DefaultMaxUsernameLength, "Cut visitors' usernames to this many characters (0 for no limit)")
+	cmdPrefix := flag.String("cmd-prefix", "/", "Prefix that marks chat input as a command (e.g. ! or .)")
+	firstMessage := flag.String("first-message", "off", "Gate the first chat message of a new key: off, challenge (answer a small sum) or approve (operator approves it)")
+	paste := flag.String("paste", "message", "Multi-line paste handling: message (send as one message) or reject")
+	redact := flag.Bool("redact", false, "Mask candidate addresses in log output")
+	maxCandidates := flag.Int("max-candidates", 0, "Maximum number of candidates to advertise (0 for no limit)")
+	probeCandidates := flag.Bool("probe-candidates", false, "Only advertise public candidates the entrypoint can reach")
+	logFile := flag.String("log-file", "", "Write logs to this file instead of stderr")
+	logLines := flag.Int("log-lines", logfile.DefaultRingLines, "Keep this many recent log lines in memory for the operator's /log command (0 = none)")
+	logMaxSize := flag.Int64("log-max-size", 10, "Roll over the log file at this size in MiB")
+	themeAccent := flag.String("theme-accent", "", "Accent color for the room header (color name or #rrggbb)")
+	themeTitle := flag.String("theme-title", "", "Title shown in the room header instead of the default")
+	bannerMaxLines := flag.Int("banner-max-lines", banner.DefaultMaxLines, "Truncate room.asc to this many lines (0 for no limit)")
+	restore := flag.String("restore", "", "Restore room state saved with /snapshot save from this file")
+	bannerMaxBytes := flag.Int("banner-max-bytes", banner.DefaultMaxBytes, "Truncate room.asc to this many bytes (0 for no limit)")
+	bannerEncoding := flag.String("banner-encoding", "auto", "Encoding of room.asc: auto (UTF-8, else CP437), utf-8 or cp437")
+	offerTimeout := flag.Duration("offer-timeout", 2*time.Minute, "How long a /send offer waits for /accept before both people are told it expired")
+	restoreScroll := flag.Bool("restore-scroll", false, "Scroll a rejoining user's chat back to where they left off instead of to the newest message")
+	writeDelay := flag.Duration("write-delay", 0, "Collect small writes to a terminal for up to this long and send them as one, e.g. 5ms (0 = send right away)")
+	indexInterval := flag.Duration("index-interval", 0, "Keep an in-memory index of the files, rebuilt at this interval, for large directories (0 to read the disk on every request)")
+	bandwidthLog := flag.Duration("bandwidth-log", 0, "Log the room's upload and download throughput at this interval (0 to disable)")
+	permDownloads := flag.String("perm-downloads", "on", "Who may download files: on (everyone), verified-only or off (operator only)")
+	permDoors := flag.String("perm-doors", "on", "Who may open doors: on (everyone), verified-only or off (operator only)")
+	candidateRefresh := flag.Duration("candidate-refresh", 0, "Rediscover STUN and local candidates when the people count changes, at most once per this interval, e.g. 1m (0 = keep the candidates found at registration)")
+	compressSSH := flag.Bool("compress-ssh", false, "Deflate-compress the output to clients started with -compress-ssh (saves bandwidth on slow links, costs some CPU)")
+	listenTCP := flag.Bool("listen-tcp", false, "Also serve SSH over plain TCP on the same port, for visitors using unn-client -allow-direct-ssh when the room has a public IP or a forwarded port")
+	rebind := flag.Bool("rebind", true, "Recreate the UDP/QUIC listener and re-register if its socket fails")
+	minKeyStrength := flag.String("min-key-strength", "off", "How to handle client keys weaker than the minimum (DSA, or RSA below 2048 bits): off, warn or refuse, optionally with the RSA minimum, e.g. refuse:3072")
 	flag.Parse()
 
-	// Handle room files symlink
-	if *roomFiles != "" {
-		absFiles, err := filepath.Abs(*roomFiles)
-		if err == nil {
-			os.Remove("./room_files") // Remove existing if any
-			os.Symlink(absFiles, "./room_files")
+	if *requireEntrypoint && *entryPointAddr == "" {
+		log.Fatalf("Invalid -require-entrypoint: no -entrypoint given")
+	}
+
+	// Redirect logging to a rotating file if requested
+	if *logFile != "" {
+		w, err := logfile.Open(*logFile, *logMaxSize*1024*1024)
+		if err != nil {
+			log.Fatalf("Failed to open log file: %v", err)
+		}
+		defer w.Close()
+		log.SetOutput(w)
+	}
+	var logRing *logfile.Ring
+	if *logLines > 0 {
+		logRing = logfile.NewRing(*logLines)
+		log.SetOutput(io.MultiWriter(log.Writer(), logRing))
+	}
+
+	nat.SetRedaction(*redact)
+
+	includeIfaces := splitNames(*candidateInclude)
+	excludeIfaces := splitNames(*candidateExclude)
+
+	// Handle room files: a single directory is symlinked as ./room_files,
+	// multiple directories become top-level folders inside ./room_files
+	var fileRoots []string
+	for _, dir := range roomFiles {
+		if absFiles, err := filepath.Abs(dir); err == nil {
+			fileRoots = append(fileRoots, absFiles)
+		}
+	}
+	if len(fileRoots) == 1 {
+		os.Remove("./room_files") // Remove existing if any
+		os.Symlink(fileRoots[0], "./room_files")
+	} else if len(fileRoots) > 1 {
+		if info, err := os.Lstat("./room_files"); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			os.Remove("./room_files")
+		}
+		os.MkdirAll("./room_files", 0755)
+		for _, root := range fileRoots {
+			link := filepath.Join("./room_files", filepath.Base(root))
+			if info, err := os.Lstat(link); err == nil && info.Mode()&os.ModeSymlink != 0 {
+				os.Remove(link)
+			}
+			if err := os.Symlink(root, link); err != nil {
+				log.Printf("Warning: Could not link files folder %s: %v", root, err)
+			}
 		}
 	}
 
@@ -57,6 +147,14 @@ func main() {
 
 	// Initialize door manager
 	doorManager := doors.NewManager(*doorsDir)
+	doorManager.SetLimits(*doorMaxInstances, *doorsMaxTotal)
+	if *createDirs {
+		if created, err := doorManager.EnsureDir(); err != nil {
+			log.Printf("Warning: %v", err)
+		} else if created {
+			log.Printf("Created doors directory %s", *doorsDir)
+		}
+	}
 	if err := doorManager.Scan(); err != nil {
 		log.Printf("Warning: Could not scan doors directory: %v", err)
 	}
@@ -65,7 +163,7 @@ func main() {
 	if len(doorList) > 0 {
 		log.Printf("Found %d doors: %v", len(doorList), doorList)
 	} else {
-		log.Printf("No doors found in %s", *doorsDir)
+		log.Printf("No doors found: %s", doorManager.Diagnose())
 	}
 
 	// Create and start SSH server
@@ -75,10 +173,90 @@ func main() {
 		log.Fatalf("Failed to create SSH server: %v", err)
 	}
 
+	streamWindow, err := nat.ParseWindow(*quicStreamWindow)
+	if err != nil {
+		log.Fatalf("Invalid -quic-stream-window: %v", err)
+	}
+	quicTuning := nat.QUICTuning{MaxIdleTimeout: *quicIdleTimeout, KeepAlivePeriod: *quicKeepalive, StreamWindow: streamWindow}
+	parsedNAT, err := nat.ParseNATType(*natType)
+	if err != nil {
+		log.Fatalf("Invalid -nat-type: %v", err)
+	}
+	if quicTuning.KeepAlivePeriod == 0 {
+		quicTuning.KeepAlivePeriod = nat.KeepaliveInterval(parsedNAT)
+	}
+	if err := quicTuning.Validate(); err != nil {
+		log.Fatalf("Invalid -quic-idle-timeout or -quic-keepalive: %v", err)
+	}
+	server.SetQUICTuning(quicTuning)
+
 	if err := server.Start(); err != nil {
 		log.Fatalf("Failed to start SSH server: %v", err)
 	}
+	if *listenTCP {
+		if err := server.ListenTCP(); err != nil {
+			log.Fatalf("Failed to start SSH server: %v", err)
+		}
+	}
 	server.SetHeadless(*headless)
+	common.SetASCII(*ascii)
+	server.SetRebind(*rebind)
+	server.SetBandwidthLog(*bandwidthLog)
+	if len(fileRoots) > 0 {
+		if err := server.SetFileRoots(fileRoots); err != nil {
+			log.Fatalf("Invalid -files: %v", err)
+		}
+	}
+	server.SetIndexInterval(*indexInterval)
+	server.SetWriteDelay(*writeDelay)
+	server.SetLogRing(logRing)
+	server.SetRestoreScroll(*restoreScroll)
+	server.SetOfferTimeout(*offerTimeout)
+	server.SetMaxUsernameLength(*maxUsername)
+	server.SetSignMessages(*signMessages)
+	server.SetClipboard(*clipboard)
+	pasteMode, err := ui.ParsePasteMode(*paste)
+	if err != nil {
+		log.Fatalf("Invalid -paste: %v", err)
+	}
+	server.SetPasteMode(pasteMode)
+	gate, err := sshserver.ParseFirstMessageGate(*firstMessage)
+	if err != nil {
+		log.Fatalf("Invalid -first-message: %v", err)
+	}
+	server.SetFirstMessageGate(gate)
+	downloadPerm, err := sshserver.ParsePermission(*permDownloads)
+	if err != nil {
+		log.Fatalf("Invalid -perm-downloads: %v", err)
+	}
+	doorPerm, err := sshserver.ParsePermission(*permDoors)
+	if err != nil {
+		log.Fatalf("Invalid -perm-doors: %v", err)
+	}
+	server.SetPermissions(downloadPerm, doorPerm)
+	keyPolicy, err := keystrength.Parse(*minKeyStrength)
+	if err != nil {
+		log.Fatalf("Invalid -min-key-strength: %v", err)
+	}
+	server.SetKeyPolicy(keyPolicy)
+	server.SetCompression(*compressSSH)
+	if err := server.SetCommandPrefix(*cmdPrefix); err != nil {
+		log.Fatalf("Invalid -cmd-prefix: %v", err)
+	}
+	server.SetBannerLimits(*bannerMaxLines, *bannerMaxBytes)
+	enc, err := banner.ParseEncoding(*bannerEncoding)
+	if err != nil {
+		log.Fatalf("Invalid -banner-encoding: %v", err)
+	}
+	server.SetBannerEncoding(enc)
+	if err := server.SetTheme(*themeAccent, *themeTitle); err != nil {
+		log.Fatalf("Invalid theme: %v", err)
+	}
+	if *restore != "" {
+		if err := server.RestoreSnapshot(*restore); err != nil {
+			log.Fatalf("Failed to restore snapshot: %v", err)
+		}
+	}
 
 	// Get actual port (important when port 0 is used for random port)
 	actualPort := server.GetPort()
@@ -87,11 +265,15 @@ func main() {
 	// via the unn-signaling subsystem instead of HTTP
 
 	log.Printf("UNN Room '%s' is now online", *roomName)
-	log.Printf("Connect with: ssh -p %d %s", actualPort, *bind)
+	if *listenTCP {
+		log.Printf("Connect with: ssh -p %d %s", actualPort, *bind)
+	}
 
 	// Connect to entry point if specified
 	var epClient *entrypoint.Client
-	if *entryPointAddr != "" {
+	if *entryPointAddr == "" {
+		log.Printf("Room is local-only: no -entrypoint given, so visitors have no way to reach it")
+	} else {
 		// Determine entrypoint connection username (matches client logic)
 		epUser := os.Getenv("USER")
 		if epUser == "" {
@@ -111,6 +293,7 @@ func main() {
 		}
 
 		log.Printf("Connecting to entry point: %s as %s", *entryPointAddr, epUser)
+		reg := newRegistration(*entryPointAddr, *requireEntrypoint)
 
 		go func() {
 			backoff := 1 * time.Second
@@ -119,6 +302,7 @@ func main() {
 			for {
 				epClient = entrypoint.NewClient(*entryPointAddr, epUser, signer)
 				if err := epClient.Connect(); err != nil {
+					reg.failed(err)
 					log.Printf("Failed to connect to entry point: %v. Reconnecting in %v...", err, backoff)
 					time.Sleep(backoff)
 					backoff *= 2
@@ -131,35 +315,79 @@ func main() {
 				// Reset backoff on successful connection
 				backoff = 1 * time.Second
 
+				// The port can change when the listener is rebound
+				actualPort := server.GetPort()
+
 				// Discover NAT candidates using actual port
-				candidates := nat.GetLocalCandidates(actualPort)
-				stunCand, err := nat.DiscoverPublicAddress(actualPort) // STUN from actual port
-				if err == nil {
-					candidates = append([]nat.Candidate{*stunCand}, candidates...)
-					log.Printf("STUN discovered: %s:%d", stunCand.IP, stunCand.Port)
-				}
+				discover := func() []string {
+					candidates := nat.GetLocalCandidates(actualPort, includeIfaces, excludeIfaces)
+					stunCand, err := nat.DiscoverPublicAddress(actualPort) // STUN from actual port
+					if err == nil {
+						candidates = append([]nat.Candidate{*stunCand}, candidates...)
+						log.Printf("STUN discovered: %s", nat.RedactCandidate(fmt.Sprintf("%s:%d", stunCand.IP, stunCand.Port)))
+					} else {
+						log.Printf("Warning: STUN discovery failed, advertising local addresses only: %v", err)
+					}
+
+					if *probeCandidates {
+						reflector, err := nat.NewSSHReflector(epClient.Connection())
+						if err != nil {
+							log.Printf("Warning: Failed to start candidate probing: %v", err)
+						} else {
+							candidates = nat.ProbeReachable(candidates, reflector)
+							reflector.Close()
+						}
+					}
 
-				candidateStrs := nat.CandidatesToStrings(candidates)
+					return nat.CandidatesToStrings(nat.FilterCandidates(candidates, *maxCandidates))
+				}
+				candidates := newCandidateCache(discover, *candidateRefresh)
 
-				// Read public key
-				publicKeys := []string{string(pubKeyBytes)}
+				// Current host public key (may have been rotated since startup)
+				publicKeys := []string{string(ssh.MarshalAuthorizedKey(server.GetHostKey().PublicKey()))}
 
 				// Register with entry point
 				peopleCount := len(server.GetPeople())
 				if err := epClient.Register(*roomName, doorList, actualPort, publicKeys, peopleCount); err != nil {
+					reg.failed(err)
 					log.Printf("Failed to register with entry point: %v. Reconnecting...", err)
 					epClient.Close()
 					time.Sleep(1 * time.Second)
 					continue
 				}
+				reg.succeeded()
+
+				// Answer banner preview requests from the entrypoint
+				epClient.SetPreviewHandler(server.GetBanner)
 
 				// Report people count updates
 				server.OnPeopleChange = func(count int) {
+					// Discovery can take seconds, so keep it off the join path
+					go func() {
+						if candidates.refresh() {
+							log.Printf("Refreshed candidates after the people count changed to %d", count)
+						}
+					}()
 					if epClient != nil {
 						epClient.Register(*roomName, doorList, actualPort, publicKeys, count)
 					}
 				}
 
+				// Re-register with the new key after /rotate-key
+				server.SetOnHostKeyChange(func(pub ssh.PublicKey) {
+					publicKeys = []string{string(ssh.MarshalAuthorizedKey(pub))}
+					if err := epClient.Register(*roomName, doorList, actualPort, publicKeys, len(server.GetPeople())); err != nil {
+						log.Printf("Failed to register rotated host key: %v", err)
+					}
+				})
+
+				// After the UDP socket failed and was recreated, reconnect so
+				// candidates are rediscovered and registered again
+				server.SetOnRebind(func(port int) {
+					log.Printf("Re-registering after listener rebind on port %d", port)
+					epClient.Close()
+				})
+
 				// Listen for messages (this blocks until the connection is lost)
 				err = epClient.ListenForMessages(nil, func(offer protocol.PunchOfferPayload) {
 					// Authorize the person's key
@@ -167,6 +395,9 @@ func main() {
 						pubKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(offer.PersonKey))
 						if err == nil {
 							server.AuthorizeKey(pubKey, offer.Username)
+							if offer.Verified {
+								server.VerifyKey(pubKey)
+							}
 						} else {
 							log.Printf("Warning: Failed to parse person public key: %v", err)
 						}
@@ -174,7 +405,7 @@ func main() {
 
 					// Send UDP punch packets to client's candidates
 					if len(offer.Candidates) > 0 {
-						log.Printf("Sending UDP punch packets to client %s at %v", offer.Username, offer.Candidates)
+						log.Printf("Sending UDP punch packets to client %s at %v", offer.Username, nat.RedactCandidates(offer.Candidates))
 
 						// Register room with signaling just-in-time (30s TTL)
 						if p2pPeer := server.GetP2PPeer(); p2pPeer != nil {
@@ -203,7 +434,7 @@ func main() {
 							for _, candidate := range offer.Candidates {
 								addr, err := net.ResolveUDPAddr("udp4", candidate)
 								if err != nil {
-									log.Printf("Failed to resolve candidate %s: %v", candidate, err)
+									log.Printf("Failed to resolve candidate %s: %v", nat.RedactCandidate(candidate), err)
 									continue
 								}
 
@@ -212,7 +443,7 @@ func main() {
 									udpConn.WriteToUDP([]byte("PUNCH"), addr)
 									time.Sleep(100 * time.Millisecond)
 								}
-								log.Printf("Sent UDP punch packets to %s", candidate)
+								log.Printf("Sent UDP punch packets to %s", nat.RedactCandidate(candidate))
 							}
 						} else {
 							log.Printf("Warning: No UDP connection available for hole-punching")
@@ -222,7 +453,7 @@ func main() {
 					// Send PunchAnswer back to entrypoint with room's candidates
 					answer := protocol.PunchAnswerPayload{
 						PersonID:   offer.PersonID,
-						Candidates: candidateStrs,
+						Candidates: candidates.get(),
 						SSHPort:    actualPort,
 					}
 					if err := epClient.SendPunchAnswer(answer); err != nil {
@@ -230,12 +461,12 @@ func main() {
 					} else {
 						log.Printf("Sent PunchAnswer for person %s", offer.PersonID)
 					}
-				}, nil, actualPort, candidateStrs)
+				}, nil, actualPort, candidates.get)
 
 				// If we reach here, the connection was lost
 				if err != nil {
 					errMsg := err.Error()
-					if strings.Contains(errMsg, "taken") || strings.Contains(errMsg, "Invalid") {
+					if strings.Contains(errMsg, "taken") || strings.Contains(errMsg, "Invalid") || strings.Contains(errMsg, "reserved") {
 						fmt.Printf("\n\033[1;31mRegistration Error: %s\033[0m\n", errMsg)
 						if strings.Contains(errMsg, "taken") {
 							fmt.Printf("\033[1mYour Room Host Key Hash is:\033[0m \033[1;36m%s\033[0m\n", hostKeyHash)
@@ -246,7 +477,7 @@ func main() {
 					}
 				}
 
-				log.Printf("Entry point connection broken: %v. Reconnecting in %v...", err, backoff)
+				log.Printf("Entry point connection broken: %v. Reconnecting in %v (people in the room stay connected)...", err, backoff)
 				epClient.Close()
 				time.Sleep(backoff)
 				backoff *= 2
@@ -269,6 +500,29 @@ func main() {
 	server.Stop()
 }
 
+// splitNames splits a comma-separated flag value, dropping empty names
+func splitNames(value string) []string {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// stringList collects the values of a repeatable string flag
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
 func findPragmaticSigner(hostKey ssh.Signer, identityPath string) ssh.Signer {
 	// 1. Explicit identity takes precedence
 	if identityPath != "" {
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"log"
+	"os"
+	"sync"
+)
+
+// registration reports, once, whether the room made it onto the entrypoint.
+// A room that could not register is only reachable by people who know its
+// address; with -require-entrypoint that is a fatal error instead.
+type registration struct {
+	entrypoint string
+	required   bool
+	once       sync.Once
+	exit       func(code int) // os.Exit, replaced in tests
+}
+
+func newRegistration(entrypoint string, required bool) *registration {
+	return &registration{entrypoint: entrypoint, required: required, exit: os.Exit}
+}
+
+// failed is called when connecting or registering failed. Only the first
+// outcome counts: once the room was listed, later drops just reconnect.
+func (r *registration) failed(err error) {
+	r.once.Do(func() {
+		log.Printf("Room is local-only: not reachable via entrypoint %s: %v", r.entrypoint, err)
+		if r.required {
+			log.Printf("Exiting because -require-entrypoint is set")
+			r.exit(1)
+			return
+		}
+		log.Printf("Visitors cannot reach the room until registration succeeds; retrying in the background")
+	})
+}
+
+// succeeded is called after the room registered with the entrypoint
+func (r *registration) succeeded() {
+	r.once.Do(func() {})
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRegistration(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	newTestRegistration := func(required bool) (*registration, *int) {
+		code := -1
+		r := newRegistration("localhost:44322", required)
+		r.exit = func(c int) { code = c }
+		return r, &code
+	}
+
+	t.Run("required exits", func(t *testing.T) {
+		logs.Reset()
+		r, code := newTestRegistration(true)
+		r.failed(errors.New("connection refused"))
+		if *code != 1 {
+			t.Errorf("Expected exit code 1, got %d", *code)
+		}
+		if !strings.Contains(logs.String(), "Room is local-only") {
+			t.Errorf("Expected a local-only summary, got %q", logs.String())
+		}
+	})
+
+	t.Run("optional keeps running", func(t *testing.T) {
+		logs.Reset()
+		r, code := newTestRegistration(false)
+		r.failed(errors.New("connection refused"))
+		r.failed(errors.New("connection refused"))
+		if *code != -1 {
+			t.Errorf("Expected no exit, got %d", *code)
+		}
+		if n := strings.Count(logs.String(), "Room is local-only"); n != 1 {
+			t.Errorf("Expected the summary once, got %d times", n)
+		}
+	})
+
+	t.Run("later failures after success", func(t *testing.T) {
+		logs.Reset()
+		r, code := newTestRegistration(true)
+		r.succeeded()
+		r.failed(errors.New("connection reset"))
+		if *code != -1 {
+			t.Errorf("Expected a registered room not to exit, got %d", *code)
+		}
+		if logs.Len() != 0 {
+			t.Errorf("Expected no local-only summary, got %q", logs.String())
+		}
+	})
+}
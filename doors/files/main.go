@@ -33,19 +33,23 @@ func main() {
 		return
 	}
 
+	// Current folder relative to filesDir (multiple -files roots appear as folders)
+	subDir := ""
+
 	for {
-		files, err := listFiles(filesDir)
+		currentDir := filepath.Join(filesDir, subDir)
+		files, err := listFiles(currentDir)
 		if err != nil {
 			fmt.Printf("Error listing files: %v\n", err)
 			return
 		}
 
-		currentDir, _ := filepath.Abs(filesDir)
+		absDir, _ := filepath.Abs(currentDir)
 		fmt.Printf("\033[H\033[2J") // Clear screen
 		fmt.Println("--- UNN File Manager ---")
-		fmt.Printf("Location: %s\n\n", currentDir)
+		fmt.Printf("Location: %s\n\n", absDir)
 
-		if len(files) == 0 {
+		if len(files) == 0 && subDir == "" {
 			fmt.Println("No files available.")
 			fmt.Printf("\nPress Enter to exit...")
 			fmt.Scanln()
@@ -53,7 +57,14 @@ func main() {
 		}
 
 		for i, f := range files {
-			fmt.Printf(" [\033[1;32m%d\033[0m] %-30s %10s\n", i+1, f.name, formatSize(f.size))
+			if f.dir {
+				fmt.Printf(" [\033[1;32m%d\033[0m] %-30s %10s\n", i+1, f.name+"/", "")
+			} else {
+				fmt.Printf(" [\033[1;32m%d\033[0m] %-30s %10s\n", i+1, f.name, formatSize(f.size))
+			}
+		}
+		if subDir != "" {
+			fmt.Printf(" [\033[1;33mB\033[0m] Back\n")
 		}
 		fmt.Printf(" [\033[1;31mQ\033[0m] Quit\n\n")
 
@@ -64,6 +75,13 @@ func main() {
 		if strings.ToLower(input) == "q" {
 			return
 		}
+		if strings.ToLower(input) == "b" && subDir != "" {
+			subDir = filepath.Dir(subDir)
+			if subDir == "." {
+				subDir = ""
+			}
+			continue
+		}
 
 		idx, err := strconv.Atoi(input)
 		if err != nil || idx < 1 || idx > len(files) {
@@ -71,13 +89,18 @@ func main() {
 		}
 
 		selected := files[idx-1]
-		downloadFile(filepath.Join(filesDir, selected.name), selected.name)
+		if selected.dir {
+			subDir = filepath.Join(subDir, selected.name)
+			continue
+		}
+		downloadFile(filepath.Join(currentDir, selected.name), selected.name)
 	}
 }
 
 type fileInfo struct {
 	name string
 	size int64
+	dir  bool
 }
 
 func listFiles(dir string) ([]fileInfo, error) {
@@ -88,10 +111,12 @@ func listFiles(dir string) ([]fileInfo, error) {
 
 	var files []fileInfo
 	for _, e := range entries {
-		if !e.IsDir() {
-			info, _ := e.Info()
-			files = append(files, fileInfo{name: e.Name(), size: info.Size()})
+		// Stat follows symlinks so linked folders are listed as folders
+		info, err := os.Stat(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
 		}
+		files = append(files, fileInfo{name: e.Name(), size: info.Size(), dir: info.IsDir()})
 	}
 	return files, nil
 }
@@ -118,14 +143,7 @@ func downloadFile(path, filename string) {
 
 	fmt.Printf("Starting transfer of %s (%d blocks)...\n", filename, count)
 
-	buf := make([]byte, blockSize)
-	for i := 0; i < count; i++ {
-		n, err := file.Read(buf)
-		if err != nil && err != io.EOF {
-			fmt.Printf("Error reading file: %v\n", err)
-			return
-		}
-
+	err = readBlocks(file, blockSize, count, func(i int, data []byte) {
 		payload := FileBlockPayload{
 			Action:   "transfer_block",
 			Filename: filename,
@@ -133,7 +151,7 @@ func downloadFile(path, filename string) {
 			Count:    count,
 			Index:    i,
 			Checksum: checksum,
-			Data:     base64.StdEncoding.EncodeToString(buf[:n]),
+			Data:     base64.StdEncoding.EncodeToString(data),
 		}
 
 		sendOSC("transfer_block", payload)
@@ -144,11 +162,37 @@ func downloadFile(path, filename string) {
 		// Small delay to simulate rate limiting and let client process
 		// 8KB per 10ms is ~800KB/s
 		time.Sleep(10 * time.Millisecond)
+	})
+	if err != nil {
+		fmt.Printf("Error reading file: %v\n", err)
+		return
 	}
 	fmt.Printf("\n\n\033[1;32mTransfer of %s complete!\033[0m\n", filename)
 	time.Sleep(1 * time.Second)
 }
 
+// readBlocks reads count blocks of blockSize from r and passes each to send
+// with its index. A read may return fewer bytes than asked without being at
+// the end, so every block is filled with io.ReadFull: the receiver places
+// block i at offset i*blockSize, and only the last block may be shorter.
+func readBlocks(r io.Reader, blockSize, count int, send func(index int, data []byte)) error {
+	buf := make([]byte, blockSize)
+	for i := 0; i < count; i++ {
+		n, err := io.ReadFull(r, buf)
+		if err == io.ErrUnexpectedEOF && i == count-1 {
+			err = nil
+		}
+		if err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return fmt.Errorf("file ended at block %d of %d", i+1, count)
+			}
+			return err
+		}
+		send(i, buf[:n])
+	}
+	return nil
+}
+
 func sendOSC(action string, payload interface{}) {
 	jsonData, _ := json.Marshal(payload)
 	// We print directly to stdout as it will be captured by the client
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReadBlocks(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 250) // 2500 bytes
+	const blockSize = 1000
+
+	t.Run("short reads give full blocks", func(t *testing.T) {
+		var got []byte
+		var sizes []int
+		err := readBlocks(iotest.HalfReader(bytes.NewReader(data)), blockSize, 3, func(i int, block []byte) {
+			if i != len(sizes) {
+				t.Errorf("Expected block %d, got %d", len(sizes), i)
+			}
+			sizes = append(sizes, len(block))
+			got = append(got, block...)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sizes) != 3 || sizes[0] != blockSize || sizes[1] != blockSize || sizes[2] != 500 {
+			t.Errorf("Expected blocks of 1000, 1000 and 500 bytes, got %v", sizes)
+		}
+		if !bytes.Equal(got, data) {
+			t.Error("Expected the blocks to add up to the file")
+		}
+	})
+
+	t.Run("one byte at a time", func(t *testing.T) {
+		var got []byte
+		err := readBlocks(iotest.OneByteReader(bytes.NewReader(data)), blockSize, 3, func(i int, block []byte) {
+			if !bytes.Equal(block, data[i*blockSize:min((i+1)*blockSize, len(data))]) {
+				t.Errorf("Block %d does not match its offset in the file", i)
+			}
+			got = append(got, block...)
+		})
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("Expected the whole file, got %d bytes and %v", len(got), err)
+		}
+	})
+
+	t.Run("file shrank", func(t *testing.T) {
+		sent := 0
+		err := readBlocks(bytes.NewReader(data[:1500]), blockSize, 3, func(int, []byte) { sent++ })
+		if err == nil || !strings.Contains(err.Error(), "file ended at block 2 of 3") {
+			t.Errorf("Expected a truncation error, got %v", err)
+		}
+		if sent != 1 {
+			t.Errorf("Expected only the full block to be sent, got %d", sent)
+		}
+	})
+
+	t.Run("read errors", func(t *testing.T) {
+		err := readBlocks(iotest.ErrReader(iotest.ErrTimeout), blockSize, 1, func(int, []byte) {})
+		if err != iotest.ErrTimeout {
+			t.Errorf("Expected the read error, got %v", err)
+		}
+	})
+}
@@ -1,14 +1,19 @@
 package doors
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/creack/pty"
 )
@@ -17,12 +22,63 @@ import (
 type Door struct {
 	Name string
 	Path string
+
+	// RestartMax is how often a crashed door is relaunched within
+	// RestartWindow before the person is sent back to the chat (0 = never)
+	RestartMax    int
+	RestartWindow time.Duration
+}
+
+// DefaultRestartWindow is the crash-loop window when a door's metadata does
+// not set one
+const DefaultRestartWindow = time.Minute
+
+// doorExitGrace is how long a door may keep running after its terminal
+// closed before it is killed
+var doorExitGrace = 2 * time.Second
+
+// Metadata is read from an optional <door>.json next to the door program
+type Metadata struct {
+	RestartMax    int    `json:"restart_max"`    // relaunch a crashed door at most this often per window
+	RestartWindow string `json:"restart_window"` // crash-loop window such as "5m" (default 1m)
 }
 
+// ErrBusy is returned when a door, or the room, runs as many door
+// processes as it is allowed to
+var ErrBusy = errors.New("this door is busy, try later")
+
 // Manager handles door discovery and execution
 type Manager struct {
 	doorsDir string
 	doors    map[string]*Door
+	missing  bool // the doors directory did not exist at the last scan
+	skipped  int  // files skipped at the last scan for not being executable
+
+	mu         sync.Mutex
+	running    map[string]int // running processes per door
+	total      int            // running processes over all doors
+	maxPerDoor int            // 0 = no limit
+	maxTotal   int            // 0 = no limit
+	stats      map[string]*Stats
+	restarts   map[string][]time.Time // recent crash restarts per door
+}
+
+// Stats is what the manager recorded about a door's runs since startup
+type Stats struct {
+	Name      string
+	Runs      int
+	TotalTime time.Duration
+	LastTime  time.Duration
+	LastRun   time.Time
+	LastError string // of the last run, "" if it succeeded
+}
+
+// Average returns the mean runtime, or 0 if the door never ran
+func (st Stats) Average() time.Duration {
+	if st.Runs == 0 {
+		return 0
+	}
+	return st.TotalTime / time.Duration(st.Runs)
 }
 
 // NewManager creates a new door manager for the given directory
@@ -30,16 +86,102 @@ func NewManager(doorsDir string) *Manager {
 	return &Manager{
 		doorsDir: doorsDir,
 		doors:    make(map[string]*Door),
+		running:  make(map[string]int),
+		stats:    make(map[string]*Stats),
+		restarts: make(map[string][]time.Time),
+	}
+}
+
+// SetLimits caps how many processes of one door, and of all doors together,
+// may run at once (0 means no limit)
+func (m *Manager) SetLimits(maxPerDoor, maxTotal int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxPerDoor = maxPerDoor
+	m.maxTotal = maxTotal
+}
+
+// Busy reports whether launching the door now would exceed a limit
+func (m *Manager) Busy(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.full(name)
+}
+
+func (m *Manager) full(name string) bool {
+	return (m.maxPerDoor > 0 && m.running[name] >= m.maxPerDoor) ||
+		(m.maxTotal > 0 && m.total >= m.maxTotal)
+}
+
+// acquire reserves a slot for a door process, or returns ErrBusy
+func (m *Manager) acquire(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.full(name) {
+		return ErrBusy
+	}
+	m.running[name]++
+	m.total++
+	return nil
+}
+
+func (m *Manager) release(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.running[name]--
+	if m.running[name] <= 0 {
+		delete(m.running, name)
+	}
+	m.total--
+}
+
+// record adds a finished run to the door's statistics
+func (m *Manager) record(name string, started time.Time, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st, ok := m.stats[name]
+	if !ok {
+		st = &Stats{Name: name}
+		m.stats[name] = st
+	}
+	elapsed := time.Since(started)
+	st.Runs++
+	st.TotalTime += elapsed
+	st.LastTime = elapsed
+	st.LastRun = started
+	st.LastError = ""
+	if err != nil {
+		st.LastError = err.Error()
+	}
+}
+
+// Stats returns the statistics of every current door, sorted by name. Doors
+// that never ran are included with zero runs.
+func (m *Manager) Stats() []Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]Stats, 0, len(m.doors))
+	for name := range m.doors {
+		if st, ok := m.stats[name]; ok {
+			list = append(list, *st)
+		} else {
+			list = append(list, Stats{Name: name})
+		}
 	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
 }
 
 // Scan discovers executable doors in the doors directory
 func (m *Manager) Scan() error {
 	m.doors = make(map[string]*Door)
+	m.missing = false
+	m.skipped = 0
 
 	entries, err := os.ReadDir(m.doorsDir)
 	if err != nil {
 		if os.IsNotExist(err) {
+			m.missing = true
 			return nil // No doors directory is fine
 		}
 		return fmt.Errorf("failed to read doors directory: %w", err)
@@ -59,16 +201,86 @@ func (m *Manager) Scan() error {
 		// Check if executable
 		if info.Mode()&0111 != 0 {
 			name := strings.TrimPrefix(entry.Name(), "/")
-			m.doors[name] = &Door{
+			door := &Door{
 				Name: name,
 				Path: path,
 			}
+			if err := loadMetadata(door); err != nil {
+				log.Printf("Ignoring metadata of door %s: %v", name, err)
+			}
+			m.doors[name] = door
+		} else if filepath.Ext(entry.Name()) != ".json" {
+			m.skipped++
 		}
 	}
 
 	return nil
 }
 
+// loadMetadata applies the door's <name>.json, if there is one
+func loadMetadata(door *Door) error {
+	data, err := os.ReadFile(door.Path + ".json")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var meta Metadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return err
+	}
+	if meta.RestartMax < 0 {
+		return fmt.Errorf("restart_max must not be negative")
+	}
+	window := DefaultRestartWindow
+	if meta.RestartWindow != "" {
+		if window, err = time.ParseDuration(meta.RestartWindow); err != nil || window <= 0 {
+			return fmt.Errorf("invalid restart_window: %s", meta.RestartWindow)
+		}
+	}
+	door.RestartMax = meta.RestartMax
+	door.RestartWindow = window
+	return nil
+}
+
+// EnsureDir creates the doors directory if it does not exist yet
+func (m *Manager) EnsureDir() (created bool, err error) {
+	if _, err := os.Stat(m.doorsDir); err == nil {
+		return false, nil
+	} else if !os.IsNotExist(err) {
+		return false, err
+	}
+	if err := os.MkdirAll(m.doorsDir, 0755); err != nil {
+		return false, fmt.Errorf("failed to create doors directory: %w", err)
+	}
+	return true, nil
+}
+
+// Dir returns the doors directory
+func (m *Manager) Dir() string {
+	return m.doorsDir
+}
+
+// Missing reports whether the doors directory did not exist at the last scan
+func (m *Manager) Missing() bool {
+	return m.missing
+}
+
+// Diagnose explains why no doors were found at the last scan, or returns ""
+// if there are doors
+func (m *Manager) Diagnose() string {
+	switch {
+	case len(m.doors) > 0:
+		return ""
+	case m.missing:
+		return fmt.Sprintf("Doors directory %s does not exist (create it or use -create-dirs)", m.doorsDir)
+	case m.skipped > 0:
+		return fmt.Sprintf("Doors directory %s has %d files but none are executable (chmod +x them)", m.doorsDir, m.skipped)
+	}
+	return fmt.Sprintf("Doors directory %s is empty, add executable programs to offer doors", m.doorsDir)
+}
+
 // List returns all available door names
 func (m *Manager) List() []string {
 	names := make([]string, 0, len(m.doors))
@@ -91,12 +303,65 @@ func (m *Manager) Execute(name string, stdin io.Reader, stdout, stderr io.Writer
 		return fmt.Errorf("door not found: %s", name)
 	}
 
+	if err := m.acquire(name); err != nil {
+		return err
+	}
+	defer m.release(name)
+
+	for {
+		started := time.Now()
+		crash, err := m.run(door, stdin, stdout)
+		if err != nil {
+			m.record(name, started, err)
+		} else {
+			m.record(name, started, crash)
+		}
+		if err != nil || crash == nil || door.RestartMax <= 0 {
+			return err
+		}
+
+		n, ok := m.allowRestart(door, time.Now())
+		if !ok {
+			fmt.Fprintf(stdout, "\r\n[Door %s crashed (%v) %d times within %v, not restarting]\r\n", name, crash, door.RestartMax, door.RestartWindow)
+			return nil
+		}
+		fmt.Fprintf(stdout, "\r\n[Door %s crashed (%v), restarting (%d/%d)]\r\n", name, crash, n, door.RestartMax)
+		// The finished run signalled the input to stop, so re-arm it
+		if r, ok := stdin.(interface{ Reset() }); ok {
+			r.Reset()
+		}
+	}
+}
+
+// allowRestart records a crash restart of the door and returns its number
+// within the window, or false once the door crashed RestartMax times in it
+func (m *Manager) allowRestart(door *Door, now time.Time) (int, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	recent := m.restarts[door.Name][:0]
+	for _, t := range m.restarts[door.Name] {
+		if now.Sub(t) < door.RestartWindow {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= door.RestartMax {
+		m.restarts[door.Name] = recent
+		return 0, false
+	}
+	m.restarts[door.Name] = append(recent, now)
+	return len(recent) + 1, true
+}
+
+// run starts the door on a PTY and copies its I/O until it exits. A door
+// that exits with a non-zero status or is killed by a signal is returned as
+// a crash; err is only set when the door could not be run or its I/O failed.
+func (m *Manager) run(door *Door, stdin io.Reader, stdout io.Writer) (crash error, err error) {
 	cmd := exec.Command(door.Path)
 
 	// Start the command with a pty
 	f, err := pty.Start(cmd)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer f.Close()
 
@@ -121,7 +386,39 @@ func (m *Manager) Execute(name string, stdin io.Reader, stdout, stderr io.Writer
 
 	if err != nil && (errors.Is(err, syscall.EIO) || strings.Contains(err.Error(), "input/output error")) {
 		// Suppress EIO error on Linux when PTY slave is closed (process exit)
-		return nil
+		err = nil
+	}
+
+	// Reap the process, killing it if it outlives its terminal
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	var waitErr error
+	select {
+	case waitErr = <-exited:
+	case <-time.After(doorExitGrace):
+		cmd.Process.Kill()
+		<-exited
+		return nil, err
+	}
+	if err == nil {
+		var exitErr *exec.ExitError
+		if errors.As(waitErr, &exitErr) && isCrash(exitErr) {
+			crash = exitErr
+		}
+	}
+	return crash, err
+}
+
+// isCrash tells a crash from a door the person left: exiting with a failure
+// status or dying of a fault signal is a crash, being interrupted is not
+func isCrash(exitErr *exec.ExitError) bool {
+	if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		switch ws.Signal() {
+		case syscall.SIGSEGV, syscall.SIGABRT, syscall.SIGBUS, syscall.SIGFPE, syscall.SIGILL:
+			return true
+		}
+		return false
 	}
-	return err
+	code := exitErr.ExitCode()
+	return code > 0 && code != 128+int(syscall.SIGINT)
 }
@@ -0,0 +1,211 @@
+package doors
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScanMissingVersusEmpty(t *testing.T) {
+	t.Run("missing directory", func(t *testing.T) {
+		m := NewManager(filepath.Join(t.TempDir(), "doors"))
+		if err := m.Scan(); err != nil {
+			t.Fatalf("Expected a missing directory to be fine, got %v", err)
+		}
+		if !m.Missing() || len(m.List()) != 0 {
+			t.Errorf("Expected missing directory with no doors")
+		}
+		if !strings.Contains(m.Diagnose(), "does not exist") {
+			t.Errorf("Unexpected diagnosis: %q", m.Diagnose())
+		}
+	})
+
+	t.Run("empty directory", func(t *testing.T) {
+		m := NewManager(t.TempDir())
+		m.Scan()
+		if m.Missing() {
+			t.Error("Expected an existing directory not to be reported missing")
+		}
+		if !strings.Contains(m.Diagnose(), "is empty") {
+			t.Errorf("Unexpected diagnosis: %q", m.Diagnose())
+		}
+	})
+
+	t.Run("no executables", func(t *testing.T) {
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, "readme.txt"), []byte("hi"), 0644)
+		m := NewManager(dir)
+		m.Scan()
+		if !strings.Contains(m.Diagnose(), "none are executable") {
+			t.Errorf("Unexpected diagnosis: %q", m.Diagnose())
+		}
+	})
+
+	t.Run("doors found", func(t *testing.T) {
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, "files"), []byte("#!/bin/sh\n"), 0755)
+		m := NewManager(dir)
+		m.Scan()
+		if _, ok := m.Get("files"); !ok || m.Diagnose() != "" {
+			t.Errorf("Expected door to be found without a diagnosis, got %q", m.Diagnose())
+		}
+	})
+
+	t.Run("create dir", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "doors")
+		m := NewManager(dir)
+		created, err := m.EnsureDir()
+		if err != nil || !created {
+			t.Fatalf("Expected directory to be created, got %v, %v", created, err)
+		}
+		if created, _ := m.EnsureDir(); created {
+			t.Error("Expected existing directory not to be created again")
+		}
+		m.Scan()
+		if m.Missing() {
+			t.Error("Expected created directory to be found")
+		}
+	})
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"game", "files"} {
+		os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0755)
+	}
+	m := NewManager(dir)
+	m.Scan()
+
+	t.Run("per door", func(t *testing.T) {
+		m.SetLimits(1, 0)
+		if err := m.acquire("game"); err != nil {
+			t.Fatal(err)
+		}
+		defer m.release("game")
+
+		if !m.Busy("game") {
+			t.Error("Expected game to be busy at its cap")
+		}
+		if err := m.Execute("game", strings.NewReader(""), io.Discard, io.Discard); !errors.Is(err, ErrBusy) {
+			t.Errorf("Expected ErrBusy, got %v", err)
+		}
+		if m.Busy("files") {
+			t.Error("Expected other doors to be unaffected by the per-door cap")
+		}
+	})
+
+	t.Run("global", func(t *testing.T) {
+		m.SetLimits(0, 1)
+		if err := m.acquire("game"); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.acquire("files"); !errors.Is(err, ErrBusy) {
+			t.Errorf("Expected ErrBusy over the global cap, got %v", err)
+		}
+		m.release("game")
+		if err := m.acquire("files"); err != nil {
+			t.Errorf("Expected a slot after release, got %v", err)
+		}
+		m.release("files")
+	})
+}
+
+func TestStats(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "hello"), []byte("#!/bin/sh\necho hello\n"), 0755)
+	os.WriteFile(filepath.Join(dir, "unused"), []byte("#!/bin/sh\n"), 0755)
+	m := NewManager(dir)
+	m.Scan()
+
+	for i := 0; i < 3; i++ {
+		if err := m.Execute("hello", strings.NewReader(""), io.Discard, io.Discard); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+	}
+
+	stats := m.Stats()
+	if len(stats) != 2 || stats[0].Name != "hello" || stats[1].Name != "unused" {
+		t.Fatalf("Expected stats for both doors sorted by name, got %+v", stats)
+	}
+	if stats[0].Runs != 3 {
+		t.Errorf("Expected 3 runs, got %d", stats[0].Runs)
+	}
+	if stats[0].LastRun.IsZero() || stats[0].LastError != "" {
+		t.Errorf("Expected a successful last run, got %+v", stats[0])
+	}
+	if stats[1].Runs != 0 || stats[1].Average() != 0 {
+		t.Errorf("Expected the unused door to have no runs, got %+v", stats[1])
+	}
+}
+
+func TestRestartOnCrash(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "crash"), []byte("#!/bin/sh\necho run\nexit 3\n"), 0755)
+	os.WriteFile(filepath.Join(dir, "crash.json"), []byte(`{"restart_max": 2, "restart_window": "1h"}`), 0644)
+	os.WriteFile(filepath.Join(dir, "plain"), []byte("#!/bin/sh\necho run\nexit 3\n"), 0755)
+	os.WriteFile(filepath.Join(dir, "quit"), []byte("#!/bin/sh\necho run\nexit 130\n"), 0755)
+	os.WriteFile(filepath.Join(dir, "quit.json"), []byte(`{"restart_max": 2}`), 0644)
+	m := NewManager(dir)
+	m.Scan()
+
+	run := func(name string) (string, error) {
+		var out strings.Builder
+		err := m.Execute(name, strings.NewReader(""), &out, &out)
+		return out.String(), err
+	}
+
+	t.Run("metadata", func(t *testing.T) {
+		door, _ := m.Get("crash")
+		if door.RestartMax != 2 || door.RestartWindow != time.Hour {
+			t.Errorf("Expected restart policy 2 per hour, got %d per %v", door.RestartMax, door.RestartWindow)
+		}
+		if door, _ := m.Get("quit"); door.RestartWindow != DefaultRestartWindow {
+			t.Errorf("Expected the default window, got %v", door.RestartWindow)
+		}
+		if m.Diagnose() != "" || len(m.List()) != 3 {
+			t.Errorf("Expected metadata files not to be doors or skipped files")
+		}
+	})
+
+	t.Run("restarts up to the cap", func(t *testing.T) {
+		out, err := run("crash")
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if n := strings.Count(out, "run"); n != 3 {
+			t.Errorf("Expected 1 run and 2 restarts, got %d runs in %q", n, out)
+		}
+		if !strings.Contains(out, "restarting (2/2)") || !strings.Contains(out, "not restarting") {
+			t.Errorf("Expected the person to be told about restarts, got %q", out)
+		}
+		stats := m.Stats()
+		if stats[0].Name != "crash" || stats[0].Runs != 3 || stats[0].LastError != "exit status 3" {
+			t.Errorf("Expected 3 crashed runs in the stats, got %+v", stats[0])
+		}
+	})
+
+	t.Run("crash loop stays capped", func(t *testing.T) {
+		out, _ := run("crash")
+		if n := strings.Count(out, "run"); n != 1 {
+			t.Errorf("Expected no restarts within the window, got %d runs", n)
+		}
+	})
+
+	t.Run("no policy", func(t *testing.T) {
+		out, _ := run("plain")
+		if n := strings.Count(out, "run"); n != 1 || strings.Contains(out, "restart") {
+			t.Errorf("Expected a single run without restarts, got %q", out)
+		}
+	})
+
+	t.Run("interrupted is not a crash", func(t *testing.T) {
+		out, _ := run("quit")
+		if n := strings.Count(out, "run"); n != 1 {
+			t.Errorf("Expected an interrupted door not to restart, got %q", out)
+		}
+	})
+}
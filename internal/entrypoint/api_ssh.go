@@ -16,7 +16,8 @@ const (
 	APITypeRoomList     = "room_list"
 	APITypeUserStatus   = "user_status"
 	APITypeUserRegister = "user_register"
-	APITypePreparePunch = "prepare_punch" // Request coordinated hole-punching
+	APITypePreparePunch = "prepare_punch"   // Request coordinated hole-punching
+	APITypeProbe        = "probe_candidate" // Test whether a room candidate is reachable
 	APITypeResponse     = "response"
 	APITypeError        = "error"
 )
@@ -53,6 +54,16 @@ type APIUserRegisterResponse struct {
 	Username string `json:"username"`
 }
 
+// APIProbeRequest asks the entrypoint to dial one of the caller's candidates
+type APIProbeRequest struct {
+	Address string `json:"address"`
+}
+
+// APIProbeResponse reports whether the candidate accepted a QUIC handshake
+type APIProbeResponse struct {
+	Reachable bool `json:"reachable"`
+}
+
 // handleAPI processes the unn-api SSH subsystem
 // This handles room queries and user registration
 func (s *Server) handleAPI(channel ssh.Channel, conn *ssh.ServerConn) {
@@ -115,6 +126,14 @@ func (s *Server) handleAPI(channel ssh.Channel, conn *ssh.ServerConn) {
 				})
 			}
 
+		case APITypeProbe:
+			var req APIProbeRequest
+			if err := json.Unmarshal(msg.Payload, &req); err != nil {
+				s.sendAPIError(encoder, "invalid probe_candidate payload")
+				continue
+			}
+			s.handleAPIProbe(encoder, conn, req)
+
 		default:
 			s.sendAPIError(encoder, "unknown message type: "+msg.Type)
 		}
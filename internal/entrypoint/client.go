@@ -16,6 +16,7 @@ type Client struct {
 	sshConfig *ssh.ClientConfig
 	sshClient *ssh.Client
 	channel   ssh.Channel
+	onPreview func() []string
 }
 
 // NewClient creates a new entry point client
@@ -141,8 +142,15 @@ func (c *Client) Register(roomName string, doors []string, sshPort int, publicKe
 	return nil
 }
 
-// ListenForMessages starts listening for messages from the entry point
-func (c *Client) ListenForMessages(onRoomList func([]protocol.RoomInfo), onPunchOffer func(protocol.PunchOfferPayload), onError func(error), sshPort int, candidates []string) error {
+// SetPreviewHandler sets the callback that provides the room banner for previews
+func (c *Client) SetPreviewHandler(onPreview func() []string) {
+	c.onPreview = onPreview
+}
+
+// ListenForMessages starts listening for messages from the entry point.
+// candidates is called for every punch answer, so they can change while
+// listening.
+func (c *Client) ListenForMessages(onRoomList func([]protocol.RoomInfo), onPunchOffer func(protocol.PunchOfferPayload), onError func(error), sshPort int, candidates func() []string) error {
 	decoder := json.NewDecoder(c.channel)
 	encoder := json.NewEncoder(c.channel)
 
@@ -185,11 +193,24 @@ func (c *Client) ListenForMessages(onRoomList func([]protocol.RoomInfo), onPunch
 			// Send punch_answer with our candidates
 			answerPayload := protocol.PunchAnswerPayload{
 				PersonID:   offerPayload.PersonID,
-				Candidates: candidates,
+				Candidates: candidates(),
 				SSHPort:    sshPort,
 			}
 			answerMsg, _ := protocol.NewMessage(protocol.MsgTypePunchAnswer, answerPayload)
 			encoder.Encode(answerMsg)
+
+		case protocol.MsgTypePreviewRequest:
+			var reqPayload protocol.PreviewRequestPayload
+			if err := msg.ParsePayload(&reqPayload); err != nil {
+				continue
+			}
+
+			respPayload := protocol.PreviewResponsePayload{RequestID: reqPayload.RequestID}
+			if c.onPreview != nil {
+				respPayload.Banner = c.onPreview()
+			}
+			respMsg, _ := protocol.NewMessage(protocol.MsgTypePreviewResponse, respPayload)
+			encoder.Encode(respMsg)
 		}
 	}
 }
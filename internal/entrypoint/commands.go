@@ -3,10 +3,12 @@ package entrypoint
 import (
 	"fmt"
 	"log"
+	"net"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
-	"github.com/gdamore/tcell/v2"
 	"github.com/mevdschee/underground-node-network/internal/protocol"
 	"github.com/mevdschee/underground-node-network/internal/ui"
 	"github.com/mevdschee/underground-node-network/internal/ui/common"
@@ -35,6 +37,7 @@ func (s *Server) updatePersonRoomsWithData(p *Person, rooms []protocol.RoomInfo)
 	if p.UI == nil {
 		return
 	}
+	favorites := s.getFavorites(p.PubKeyHash)
 	uiRooms := make([]ui.RoomInfo, 0, len(rooms))
 	for _, r := range rooms {
 		uiRooms = append(uiRooms, ui.RoomInfo{
@@ -42,6 +45,7 @@ func (s *Server) updatePersonRoomsWithData(p *Person, rooms []protocol.RoomInfo)
 			Owner:       r.Owner,
 			Doors:       r.Doors,
 			PeopleCount: r.PeopleCount,
+			Favorite:    slices.Contains(favorites, r.Name),
 		})
 	}
 	p.UI.SetRooms(uiRooms)
@@ -60,6 +64,10 @@ func (s *Server) handlePersonCommand(p *Person, conn *ssh.ServerConn, input stri
 	log.Printf("Person %s command: %s", p.Username, input)
 	input = strings.TrimSpace(input)
 
+	s.mu.Lock()
+	p.LastActive = time.Now()
+	s.mu.Unlock()
+
 	if p.PubKeyHash != "" {
 		s.addCommandToHistory(p.PubKeyHash, input)
 	}
@@ -78,15 +86,41 @@ func (s *Server) handlePersonCommand(p *Person, conn *ssh.ServerConn, input stri
 		case "help":
 			s.showMessage(p, "/help                     - Show this help message", ui.MsgServer)
 			s.showMessage(p, "/rooms                    - List all active rooms", ui.MsgServer)
-			s.showMessage(p, "/join <room_name>         - Join a room by name", ui.MsgServer)
+			s.showMessage(p, "/join [room_name]         - Join a room by name, or your first online favorite", ui.MsgServer)
+			s.showMessage(p, "/favorite <room_name>     - Mark a room as favorite", ui.MsgServer)
+			s.showMessage(p, "/unfavorite <room_name>   - Unmark a favorite room", ui.MsgServer)
+			s.showMessage(p, "/favorites                - List your favorite rooms", ui.MsgServer)
+			s.showMessage(p, "/raw <room_name>          - Show how to connect to a room by hand", ui.MsgServer)
+			s.showMessage(p, "/preview <room_name>      - Show a room's banner", ui.MsgServer)
+			s.showMessage(p, "/whoami                   - Show your identity", ui.MsgServer)
+			s.showMessage(p, "/platforms [check]        - List identity platforms", ui.MsgServer)
+			if s.isAdmin(conn) {
+				s.showMessage(p, "/metrics                  - Show connection and punch counters", ui.MsgServer)
+			}
 			s.showMessage(p, "/quit                     - Exit", ui.MsgServer)
+			s.showMessage(p, "Tab, arrows, Enter        - Pick a room from the list", ui.MsgServer)
 			s.showMessage(p, "Ctrl+C                    - Exit", ui.MsgServer)
+		case "platforms":
+			s.handlePlatforms(p, len(parts) > 1 && parts[1] == "check")
 		case "join":
 			if len(parts) < 2 {
+				if favorite := s.firstOnlineFavorite(p.PubKeyHash); favorite != "" {
+					s.showMessage(p, fmt.Sprintf("Joining your favorite room %s...", favorite), ui.MsgServer)
+					s.handleRoomJoin(p, conn, favorite)
+					return
+				}
 				s.showMessage(p, "Usage: /join <room_name>", ui.MsgServer)
 				return
 			}
 			s.handleRoomJoin(p, conn, parts[1])
+		case "favorite", "unfavorite", "favorites":
+			s.handleFavorite(p, command, parts[1:])
+		case "raw":
+			if len(parts) < 2 {
+				s.showMessage(p, "Usage: /raw <room_name>", ui.MsgServer)
+				return
+			}
+			s.handleRawJoin(p, conn, parts[1])
 		case "rooms":
 			s.mu.RLock()
 			var rooms []protocol.RoomInfo
@@ -100,9 +134,19 @@ func (s *Server) handlePersonCommand(p *Person, conn *ssh.ServerConn, input stri
 			} else {
 				s.showMessage(p, "Rooms:", ui.MsgServer)
 				for _, room := range rooms {
-					s.showMessage(p, fmt.Sprintf("• %s (%d) @%s", room.Name, room.PeopleCount, room.Owner), ui.MsgServer)
+					s.showMessage(p, fmt.Sprintf("%s %s (%d) @%s", common.Glyphs.Bullet, room.Name, room.PeopleCount, room.Owner), ui.MsgServer)
 				}
 			}
+		case "preview":
+			if len(parts) < 2 {
+				s.showMessage(p, "Usage: /preview <room_name>", ui.MsgServer)
+				return
+			}
+			s.handleRoomPreview(p, parts[1])
+		case "whoami":
+			s.handleWhoami(p, conn)
+		case "metrics":
+			s.handleMetrics(p, conn)
 		case "quit", "exit":
 			p.UI.Close(false)
 		default:
@@ -116,17 +160,283 @@ func (s *Server) handlePersonCommand(p *Person, conn *ssh.ServerConn, input stri
 	s.showMessage(p, "Use /rooms to list rooms and /join <room> to join.", ui.MsgServer)
 }
 
-func (s *Server) handleRoomJoin(p *Person, conn *ssh.ServerConn, roomName string) {
-	// Try to connect to room via hole-punching
+// previewCacheTTL is how long a fetched room banner is reused
+const previewCacheTTL = 5 * time.Minute
+
+// previewTimeout is how long to wait for a room to answer a preview request
+var previewTimeout = 5 * time.Second
+
+func (s *Server) handleRoomPreview(p *Person, roomName string) {
+	banner, err := s.fetchRoomPreview(roomName)
+	if err != nil {
+		s.showMessage(p, fmt.Sprintf("Preview failed: %v", err), ui.MsgServer)
+		return
+	}
+	s.showMessage(p, fmt.Sprintf("--- Preview of %s ---", roomName), ui.MsgServer)
+	if len(banner) == 0 {
+		s.showMessage(p, "This room has no banner.", ui.MsgServer)
+	}
+	for _, line := range banner {
+		s.showMessage(p, line, ui.MsgServer)
+	}
+	s.showMessage(p, fmt.Sprintf("Type /join %s to enter.", roomName), ui.MsgServer)
+}
+
+// fetchRoomPreview asks a room for its banner over the operator channel,
+// using a cached copy when it is recent enough. Rooms whose connection
+// dropped are skipped while they are waiting to reconnect.
+func (s *Server) fetchRoomPreview(roomName string) ([]string, error) {
 	s.mu.RLock()
 	room, ok := s.rooms[roomName]
+	lost := ok && !room.lostAt.IsZero()
+	cached, hasCache := s.previewCache[roomName]
 	s.mu.RUnlock()
 
 	if !ok {
-		s.showMessage(p, fmt.Sprintf("Room not found: %s", roomName), ui.MsgServer)
+		return nil, fmt.Errorf("room not found: %s", roomName)
+	}
+	if lost {
+		return nil, fmt.Errorf("room %s is reconnecting, try again shortly", roomName)
+	}
+	if hasCache && time.Since(cached.FetchedAt) < previewCacheTTL {
+		return cached.Banner, nil
+	}
+
+	requestID := fmt.Sprintf("preview-%s-%d", roomName, time.Now().UnixNano())
+	waiter := make(chan []string, 1)
+	s.mu.Lock()
+	s.previewWaiters[requestID] = waiter
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.previewWaiters, requestID)
+		s.mu.Unlock()
+	}()
+
+	reqMsg, _ := protocol.NewMessage(protocol.MsgTypePreviewRequest, protocol.PreviewRequestPayload{RequestID: requestID})
+	s.mu.RLock()
+	if room.Encoder != nil {
+		room.Encoder.Encode(reqMsg)
+	}
+	s.mu.RUnlock()
+
+	select {
+	case banner := <-waiter:
+		s.mu.Lock()
+		s.previewCache[roomName] = roomPreview{Banner: banner, FetchedAt: time.Now()}
+		s.mu.Unlock()
+		return banner, nil
+	case <-time.After(previewTimeout):
+		return nil, fmt.Errorf("room %s did not respond", roomName)
+	}
+}
+
+// reapIdle disconnects people who have not entered a command within the idle timeout
+func (s *Server) reapIdle(now time.Time) int {
+	if s.idleTimeout <= 0 {
+		return 0
+	}
+
+	s.mu.RLock()
+	var idle []*Person
+	for _, p := range s.people {
+		timeout := s.idleTimeout
+		if p.Conn != nil && p.Conn.Permissions != nil && p.Conn.Permissions.Extensions["verified"] == "true" {
+			timeout *= 2
+		}
+		if now.Sub(p.LastActive) > timeout {
+			idle = append(idle, p)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, p := range idle {
+		log.Printf("Disconnecting idle person: %s", p.Username)
+		s.showMessage(p, "Disconnected after being idle for too long.", ui.MsgServer)
+		if p.UI != nil {
+			p.UI.Close(false)
+		}
+		if p.Conn != nil {
+			go func(c *ssh.ServerConn) {
+				time.Sleep(500 * time.Millisecond)
+				c.Close()
+			}(p.Conn)
+		}
+	}
+	return len(idle)
+}
+
+// reapLoop periodically disconnects idle people until the server is stopped
+func (s *Server) reapLoop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.reapIdle(now)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Server) handleWhoami(p *Person, conn *ssh.ServerConn) {
+	s.showMessage(p, fmt.Sprintf("Username: %s", p.Username), ui.MsgServer)
+	if p.PubKey != nil {
+		s.showMessage(p, fmt.Sprintf("Key: %s %s", p.PubKey.Type(), ssh.FingerprintSHA256(p.PubKey)), ui.MsgServer)
+	}
+	if p.PubKeyHash != "" {
+		s.showMessage(p, fmt.Sprintf("Hash: %s", p.PubKeyHash), ui.MsgServer)
+	}
+
+	if conn != nil && conn.Permissions != nil && conn.Permissions.Extensions["verified"] == "true" {
+		s.showMessage(p, fmt.Sprintf("Verified: yes (%s)", conn.Permissions.Extensions["platform"]), ui.MsgServer)
+	} else {
+		s.showMessage(p, "Verified: no", ui.MsgServer)
+	}
+
+	// Rooms registered to this user ("hostKeyHash ownerUsername lastSeenDate")
+	var owned []string
+	s.mu.RLock()
+	for name, info := range s.registeredRooms {
+		parts := strings.Fields(info)
+		if len(parts) >= 2 && parts[1] == p.Username {
+			owned = append(owned, name)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Strings(owned)
+	if len(owned) == 0 {
+		s.showMessage(p, "Rooms owned: none", ui.MsgServer)
+	} else {
+		s.showMessage(p, fmt.Sprintf("Rooms owned: %s", strings.Join(owned, ", ")), ui.MsgServer)
+	}
+}
+
+// DefaultJoinRetryWindow covers a room and a client being started together
+const DefaultJoinRetryWindow = 3 * time.Second
+
+// joinRetryInterval is how often waitForRoom checks the room list
+var joinRetryInterval = 500 * time.Millisecond
+
+func (s *Server) lookupRoom(roomName string) (*Room, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	room, ok := s.rooms[roomName]
+	if ok && !room.lostAt.IsZero() {
+		return nil, false // listed, but waiting for the room to reconnect
+	}
+	return room, ok
+}
+
+// waitForRoom polls for a room that may register moments after a /join,
+// giving up after window
+func (s *Server) waitForRoom(roomName string, window time.Duration) (*Room, bool) {
+	deadline := time.Now().Add(window)
+	for time.Now().Before(deadline) {
+		time.Sleep(joinRetryInterval)
+		if room, ok := s.lookupRoom(roomName); ok {
+			return room, true
+		}
+	}
+	return nil, false
+}
+
+func (s *Server) handleRoomJoin(p *Person, conn *ssh.ServerConn, roomName string) {
+	startPayload, ok := s.requestRoomAccess(p, conn, roomName)
+	if !ok {
 		return
 	}
 
+	// Store data for teleportation
+	p.TeleportData = startPayload
+
+	// Send OSC teleport data to UNN-aware clients
+	common.SendOSC(p.Bus, "teleport", map[string]interface{}{
+		"room_name":   startPayload.RoomName,
+		"candidates":  startPayload.Candidates,
+		"ssh_port":    startPayload.SSHPort,
+		"public_keys": startPayload.PublicKeys,
+	})
+
+	// Final TUI message
+	s.showMessage(p, "Room joined! Teleporting...", ui.MsgSystem)
+
+	// Close the TUI loop immediately
+	p.UI.Close(true)
+}
+
+// handleRawJoin authorizes the person's key with a room like /join does, but
+// prints how to connect instead of teleporting, for clients that do not
+// understand the OSC teleport
+func (s *Server) handleRawJoin(p *Person, conn *ssh.ServerConn, roomName string) {
+	startPayload, ok := s.requestRoomAccess(p, conn, roomName)
+	if !ok {
+		return
+	}
+	entrypoint := conn.LocalAddr().String()
+	if host, port, err := net.SplitHostPort(entrypoint); err == nil && port == "44322" {
+		entrypoint = host
+	}
+	for _, line := range s.rawInstructions(startPayload, entrypoint) {
+		s.showMessage(p, line, ui.MsgServer)
+	}
+}
+
+// rawInstructions formats the unn-client command, the room's addresses and
+// its host key fingerprints. Rooms serve SSH over QUIC, so a stock ssh client
+// cannot connect to them unless they also listen on TCP.
+func (s *Server) rawInstructions(start *protocol.PunchStartPayload, entrypoint string) []string {
+	lines := []string{
+		fmt.Sprintf("%s serves SSH over QUIC, which plain ssh cannot connect to. Join it with the UNN client:", start.RoomName),
+		fmt.Sprintf("  unn-client unn://%s/%s", entrypoint, start.RoomName),
+	}
+
+	var addrs []string
+	seen := make(map[string]bool)
+	for _, c := range start.Candidates {
+		host, _, err := net.SplitHostPort(c)
+		if err != nil || host == "" || seen[c] {
+			continue
+		}
+		seen[c] = true
+		addrs = append(addrs, "  "+c)
+	}
+	if len(addrs) == 0 {
+		lines = append(lines, "The room did not advertise any addresses.")
+	} else {
+		lines = append(lines, "The room advertised these UDP addresses:")
+		lines = append(lines, addrs...)
+	}
+
+	if len(start.PublicKeys) > 0 {
+		lines = append(lines, "Verify the host key:")
+		for _, key := range start.PublicKeys {
+			lines = append(lines, "  "+s.calculateSHA256Fingerprint(key))
+		}
+	}
+	return lines
+}
+
+// punchTimeout is how long a room has to answer a punch offer
+var punchTimeout = 10 * time.Second
+
+// requestRoomAccess asks the room to authorize the person's key and returns
+// the room's connection details
+func (s *Server) requestRoomAccess(p *Person, conn *ssh.ServerConn, roomName string) (*protocol.PunchStartPayload, bool) {
+	// Try to connect to room via hole-punching
+	room, ok := s.lookupRoom(roomName)
+	if !ok && s.joinRetryWindow > 0 {
+		s.showMessage(p, fmt.Sprintf("Waiting for room %s to come online...", roomName), ui.MsgServer)
+		room, ok = s.waitForRoom(roomName, s.joinRetryWindow)
+	}
+
+	if !ok {
+		s.showMessage(p, fmt.Sprintf("Room not found: %s", roomName), ui.MsgServer)
+		return nil, false
+	}
+
 	// Generate person ID
 	personID := fmt.Sprintf("%s-%d", p.Username, time.Now().UnixNano())
 
@@ -168,6 +478,7 @@ func (s *Server) handleRoomJoin(p *Person, conn *ssh.ServerConn, roomName string
 		PersonKey:   personKey,
 		DisplayName: displayName,
 		Username:    unnUsername,
+		Verified:    conn.Permissions != nil && conn.Permissions.Extensions["verified"] == "true",
 	}
 	offerMsg, _ := protocol.NewMessage(protocol.MsgTypePunchOffer, offerPayload)
 
@@ -182,43 +493,29 @@ func (s *Server) handleRoomJoin(p *Person, conn *ssh.ServerConn, roomName string
 		var startPayload protocol.PunchStartPayload
 		if err := startMsg.ParsePayload(&startPayload); err != nil {
 			s.showMessage(p, fmt.Sprintf("Error: %v", err), ui.MsgServer)
-			return
+			return nil, false
 		}
-
-		// Store data for teleportation
-		p.TeleportData = &startPayload
-
-		// Send OSC teleport data to UNN-aware clients
-		common.SendOSC(p.Bus, "teleport", map[string]interface{}{
-			"room_name":   startPayload.RoomName,
-			"candidates":  startPayload.Candidates,
-			"ssh_port":    startPayload.SSHPort,
-			"public_keys": startPayload.PublicKeys,
-		})
-
-		// Final TUI message
-		s.showMessage(p, "Room joined! Teleporting...", ui.MsgSystem)
-
-		// Close the TUI loop immediately
-		p.UI.Close(true)
-	case <-time.After(10 * time.Second):
+		s.metrics.punchSuccesses.Add(1)
+		return &startPayload, true
+	case <-time.After(punchTimeout):
+		s.metrics.punchTimeouts.Add(1)
 		s.showMessage(p, "Timeout waiting for room operator.", ui.MsgServer)
+		return nil, false
 	}
 }
 
 func (s *Server) handlePerson(p *Person, conn *ssh.ServerConn) {
 	entryUI := p.UI
 	if !s.headless {
-		screen, err := tcell.NewTerminfoScreenFromTty(p.Bus)
+		screen, err := common.NewScreen(p.Bus)
 		if err != nil {
-			log.Printf("Failed to create screen for %s: %v", p.Username, err)
-			return
-		}
-		if err := screen.Init(); err != nil {
-			log.Printf("Failed to init screen for %s: %v", p.Username, err)
-			return
+			// Keep the session usable on terminals tcell cannot drive
+			log.Printf("Failed to create screen for %s, using line mode: %v", p.Username, err)
+			entryUI.LineMode = true
+			entryUI.ShowMessage("*** Terminal not supported, using line mode ***", ui.MsgSystem)
+		} else {
+			entryUI.SetScreen(screen)
 		}
-		entryUI.SetScreen(screen)
 	}
 
 	// Handle verification and command setup in background so entryUI.Run() can start
@@ -273,6 +570,10 @@ func (s *Server) handlePerson(p *Person, conn *ssh.ServerConn) {
 			}
 		}
 
+		if warning := conn.Permissions.Extensions["weakkey"]; warning != "" {
+			entryUI.ShowMessage("*** "+warning+" ***", ui.MsgServer)
+		}
+
 		// Process initial command after onboarding is done
 		if p.InitialCommand != "" {
 			s.handlePersonCommand(p, conn, p.InitialCommand)
@@ -297,7 +598,10 @@ func (s *Server) handlePerson(p *Person, conn *ssh.ServerConn) {
 
 	// Clear screen on exit to clean up the TUI artifacts
 	// First reset colors to avoid black background spill
-	fmt.Fprint(p.Bus, "\033[m\033[2J\033[H")
+	if !entryUI.LineMode {
+		fmt.Fprint(p.Bus, "\033[m\033[2J\033[H")
+	}
+	p.Bus.Flush()
 
 	// If success (joined a room), keep connection open for client to do signaling
 	// Client will close when done. If not success, close immediately.
@@ -0,0 +1,154 @@
+package entrypoint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"github.com/mevdschee/underground-node-network/internal/ui/common"
+)
+
+// maxFavorites caps how many rooms one person can mark as favorite
+const maxFavorites = 50
+
+// favoritesPath returns the file holding a person's favorite rooms
+func (s *Server) favoritesPath(pubHash string) string {
+	return filepath.Join(s.usersDir, "favorites", pubHash)
+}
+
+// getFavorites returns a person's favorite rooms, reading them from disk on
+// first use
+func (s *Server) getFavorites(pubHash string) []string {
+	if pubHash == "" {
+		return nil
+	}
+	s.mu.RLock()
+	list, ok := s.favorites[pubHash]
+	s.mu.RUnlock()
+	if ok {
+		return list
+	}
+
+	list = nil
+	if data, err := os.ReadFile(s.favoritesPath(pubHash)); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if name := strings.TrimSpace(line); name != "" {
+				list = append(list, name)
+			}
+		}
+	}
+	s.mu.Lock()
+	s.favorites[pubHash] = list
+	s.mu.Unlock()
+	return list
+}
+
+// saveFavorites replaces and persists a person's favorite rooms
+func (s *Server) saveFavorites(pubHash string, list []string) error {
+	dir := filepath.Dir(s.favoritesPath(pubHash))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	data := ""
+	if len(list) > 0 {
+		data = strings.Join(list, "\n") + "\n"
+	}
+	if err := writeFileAtomic(s.favoritesPath(pubHash), []byte(data), 0600); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.favorites[pubHash] = list
+	s.mu.Unlock()
+	return nil
+}
+
+// addFavorite marks a room as favorite. The room does not need to be online,
+// so rooms that come and go can still be remembered.
+func (s *Server) addFavorite(pubHash, roomName string) error {
+	list := s.getFavorites(pubHash)
+	if slices.Contains(list, roomName) {
+		return fmt.Errorf("%s is already a favorite", roomName)
+	}
+	if len(list) >= maxFavorites {
+		return fmt.Errorf("you already have %d favorites", maxFavorites)
+	}
+	return s.saveFavorites(pubHash, append(slices.Clone(list), roomName))
+}
+
+// removeFavorite unmarks a favorite room
+func (s *Server) removeFavorite(pubHash, roomName string) error {
+	list := s.getFavorites(pubHash)
+	i := slices.Index(list, roomName)
+	if i < 0 {
+		return fmt.Errorf("%s is not a favorite", roomName)
+	}
+	return s.saveFavorites(pubHash, slices.Delete(slices.Clone(list), i, i+1))
+}
+
+// firstOnlineFavorite returns the first favorite room that is online, or ""
+func (s *Server) firstOnlineFavorite(pubHash string) string {
+	list := s.getFavorites(pubHash)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, name := range list {
+		if _, ok := s.rooms[name]; ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// handleFavorite runs /favorite, /unfavorite and /favorites
+func (s *Server) handleFavorite(p *Person, command string, args []string) {
+	if p.PubKeyHash == "" {
+		s.showMessage(p, "Favorites need a public key login.", ui.MsgServer)
+		return
+	}
+	if command == "favorites" {
+		list := s.getFavorites(p.PubKeyHash)
+		if len(list) == 0 {
+			s.showMessage(p, "No favorites. Add one with /favorite <room_name>.", ui.MsgServer)
+			return
+		}
+		lines := make([]string, 0, len(list))
+		s.mu.RLock()
+		for _, name := range list {
+			status := "offline"
+			if room, ok := s.rooms[name]; ok {
+				status = fmt.Sprintf("%d online", room.Info.PeopleCount)
+			}
+			lines = append(lines, fmt.Sprintf("%s %s (%s)", common.Glyphs.Favorite, name, status))
+		}
+		s.mu.RUnlock()
+		s.showMessage(p, "Favorites:", ui.MsgServer)
+		for _, line := range lines {
+			s.showMessage(p, line, ui.MsgServer)
+		}
+		return
+	}
+
+	if len(args) < 1 {
+		s.showMessage(p, fmt.Sprintf("Usage: /%s <room_name>", command), ui.MsgServer)
+		return
+	}
+	roomName := args[0]
+	var err error
+	if command == "favorite" {
+		err = s.addFavorite(p.PubKeyHash, roomName)
+	} else {
+		err = s.removeFavorite(p.PubKeyHash, roomName)
+	}
+	if err != nil {
+		s.showMessage(p, fmt.Sprintf("Error: %v", err), ui.MsgServer)
+		return
+	}
+	if command == "favorite" {
+		s.showMessage(p, fmt.Sprintf("Added %s to your favorites.", roomName), ui.MsgServer)
+	} else {
+		s.showMessage(p, fmt.Sprintf("Removed %s from your favorites.", roomName), ui.MsgServer)
+	}
+	s.updatePersonRooms(p)
+}
@@ -0,0 +1,55 @@
+package entrypoint
+
+import (
+	"log"
+	"time"
+)
+
+// DefaultRoomGrace is how long a room that lost its connection stays listed
+const DefaultRoomGrace = 30 * time.Second
+
+// SetRoomGrace sets how long a room whose connection dropped stays in the
+// room list before it is announced offline (0 removes it immediately). A
+// room that reconnects within the grace period is not announced at all, and
+// people in the room are not affected either way, as they are connected to
+// the room directly.
+func (s *Server) SetRoomGrace(d time.Duration) {
+	s.roomGrace = d
+}
+
+// roomDisconnected handles the operator connection of a room ending
+func (s *Server) roomDisconnected(roomName string) {
+	s.mu.Lock()
+	room, ok := s.rooms[roomName]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	if s.roomGrace <= 0 {
+		delete(s.rooms, roomName)
+		s.mu.Unlock()
+		log.Printf("Room unregistered: %s", roomName)
+		s.updateAllPeople()
+		return
+	}
+	room.lostAt = time.Now()
+	s.mu.Unlock()
+
+	log.Printf("Room %s lost its connection, keeping it listed for %v", roomName, s.roomGrace)
+	time.AfterFunc(s.roomGrace, func() { s.expireRoom(roomName, room) })
+}
+
+// expireRoom removes a room whose grace period ran out, unless it has
+// registered again meanwhile
+func (s *Server) expireRoom(roomName string, room *Room) {
+	s.mu.Lock()
+	if s.rooms[roomName] != room || room.lostAt.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.rooms, roomName)
+	s.mu.Unlock()
+
+	log.Printf("Room unregistered: %s (did not reconnect within %v)", roomName, s.roomGrace)
+	s.updateAllPeople()
+}
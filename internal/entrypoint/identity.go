@@ -4,10 +4,9 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -72,13 +71,99 @@ func (s *Server) saveUsers() error {
 			buf.WriteString(fmt.Sprintf("%s %s %s %s\n", hash, unnUsername, platformInfo, lastSeen))
 		}
 	}
-	err := os.WriteFile(filepath.Join(s.usersDir, "users"), buf.Bytes(), 0600)
+	err := writeFileAtomic(filepath.Join(s.usersDir, "users"), buf.Bytes(), 0600)
 	if err != nil {
 		log.Printf("Error saving users file: %v", err)
 	}
 	return err
 }
 
+// pruneUsers removes identities not seen for more than the given number of days.
+// Identities without a last seen date are kept. Caller must hold s.mu.
+func (s *Server) pruneUsers(days int, now time.Time) int {
+	if days <= 0 {
+		return 0
+	}
+	cutoff := now.AddDate(0, 0, -days).Format("2006-01-02")
+	pruned := 0
+	released := make(map[string]string)
+	for hash, info := range s.identities {
+		// info is "unnUsername platform_username@platform lastSeenDate"
+		fields := strings.Fields(info)
+		if len(fields) < 3 || fields[2] >= cutoff {
+			continue
+		}
+		delete(s.identities, hash)
+		released[fields[0]] = fields[1]
+		pruned++
+	}
+	// A username stays taken while another key of the same person uses it
+	for _, info := range s.identities {
+		if fields := strings.Fields(info); len(fields) >= 1 {
+			delete(released, fields[0])
+		}
+	}
+	for name, platform := range released {
+		if s.usernames[name] == platform {
+			delete(s.usernames, name)
+		}
+	}
+	return pruned
+}
+
+// pruneLoop periodically prunes stale identities until the server is stopped
+func (s *Server) pruneLoop() {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+	for {
+		s.mu.Lock()
+		if n := s.pruneUsers(s.userRetentionDays, time.Now()); n > 0 {
+			log.Printf("Pruned %d identities not seen for %d days", n, s.userRetentionDays)
+			s.saveUsers()
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-ticker.C:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// writeFileAtomic writes data to a temp file and renames it over path,
+// keeping the previous version as path.bak
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+
+	if old, err := os.ReadFile(path); err == nil {
+		if err := os.WriteFile(path+".bak", old, perm); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmpPath, path)
+}
+
 func (s *Server) loadRooms() {
 	path := filepath.Join(s.usersDir, "rooms")
 	data, err := os.ReadFile(path)
@@ -118,7 +203,7 @@ func (s *Server) saveRooms() error {
 			buf.WriteString(fmt.Sprintf("%s %s %s %s\n", hostHash, name, owner, date))
 		}
 	}
-	err := os.WriteFile(filepath.Join(s.usersDir, "rooms"), buf.Bytes(), 0600)
+	err := writeFileAtomic(filepath.Join(s.usersDir, "rooms"), buf.Bytes(), 0600)
 	if err != nil {
 		log.Printf("Error saving rooms file: %v", err)
 	}
@@ -126,31 +211,13 @@ func (s *Server) saveRooms() error {
 }
 
 func (s *Server) VerifyIdentity(platform, username string, offeredKey ssh.PublicKey) (bool, error) {
-	url := ""
-	switch platform {
-	case "github":
-		url = fmt.Sprintf("https://github.com/%s.keys", username)
-	case "gitlab":
-		url = fmt.Sprintf("https://gitlab.com/%s.keys", username)
-	case "sourcehut":
-		url = fmt.Sprintf("https://meta.sr.ht/~%s.keys", username)
-	case "codeberg":
-		url = fmt.Sprintf("https://codeberg.org/%s.keys", username)
-	default:
+	p, ok := findPlatform(platform)
+	if !ok {
 		return false, fmt.Errorf("unsupported platform: %s", platform)
 	}
+	url := fmt.Sprintf(p.KeysURL, username)
 
-	resp, err := s.httpClient.Get(url)
-	if err != nil {
-		return false, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return false, fmt.Errorf("platform returned status %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
+	body, err := s.fetchKeys(url)
 	if err != nil {
 		return false, err
 	}
@@ -198,7 +265,7 @@ func (s *Server) handleOnboardingForm(p *Person, conn *ssh.ServerConn) bool {
 	sshUser := conn.User()
 
 	fields := []form.FormField{
-		{Label: "Platform (github, gitlab, sourcehut, codeberg)", Value: "github"},
+		{Label: "Platform (" + strings.Join(platformNames(), ", ") + ")", Value: "github"},
 		{Label: "Platform Username", Value: ""},
 		{Label: "UNN Username", Value: sshUser, MaxLength: 20, Alphanumeric: true},
 	}
@@ -225,15 +292,7 @@ func (s *Server) handleOnboardingForm(p *Person, conn *ssh.ServerConn) bool {
 			fields[i].Error = ""
 		}
 
-		platforms := []string{"github", "gitlab", "sourcehut", "codeberg"}
-		validPlatform := false
-		for _, v := range platforms {
-			if platform == v {
-				validPlatform = true
-				break
-			}
-		}
-		if !validPlatform {
+		if _, ok := findPlatform(platform); !ok {
 			fields[0].Error = "unsupported platform"
 			continue
 		}
@@ -258,33 +317,32 @@ func (s *Server) handleOnboardingForm(p *Person, conn *ssh.ServerConn) bool {
 		offeredKey, _, _, _, _ := ssh.ParseAuthorizedKey([]byte(pubKeyStr))
 
 		matched, err := s.VerifyIdentity(platform, platformUser, offeredKey)
+		if matched {
+			s.metrics.verifications.Add(1)
+		} else {
+			s.metrics.verifyFailures.Add(1)
+		}
 		if err != nil {
-			if strings.Contains(err.Error(), "status 404") {
+			if errors.Is(err, errUserNotFound) {
 				fields[1].Error = "username not found"
+			} else if errors.Is(err, errPlatformUnavailable) {
+				s.showMessage(p, fmt.Sprintf("%s is temporarily unavailable, please try again in a moment.", platform), ui.MsgServer)
 			} else {
-				s.showMessage(p, fmt.Sprintf("\033[1;31mError verifying identity: %v\033[0m", err), ui.MsgServer)
+				s.showMessage(p, fmt.Sprintf("Error verifying identity: %v", err), ui.MsgServer)
 			}
 			continue
 		}
 
 		if matched {
 			currentPlatform := fmt.Sprintf("%s@%s", platformUser, platform)
-			s.mu.RLock()
-			ownerPlatform, taken := s.usernames[unnUsername]
-			s.mu.RUnlock()
-
-			if taken && ownerPlatform != currentPlatform {
+			revoked, err := s.claimUsername(s.calculatePubKeyHash(offeredKey), unnUsername, currentPlatform)
+			if err != nil {
 				fields[2].Error = "not available"
 				continue
 			}
-
-			s.mu.Lock()
-			currentDate := time.Now().Format("2006-01-02")
-			pubKeyHash := s.calculatePubKeyHash(offeredKey)
-			s.usernames[unnUsername] = currentPlatform
-			s.identities[pubKeyHash] = fmt.Sprintf("%s %s %s", unnUsername, currentPlatform, currentDate)
-			s.saveUsers()
-			s.mu.Unlock()
+			if revoked > 0 {
+				s.showMessage(p, fmt.Sprintf("Username %s is now bound to this key; %d old key(s) no longer work.", unnUsername, revoked), ui.MsgServer)
+			}
 
 			p.Username = unnUsername
 			p.UI.SetUsername(unnUsername)
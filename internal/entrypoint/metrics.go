@@ -0,0 +1,108 @@
+package entrypoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"golang.org/x/crypto/ssh"
+)
+
+// metrics counts events since startup. They are bumped on the connection and
+// punch paths, so they are atomics instead of being guarded by s.mu.
+type metrics struct {
+	started        time.Time
+	connections    atomic.Int64 // completed SSH handshakes
+	verifications  atomic.Int64 // identities confirmed against a platform
+	verifyFailures atomic.Int64 // platform key checks that did not match or failed
+	punchSuccesses atomic.Int64 // rooms that answered a punch offer
+	punchTimeouts  atomic.Int64 // rooms that did not answer in time
+}
+
+// metricsSnapshot is a point-in-time copy of the metrics, as logged
+type metricsSnapshot struct {
+	Uptime         string `json:"uptime"`
+	Connections    int64  `json:"connections"`
+	Verifications  int64  `json:"verifications"`
+	VerifyFailures int64  `json:"verify_failures"`
+	PunchSuccesses int64  `json:"punch_successes"`
+	PunchTimeouts  int64  `json:"punch_timeouts"`
+}
+
+func (m *metrics) snapshot(now time.Time) metricsSnapshot {
+	uptime := time.Duration(0)
+	if !m.started.IsZero() {
+		uptime = now.Sub(m.started).Round(time.Second)
+	}
+	return metricsSnapshot{
+		Uptime:         uptime.String(),
+		Connections:    m.connections.Load(),
+		Verifications:  m.verifications.Load(),
+		VerifyFailures: m.verifyFailures.Load(),
+		PunchSuccesses: m.punchSuccesses.Load(),
+		PunchTimeouts:  m.punchTimeouts.Load(),
+	}
+}
+
+// SetAdmins sets the verified UNN usernames allowed to run admin commands
+// such as /metrics
+func (s *Server) SetAdmins(usernames []string) {
+	admins := make(map[string]bool)
+	for _, name := range usernames {
+		if name = strings.TrimSpace(name); name != "" {
+			admins[name] = true
+		}
+	}
+	s.mu.Lock()
+	s.admins = admins
+	s.mu.Unlock()
+}
+
+// isAdmin reports whether a connection is verified as one of the admins
+func (s *Server) isAdmin(conn *ssh.ServerConn) bool {
+	if conn == nil || conn.Permissions == nil || conn.Permissions.Extensions["verified"] != "true" {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.admins[conn.Permissions.Extensions["username"]]
+}
+
+// SetMetricsLog logs the metrics as a JSON line at this interval (0 = never)
+func (s *Server) SetMetricsLog(interval time.Duration) {
+	s.metricsLog = interval
+}
+
+// metricsLoop writes the periodic metrics line until the server is stopped
+func (s *Server) metricsLoop() {
+	ticker := time.NewTicker(s.metricsLog)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			data, _ := json.Marshal(s.metrics.snapshot(now))
+			log.Printf("Metrics: %s", data)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// handleMetrics shows the counters to an admin
+func (s *Server) handleMetrics(p *Person, conn *ssh.ServerConn) {
+	if !s.isAdmin(conn) {
+		s.showMessage(p, "Only entrypoint admins can view metrics.", ui.MsgServer)
+		return
+	}
+	m := s.metrics.snapshot(time.Now())
+	s.showMessage(p, fmt.Sprintf("--- Metrics (up %s) ---", m.Uptime), ui.MsgServer)
+	s.showMessage(p, fmt.Sprintf("Connections:          %d", m.Connections), ui.MsgServer)
+	s.showMessage(p, fmt.Sprintf("Verifications:        %d", m.Verifications), ui.MsgServer)
+	s.showMessage(p, fmt.Sprintf("Failed verifications: %d", m.VerifyFailures), ui.MsgServer)
+	s.showMessage(p, fmt.Sprintf("Punch successes:      %d", m.PunchSuccesses), ui.MsgServer)
+	s.showMessage(p, fmt.Sprintf("Punch timeouts:       %d", m.PunchTimeouts), ui.MsgServer)
+}
@@ -2,6 +2,7 @@ package entrypoint
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -12,6 +13,90 @@ import (
 	"golang.org/x/crypto/ssh"
 )
 
+// claimRoom registers a new room name for the host key, or renews the
+// registration of the key that owns it. Checking and writing the registry
+// both happen under s.mu, which the caller holds, so of two rooms racing for
+// the same new name the first one wins and the other is told it is taken.
+// hostKeyHash is empty when the room sent no parseable host key: the room is
+// then identified by connKeyHash, the key it connected with. verifiedAs is
+// the connection's verified username, if any. It returns the registered owner.
+func (s *Server) claimRoom(name, hostKeyHash, connKeyHash, username, verifiedAs string) (string, error) {
+	currentDate := time.Now().Format("2006-01-02")
+	if hostKeyHash == "" {
+		hostKeyHash = connKeyHash
+	}
+
+	if info, ok := s.registeredRooms[name]; ok {
+		// format: hostKeyHash owner date
+		parts := strings.Split(info, " ")
+		registeredHostHash, registeredOwner := parts[0], parts[1]
+
+		if hostKeyHash != registeredHostHash {
+			// Host key is different - only the owner may rotate it
+			if verifiedAs != registeredOwner {
+				return "", fmt.Errorf("Room name '%s' is already taken by another user.", name)
+			}
+			log.Printf("Room %s host key rotated by owner %s (new hash: %s)", name, registeredOwner, hostKeyHash)
+		}
+
+		// Update registry (handles both rotation and last-seen updates)
+		s.registeredRooms[name] = fmt.Sprintf("%s %s %s", hostKeyHash, registeredOwner, currentDate)
+		s.saveRooms()
+		return registeredOwner, nil
+	}
+
+	// Silent auto-registration
+	if problem := s.checkNewRoomName(name); problem != "" {
+		return "", errors.New(problem)
+	}
+	s.registeredRooms[name] = fmt.Sprintf("%s %s %s", hostKeyHash, username, currentDate)
+	s.saveRooms()
+	log.Printf("New room auto-registered: %s by %s", name, username)
+	return username, nil
+}
+
+// SetMaxRoomsPerOwner caps how many rooms one owner can have online at once
+// (0 = no limit)
+func (s *Server) SetMaxRoomsPerOwner(n int) {
+	s.mu.Lock()
+	s.maxRoomsPerOwner = n
+	s.mu.Unlock()
+}
+
+// roomOwnerKey returns who a room counts against for -max-rooms-per-owner:
+// the verified identity, or else the connection's key, since an unverified
+// username can be picked freely
+func roomOwnerKey(verifiedAs, keyHash string) string {
+	if verifiedAs != "" {
+		return verifiedAs
+	}
+	return "key:" + keyHash
+}
+
+// checkRoomLimit rejects bringing a room online for an owner who already has
+// the maximum number of rooms online. A room that is already online, for
+// instance reconnecting within its grace period, is always let back in.
+// ownerKey comes from roomOwnerKey. Caller must hold s.mu.
+func (s *Server) checkRoomLimit(name, ownerKey string) error {
+	if s.maxRoomsPerOwner <= 0 {
+		return nil
+	}
+	if _, online := s.rooms[name]; online {
+		return nil
+	}
+	count := 0
+	for _, room := range s.rooms {
+		if room.ownerKey == ownerKey {
+			count++
+		}
+	}
+	if count >= s.maxRoomsPerOwner {
+		owner := strings.TrimPrefix(ownerKey, "key:")
+		return fmt.Errorf("%s already has %d rooms online, the most allowed on this entrypoint.", owner, count)
+	}
+	return nil
+}
+
 func (s *Server) handleOperator(channel ssh.Channel, conn *ssh.ServerConn, username string, roomName *string) {
 	decoder := json.NewDecoder(channel)
 	encoder := json.NewEncoder(channel)
@@ -33,65 +118,34 @@ func (s *Server) handleOperator(channel ssh.Channel, conn *ssh.ServerConn, usern
 				continue
 			}
 
-			s.mu.Lock()
-			currentDate := time.Now().Format("2006-01-02")
-			connPubKeyHash := conn.Permissions.Extensions["pubkeyhash"]
-
-			if info, ok := s.registeredRooms[payload.RoomName]; ok {
-				parts := strings.Split(info, " ")
-				// format: hostKeyHash owner date
-				registeredHostHash := parts[0]
-				registeredOwner := parts[1]
-
-				// Get the host key hash from the payload
-				var payloadHostHash string
-				if len(payload.PublicKeys) > 0 {
-					hPubKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(payload.PublicKeys[0]))
-					if err == nil {
-						payloadHostHash = protocol.CalculatePubKeyHash(hPubKey)
-					}
-				}
-
-				// Check if the host key from the payload matches the registered one
-				if payloadHostHash != "" && payloadHostHash != registeredHostHash {
-					// Host key is different - check if this is the owner rotating the key
-					isOwner := conn.Permissions.Extensions["verified"] == "true" && conn.Permissions.Extensions["username"] == registeredOwner
-					if isOwner {
-						log.Printf("Room %s host key rotated by owner %s (new hash: %s)", payload.RoomName, registeredOwner, payloadHostHash)
-						registeredHostHash = payloadHostHash
-					} else {
-						s.mu.Unlock()
-						log.Printf("Rejected room registration: %s host key mismatch (payload: %s, registered: %s)", payload.RoomName, payloadHostHash, registeredHostHash)
-						s.sendError(encoder, fmt.Sprintf("Room name '%s' is already taken by another user.", payload.RoomName))
-						continue
-					}
-				}
-
-				username = registeredOwner
-
-				// Update registry (handles both rotation and last-seen updates)
-				s.registeredRooms[payload.RoomName] = fmt.Sprintf("%s %s %s", registeredHostHash, registeredOwner, currentDate)
-				s.saveRooms()
-			} else {
-				// Silent auto-registration
-				if !isValidRoomName(payload.RoomName) {
-					s.mu.Unlock()
-					s.sendError(encoder, "Invalid room name. Must be 3-20 characters, alphanumeric.")
-					continue
+			// The room is identified by its host key, if it sent one
+			hostKeyHash := ""
+			if len(payload.PublicKeys) > 0 {
+				hPubKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(payload.PublicKeys[0]))
+				if err == nil {
+					hostKeyHash = protocol.CalculatePubKeyHash(hPubKey)
 				}
+			}
+			verifiedAs := ""
+			if conn.Permissions.Extensions["verified"] == "true" {
+				verifiedAs = conn.Permissions.Extensions["username"]
+			}
 
-				hostKeyHash := connPubKeyHash
-				if len(payload.PublicKeys) > 0 {
-					hPubKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(payload.PublicKeys[0]))
-					if err == nil {
-						hostKeyHash = protocol.CalculatePubKeyHash(hPubKey)
-					}
-				}
+			ownerKey := roomOwnerKey(verifiedAs, conn.Permissions.Extensions["pubkeyhash"])
 
-				s.registeredRooms[payload.RoomName] = fmt.Sprintf("%s %s %s", hostKeyHash, username, currentDate)
-				s.saveRooms()
-				log.Printf("New room auto-registered: %s by %s", payload.RoomName, username)
+			s.mu.Lock()
+			err := s.checkRoomLimit(payload.RoomName, ownerKey)
+			var owner string
+			if err == nil {
+				owner, err = s.claimRoom(payload.RoomName, hostKeyHash, conn.Permissions.Extensions["pubkeyhash"], username, verifiedAs)
+			}
+			if err != nil {
+				s.mu.Unlock()
+				log.Printf("Rejected room registration: %q: %v", payload.RoomName, err)
+				s.sendError(encoder, err.Error())
+				continue
 			}
+			username = owner
 
 			_, alreadyOnline := s.rooms[payload.RoomName]
 			*roomName = payload.RoomName
@@ -109,6 +163,7 @@ func (s *Server) handleOperator(channel ssh.Channel, conn *ssh.ServerConn, usern
 				Connection: conn,
 				Channel:    channel,
 				Encoder:    encoder,
+				ownerKey:   ownerKey,
 			}
 			s.mu.Unlock()
 
@@ -165,6 +220,23 @@ func (s *Server) handleOperator(channel ssh.Channel, conn *ssh.ServerConn, usern
 				}
 			}
 
+		case protocol.MsgTypePreviewResponse:
+			var payload protocol.PreviewResponsePayload
+			if err := msg.ParsePayload(&payload); err != nil {
+				log.Printf("Invalid preview_response payload: %v", err)
+				continue
+			}
+
+			s.mu.RLock()
+			waiter, ok := s.previewWaiters[payload.RequestID]
+			s.mu.RUnlock()
+			if ok {
+				select {
+				case waiter <- payload.Banner:
+				default:
+				}
+			}
+
 		}
 	}
 }
@@ -0,0 +1,181 @@
+package entrypoint
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"github.com/mevdschee/underground-node-network/internal/ui/common"
+)
+
+// identityPlatform is a site whose published SSH keys can verify an identity
+type identityPlatform struct {
+	Name    string
+	KeysURL string // format string taking the platform username
+}
+
+var identityPlatforms = []identityPlatform{
+	{Name: "github", KeysURL: "https://github.com/%s.keys"},
+	{Name: "gitlab", KeysURL: "https://gitlab.com/%s.keys"},
+	{Name: "sourcehut", KeysURL: "https://meta.sr.ht/~%s.keys"},
+	{Name: "codeberg", KeysURL: "https://codeberg.org/%s.keys"},
+}
+
+// platformCheckTTL is how long a reachability check result is reused
+var platformCheckTTL = 5 * time.Minute
+
+const (
+	// DefaultPlatformTimeout bounds one request to an identity platform
+	DefaultPlatformTimeout = 30 * time.Second
+	// DefaultPlatformRetries is how often a failed key fetch is retried
+	DefaultPlatformRetries = 2
+)
+
+// platformBackoff is the wait before the first retry of a key fetch; it
+// doubles for every further retry
+var platformBackoff = time.Second
+
+var (
+	errUserNotFound        = errors.New("username not found")
+	errPlatformUnavailable = errors.New("platform temporarily unavailable, try again")
+)
+
+// platformCheck is the cached result of checking a platform is reachable
+type platformCheck struct {
+	Status    string
+	CheckedAt time.Time
+}
+
+// host returns the platform's host name
+func (p identityPlatform) host() string {
+	u, err := url.Parse(fmt.Sprintf(p.KeysURL, "x"))
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+func findPlatform(name string) (identityPlatform, bool) {
+	for _, platform := range identityPlatforms {
+		if platform.Name == name {
+			return platform, true
+		}
+	}
+	return identityPlatform{}, false
+}
+
+func platformNames() []string {
+	names := make([]string, len(identityPlatforms))
+	for i, platform := range identityPlatforms {
+		names[i] = platform.Name
+	}
+	return names
+}
+
+// SetPlatformTimeout sets how long one request to an identity platform may
+// take before it counts as failed
+func (s *Server) SetPlatformTimeout(d time.Duration) {
+	s.httpClient.Timeout = d
+}
+
+// SetPlatformRetries sets how often a key fetch that failed with a network
+// error or a server error is retried (0 = no retries)
+func (s *Server) SetPlatformRetries(n int) {
+	s.platformRetries = n
+}
+
+// fetchKeys downloads the published keys at url. A 404 means the user does
+// not exist and is not retried; network errors and 5xx responses are, with
+// backoff, before giving up with errPlatformUnavailable.
+func (s *Server) fetchKeys(url string) ([]byte, error) {
+	var lastErr error
+	for attempt := 0; attempt <= s.platformRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(platformBackoff << (attempt - 1))
+		}
+		resp, err := s.httpClient.Get(url)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			return nil, errUserNotFound
+		case resp.StatusCode >= 500:
+			lastErr = fmt.Errorf("status %d", resp.StatusCode)
+		case resp.StatusCode != http.StatusOK:
+			return nil, fmt.Errorf("platform returned status %d", resp.StatusCode)
+		case err != nil:
+			lastErr = err
+		default:
+			return body, nil
+		}
+	}
+	return nil, fmt.Errorf("%w (%v)", errPlatformUnavailable, lastErr)
+}
+
+// checkPlatform reports whether the platform's site answers, reusing a
+// recent result so /platforms check cannot be used to hammer the sites
+func (s *Server) checkPlatform(platform identityPlatform) string {
+	s.mu.RLock()
+	cached, ok := s.platformChecks[platform.Name]
+	s.mu.RUnlock()
+	if ok && time.Since(cached.CheckedAt) < platformCheckTTL {
+		return cached.Status
+	}
+
+	status := "reachable"
+	resp, err := s.httpClient.Get("https://" + platform.host() + "/")
+	if err != nil {
+		status = "unreachable"
+	} else {
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			status = fmt.Sprintf("unavailable (status %d)", resp.StatusCode)
+		}
+	}
+
+	s.mu.Lock()
+	if s.platformChecks == nil {
+		s.platformChecks = make(map[string]platformCheck)
+	}
+	s.platformChecks[platform.Name] = platformCheck{Status: status, CheckedAt: time.Now()}
+	s.mu.Unlock()
+	return status
+}
+
+// handlePlatforms lists the identity platforms, checking each one is
+// reachable when check is set
+func (s *Server) handlePlatforms(p *Person, check bool) {
+	statuses := make([]string, len(identityPlatforms))
+	if check {
+		var wg sync.WaitGroup
+		for i, platform := range identityPlatforms {
+			wg.Add(1)
+			go func(i int, platform identityPlatform) {
+				defer wg.Done()
+				statuses[i] = s.checkPlatform(platform)
+			}(i, platform)
+		}
+		wg.Wait()
+	}
+
+	s.showMessage(p, "Identity platforms:", ui.MsgServer)
+	for i, platform := range identityPlatforms {
+		line := fmt.Sprintf("%s %s (%s)", common.Glyphs.Bullet, platform.Name, platform.host())
+		if statuses[i] != "" {
+			line += ": " + statuses[i]
+		}
+		s.showMessage(p, line, ui.MsgServer)
+	}
+	if !check {
+		s.showMessage(p, "Use /platforms check to test if they can be reached.", ui.MsgServer)
+	}
+}
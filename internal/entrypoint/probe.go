@@ -0,0 +1,57 @@
+package entrypoint
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"log"
+	"net"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"golang.org/x/crypto/ssh"
+)
+
+// probeTimeout bounds a single candidate reachability probe
+const probeTimeout = 2 * time.Second
+
+// handleAPIProbe dials a room candidate over QUIC and reports whether the
+// handshake completed. Only addresses on the caller's own public IP may be
+// probed, so the entrypoint cannot be used to scan third parties.
+func (s *Server) handleAPIProbe(encoder *json.Encoder, conn *ssh.ServerConn, req APIProbeRequest) {
+	host, _, err := net.SplitHostPort(req.Address)
+	if err != nil {
+		s.sendAPIError(encoder, "invalid address")
+		return
+	}
+	remoteHost, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+	if !net.ParseIP(host).Equal(net.ParseIP(remoteHost)) {
+		s.sendAPIError(encoder, "can only probe addresses on your own public IP")
+		return
+	}
+
+	reachable := probeQUIC(req.Address)
+	log.Printf("Probed candidate for %s: reachable=%v", conn.User(), reachable)
+
+	payload, _ := json.Marshal(APIProbeResponse{Reachable: reachable})
+	encoder.Encode(APIMessage{
+		Type:    APITypeResponse,
+		Payload: payload,
+	})
+}
+
+// probeQUIC reports whether a p2pquic listener answers at addr
+func probeQUIC(addr string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	qconn, err := quic.DialAddr(ctx, addr, &tls.Config{
+		InsecureSkipVerify: true,
+		NextProtos:         []string{"p2pquic"},
+	}, nil)
+	if err != nil {
+		return false
+	}
+	qconn.CloseWithError(0, "probe")
+	return true
+}
@@ -20,6 +20,9 @@ import (
 func (s *Server) SendPunchPrepare(roomName string, clientPeerID string, clientCandidates []string, conn *ssh.ServerConn) error {
 	s.mu.RLock()
 	room := s.rooms[roomName]
+	if room != nil && !room.lostAt.IsZero() {
+		room = nil
+	}
 	s.mu.RUnlock()
 
 	if room == nil {
@@ -0,0 +1,91 @@
+package entrypoint
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultConnLimit is how many handshakes one IP may start per window
+const DefaultConnLimit = 30
+
+// DefaultConnWindow is the sliding window the connection limit applies to
+const DefaultConnWindow = time.Minute
+
+// ipLimiter blocks source IPs that open too many connections, before any
+// key is offered. A blocked IP stays blocked for one window.
+type ipLimiter struct {
+	mu      sync.Mutex
+	max     int
+	window  time.Duration
+	hits    map[string][]time.Time // IP -> handshake times inside the window
+	blocked map[string]time.Time   // IP -> end of block
+	now     func() time.Time
+}
+
+func newIPLimiter(max int, window time.Duration) *ipLimiter {
+	return &ipLimiter{
+		max:     max,
+		window:  window,
+		hits:    make(map[string][]time.Time),
+		blocked: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// allow records a connection from ip and reports whether it may proceed.
+// The second result is true only for the connection that caused the block,
+// so callers log each block once.
+func (l *ipLimiter) allow(ip string) (bool, bool) {
+	if l == nil || l.max <= 0 {
+		return true, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if until, ok := l.blocked[ip]; ok {
+		if now.Before(until) {
+			return false, false
+		}
+		delete(l.blocked, ip)
+	}
+
+	cutoff := now.Add(-l.window)
+	recent := l.hits[ip][:0]
+	for _, t := range l.hits[ip] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, now)
+
+	if len(recent) > l.max {
+		delete(l.hits, ip)
+		l.blocked[ip] = now.Add(l.window)
+		return false, true
+	}
+	l.hits[ip] = recent
+
+	// Forget quiet IPs now and then so the map does not grow unbounded
+	if len(l.hits) > 4096 {
+		for k, ts := range l.hits {
+			if len(ts) == 0 || !ts[len(ts)-1].After(cutoff) {
+				delete(l.hits, k)
+			}
+		}
+	}
+	return true, false
+}
+
+// remoteIP returns the host part of a connection's remote address
+func remoteIP(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
@@ -0,0 +1,96 @@
+package entrypoint
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// ReclaimMode decides what happens when a new key is verified against the
+// platform account that a taken UNN username belongs to, for instance after
+// its owner lost their old key
+type ReclaimMode int
+
+const (
+	ReclaimAdd    ReclaimMode = iota // the new key is added next to the old ones
+	ReclaimRebind                    // the new key replaces the username's other keys
+	ReclaimOff                       // taken usernames only accept the keys they already have
+)
+
+// errUsernameTaken is returned when a username belongs to someone else, or
+// cannot be reclaimed in the current mode
+var errUsernameTaken = errors.New("username not available")
+
+// ParseReclaimMode maps a flag value ("add", "rebind" or "off") to a
+// ReclaimMode
+func ParseReclaimMode(name string) (ReclaimMode, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "add":
+		return ReclaimAdd, nil
+	case "rebind":
+		return ReclaimRebind, nil
+	case "off":
+		return ReclaimOff, nil
+	}
+	return ReclaimAdd, fmt.Errorf("unknown reclaim mode: %s", name)
+}
+
+// SetReclaimMode sets how a taken username can be recovered with a new key
+func (s *Server) SetReclaimMode(mode ReclaimMode) {
+	s.mu.Lock()
+	s.reclaimMode = mode
+	s.mu.Unlock()
+}
+
+// claimUsername binds a key to a UNN username after the key was found on
+// platformInfo ("platform_username@platform"). A taken username can only be
+// claimed from the exact platform account it was first verified against, so
+// proving control of that account is what allows recovery. In rebind mode the
+// username's other keys are dropped, so a lost or leaked key stops working.
+// It returns how many old keys were dropped.
+func (s *Server) claimUsername(pubKeyHash, unnUsername, platformInfo string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ownerPlatform, taken := s.usernames[unnUsername]
+	if taken && ownerPlatform != platformInfo {
+		return 0, errUsernameTaken
+	}
+
+	revoked := 0
+	if taken {
+		known := false
+		var others []string
+		for hash, info := range s.identities {
+			fields := strings.Fields(info)
+			if len(fields) < 2 || fields[0] != unnUsername {
+				continue
+			}
+			if hash == pubKeyHash {
+				known = true
+			} else {
+				others = append(others, hash)
+			}
+		}
+		if s.reclaimMode == ReclaimOff && !known && len(others) > 0 {
+			return 0, errUsernameTaken
+		}
+		if s.reclaimMode == ReclaimRebind {
+			for _, hash := range others {
+				delete(s.identities, hash)
+			}
+			revoked = len(others)
+		}
+		if !known && len(others) > 0 {
+			log.Printf("Username %s reclaimed by a new key verified as %s (%d old keys dropped)", unnUsername, platformInfo, revoked)
+		}
+	}
+
+	currentDate := time.Now().Format("2006-01-02")
+	s.usernames[unnUsername] = platformInfo
+	s.identities[pubKeyHash] = fmt.Sprintf("%s %s %s", unnUsername, platformInfo, currentDate)
+	s.saveUsers()
+	return revoked, nil
+}
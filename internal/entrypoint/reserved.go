@@ -0,0 +1,46 @@
+package entrypoint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// loadReservedRooms reads the names no room may claim from reserved_rooms in
+// the users directory, one per line, with # starting a comment. Matching is
+// case-insensitive so "Admin" cannot stand in for "admin".
+func (s *Server) loadReservedRooms() {
+	s.reservedRooms = make(map[string]bool)
+	data, err := os.ReadFile(filepath.Join(s.usersDir, "reserved_rooms"))
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line != "" {
+			s.reservedRooms[strings.ToLower(line)] = true
+		}
+	}
+}
+
+// isReservedRoomName reports whether name is on the reserved list.
+// The caller must hold s.mu.
+func (s *Server) isReservedRoomName(name string) bool {
+	return s.reservedRooms[strings.ToLower(name)]
+}
+
+// checkNewRoomName returns why a name cannot be claimed, or "" if it can.
+// The caller must hold s.mu.
+func (s *Server) checkNewRoomName(name string) string {
+	if !isValidRoomName(name) {
+		return "Invalid room name. Must be 3-20 characters, alphanumeric."
+	}
+	if s.isReservedRoomName(name) {
+		return fmt.Sprintf("Room name '%s' is reserved.", name)
+	}
+	return ""
+}
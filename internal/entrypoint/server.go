@@ -14,8 +14,10 @@ import (
 	"time"
 
 	"github.com/mevdschee/p2pquic-go/pkg/signaling"
+	"github.com/mevdschee/underground-node-network/internal/keystrength"
 	"github.com/mevdschee/underground-node-network/internal/protocol"
 	"github.com/mevdschee/underground-node-network/internal/ui"
+	"github.com/mevdschee/underground-node-network/internal/ui/banner"
 	"github.com/mevdschee/underground-node-network/internal/ui/bridge"
 	"github.com/mevdschee/underground-node-network/internal/ui/common"
 	"golang.org/x/crypto/ssh"
@@ -27,6 +29,8 @@ type Room struct {
 	Connection *ssh.ServerConn
 	Channel    ssh.Channel // For sending messages to operator
 	Encoder    *json.Encoder
+	lostAt     time.Time // when the connection dropped, zero while connected
+	ownerKey   string    // what -max-rooms-per-owner counts, see roomOwnerKey
 }
 
 // PunchSession tracks an active hole-punch negotiation
@@ -36,6 +40,12 @@ type PunchSession struct {
 	PersonChan chan *protocol.Message // Send punch_start to person
 }
 
+// roomPreview is a cached room banner
+type roomPreview struct {
+	Banner    []string
+	FetchedAt time.Time
+}
+
 // Person represents a connected user with a TUI session
 type Person struct {
 	SessionID      string
@@ -49,6 +59,7 @@ type Person struct {
 	PubKeyHash     string
 	Conn           *ssh.ServerConn
 	InitialCommand string
+	LastActive     time.Time // Time of the last command, guarded by Server.mu
 }
 
 // Server is the entry point SSH server
@@ -64,13 +75,36 @@ type Server struct {
 	rooms           map[string]*Room         // room name -> *Room
 	people          map[string]*Person       // session ID -> *Person
 	punchSessions   map[string]*PunchSession // keyed by person ID
+	previewWaiters  map[string]chan []string // keyed by preview request ID
+	previewCache    map[string]roomPreview   // room name -> cached banner
 	identities      map[string]string        // keyHash -> "unnUsername platform_username@platform"
 	usernames       map[string]string        // unnUsername -> platformOwner (e.g. user@github)
 	registeredRooms map[string]string        // roomName -> "hostKeyHash ownerUsername lastSeenDate"
+	reservedRooms   map[string]bool          // lowercase names no room may claim
+	platformChecks  map[string]platformCheck // identity platform -> last reachability check
 	histories       map[string][]ui.Message  // keyed by pubkey hash (hex)
 	cmdHistories    map[string][]string      // keyed by pubkey hash (hex)
+	favorites       map[string][]string      // pubkey hash -> favorite room names, loaded on first use
 	banner          []string
 	headless        bool
+	keyPolicy       keystrength.Policy // how keys weaker than the minimum are handled
+
+	userRetentionDays int             // prune identities not seen for this many days (0 = keep forever)
+	idleTimeout       time.Duration   // disconnect idle people after this long (0 = never)
+	joinRetryWindow   time.Duration   // how long /join waits for a room that is not online yet
+	roomGrace         time.Duration   // how long a disconnected room stays listed
+	bannerMaxLines    int             // cap on banner.asc lines (0 = no limit)
+	bannerMaxBytes    int             // cap on banner.asc size (0 = no limit)
+	bannerEncoding    banner.Encoding // character encoding of banner.asc
+	connLimiter       *ipLimiter      // per-IP handshake rate limit (nil = none)
+	writeDelay        time.Duration   // how long output is coalesced (0 = unbuffered)
+	platformRetries   int             // retries of a failed identity key fetch
+	admins            map[string]bool // verified UNN usernames allowed to run admin commands
+	metrics           metrics         // event counters since startup
+	metricsLog        time.Duration   // how often to log the metrics (0 = never)
+	reclaimMode       ReclaimMode     // how a taken username is recovered with a new key
+	maxRoomsPerOwner  int             // rooms one owner may have online at once (0 = no limit)
+	done              chan struct{}   // closed on Stop
 }
 
 // NewServer creates a new entry point server
@@ -106,21 +140,38 @@ func NewServer(address, hostKeyPath, usersDir string) (*Server, error) {
 		rooms:           make(map[string]*Room),
 		people:          make(map[string]*Person),
 		punchSessions:   make(map[string]*PunchSession),
-		httpClient:      &http.Client{Timeout: 30 * time.Second},
+		previewWaiters:  make(map[string]chan []string),
+		previewCache:    make(map[string]roomPreview),
+		httpClient:      &http.Client{Timeout: DefaultPlatformTimeout},
 		signalingServer: signalingServer,
 		identities:      make(map[string]string),
 		usernames:       make(map[string]string),
 		registeredRooms: make(map[string]string),
 		histories:       make(map[string][]ui.Message),
 		cmdHistories:    make(map[string][]string),
+		favorites:       make(map[string][]string),
+		done:            make(chan struct{}),
+		bannerMaxLines:  banner.DefaultMaxLines,
+		bannerMaxBytes:  banner.DefaultMaxBytes,
+		bannerEncoding:  banner.EncodingAuto,
+		joinRetryWindow: DefaultJoinRetryWindow,
+		roomGrace:       DefaultRoomGrace,
+		connLimiter:     newIPLimiter(DefaultConnLimit, DefaultConnWindow),
+		platformRetries: DefaultPlatformRetries,
 	}
+	s.metrics.started = time.Now()
 
-	// Load data from files
+	// Load data from files (the banner is loaded in Start, after its limits are set)
 	s.loadUsers()
 	s.loadRooms()
-	s.loadBanner()
+	s.loadReservedRooms()
 
 	config.PublicKeyCallback = func(c ssh.ConnMetadata, pubKey ssh.PublicKey) (*ssh.Permissions, error) {
+		warning, err := s.keyPolicy.Check(pubKey)
+		if err != nil {
+			log.Printf("Refused key for %s: %v", c.User(), err)
+			return nil, err
+		}
 		pubKeyHash := s.calculatePubKeyHash(pubKey)
 		requestedUser := c.User()
 
@@ -133,6 +184,7 @@ func NewServer(address, hostKeyPath, usersDir string) (*Server, error) {
 			Extensions: map[string]string{
 				"pubkey":     string(ssh.MarshalAuthorizedKey(pubKey)),
 				"pubkeyhash": pubKeyHash,
+				"weakkey":    warning,
 			},
 		}
 
@@ -181,6 +233,8 @@ func NewServer(address, hostKeyPath, usersDir string) (*Server, error) {
 
 // Start begins listening for QUIC connections
 func (s *Server) Start() error {
+	s.loadBanner()
+
 	// Parse address to get port
 	_, portStr, err := net.SplitHostPort(s.address)
 	if err != nil {
@@ -201,11 +255,73 @@ func (s *Server) Start() error {
 	log.Printf("P2PQUIC signaling server ready (entrypoint is signaling-only, not a peer)")
 
 	go s.acceptLoop()
+	if s.metricsLog > 0 {
+		go s.metricsLoop()
+	}
+	if s.userRetentionDays > 0 {
+		go s.pruneLoop()
+	}
+	if s.idleTimeout > 0 {
+		go s.reapLoop()
+	}
 	return nil
 }
 
+// SetKeyPolicy sets how keys weaker than the minimum strength are handled:
+// accepted, accepted with a warning, or refused
+func (s *Server) SetKeyPolicy(policy keystrength.Policy) {
+	s.keyPolicy = policy
+}
+
+// SetIdleTimeout sets how long a person may sit in the lobby without
+// entering a command. Verified users get twice as long.
+func (s *Server) SetIdleTimeout(d time.Duration) {
+	s.idleTimeout = d
+}
+
+// SetWriteDelay sets how long small writes to a person's terminal are
+// collected before being sent as one (0 sends every write right away)
+func (s *Server) SetWriteDelay(d time.Duration) {
+	s.writeDelay = d
+}
+
+// SetJoinRetryWindow sets how long /join keeps looking for a room that has
+// not registered yet (0 fails immediately)
+func (s *Server) SetJoinRetryWindow(d time.Duration) {
+	s.joinRetryWindow = d
+}
+
+// SetBannerLimits caps the size of banner.asc (0 means no limit)
+func (s *Server) SetBannerLimits(maxLines, maxBytes int) {
+	s.bannerMaxLines = maxLines
+	s.bannerMaxBytes = maxBytes
+}
+
+// SetBannerEncoding sets the character encoding banner.asc is read in
+func (s *Server) SetBannerEncoding(enc banner.Encoding) {
+	s.bannerEncoding = enc
+}
+
+// SetConnectionLimit blocks source IPs that start more than max handshakes
+// within window, for one window (max 0 disables the limit)
+func (s *Server) SetConnectionLimit(max int, window time.Duration) {
+	s.connLimiter = newIPLimiter(max, window)
+}
+
+// SetUserRetention sets the number of days after which unseen identities are pruned
+func (s *Server) SetUserRetention(days int) {
+	s.userRetentionDays = days
+}
+
 // Stop stops the server
 func (s *Server) Stop() error {
+	if s.done != nil {
+		select {
+		case <-s.done:
+		default:
+			close(s.done)
+		}
+	}
 	// Stop the signaling server cleanup goroutine
 	if s.signalingServer != nil {
 		s.signalingServer.Close()
@@ -216,6 +332,14 @@ func (s *Server) Stop() error {
 	return nil
 }
 
+// Addr returns the address the entry point listens on, or nil before Start
+func (s *Server) Addr() net.Addr {
+	if s.tcpListener == nil {
+		return nil
+	}
+	return s.tcpListener.Addr()
+}
+
 // GetRooms returns a list of active rooms
 func (s *Server) GetRooms() []protocol.RoomInfo {
 	s.mu.RLock()
@@ -245,20 +369,30 @@ func (s *Server) acceptLoop() {
 }
 
 func (s *Server) handleConnection(conn net.Conn) {
+	ip := remoteIP(conn.RemoteAddr())
+	if ok, newlyBlocked := s.connLimiter.allow(ip); !ok {
+		if newlyBlocked {
+			log.Printf("Blocking %s: more than %d connections in %v", ip, s.connLimiter.max, s.connLimiter.window)
+		}
+		conn.Close()
+		return
+	}
+
 	sshConn, chans, reqs, err := ssh.NewServerConn(conn, s.config)
 	if err != nil {
 		if err != io.EOF {
-			log.Printf("Failed SSH handshake: %v", err)
+			log.Printf("Failed SSH handshake from %s: %v", ip, err)
 		}
 		return
 	}
 	defer sshConn.Close()
+	s.metrics.connections.Add(1)
 
 	username := sshConn.User()
 	if sshConn.Permissions != nil && sshConn.Permissions.Extensions["verified"] == "true" {
 		username = sshConn.Permissions.Extensions["username"]
 	}
-	log.Printf("Connection from: %s", username)
+	log.Printf("Connection from: %s (%s)", username, ip)
 
 	// Discard global requests
 	go ssh.DiscardRequests(reqs)
@@ -325,11 +459,7 @@ func (s *Server) handleSession(newChannel ssh.NewChannel, conn *ssh.ServerConn,
 
 				// Clean up room when operator disconnects
 				if roomName != "" {
-					s.mu.Lock()
-					delete(s.rooms, roomName)
-					s.mu.Unlock()
-					log.Printf("Room unregistered: %s", roomName)
-					s.updateAllPeople()
+					s.roomDisconnected(roomName)
 				}
 				return
 
@@ -374,10 +504,17 @@ func (s *Server) handleSession(newChannel ssh.NewChannel, conn *ssh.ServerConn,
 				PubKey:     parsedPubKey,
 				PubKeyHash: pubKeyHash,
 				Conn:       conn,
+				LastActive: time.Now(),
 			}
+			p.Bus.SetWriteDelay(s.writeDelay)
 			p.UI = ui.NewEntryUI(nil, p.Username, s.address)
 			p.UI.Headless = s.headless
 			p.UI.Input = p.Bus
+			if strings.HasPrefix(string(conn.ClientVersion()), "SSH-2.0-UNN-CLIENT") {
+				common.SendOSC(p.Bus, "title", map[string]interface{}{
+					"title": "UNN: lobby",
+				})
+			}
 
 			s.mu.Lock()
 			// Disconnect old person with same key
@@ -465,10 +602,10 @@ func loadOrGenerateHostKey(path string) (ssh.Signer, error) {
 }
 
 func (s *Server) loadBanner() {
-	data, err := os.ReadFile("banner.asc")
+	lines, err := banner.Load("banner.asc", s.bannerMaxLines, s.bannerMaxBytes, s.bannerEncoding)
 	if err != nil {
 		log.Printf("No banner.asc file found")
 		return
 	}
-	s.banner = strings.Split(string(data), "\n")
+	s.banner = lines
 }
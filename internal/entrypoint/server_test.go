@@ -3,15 +3,23 @@ package entrypoint
 import (
 	"crypto/ed25519"
 	"crypto/rand"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/mevdschee/underground-node-network/internal/protocol"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"github.com/mevdschee/underground-node-network/internal/ui/common"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -252,3 +260,1052 @@ func TestNewServer(t *testing.T) {
 		t.Error("Maps not initialized")
 	}
 }
+
+func TestWhoami(t *testing.T) {
+	s := &Server{
+		registeredRooms: make(map[string]string),
+		histories:       make(map[string][]ui.Message),
+	}
+
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	sshPubKey, _ := ssh.NewPublicKey(pub)
+	hash := s.calculatePubKeyHash(sshPubKey)
+
+	s.registeredRooms["lounge"] = "somehostkeyhash alice 2024-01-01"
+	s.registeredRooms["other"] = "somehostkeyhash bob 2024-01-01"
+
+	p := &Person{Username: "alice", PubKey: sshPubKey, PubKeyHash: hash}
+	conn := &ssh.ServerConn{Permissions: &ssh.Permissions{
+		Extensions: map[string]string{"verified": "true", "platform": "github"},
+	}}
+
+	s.handleWhoami(p, conn)
+
+	var lines []string
+	for _, m := range s.histories[hash] {
+		lines = append(lines, m.Text)
+	}
+	output := strings.Join(lines, "\n")
+
+	expected := []string{
+		"Username: alice",
+		ssh.FingerprintSHA256(sshPubKey),
+		"Hash: " + hash,
+		"Verified: yes (github)",
+		"Rooms owned: lounge",
+	}
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Errorf("Expected %q in whoami output:\n%s", e, output)
+		}
+	}
+}
+
+func TestUsersAtomicWriteAndPrune(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	s := &Server{
+		usersDir:   tmpDir,
+		identities: make(map[string]string),
+		usernames:  make(map[string]string),
+	}
+	usersPath := filepath.Join(tmpDir, "users")
+
+	s.identities["hash1"] = "alice alice@github 2024-01-01"
+	s.usernames["alice"] = "alice@github"
+	if err := s.saveUsers(); err != nil {
+		t.Fatalf("saveUsers failed: %v", err)
+	}
+	first, _ := os.ReadFile(usersPath)
+
+	s.identities["hash2"] = "bob bob@gitlab 2024-03-01"
+	s.usernames["bob"] = "bob@gitlab"
+	if err := s.saveUsers(); err != nil {
+		t.Fatalf("saveUsers failed: %v", err)
+	}
+
+	t.Run("backup kept", func(t *testing.T) {
+		backup, err := os.ReadFile(usersPath + ".bak")
+		if err != nil {
+			t.Fatalf("Backup not written: %v", err)
+		}
+		if string(backup) != string(first) {
+			t.Errorf("Backup doesn't match previous version. Got: %s", backup)
+		}
+	})
+
+	t.Run("no temp files left", func(t *testing.T) {
+		entries, _ := os.ReadDir(tmpDir)
+		for _, e := range entries {
+			if strings.Contains(e.Name(), ".tmp") {
+				t.Errorf("Temp file left behind: %s", e.Name())
+			}
+		}
+	})
+
+	t.Run("prune", func(t *testing.T) {
+		now := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+		s.identities["hash3"] = "carol carol@github"
+		s.usernames["carol"] = "carol@github"
+
+		pruned := s.pruneUsers(30, now)
+		if pruned != 1 {
+			t.Errorf("Expected 1 pruned identity, got %d", pruned)
+		}
+		if _, ok := s.identities["hash1"]; ok {
+			t.Errorf("Stale identity was not pruned")
+		}
+		if _, ok := s.usernames["alice"]; ok {
+			t.Errorf("Stale username was not released")
+		}
+		if _, ok := s.identities["hash2"]; !ok {
+			t.Errorf("Recent identity was pruned")
+		}
+		if _, ok := s.identities["hash3"]; !ok {
+			t.Errorf("Identity without last seen date was pruned")
+		}
+	})
+
+	t.Run("prune one of two keys", func(t *testing.T) {
+		now := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+		s.identities["laptop"] = "dave dave@github 2024-01-01"
+		s.identities["phone"] = "dave dave@github 2024-03-10"
+		s.usernames["dave"] = "dave@github"
+
+		if pruned := s.pruneUsers(30, now); pruned != 1 {
+			t.Errorf("Expected 1 pruned identity, got %d", pruned)
+		}
+		if _, ok := s.identities["laptop"]; ok {
+			t.Errorf("Stale key was not pruned")
+		}
+		if s.usernames["dave"] != "dave@github" {
+			t.Errorf("Username was released while another key still uses it")
+		}
+	})
+}
+
+func TestReapIdle(t *testing.T) {
+	s := &Server{
+		people:      make(map[string]*Person),
+		histories:   make(map[string][]ui.Message),
+		idleTimeout: 10 * time.Minute,
+	}
+
+	now := time.Now()
+	idle := &Person{Username: "idle", PubKeyHash: "idlehash", LastActive: now.Add(-15 * time.Minute)}
+	idle.UI = ui.NewEntryUI(nil, idle.Username, "localhost")
+	idleClosed := false
+	idle.UI.OnClose(func() { idleClosed = true })
+
+	active := &Person{Username: "active", LastActive: now.Add(-5 * time.Minute)}
+	active.UI = ui.NewEntryUI(nil, active.Username, "localhost")
+	activeClosed := false
+	active.UI.OnClose(func() { activeClosed = true })
+
+	verified := &Person{
+		Username:   "verified",
+		LastActive: now.Add(-15 * time.Minute),
+		Conn: &ssh.ServerConn{Permissions: &ssh.Permissions{
+			Extensions: map[string]string{"verified": "true"},
+		}},
+	}
+
+	s.people["idle"] = idle
+	s.people["active"] = active
+	s.people["verified"] = verified
+
+	if n := s.reapIdle(now); n != 1 {
+		t.Errorf("Expected 1 reaped session, got %d", n)
+	}
+	if !idleClosed {
+		t.Errorf("Idle session was not closed")
+	}
+	if activeClosed {
+		t.Errorf("Active session was closed")
+	}
+	if len(s.histories["idlehash"]) == 0 {
+		t.Errorf("Idle person did not get a notice")
+	}
+}
+
+func TestRoomPreview(t *testing.T) {
+	s := &Server{
+		rooms:          make(map[string]*Room),
+		previewWaiters: make(map[string]chan []string),
+		previewCache:   make(map[string]roomPreview),
+	}
+
+	// Fake room answering preview requests on its operator channel
+	r, w := io.Pipe()
+	s.rooms["lounge"] = &Room{Encoder: json.NewEncoder(w)}
+	requests := 0
+	go func() {
+		decoder := json.NewDecoder(r)
+		for {
+			var msg protocol.Message
+			if err := decoder.Decode(&msg); err != nil {
+				return
+			}
+			var req protocol.PreviewRequestPayload
+			msg.ParsePayload(&req)
+			requests++
+			s.mu.RLock()
+			waiter := s.previewWaiters[req.RequestID]
+			s.mu.RUnlock()
+			waiter <- []string{"Welcome to the lounge"}
+		}
+	}()
+
+	t.Run("fetch", func(t *testing.T) {
+		banner, err := s.fetchRoomPreview("lounge")
+		if err != nil {
+			t.Fatalf("Preview failed: %v", err)
+		}
+		if len(banner) != 1 || banner[0] != "Welcome to the lounge" {
+			t.Errorf("Unexpected banner: %v", banner)
+		}
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		if _, err := s.fetchRoomPreview("lounge"); err != nil {
+			t.Fatalf("Preview failed: %v", err)
+		}
+		if requests != 1 {
+			t.Errorf("Expected cached preview, room was asked %d times", requests)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		old := previewTimeout
+		previewTimeout = 50 * time.Millisecond
+		defer func() { previewTimeout = old }()
+
+		s.rooms["silent"] = &Room{Encoder: json.NewEncoder(io.Discard)}
+		if _, err := s.fetchRoomPreview("silent"); err == nil {
+			t.Errorf("Expected timeout for silent room")
+		}
+	})
+
+	t.Run("unknown room", func(t *testing.T) {
+		if _, err := s.fetchRoomPreview("nowhere"); err == nil {
+			t.Errorf("Expected error for unknown room")
+		}
+	})
+
+	t.Run("lost room", func(t *testing.T) {
+		s.rooms["gone"] = &Room{Encoder: json.NewEncoder(w), lostAt: time.Now()}
+		before := requests
+		if _, err := s.fetchRoomPreview("gone"); err == nil || !strings.Contains(err.Error(), "reconnecting") {
+			t.Errorf("Expected a room in its grace period to be skipped, got %v", err)
+		}
+		if requests != before {
+			t.Errorf("Expected no preview request to a lost room")
+		}
+	})
+
+	t.Run("room answers", func(t *testing.T) {
+		// A real room client on the other end of the operator channel
+		toRoom, fromEntrypoint := io.Pipe()
+		fromRoom, toEntrypoint := io.Pipe()
+		c := &Client{channel: &pipeChannel{Reader: toRoom, Writer: toEntrypoint}}
+		c.SetPreviewHandler(func() []string { return []string{"Hello from the den"} })
+		go c.ListenForMessages(nil, nil, nil, 2222, func() []string { return nil })
+		defer fromEntrypoint.Close()
+
+		// What handleOperator does with the room's answer
+		go func() {
+			decoder := json.NewDecoder(fromRoom)
+			for {
+				var msg protocol.Message
+				if err := decoder.Decode(&msg); err != nil {
+					return
+				}
+				var payload protocol.PreviewResponsePayload
+				if msg.Type != protocol.MsgTypePreviewResponse || msg.ParsePayload(&payload) != nil {
+					continue
+				}
+				s.mu.RLock()
+				waiter := s.previewWaiters[payload.RequestID]
+				s.mu.RUnlock()
+				waiter <- payload.Banner
+			}
+		}()
+
+		s.rooms["den"] = &Room{Encoder: json.NewEncoder(fromEntrypoint)}
+		banner, err := s.fetchRoomPreview("den")
+		if err != nil {
+			t.Fatalf("Preview failed: %v", err)
+		}
+		if len(banner) != 1 || banner[0] != "Hello from the den" {
+			t.Errorf("Expected the room's banner, got %v", banner)
+		}
+	})
+}
+
+// pipeChannel is an ssh.Channel over plain pipes
+type pipeChannel struct {
+	io.Reader
+	io.Writer
+}
+
+func (c *pipeChannel) Close() error      { return nil }
+func (c *pipeChannel) CloseWrite() error { return nil }
+func (c *pipeChannel) SendRequest(string, bool, []byte) (bool, error) {
+	return false, nil
+}
+func (c *pipeChannel) Stderr() io.ReadWriter { return nil }
+
+func TestJoinRetry(t *testing.T) {
+	old := joinRetryInterval
+	joinRetryInterval = 10 * time.Millisecond
+	defer func() { joinRetryInterval = old }()
+
+	s := &Server{rooms: make(map[string]*Room)}
+
+	t.Run("room registers late", func(t *testing.T) {
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			s.mu.Lock()
+			s.rooms["late"] = &Room{}
+			s.mu.Unlock()
+		}()
+		if _, ok := s.waitForRoom("late", time.Second); !ok {
+			t.Errorf("Room that registered within the window was not found")
+		}
+	})
+
+	t.Run("room never registers", func(t *testing.T) {
+		start := time.Now()
+		if _, ok := s.waitForRoom("nowhere", 100*time.Millisecond); ok {
+			t.Errorf("Expected unknown room to stay missing")
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Waited %v, longer than the retry window", elapsed)
+		}
+	})
+}
+
+func TestReservedRoomNames(t *testing.T) {
+	tmpDir := t.TempDir()
+	list := "# names that look official\nadmin\nSystem\n\nofficial # staff only\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "reserved_rooms"), []byte(list), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{usersDir: tmpDir}
+	s.loadReservedRooms()
+
+	t.Run("reserved names are rejected in any case", func(t *testing.T) {
+		for _, name := range []string{"admin", "ADMIN", "system", "Official"} {
+			if problem := s.checkNewRoomName(name); !strings.Contains(problem, "reserved") {
+				t.Errorf("Expected %q to be reserved, got %q", name, problem)
+			}
+		}
+	})
+
+	t.Run("malformed names are rejected", func(t *testing.T) {
+		for _, name := range []string{"ab", strings.Repeat("x", 21), "bad\x1bname", "tab\tname", "dots.name"} {
+			if problem := s.checkNewRoomName(name); !strings.Contains(problem, "Invalid") {
+				t.Errorf("Expected %q to be invalid, got %q", name, problem)
+			}
+		}
+	})
+
+	t.Run("other names are allowed", func(t *testing.T) {
+		for _, name := range []string{"lobby", "admins", "my_room-2"} {
+			if problem := s.checkNewRoomName(name); problem != "" {
+				t.Errorf("Expected %q to be allowed, got %q", name, problem)
+			}
+		}
+	})
+
+	t.Run("missing file reserves nothing", func(t *testing.T) {
+		s := &Server{usersDir: t.TempDir()}
+		s.loadReservedRooms()
+		if problem := s.checkNewRoomName("admin"); problem != "" {
+			t.Errorf("Expected admin to be allowed without a list, got %q", problem)
+		}
+	})
+}
+
+func TestConnectionLimit(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := newIPLimiter(3, time.Minute)
+	l.now = func() time.Time { return now }
+
+	t.Run("flooding IP is blocked", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			if ok, _ := l.allow("203.0.113.7"); !ok {
+				t.Fatalf("Connection %d was blocked below the limit", i+1)
+			}
+		}
+		ok, newlyBlocked := l.allow("203.0.113.7")
+		if ok || !newlyBlocked {
+			t.Fatalf("Expected the 4th connection to start a block, got ok=%v newlyBlocked=%v", ok, newlyBlocked)
+		}
+		if ok, newlyBlocked := l.allow("203.0.113.7"); ok || newlyBlocked {
+			t.Errorf("Expected a blocked IP to stay blocked without logging again")
+		}
+	})
+
+	t.Run("other IPs are unaffected", func(t *testing.T) {
+		if ok, _ := l.allow("198.51.100.1"); !ok {
+			t.Errorf("A different IP was blocked")
+		}
+	})
+
+	t.Run("block expires after the window", func(t *testing.T) {
+		now = now.Add(61 * time.Second)
+		if ok, _ := l.allow("203.0.113.7"); !ok {
+			t.Errorf("Expected the block to expire")
+		}
+	})
+
+	t.Run("slow connections never trip the limit", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			now = now.Add(30 * time.Second)
+			if ok, _ := l.allow("192.0.2.5"); !ok {
+				t.Fatalf("Connection %d was blocked at 2 per minute", i+1)
+			}
+		}
+	})
+
+	t.Run("handshake is refused when blocked", func(t *testing.T) {
+		s := &Server{connLimiter: newIPLimiter(1, time.Minute)}
+		addr := &net.TCPAddr{IP: net.ParseIP("203.0.113.9"), Port: 5555}
+		s.connLimiter.allow(remoteIP(addr))
+
+		server, client := net.Pipe()
+		done := make(chan struct{})
+		go func() {
+			s.handleConnection(&fakeAddrConn{Conn: server, remote: addr})
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Blocked connection was not closed")
+		}
+		client.Close()
+	})
+}
+
+// fakeAddrConn reports a chosen remote address
+type fakeAddrConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c *fakeAddrConn) RemoteAddr() net.Addr { return c.remote }
+
+func TestRawInstructions(t *testing.T) {
+	s := &Server{}
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	sshPub, _ := ssh.NewPublicKey(pub)
+	hostKey := string(ssh.MarshalAuthorizedKey(sshPub))
+
+	t.Run("client command, addresses and fingerprints", func(t *testing.T) {
+		lines := s.rawInstructions(&protocol.PunchStartPayload{
+			RoomName:   "lounge",
+			Candidates: []string{"192.168.1.5:40000", "192.168.1.5:40000", "[2001:db8::1]:40000", "bogus"},
+			SSHPort:    2222,
+			PublicKeys: []string{hostKey},
+		}, "unn.example")
+		out := strings.Join(lines, "\n")
+
+		for _, want := range []string{
+			"unn-client unn://unn.example/lounge",
+			"192.168.1.5:40000",
+			"[2001:db8::1]:40000",
+			"Verify the host key:",
+			ssh.FingerprintSHA256(sshPub),
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("Expected %q in instructions:\n%s", want, out)
+			}
+		}
+		if strings.Contains(out, "ssh -p") {
+			t.Errorf("Expected no plain ssh commands, rooms do not listen on TCP:\n%s", out)
+		}
+		if n := strings.Count(out, "192.168.1.5:40000"); n != 1 {
+			t.Errorf("Expected duplicate addresses to be listed once, got %d", n)
+		}
+		if strings.Contains(out, "bogus") {
+			t.Errorf("Expected malformed candidate to be skipped:\n%s", out)
+		}
+	})
+
+	t.Run("no candidates", func(t *testing.T) {
+		lines := s.rawInstructions(&protocol.PunchStartPayload{RoomName: "lounge", SSHPort: 2222}, "unn.example")
+		out := strings.Join(lines, "\n")
+		if !strings.Contains(out, "did not advertise any addresses") {
+			t.Errorf("Expected a note about missing addresses:\n%s", out)
+		}
+		if strings.Contains(out, "Verify the host key") {
+			t.Errorf("Expected no fingerprint section without keys:\n%s", out)
+		}
+	})
+}
+
+func TestClaimRoomRace(t *testing.T) {
+	s := &Server{usersDir: t.TempDir(), registeredRooms: make(map[string]string)}
+	s.loadReservedRooms()
+
+	t.Run("concurrent registrations", func(t *testing.T) {
+		type result struct {
+			who string
+			err error
+		}
+		for i := 0; i < 50; i++ {
+			name := fmt.Sprintf("race%d", i)
+			start := make(chan struct{})
+			results := make(chan result, 2)
+			for _, who := range []string{"alice", "bob"} {
+				go func(who string) {
+					<-start
+					s.mu.Lock()
+					_, err := s.claimRoom(name, "hostkey-"+who, "connkey-"+who, who, "")
+					s.mu.Unlock()
+					results <- result{who, err}
+				}(who)
+			}
+			close(start)
+
+			winner := ""
+			for j := 0; j < 2; j++ {
+				r := <-results
+				if r.err == nil {
+					if winner != "" {
+						t.Fatalf("Expected only the first registration of %s to succeed, both did", name)
+					}
+					winner = r.who
+				} else if !strings.Contains(r.err.Error(), "already taken") {
+					t.Errorf("Expected the loser to get the taken error, got %v", r.err)
+				}
+			}
+			if winner == "" {
+				t.Fatalf("Expected one registration of %s to succeed, none did", name)
+			}
+			// The loser must not have overwritten the first owner's entry
+			if fields := strings.Fields(s.registeredRooms[name]); fields[0] != "hostkey-"+winner || fields[1] != winner {
+				t.Errorf("Expected %s to stay registered to %s, got %q", name, winner, s.registeredRooms[name])
+			}
+		}
+	})
+
+	t.Run("no host key in the payload", func(t *testing.T) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.claimRoom("attic", "", "connkey-dave", "dave", "")
+		if fields := strings.Fields(s.registeredRooms["attic"]); fields[0] != "connkey-dave" {
+			t.Errorf("Expected a new name to be registered to the connection key, got %q", s.registeredRooms["attic"])
+		}
+		if owner, err := s.claimRoom("attic", "", "connkey-dave", "dave", ""); err != nil || owner != "dave" {
+			t.Errorf("Expected the same connection key to renew, got %q, %v", owner, err)
+		}
+	})
+
+	t.Run("another key cannot take over", func(t *testing.T) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.claimRoom("lounge", "hostkey-alice", "connkey-alice", "alice", "")
+		if _, err := s.claimRoom("lounge", "hostkey-mallory", "connkey-mallory", "mallory", ""); err == nil {
+			t.Error("Expected a different key to be rejected")
+		}
+		if _, err := s.claimRoom("lounge", "", "connkey-mallory", "mallory", ""); err == nil {
+			t.Error("Expected a different key without a host key to be rejected")
+		}
+		owner, err := s.claimRoom("lounge", "hostkey-alice", "connkey-someone", "someone", "")
+		if err != nil || owner != "alice" {
+			t.Errorf("Expected the registered key to renew as alice, got %q, %v", owner, err)
+		}
+		if _, err := s.claimRoom("lounge", "hostkey-new", "connkey-alice", "alice", "alice"); err != nil {
+			t.Errorf("Expected the verified owner to rotate the key, got %v", err)
+		}
+	})
+}
+
+func TestRoomGrace(t *testing.T) {
+	newServer := func(grace time.Duration) *Server {
+		s := &Server{rooms: make(map[string]*Room), people: make(map[string]*Person)}
+		s.SetRoomGrace(grace)
+		s.rooms["lounge"] = &Room{Info: protocol.RoomInfo{Name: "lounge", PeopleCount: 2}}
+		return s
+	}
+
+	t.Run("flap keeps the room listed", func(t *testing.T) {
+		s := newServer(time.Minute)
+		old := s.rooms["lounge"]
+		s.roomDisconnected("lounge")
+
+		rooms := s.GetRooms()
+		if len(rooms) != 1 || rooms[0].PeopleCount != 2 {
+			t.Fatalf("Expected the room and its people to stay listed, got %+v", rooms)
+		}
+		if _, ok := s.lookupRoom("lounge"); ok {
+			t.Error("Expected joins to wait while the room is reconnecting")
+		}
+
+		// The room registers again, then the old grace timer fires
+		s.mu.Lock()
+		s.rooms["lounge"] = &Room{Info: protocol.RoomInfo{Name: "lounge", PeopleCount: 2}}
+		s.mu.Unlock()
+		s.expireRoom("lounge", old)
+		if _, ok := s.lookupRoom("lounge"); !ok {
+			t.Error("Expected the reconnected room to stay online")
+		}
+	})
+
+	t.Run("offline after the grace period", func(t *testing.T) {
+		s := newServer(50 * time.Millisecond)
+		s.roomDisconnected("lounge")
+		time.Sleep(200 * time.Millisecond)
+		if rooms := s.GetRooms(); len(rooms) != 0 {
+			t.Errorf("Expected the room to be gone, got %+v", rooms)
+		}
+	})
+
+	t.Run("no grace", func(t *testing.T) {
+		s := newServer(0)
+		s.roomDisconnected("lounge")
+		if rooms := s.GetRooms(); len(rooms) != 0 {
+			t.Errorf("Expected the room to be removed immediately, got %+v", rooms)
+		}
+	})
+}
+
+func TestPlatforms(t *testing.T) {
+	var requests atomic.Int32
+	s := &Server{
+		histories: make(map[string][]ui.Message),
+		httpClient: &http.Client{Transport: &mockTransport{roundTrip: func(r *http.Request) (*http.Response, error) {
+			requests.Add(1)
+			if r.URL.Host == "github.com" {
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))}, nil
+			}
+			return nil, fmt.Errorf("no route to host")
+		}}},
+	}
+	p := &Person{Username: "alice", PubKeyHash: "abc"}
+	output := func() string {
+		var lines []string
+		for _, m := range s.histories[p.PubKeyHash] {
+			lines = append(lines, m.Text)
+		}
+		s.histories[p.PubKeyHash] = nil
+		return strings.Join(lines, "\n")
+	}
+
+	t.Run("lists the built-in platforms", func(t *testing.T) {
+		s.handlePlatforms(p, false)
+		out := output()
+		for _, want := range []string{"github (github.com)", "gitlab (gitlab.com)", "sourcehut (meta.sr.ht)", "codeberg (codeberg.org)"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("Expected %q in:\n%s", want, out)
+			}
+		}
+		if requests.Load() != 0 {
+			t.Error("Expected no requests without check")
+		}
+	})
+
+	t.Run("check is cached", func(t *testing.T) {
+		s.handlePlatforms(p, true)
+		out := output()
+		if !strings.Contains(out, "github (github.com): reachable") || !strings.Contains(out, "gitlab (gitlab.com): unreachable") {
+			t.Errorf("Unexpected check output:\n%s", out)
+		}
+		s.handlePlatforms(p, true)
+		if int(requests.Load()) != len(identityPlatforms) {
+			t.Errorf("Expected %d requests over two checks, got %d", len(identityPlatforms), requests.Load())
+		}
+	})
+}
+
+func TestVerifyIdentityRetry(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	sshPubKey, _ := ssh.NewPublicKey(pub)
+	authKey := string(ssh.MarshalAuthorizedKey(sshPubKey))
+
+	defer func(d time.Duration) { platformBackoff = d }(platformBackoff)
+	platformBackoff = time.Millisecond
+
+	newServer := func(statuses ...int) (*Server, *atomic.Int32) {
+		var requests atomic.Int32
+		s := &Server{
+			platformRetries: DefaultPlatformRetries,
+			httpClient: &http.Client{Transport: &mockTransport{roundTrip: func(r *http.Request) (*http.Response, error) {
+				n := int(requests.Add(1))
+				status := statuses[len(statuses)-1]
+				if n <= len(statuses) {
+					status = statuses[n-1]
+				}
+				body := ""
+				if status == http.StatusOK {
+					body = authKey
+				}
+				return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body))}, nil
+			}}},
+		}
+		return s, &requests
+	}
+
+	t.Run("500 then 200", func(t *testing.T) {
+		s, requests := newServer(http.StatusInternalServerError, http.StatusOK)
+		matched, err := s.VerifyIdentity("github", "testuser", sshPubKey)
+		if err != nil || !matched {
+			t.Fatalf("Expected a match after a retry, got %v, %v", matched, err)
+		}
+		if n := requests.Load(); n != 2 {
+			t.Errorf("Expected 2 requests, got %d", n)
+		}
+	})
+
+	t.Run("404 is not retried", func(t *testing.T) {
+		s, requests := newServer(http.StatusNotFound)
+		_, err := s.VerifyIdentity("github", "nobody", sshPubKey)
+		if !errors.Is(err, errUserNotFound) {
+			t.Errorf("Expected errUserNotFound, got %v", err)
+		}
+		if n := requests.Load(); n != 1 {
+			t.Errorf("Expected 1 request, got %d", n)
+		}
+	})
+
+	t.Run("persistent 503", func(t *testing.T) {
+		s, requests := newServer(http.StatusServiceUnavailable)
+		_, err := s.VerifyIdentity("github", "testuser", sshPubKey)
+		if !errors.Is(err, errPlatformUnavailable) {
+			t.Errorf("Expected errPlatformUnavailable, got %v", err)
+		}
+		if n := requests.Load(); n != int32(DefaultPlatformRetries+1) {
+			t.Errorf("Expected %d requests, got %d", DefaultPlatformRetries+1, n)
+		}
+	})
+
+	t.Run("network error", func(t *testing.T) {
+		var requests atomic.Int32
+		s := &Server{
+			platformRetries: 1,
+			httpClient: &http.Client{Transport: &mockTransport{roundTrip: func(r *http.Request) (*http.Response, error) {
+				requests.Add(1)
+				return nil, errors.New("connection reset")
+			}}},
+		}
+		_, err := s.VerifyIdentity("github", "testuser", sshPubKey)
+		if !errors.Is(err, errPlatformUnavailable) {
+			t.Errorf("Expected errPlatformUnavailable, got %v", err)
+		}
+		if n := requests.Load(); n != 2 {
+			t.Errorf("Expected 2 requests, got %d", n)
+		}
+	})
+}
+
+func TestMetrics(t *testing.T) {
+	defer func(d time.Duration) { punchTimeout = d }(punchTimeout)
+	punchTimeout = 50 * time.Millisecond
+
+	s := &Server{
+		rooms:         map[string]*Room{"lounge": {Info: protocol.RoomInfo{Name: "lounge"}}},
+		punchSessions: make(map[string]*PunchSession),
+		histories:     make(map[string][]ui.Message),
+	}
+	s.metrics.started = time.Now()
+	s.SetAdmins([]string{"root"})
+	conn := &ssh.ServerConn{Permissions: &ssh.Permissions{Extensions: map[string]string{}}}
+	p := &Person{Username: "alice", PubKeyHash: "alice"}
+
+	t.Run("punch timeout", func(t *testing.T) {
+		if _, ok := s.requestRoomAccess(p, conn, "lounge"); ok {
+			t.Fatal("Expected the punch to time out")
+		}
+		if n := s.metrics.punchTimeouts.Load(); n != 1 {
+			t.Errorf("Expected 1 punch timeout, got %d", n)
+		}
+	})
+
+	t.Run("punch success", func(t *testing.T) {
+		go func() {
+			for {
+				s.mu.RLock()
+				var session *PunchSession
+				for _, ps := range s.punchSessions {
+					session = ps
+				}
+				s.mu.RUnlock()
+				if session != nil {
+					msg, _ := protocol.NewMessage(protocol.MsgTypePunchStart, protocol.PunchStartPayload{RoomName: "lounge"})
+					session.PersonChan <- msg
+					return
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}()
+		if _, ok := s.requestRoomAccess(p, conn, "lounge"); !ok {
+			t.Fatal("Expected the punch to succeed")
+		}
+		if n := s.metrics.punchSuccesses.Load(); n != 1 {
+			t.Errorf("Expected 1 punch success, got %d", n)
+		}
+	})
+
+	t.Run("admins only", func(t *testing.T) {
+		s.handleMetrics(p, conn)
+		if got := s.histories["alice"][len(s.histories["alice"])-1].Text; got != "Only entrypoint admins can view metrics." {
+			t.Errorf("Expected non-admins to be refused, got %q", got)
+		}
+
+		admin := &Person{Username: "root", PubKeyHash: "root"}
+		adminConn := &ssh.ServerConn{Permissions: &ssh.Permissions{Extensions: map[string]string{"verified": "true", "username": "root"}}}
+		s.handleMetrics(admin, adminConn)
+		var lines []string
+		for _, m := range s.histories["root"] {
+			lines = append(lines, m.Text)
+		}
+		out := strings.Join(lines, "\n")
+		for _, want := range []string{"Punch successes:      1", "Punch timeouts:       1"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("Expected %q in %q", want, out)
+			}
+		}
+
+		unverified := &ssh.ServerConn{Permissions: &ssh.Permissions{Extensions: map[string]string{"username": "root"}}}
+		if s.isAdmin(unverified) {
+			t.Error("Expected an unverified connection not to be an admin")
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		s.metrics.connections.Add(2)
+		data, _ := json.Marshal(s.metrics.snapshot(time.Now()))
+		if !strings.Contains(string(data), `"connections":2`) || !strings.Contains(string(data), `"punch_successes":1`) {
+			t.Errorf("Unexpected metrics JSON %s", data)
+		}
+	})
+}
+
+func TestFavorites(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := NewServer(":0", filepath.Join(tmpDir, "host_key"), tmpDir)
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+	s.rooms["lounge"] = &Room{Info: protocol.RoomInfo{Name: "lounge", PeopleCount: 3}}
+	p := &Person{Username: "alice", PubKeyHash: "alicehash"}
+	last := func() string {
+		h := s.histories["alicehash"]
+		return h[len(h)-1].Text
+	}
+
+	t.Run("add and list", func(t *testing.T) {
+		s.handleFavorite(p, "favorite", []string{"cellar"})
+		s.handleFavorite(p, "favorite", []string{"lounge"})
+		if last() != "Added lounge to your favorites." {
+			t.Errorf("Unexpected reply %q", last())
+		}
+		s.handleFavorite(p, "favorite", []string{"lounge"})
+		if last() != "Error: lounge is already a favorite" {
+			t.Errorf("Expected duplicate to be refused, got %q", last())
+		}
+		s.handleFavorite(p, "favorites", nil)
+		h := s.histories["alicehash"]
+		got := []string{h[len(h)-2].Text, h[len(h)-1].Text}
+		want := []string{common.Glyphs.Favorite + " cellar (offline)", common.Glyphs.Favorite + " lounge (3 online)"}
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	})
+
+	t.Run("first online favorite", func(t *testing.T) {
+		if got := s.firstOnlineFavorite("alicehash"); got != "lounge" {
+			t.Errorf("Expected lounge, got %q", got)
+		}
+		if got := s.firstOnlineFavorite("nobody"); got != "" {
+			t.Errorf("Expected no favorite, got %q", got)
+		}
+	})
+
+	t.Run("persist", func(t *testing.T) {
+		data, err := os.ReadFile(filepath.Join(tmpDir, "favorites", "alicehash"))
+		if err != nil || string(data) != "cellar\nlounge\n" {
+			t.Fatalf("Unexpected favorites file %q, %v", data, err)
+		}
+		reloaded, err := NewServer(":0", filepath.Join(tmpDir, "host_key"), tmpDir)
+		if err != nil {
+			t.Fatalf("NewServer failed: %v", err)
+		}
+		if got := reloaded.getFavorites("alicehash"); strings.Join(got, ",") != "cellar,lounge" {
+			t.Errorf("Expected favorites to survive a restart, got %v", got)
+		}
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		s.handleFavorite(p, "unfavorite", []string{"cellar"})
+		s.handleFavorite(p, "unfavorite", []string{"cellar"})
+		if last() != "Error: cellar is not a favorite" {
+			t.Errorf("Expected missing favorite error, got %q", last())
+		}
+		if got := s.getFavorites("alicehash"); strings.Join(got, ",") != "lounge" {
+			t.Errorf("Expected only lounge left, got %v", got)
+		}
+	})
+}
+
+func TestReclaimUsername(t *testing.T) {
+	newServer := func(t *testing.T, mode ReclaimMode) *Server {
+		tmpDir := t.TempDir()
+		s, err := NewServer(":0", filepath.Join(tmpDir, "host_key"), tmpDir)
+		if err != nil {
+			t.Fatalf("NewServer failed: %v", err)
+		}
+		s.SetReclaimMode(mode)
+		if _, err := s.claimUsername("oldkey", "alice", "alice@github"); err != nil {
+			t.Fatalf("Initial claim failed: %v", err)
+		}
+		return s
+	}
+
+	t.Run("rebind drops the lost key", func(t *testing.T) {
+		s := newServer(t, ReclaimRebind)
+		revoked, err := s.claimUsername("newkey", "alice", "alice@github")
+		if err != nil || revoked != 1 {
+			t.Fatalf("Expected reclaim to drop 1 key, got %d, %v", revoked, err)
+		}
+		if _, ok := s.identities["oldkey"]; ok {
+			t.Error("Expected the old key to be dropped")
+		}
+		if fields := strings.Fields(s.identities["newkey"]); len(fields) < 2 || fields[0] != "alice" || fields[1] != "alice@github" {
+			t.Errorf("Expected the new key to be bound to alice, got %q", s.identities["newkey"])
+		}
+		data, _ := os.ReadFile(filepath.Join(s.usersDir, "users"))
+		if strings.Contains(string(data), "oldkey") || !strings.Contains(string(data), "newkey alice alice@github") {
+			t.Errorf("Expected the rebind to be saved, got %q", data)
+		}
+	})
+
+	t.Run("add keeps both keys", func(t *testing.T) {
+		s := newServer(t, ReclaimAdd)
+		if revoked, err := s.claimUsername("newkey", "alice", "alice@github"); err != nil || revoked != 0 {
+			t.Fatalf("Expected reclaim without revoking, got %d, %v", revoked, err)
+		}
+		if _, ok := s.identities["oldkey"]; !ok {
+			t.Error("Expected the old key to be kept")
+		}
+	})
+
+	t.Run("another platform account cannot take it", func(t *testing.T) {
+		for _, mode := range []ReclaimMode{ReclaimAdd, ReclaimRebind, ReclaimOff} {
+			s := newServer(t, mode)
+			for _, platform := range []string{"mallory@github", "alice@gitlab", "Alice@github"} {
+				if _, err := s.claimUsername("evilkey", "alice", platform); !errors.Is(err, errUsernameTaken) {
+					t.Errorf("Expected %s to be refused in mode %d, got %v", platform, mode, err)
+				}
+			}
+			if _, ok := s.identities["evilkey"]; ok || s.usernames["alice"] != "alice@github" {
+				t.Errorf("Expected alice to stay bound to alice@github in mode %d", mode)
+			}
+		}
+	})
+
+	t.Run("off refuses new keys but not known ones", func(t *testing.T) {
+		s := newServer(t, ReclaimOff)
+		if _, err := s.claimUsername("newkey", "alice", "alice@github"); !errors.Is(err, errUsernameTaken) {
+			t.Errorf("Expected reclaim to be refused, got %v", err)
+		}
+		if _, err := s.claimUsername("oldkey", "alice", "alice@github"); err != nil {
+			t.Errorf("Expected the existing key to verify again, got %v", err)
+		}
+	})
+
+	t.Run("parse", func(t *testing.T) {
+		if mode, err := ParseReclaimMode("Rebind"); err != nil || mode != ReclaimRebind {
+			t.Errorf("Expected rebind, got %v, %v", mode, err)
+		}
+		if _, err := ParseReclaimMode("steal"); err == nil {
+			t.Error("Expected an error for an unknown mode")
+		}
+	})
+}
+
+func TestMaxRoomsPerOwner(t *testing.T) {
+	s := &Server{usersDir: t.TempDir(), registeredRooms: make(map[string]string), rooms: make(map[string]*Room)}
+	s.loadReservedRooms()
+	s.SetMaxRoomsPerOwner(2)
+
+	// register brings a room online from a connection with key connKey
+	register := func(name, user, connKey, verifiedAs string) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		ownerKey := roomOwnerKey(verifiedAs, connKey)
+		if err := s.checkRoomLimit(name, ownerKey); err != nil {
+			return err
+		}
+		owner, err := s.claimRoom(name, "hostkey-"+name, connKey, user, verifiedAs)
+		if err != nil {
+			return err
+		}
+		s.rooms[name] = &Room{Info: protocol.RoomInfo{Name: name, Owner: owner}, ownerKey: ownerKey}
+		return nil
+	}
+
+	t.Run("over the limit", func(t *testing.T) {
+		for _, name := range []string{"one", "two"} {
+			if err := register(name, "squatter", "key1", ""); err != nil {
+				t.Fatalf("Expected %s to register, got %v", name, err)
+			}
+		}
+		err := register("three", "squatter", "key1", "")
+		if err == nil || !strings.Contains(err.Error(), "key1 already has 2 rooms online") {
+			t.Errorf("Expected the third room to be rejected, got %v", err)
+		}
+		if _, ok := s.registeredRooms["three"]; ok {
+			t.Error("Expected a rejected room not to claim its name")
+		}
+	})
+
+	t.Run("others are unaffected", func(t *testing.T) {
+		if err := register("lounge", "alice", "key2", ""); err != nil {
+			t.Errorf("Expected another owner to register, got %v", err)
+		}
+	})
+
+	t.Run("unverified usernames are not counted", func(t *testing.T) {
+		// Someone else calling themselves squatter uses their own slots
+		if err := register("mimic", "squatter", "key3", ""); err != nil {
+			t.Errorf("Expected another key with the same username to register, got %v", err)
+		}
+	})
+
+	t.Run("verified identities count over all keys", func(t *testing.T) {
+		if err := register("work", "bob", "laptop", "bob@github"); err != nil {
+			t.Fatal(err)
+		}
+		if err := register("home", "bob", "desktop", "bob@github"); err != nil {
+			t.Fatal(err)
+		}
+		err := register("cafe", "bob", "phone", "bob@github")
+		if err == nil || !strings.Contains(err.Error(), "bob@github already has 2 rooms online") {
+			t.Errorf("Expected a verified owner's third room to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("online rooms may reconnect", func(t *testing.T) {
+		if err := register("two", "squatter", "key1", ""); err != nil {
+			t.Errorf("Expected an online room to re-register, got %v", err)
+		}
+	})
+
+	t.Run("room goes offline", func(t *testing.T) {
+		delete(s.rooms, "one")
+		if err := register("three", "squatter", "key1", ""); err != nil {
+			t.Errorf("Expected a free slot after a room went offline, got %v", err)
+		}
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		s.SetMaxRoomsPerOwner(0)
+		if err := register("four", "squatter", "key1", ""); err != nil {
+			t.Errorf("Expected no limit, got %v", err)
+		}
+	})
+}
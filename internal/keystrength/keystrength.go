@@ -0,0 +1,100 @@
+package keystrength
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultMinRSABits is the smallest RSA key accepted as strong when the
+// policy gives no size
+const DefaultMinRSABits = 2048
+
+// Action is what happens when a client authenticates with a weak key
+type Action int
+
+const (
+	Allow  Action = iota // accept silently
+	Warn                 // accept and tell the user to upgrade
+	Refuse               // reject the key with a banner telling the user to upgrade
+)
+
+// Policy decides which keys are weak and what to do about them
+type Policy struct {
+	Action     Action
+	MinRSABits int
+}
+
+// Parse maps a -min-key-strength value to a Policy: off, warn or refuse,
+// optionally followed by the minimum RSA size, e.g. "refuse:3072"
+func Parse(value string) (Policy, error) {
+	action, bits, hasBits := strings.Cut(strings.ToLower(strings.TrimSpace(value)), ":")
+	p := Policy{MinRSABits: DefaultMinRSABits}
+	switch action {
+	case "", "off":
+		p.Action = Allow
+	case "warn":
+		p.Action = Warn
+	case "refuse":
+		p.Action = Refuse
+	default:
+		return p, fmt.Errorf("unknown key strength policy: %s", value)
+	}
+	if hasBits {
+		n, err := strconv.Atoi(bits)
+		if err != nil || n <= 0 {
+			return p, fmt.Errorf("invalid RSA key size: %s", bits)
+		}
+		p.MinRSABits = n
+	}
+	return p, nil
+}
+
+// Weakness describes why key is weak, or returns "" for a strong key. DSA
+// keys are always weak, RSA keys are weak below minRSABits.
+func Weakness(key ssh.PublicKey, minRSABits int) string {
+	if cert, ok := key.(*ssh.Certificate); ok {
+		key = cert.Key
+	}
+	switch key.Type() {
+	case ssh.InsecureKeyAlgoDSA:
+		return "DSA keys are no longer considered secure"
+	case ssh.KeyAlgoRSA:
+		cryptoKey, ok := key.(ssh.CryptoPublicKey)
+		if !ok {
+			return ""
+		}
+		rsaKey, ok := cryptoKey.CryptoPublicKey().(*rsa.PublicKey)
+		if !ok {
+			return ""
+		}
+		if bits := rsaKey.N.BitLen(); bits < minRSABits {
+			return fmt.Sprintf("your RSA key has %d bits, this server wants at least %d", bits, minRSABits)
+		}
+	}
+	return ""
+}
+
+// Check applies the policy to key. It returns a warning to show the user
+// when the key is weak and the policy warns, and an *ssh.BannerError when
+// it refuses, so SSH clients print why they could not log in.
+func (p Policy) Check(key ssh.PublicKey) (string, error) {
+	if p.Action == Allow {
+		return "", nil
+	}
+	weakness := Weakness(key, p.MinRSABits)
+	if weakness == "" {
+		return "", nil
+	}
+	message := fmt.Sprintf("Weak key: %s. Please upgrade, e.g. with: ssh-keygen -t ed25519", weakness)
+	if p.Action == Refuse {
+		return "", &ssh.BannerError{
+			Err:     fmt.Errorf("weak key refused: %s", weakness),
+			Message: message + "\r\n",
+		}
+	}
+	return message, nil
+}
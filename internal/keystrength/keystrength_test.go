@@ -0,0 +1,96 @@
+package keystrength
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func rsaKey(t *testing.T, bits int) ssh.PublicKey {
+	priv, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Policy
+	}{
+		{"", Policy{Allow, DefaultMinRSABits}},
+		{"off", Policy{Allow, DefaultMinRSABits}},
+		{"warn", Policy{Warn, DefaultMinRSABits}},
+		{"Refuse:3072", Policy{Refuse, 3072}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%q) = %+v, %v, want %+v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"strict", "warn:", "refuse:big", "warn:-1"} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("Expected an error for %q", in)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	weak := rsaKey(t, 1024)
+	strong := rsaKey(t, 2048)
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	modern, _ := ssh.NewPublicKey(pub)
+
+	t.Run("weakness", func(t *testing.T) {
+		if got := Weakness(weak, 2048); got != "your RSA key has 1024 bits, this server wants at least 2048" {
+			t.Errorf("Unexpected weakness %q", got)
+		}
+		if got := Weakness(strong, 2048); got != "" {
+			t.Errorf("Expected a 2048-bit key to pass, got %q", got)
+		}
+		if got := Weakness(strong, 3072); got == "" {
+			t.Error("Expected a 2048-bit key to fail a 3072-bit minimum")
+		}
+		if got := Weakness(modern, 2048); got != "" {
+			t.Errorf("Expected an Ed25519 key to pass, got %q", got)
+		}
+	})
+
+	t.Run("off is permissive", func(t *testing.T) {
+		if warning, err := (Policy{Allow, 2048}).Check(weak); warning != "" || err != nil {
+			t.Errorf("Expected no warning or error, got %q, %v", warning, err)
+		}
+	})
+
+	t.Run("warn accepts with a warning", func(t *testing.T) {
+		warning, err := Policy{Warn, 2048}.Check(weak)
+		if err != nil || !strings.Contains(warning, "Please upgrade") {
+			t.Errorf("Expected an upgrade warning, got %q, %v", warning, err)
+		}
+		if warning, _ := (Policy{Warn, 2048}).Check(modern); warning != "" {
+			t.Errorf("Expected no warning for a strong key, got %q", warning)
+		}
+	})
+
+	t.Run("refuse sends a banner", func(t *testing.T) {
+		_, err := Policy{Refuse, 2048}.Check(weak)
+		var bannerErr *ssh.BannerError
+		if !errors.As(err, &bannerErr) || !strings.Contains(bannerErr.Message, "ssh-keygen -t ed25519") {
+			t.Errorf("Expected a banner telling the user to upgrade, got %v", err)
+		}
+		if _, err := (Policy{Refuse, 2048}).Check(strong); err != nil {
+			t.Errorf("Expected a strong key to be accepted, got %v", err)
+		}
+	})
+}
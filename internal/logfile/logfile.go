@@ -0,0 +1,83 @@
+package logfile
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// DefaultMaxSize is the size at which a log file is rolled over (10 MiB)
+const DefaultMaxSize = 10 * 1024 * 1024
+
+// RotatingWriter is an io.Writer that appends to a file and rolls it over
+// to <path>.1 once it grows beyond MaxSize. Only one backup is kept.
+type RotatingWriter struct {
+	path    string
+	maxSize int64
+	mu      sync.Mutex
+	file    *os.File
+	size    int64
+}
+
+// Open opens (or creates) the log file at path for appending
+func Open(path string, maxSize int64) (*RotatingWriter, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	w := &RotatingWriter{
+		path:    path,
+		maxSize: maxSize,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *RotatingWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	w.file = f
+	w.size = info.Size()
+	return nil
+}
+
+// rotate moves the current file to <path>.1 and starts a new one
+func (w *RotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(w.path, w.path+".1"); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	return w.open()
+}
+
+// Write appends p to the log file, rolling over first if it would exceed MaxSize
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close closes the underlying file
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
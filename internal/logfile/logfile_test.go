@@ -0,0 +1,68 @@
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingWriterRollover(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "unn.log")
+
+	w, err := Open(path, 32)
+	if err != nil {
+		t.Fatalf("Failed to open log file: %v", err)
+	}
+	defer w.Close()
+
+	first := strings.Repeat("a", 20) + "\n"
+	second := strings.Repeat("b", 20) + "\n"
+
+	if _, err := w.Write([]byte(first)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Fatalf("Rolled over before reaching max size")
+	}
+
+	if _, err := w.Write([]byte(second)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	backup, err := os.ReadFile(path + ".1")
+	if err != nil {
+		t.Fatalf("Expected backup file after rollover: %v", err)
+	}
+	if string(backup) != first {
+		t.Errorf("Backup content mismatch: got %q, want %q", backup, first)
+	}
+
+	current, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read current log: %v", err)
+	}
+	if string(current) != second {
+		t.Errorf("Current content mismatch: got %q, want %q", current, second)
+	}
+}
+
+func TestRotatingWriterAppendsExisting(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "unn.log")
+	os.WriteFile(path, []byte(strings.Repeat("x", 30)), 0644)
+
+	w, err := Open(path, 32)
+	if err != nil {
+		t.Fatalf("Failed to open log file: %v", err)
+	}
+	defer w.Close()
+
+	if _, err := w.Write([]byte("hello\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Errorf("Expected existing file size to count towards rollover")
+	}
+}
@@ -0,0 +1,77 @@
+package logfile
+
+import (
+	"strings"
+	"sync"
+)
+
+// DefaultRingLines is how many log lines a Ring keeps by default
+const DefaultRingLines = 500
+
+// maxRingLineLength caps a single kept line, so one huge log line cannot
+// hold on to a lot of memory
+const maxRingLineLength = 1024
+
+// Ring is an io.Writer that keeps the last lines written to it in memory,
+// so a process can show its own recent log without reading it back from disk
+type Ring struct {
+	mu      sync.Mutex
+	lines   []string
+	next    int // index of the oldest line once the ring is full
+	full    bool
+	partial string // text after the last newline, waiting for the rest of its line
+}
+
+// NewRing creates a Ring holding at most size lines
+func NewRing(size int) *Ring {
+	if size <= 0 {
+		size = DefaultRingLines
+	}
+	return &Ring{lines: make([]string, size)}
+}
+
+// Write splits p into lines and keeps them, dropping the oldest when full
+func (r *Ring) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	text := r.partial + string(p)
+	parts := strings.Split(text, "\n")
+	r.partial = parts[len(parts)-1]
+	if len(r.partial) > maxRingLineLength {
+		r.partial = r.partial[:maxRingLineLength]
+	}
+	for _, line := range parts[:len(parts)-1] {
+		if len(line) > maxRingLineLength {
+			line = line[:maxRingLineLength]
+		}
+		r.lines[r.next] = line
+		r.next = (r.next + 1) % len(r.lines)
+		r.full = r.full || r.next == 0
+	}
+	return len(p), nil
+}
+
+// Lines returns up to n of the most recent complete lines, oldest first
+func (r *Ring) Lines(n int) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := r.next
+	if r.full {
+		count = len(r.lines)
+	}
+	n = min(n, count)
+	if n <= 0 {
+		return nil
+	}
+	out := make([]string, 0, n)
+	for i := count - n; i < count; i++ {
+		idx := i
+		if r.full {
+			idx = (r.next + i) % len(r.lines)
+		}
+		out = append(out, r.lines[idx])
+	}
+	return out
+}
@@ -0,0 +1,56 @@
+package logfile
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestRing(t *testing.T) {
+	t.Run("keeps recent log lines", func(t *testing.T) {
+		r := NewRing(3)
+		logger := log.New(r, "", 0)
+		for i := 1; i <= 5; i++ {
+			logger.Printf("line %d", i)
+		}
+		if got := strings.Join(r.Lines(10), ","); got != "line 3,line 4,line 5" {
+			t.Errorf("Expected the last 3 lines, got %q", got)
+		}
+		if got := strings.Join(r.Lines(2), ","); got != "line 4,line 5" {
+			t.Errorf("Expected the last 2 lines, got %q", got)
+		}
+	})
+
+	t.Run("before it is full", func(t *testing.T) {
+		r := NewRing(10)
+		if r.Lines(5) != nil {
+			t.Error("Expected no lines from an empty ring")
+		}
+		fmt.Fprint(r, "one\ntwo\n")
+		if got := strings.Join(r.Lines(5), ","); got != "one,two" {
+			t.Errorf("Expected both lines, got %q", got)
+		}
+	})
+
+	t.Run("joins split writes", func(t *testing.T) {
+		r := NewRing(10)
+		fmt.Fprint(r, "hel")
+		if len(r.Lines(5)) != 0 {
+			t.Error("Expected an incomplete line to be held back")
+		}
+		fmt.Fprint(r, "lo\nwor")
+		fmt.Fprint(r, "ld\n")
+		if got := strings.Join(r.Lines(5), ","); got != "hello,world" {
+			t.Errorf("Expected joined lines, got %q", got)
+		}
+	})
+
+	t.Run("caps long lines", func(t *testing.T) {
+		r := NewRing(10)
+		fmt.Fprintln(r, strings.Repeat("x", 5000))
+		if got := r.Lines(1); len(got) != 1 || len(got[0]) != maxRingLineLength {
+			t.Errorf("Expected a line capped at %d bytes", maxRingLineLength)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package nat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// NATType describes how a NAT maps and filters UDP, which determines how long
+// an idle mapping survives
+type NATType int
+
+const (
+	NATUnknown NATType = iota
+	NATNone            // public address, no mapping to keep open
+	NATFullCone
+	NATRestricted
+	NATPortRestricted
+	NATSymmetric
+)
+
+// DefaultKeepalive is the keepalive interval when the NAT type is unknown
+const DefaultKeepalive = 30 * time.Second
+
+var natTypeNames = map[NATType]string{
+	NATUnknown:        "unknown",
+	NATNone:           "none",
+	NATFullCone:       "full-cone",
+	NATRestricted:     "restricted",
+	NATPortRestricted: "port-restricted",
+	NATSymmetric:      "symmetric",
+}
+
+func (t NATType) String() string {
+	if name, ok := natTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("NATType(%d)", int(t))
+}
+
+// ParseNATType parses a NAT type name as printed by String
+func ParseNATType(s string) (NATType, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for t, name := range natTypeNames {
+		if name == s {
+			return t, nil
+		}
+	}
+	return NATUnknown, fmt.Errorf("unknown NAT type %q (want unknown, none, full-cone, restricted, port-restricted or symmetric)", s)
+}
+
+// KeepaliveInterval returns how often an idle UDP flow behind this NAT type
+// should send a keepalive. Port-restricted and symmetric NATs often drop
+// mappings after 30 seconds, so they get pinged more often; cone NATs and
+// public addresses can wait longer to save bandwidth and battery.
+func KeepaliveInterval(t NATType) time.Duration {
+	switch t {
+	case NATNone:
+		return 120 * time.Second
+	case NATFullCone:
+		return 60 * time.Second
+	case NATRestricted:
+		return DefaultKeepalive
+	case NATPortRestricted:
+		return 20 * time.Second
+	case NATSymmetric:
+		return 15 * time.Second
+	}
+	return DefaultKeepalive
+}
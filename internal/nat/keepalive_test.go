@@ -0,0 +1,49 @@
+package nat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeepaliveInterval(t *testing.T) {
+	tests := []struct {
+		natType NATType
+		want    time.Duration
+	}{
+		{NATUnknown, DefaultKeepalive},
+		{NATNone, 120 * time.Second},
+		{NATFullCone, 60 * time.Second},
+		{NATRestricted, 30 * time.Second},
+		{NATPortRestricted, 20 * time.Second},
+		{NATSymmetric, 15 * time.Second},
+		{NATType(99), DefaultKeepalive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.natType.String(), func(t *testing.T) {
+			if got := KeepaliveInterval(tt.natType); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("stricter NATs ping more often", func(t *testing.T) {
+		order := []NATType{NATNone, NATFullCone, NATRestricted, NATPortRestricted, NATSymmetric}
+		for i := 1; i < len(order); i++ {
+			if KeepaliveInterval(order[i]) >= KeepaliveInterval(order[i-1]) {
+				t.Errorf("Expected %v to ping more often than %v", order[i], order[i-1])
+			}
+		}
+	})
+}
+
+func TestParseNATType(t *testing.T) {
+	for natType, name := range natTypeNames {
+		got, err := ParseNATType(" " + name + " ")
+		if err != nil || got != natType {
+			t.Errorf("Expected %q to parse as %v, got %v, %v", name, natType, got, err)
+		}
+	}
+	if _, err := ParseNATType("cone"); err == nil {
+		t.Error("Expected an error for an unknown name")
+	}
+}
@@ -0,0 +1,41 @@
+package nat
+
+import (
+	"fmt"
+	"log"
+	"net"
+)
+
+// Reflector tests whether an address can be reached from outside the local
+// network, typically by asking the entrypoint to dial it back
+type Reflector interface {
+	Reflect(addr string) (bool, error)
+}
+
+// ProbeReachable returns the subset of candidates that the reflector could
+// reach. Private and loopback candidates are kept without probing: a reflector
+// on the internet cannot reach them, but visitors on the same LAN can.
+// Candidates whose probe fails with an error are kept as well, so a broken
+// reflector never leaves the room with nothing to advertise.
+func ProbeReachable(candidates []Candidate, via Reflector) []Candidate {
+	reachable := make([]Candidate, 0, len(candidates))
+	for _, c := range candidates {
+		if ip := net.ParseIP(c.IP); ip == nil || ip.IsPrivate() || ip.IsLoopback() || c.Port <= 0 {
+			reachable = append(reachable, c)
+			continue
+		}
+		addr := fmt.Sprintf("%s:%d", c.IP, c.Port)
+		ok, err := via.Reflect(addr)
+		if err != nil {
+			log.Printf("Probe of %s failed, keeping it: %v", RedactCandidate(addr), err)
+			reachable = append(reachable, c)
+			continue
+		}
+		if !ok {
+			log.Printf("Candidate %s is not reachable from outside, not advertising it", RedactCandidate(addr))
+			continue
+		}
+		reachable = append(reachable, c)
+	}
+	return reachable
+}
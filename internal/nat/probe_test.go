@@ -0,0 +1,62 @@
+package nat
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// fakeReflector answers probes from a fixed table
+type fakeReflector struct {
+	reachable map[string]bool
+	failing   map[string]bool
+	probed    []string
+}
+
+func (f *fakeReflector) Reflect(addr string) (bool, error) {
+	f.probed = append(f.probed, addr)
+	if f.failing[addr] {
+		return false, errors.New("reflector unavailable")
+	}
+	return f.reachable[addr], nil
+}
+
+func TestProbeReachable(t *testing.T) {
+	candidates := []Candidate{
+		{Type: "srflx", IP: "203.0.113.5", Port: 2222},
+		{Type: "host", IP: "198.51.100.7", Port: 2222},
+		{Type: "host", IP: "192.168.1.10", Port: 2222},
+		{Type: "host", IP: "203.0.113.9", Port: 2222},
+	}
+
+	t.Run("drops unreachable public candidates", func(t *testing.T) {
+		via := &fakeReflector{reachable: map[string]bool{"203.0.113.5:2222": true}}
+		got := ProbeReachable(candidates, via)
+		want := []Candidate{candidates[0], candidates[2]}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("does not probe private candidates", func(t *testing.T) {
+		via := &fakeReflector{}
+		ProbeReachable(candidates, via)
+		for _, addr := range via.probed {
+			if addr == "192.168.1.10:2222" {
+				t.Errorf("Private candidate should not be probed")
+			}
+		}
+		if len(via.probed) != 3 {
+			t.Errorf("Expected 3 probes, got %d", len(via.probed))
+		}
+	})
+
+	t.Run("keeps candidates when the reflector fails", func(t *testing.T) {
+		via := &fakeReflector{failing: map[string]bool{"198.51.100.7:2222": true}}
+		got := ProbeReachable(candidates, via)
+		want := []Candidate{candidates[1], candidates[2]}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	})
+}
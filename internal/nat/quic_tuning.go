@@ -0,0 +1,79 @@
+package nat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+// QUICTuning holds the QUIC transport parameters of the room listener and
+// of client dials, set with the -quic-* flags. Zero values use quic-go's
+// defaults.
+type QUICTuning struct {
+	MaxIdleTimeout  time.Duration
+	KeepAlivePeriod time.Duration
+	StreamWindow    uint64 // initial stream receive window in bytes
+}
+
+// DefaultQUICTuning matches the settings p2pquic uses itself: a 5 minute
+// idle timeout, a keepalive every 30 seconds and quic-go's 512 KiB window
+var DefaultQUICTuning = QUICTuning{MaxIdleTimeout: 5 * time.Minute, KeepAlivePeriod: DefaultKeepalive}
+
+// quic-go's default maximum stream and connection receive windows
+const (
+	defaultMaxStreamWindow     = 6 << 20
+	defaultMaxConnectionWindow = 15 << 20
+)
+
+// Config returns the quic.Config for these parameters. The connection
+// window follows the stream window like in quic-go (1.5 times as large),
+// and the maximum windows are raised when the initial ones exceed them.
+func (t QUICTuning) Config() *quic.Config {
+	c := &quic.Config{
+		MaxIdleTimeout:  t.MaxIdleTimeout,
+		KeepAlivePeriod: t.KeepAlivePeriod,
+	}
+	if t.StreamWindow > 0 {
+		c.InitialStreamReceiveWindow = t.StreamWindow
+		c.InitialConnectionReceiveWindow = t.StreamWindow * 3 / 2
+		c.MaxStreamReceiveWindow = max(c.InitialStreamReceiveWindow, defaultMaxStreamWindow)
+		c.MaxConnectionReceiveWindow = max(c.InitialConnectionReceiveWindow, defaultMaxConnectionWindow)
+	}
+	return c
+}
+
+// Validate rejects negative durations and a keepalive period that would
+// not keep the connection from idling out
+func (t QUICTuning) Validate() error {
+	if t.MaxIdleTimeout < 0 || t.KeepAlivePeriod < 0 {
+		return fmt.Errorf("durations must not be negative")
+	}
+	if t.MaxIdleTimeout > 0 && t.KeepAlivePeriod >= t.MaxIdleTimeout {
+		return fmt.Errorf("keepalive period %s must be shorter than the idle timeout %s", t.KeepAlivePeriod, t.MaxIdleTimeout)
+	}
+	return nil
+}
+
+// ParseWindow parses a window size in bytes, optionally with a K or M
+// suffix (1024 based), e.g. 512K or 2M. An empty value is 0, the default.
+func ParseWindow(s string) (uint64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+	unit := uint64(1)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		unit, s = 1<<10, strings.TrimSuffix(s, "K")
+	case strings.HasSuffix(s, "M"):
+		unit, s = 1<<20, strings.TrimSuffix(s, "M")
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || n == 0 {
+		return 0, fmt.Errorf("invalid window size: %s", s)
+	}
+	return n * unit, nil
+}
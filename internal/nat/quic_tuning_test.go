@@ -0,0 +1,62 @@
+package nat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQUICTuning(t *testing.T) {
+	t.Run("defaults match p2pquic", func(t *testing.T) {
+		c := DefaultQUICTuning.Config()
+		if c.MaxIdleTimeout != 5*time.Minute || c.KeepAlivePeriod != 30*time.Second {
+			t.Errorf("Expected 5m idle and 30s keepalive, got %v and %v", c.MaxIdleTimeout, c.KeepAlivePeriod)
+		}
+		if c.InitialStreamReceiveWindow != 0 || c.InitialConnectionReceiveWindow != 0 {
+			t.Error("Expected quic-go's default windows")
+		}
+	})
+
+	t.Run("windows", func(t *testing.T) {
+		c := QUICTuning{StreamWindow: 2 << 20}.Config()
+		if c.InitialStreamReceiveWindow != 2<<20 || c.InitialConnectionReceiveWindow != 3<<20 {
+			t.Errorf("Expected 2 MiB and 3 MiB windows, got %d and %d", c.InitialStreamReceiveWindow, c.InitialConnectionReceiveWindow)
+		}
+		if c.MaxStreamReceiveWindow != defaultMaxStreamWindow || c.MaxConnectionReceiveWindow != defaultMaxConnectionWindow {
+			t.Error("Expected quic-go's maximum windows to be kept")
+		}
+		c = QUICTuning{StreamWindow: 16 << 20}.Config()
+		if c.MaxStreamReceiveWindow != 16<<20 || c.MaxConnectionReceiveWindow != 24<<20 {
+			t.Errorf("Expected the maximum windows to grow, got %d and %d", c.MaxStreamReceiveWindow, c.MaxConnectionReceiveWindow)
+		}
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		for _, tt := range []struct {
+			tuning QUICTuning
+			ok     bool
+		}{
+			{DefaultQUICTuning, true},
+			{QUICTuning{}, true},
+			{QUICTuning{MaxIdleTimeout: time.Minute, KeepAlivePeriod: time.Minute}, false},
+			{QUICTuning{MaxIdleTimeout: -time.Second}, false},
+			{QUICTuning{KeepAlivePeriod: 10 * time.Second}, true},
+		} {
+			if err := tt.tuning.Validate(); (err == nil) != tt.ok {
+				t.Errorf("Validate(%+v) = %v", tt.tuning, err)
+			}
+		}
+	})
+
+	t.Run("parse window", func(t *testing.T) {
+		for in, want := range map[string]uint64{"": 0, "65536": 65536, "512K": 512 << 10, "2m": 2 << 20} {
+			if got, err := ParseWindow(in); err != nil || got != want {
+				t.Errorf("ParseWindow(%q) = %d, %v, want %d", in, got, err, want)
+			}
+		}
+		for _, in := range []string{"0", "-1", "2G", "lots"} {
+			if _, err := ParseWindow(in); err == nil {
+				t.Errorf("Expected ParseWindow(%q) to fail", in)
+			}
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package nat
+
+import (
+	"net"
+	"strings"
+)
+
+// redact controls whether candidate addresses are masked in log output
+var redact bool
+
+// SetRedaction enables or disables masking of candidate addresses in logs
+func SetRedaction(enabled bool) {
+	redact = enabled
+}
+
+// RedactCandidate masks the host portion of a candidate ("ip" or "ip:port")
+// when redaction is enabled, e.g. 203.0.113.7:2222 becomes 203.0.113.x:2222
+func RedactCandidate(candidate string) string {
+	if !redact {
+		return candidate
+	}
+
+	host, port, err := net.SplitHostPort(candidate)
+	if err != nil {
+		host, port = candidate, ""
+	}
+
+	masked := "x"
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			parts := strings.Split(ip4.String(), ".")
+			masked = strings.Join(parts[:3], ".") + ".x"
+		} else {
+			// Keep only the first group of IPv6 addresses
+			masked = strings.SplitN(ip.String(), ":", 2)[0] + ":x"
+		}
+	}
+
+	if port == "" {
+		return masked
+	}
+	return net.JoinHostPort(masked, port)
+}
+
+// RedactCandidates applies RedactCandidate to each candidate
+func RedactCandidates(candidates []string) []string {
+	if !redact {
+		return candidates
+	}
+	redacted := make([]string, len(candidates))
+	for i, c := range candidates {
+		redacted[i] = RedactCandidate(c)
+	}
+	return redacted
+}
@@ -0,0 +1,28 @@
+package nat
+
+import (
+	"testing"
+)
+
+func TestRedactCandidate(t *testing.T) {
+	SetRedaction(false)
+	if got := RedactCandidate("203.0.113.7:2222"); got != "203.0.113.7:2222" {
+		t.Errorf("Expected no redaction when disabled, got %s", got)
+	}
+
+	SetRedaction(true)
+	defer SetRedaction(false)
+
+	tests := map[string]string{
+		"203.0.113.7:2222":   "203.0.113.x:2222",
+		"192.168.1.10":       "192.168.1.x",
+		"[2001:db8::1]:2222": "[2001:x]:2222",
+		"room-lobby":         "x",
+		"not-an-ip:1234":     "x:1234",
+	}
+	for in, want := range tests {
+		if got := RedactCandidate(in); got != want {
+			t.Errorf("RedactCandidate(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
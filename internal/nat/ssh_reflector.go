@@ -0,0 +1,96 @@
+package nat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// API message types (must match entrypoint/api_ssh.go)
+const (
+	apiTypeProbeCandidate = "probe_candidate"
+	apiTypeError          = "error"
+)
+
+type apiMessage struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// SSHReflector asks the entrypoint to dial candidates over the unn-api
+// subsystem. It is not safe for concurrent use.
+type SSHReflector struct {
+	session *ssh.Session
+	stdin   io.WriteCloser
+	encoder *json.Encoder
+	decoder *json.Decoder
+}
+
+// NewSSHReflector opens an unn-api session on an established entrypoint connection
+func NewSSHReflector(sshClient *ssh.Client) (*SSHReflector, error) {
+	session, err := sshClient.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to get stdin: %w", err)
+	}
+
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to get stdout: %w", err)
+	}
+
+	if err := session.RequestSubsystem("unn-api"); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to request unn-api subsystem: %w", err)
+	}
+
+	return &SSHReflector{
+		session: session,
+		stdin:   stdin,
+		encoder: json.NewEncoder(stdin),
+		decoder: json.NewDecoder(stdout),
+	}, nil
+}
+
+// Reflect asks the entrypoint whether it can open a QUIC connection to addr
+func (r *SSHReflector) Reflect(addr string) (bool, error) {
+	payload, _ := json.Marshal(map[string]string{"address": addr})
+	if err := r.encoder.Encode(apiMessage{Type: apiTypeProbeCandidate, Payload: payload}); err != nil {
+		return false, fmt.Errorf("failed to send probe: %w", err)
+	}
+
+	var response apiMessage
+	if err := r.decoder.Decode(&response); err != nil {
+		return false, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if response.Type == apiTypeError {
+		var errMsg map[string]string
+		json.Unmarshal(response.Payload, &errMsg)
+		return false, fmt.Errorf("probe error: %s", errMsg["message"])
+	}
+
+	var result struct {
+		Reachable bool `json:"reachable"`
+	}
+	if err := json.Unmarshal(response.Payload, &result); err != nil {
+		return false, fmt.Errorf("failed to parse probe result: %w", err)
+	}
+	return result.Reachable, nil
+}
+
+// Close closes the API session
+func (r *SSHReflector) Close() error {
+	if r.session != nil {
+		return r.session.Close()
+	}
+	return nil
+}
@@ -3,6 +3,8 @@ package nat
 import (
 	"fmt"
 	"net"
+	"slices"
+	"sort"
 )
 
 // Candidate represents a NAT traversal candidate (IP:Port pair)
@@ -12,23 +14,34 @@ type Candidate struct {
 	Port int
 }
 
-// GetLocalCandidates discovers local interface candidates with the given port
-func GetLocalCandidates(port int) []Candidate {
+// GetLocalCandidates discovers local interface candidates with the given port.
+// Only interfaces named in include are used, unless it is empty, and those
+// named in exclude (e.g. docker0, tun0) are skipped.
+func GetLocalCandidates(port int, include, exclude []string) []Candidate {
 	candidates := make([]Candidate, 0)
 
-	addrs, err := net.InterfaceAddrs()
+	ifaces, err := net.Interfaces()
 	if err != nil {
 		return candidates
 	}
 
-	for _, addr := range addrs {
-		if ipnet, ok := addr.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
-			if ipnet.IP.To4() != nil {
-				candidates = append(candidates, Candidate{
-					Type: "host",
-					IP:   ipnet.IP.String(),
-					Port: port,
-				})
+	for _, iface := range ifaces {
+		if !useInterface(iface.Name, include, exclude) {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipnet, ok := addr.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
+				if ipnet.IP.To4() != nil {
+					candidates = append(candidates, Candidate{
+						Type: "host",
+						IP:   ipnet.IP.String(),
+						Port: port,
+					})
+				}
 			}
 		}
 	}
@@ -36,6 +49,52 @@ func GetLocalCandidates(port int) []Candidate {
 	return candidates
 }
 
+// useInterface reports whether candidates are taken from the named interface
+func useInterface(name string, include, exclude []string) bool {
+	if len(include) > 0 && !slices.Contains(include, name) {
+		return false
+	}
+	return !slices.Contains(exclude, name)
+}
+
+// FilterCandidates removes duplicate and link-local candidates and caps the
+// list at max entries (no cap if max <= 0). Reflexive candidates are kept
+// first, followed by private (routable) locals, then any remaining hosts.
+func FilterCandidates(candidates []Candidate, max int) []Candidate {
+	rank := func(c Candidate) int {
+		if c.Type == "srflx" {
+			return 0
+		}
+		if ip := net.ParseIP(c.IP); ip != nil && ip.IsPrivate() {
+			return 1
+		}
+		return 2
+	}
+
+	seen := make(map[string]bool)
+	filtered := make([]Candidate, 0, len(candidates))
+	for _, c := range candidates {
+		if ip := net.ParseIP(c.IP); ip != nil && ip.IsLinkLocalUnicast() {
+			continue
+		}
+		key := fmt.Sprintf("%s:%d", c.IP, c.Port)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		filtered = append(filtered, c)
+	}
+
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return rank(filtered[i]) < rank(filtered[j])
+	})
+
+	if max > 0 && len(filtered) > max {
+		filtered = filtered[:max]
+	}
+	return filtered
+}
+
 // DiscoverPublicAddress attempts to discover the public IP address using STUN
 // Returns nil if discovery fails
 func DiscoverPublicAddress(port int) (*Candidate, error) {
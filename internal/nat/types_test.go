@@ -0,0 +1,65 @@
+package nat
+
+import (
+	"testing"
+)
+
+func TestFilterCandidates(t *testing.T) {
+	noisy := []Candidate{
+		{Type: "host", IP: "172.17.0.1", Port: 2222},
+		{Type: "host", IP: "169.254.10.20", Port: 2222},
+		{Type: "host", IP: "192.168.1.10", Port: 2222},
+		{Type: "host", IP: "192.168.1.10", Port: 2222},
+		{Type: "host", IP: "100.64.0.5", Port: 2222},
+		{Type: "srflx", IP: "203.0.113.7", Port: 40000},
+		{Type: "host", IP: "10.8.0.2", Port: 2222},
+	}
+
+	t.Run("dedupe and order", func(t *testing.T) {
+		got := FilterCandidates(noisy, 0)
+		want := []string{
+			"203.0.113.7:40000",
+			"172.17.0.1:2222",
+			"192.168.1.10:2222",
+			"10.8.0.2:2222",
+			"100.64.0.5:2222",
+		}
+		strs := CandidatesToStrings(got)
+		if len(strs) != len(want) {
+			t.Fatalf("Expected %d candidates, got %d: %v", len(want), len(strs), strs)
+		}
+		for i := range want {
+			if strs[i] != want[i] {
+				t.Errorf("Candidate %d: expected %s, got %s", i, want[i], strs[i])
+			}
+		}
+	})
+
+	t.Run("cap keeps reflexive", func(t *testing.T) {
+		got := FilterCandidates(noisy, 2)
+		if len(got) != 2 {
+			t.Fatalf("Expected 2 candidates, got %d", len(got))
+		}
+		if got[0].Type != "srflx" {
+			t.Errorf("Expected reflexive candidate first, got %v", got[0])
+		}
+	})
+}
+
+func TestUseInterface(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		include, exclude []string
+		want             bool
+	}{
+		{"eth0", nil, nil, true},
+		{"docker0", nil, []string{"docker0", "tun0"}, false},
+		{"eth0", []string{"eth0"}, nil, true},
+		{"wlan0", []string{"eth0"}, nil, false},
+		{"eth0", []string{"eth0"}, []string{"eth0"}, false},
+	} {
+		if got := useInterface(tc.name, tc.include, tc.exclude); got != tc.want {
+			t.Errorf("useInterface(%q, %v, %v) = %v, expected %v", tc.name, tc.include, tc.exclude, got, tc.want)
+		}
+	}
+}
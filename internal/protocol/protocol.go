@@ -1,7 +1,9 @@
 package protocol
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 
@@ -26,6 +28,10 @@ const (
 	MsgTypePunchOffer   = "punch_offer"   // Entry point forwards to room
 	MsgTypePunchAnswer  = "punch_answer"  // Room sends candidates back
 	MsgTypePunchStart   = "punch_start"   // Both sides start punching
+
+	// Room preview
+	MsgTypePreviewRequest  = "preview_request"  // Entry point asks room for its banner
+	MsgTypePreviewResponse = "preview_response" // Room sends its banner back
 )
 
 // Message is the base message structure for entry point communication
@@ -79,6 +85,7 @@ type PunchOfferPayload struct {
 	PersonKey   string   `json:"person_key"` // Person's public key for P2P auth
 	DisplayName string   `json:"display_name"`
 	Username    string   `json:"username"`
+	Verified    bool     `json:"verified,omitempty"` // Username is a verified UNN identity
 }
 
 // PunchAnswerPayload is sent by room operator back to entry point
@@ -98,6 +105,17 @@ type PunchStartPayload struct {
 	StartTime  int64    `json:"start_time"`  // Unix timestamp to sync start
 }
 
+// PreviewRequestPayload is sent by the entry point to ask a room for its banner
+type PreviewRequestPayload struct {
+	RequestID string `json:"request_id"`
+}
+
+// PreviewResponsePayload is sent by the room with its banner lines
+type PreviewResponsePayload struct {
+	RequestID string   `json:"request_id"`
+	Banner    []string `json:"banner"`
+}
+
 // PopupPayload is sent to show a formatted popup message in the client
 type PopupPayload struct {
 	Action  string `json:"action,omitempty"`
@@ -106,6 +124,13 @@ type PopupPayload struct {
 	Type    string `json:"type,omitempty"` // e.g., "info", "warning", "error"
 }
 
+// ThemePayload is sent by a room on join with its branding
+type ThemePayload struct {
+	Action string `json:"action,omitempty"`
+	Accent string `json:"accent,omitempty"` // Color name or #rrggbb
+	Title  string `json:"title,omitempty"`
+}
+
 // FileBlockPayload is sent by the server to transfer a file in blocks via OSC
 type FileBlockPayload struct {
 	Action   string `json:"action,omitempty"`
@@ -117,6 +142,80 @@ type FileBlockPayload struct {
 	Data     string `json:"data"` // Base64 encoded data
 }
 
+// SignedMessagePayload carries a chat message signed by the room host key
+type SignedMessagePayload struct {
+	Action    string `json:"action,omitempty"`
+	Sender    string `json:"sender"`
+	SenderKey string `json:"sender_key"` // Sender's public key hash (empty for server messages)
+	Text      string `json:"text"`
+	Timestamp int64  `json:"timestamp"`
+	Signature string `json:"signature"` // Base64 encoded SSH signature
+}
+
+// signedData returns the bytes covered by the signature
+func (p *SignedMessagePayload) signedData() []byte {
+	return []byte(fmt.Sprintf("unn-chat\n%s\n%s\n%d\n%s", p.Sender, p.SenderKey, p.Timestamp, p.Text))
+}
+
+// Sign fills in the signature using the given signer
+func (p *SignedMessagePayload) Sign(signer ssh.Signer) error {
+	sig, err := signer.Sign(rand.Reader, p.signedData())
+	if err != nil {
+		return err
+	}
+	p.Signature = base64.StdEncoding.EncodeToString(ssh.Marshal(sig))
+	return nil
+}
+
+// Verify checks the signature against the given public key
+func (p *SignedMessagePayload) Verify(key ssh.PublicKey) error {
+	sigBytes, err := base64.StdEncoding.DecodeString(p.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	sig := new(ssh.Signature)
+	if err := ssh.Unmarshal(sigBytes, sig); err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	return key.Verify(p.signedData(), sig)
+}
+
+// HostKeyPayload announces a new room host key, signed by the previous one
+type HostKeyPayload struct {
+	Action    string `json:"action,omitempty"`
+	PublicKey string `json:"public_key"` // New key in authorized_keys format
+	Timestamp int64  `json:"timestamp"`
+	Signature string `json:"signature"` // Base64 encoded SSH signature by the old key
+}
+
+// signedData returns the bytes covered by the signature
+func (p *HostKeyPayload) signedData() []byte {
+	return []byte(fmt.Sprintf("unn-host-key\n%s\n%d", p.PublicKey, p.Timestamp))
+}
+
+// Sign fills in the signature using the outgoing host key
+func (p *HostKeyPayload) Sign(signer ssh.Signer) error {
+	sig, err := signer.Sign(rand.Reader, p.signedData())
+	if err != nil {
+		return err
+	}
+	p.Signature = base64.StdEncoding.EncodeToString(ssh.Marshal(sig))
+	return nil
+}
+
+// Verify checks the signature against the outgoing host key
+func (p *HostKeyPayload) Verify(key ssh.PublicKey) error {
+	sigBytes, err := base64.StdEncoding.DecodeString(p.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	sig := new(ssh.Signature)
+	if err := ssh.Unmarshal(sigBytes, sig); err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	return key.Verify(p.signedData(), sig)
+}
+
 // NewMessage creates a new message with the given type and payload
 func NewMessage(msgType string, payload interface{}) (*Message, error) {
 	payloadBytes, err := json.Marshal(payload)
@@ -0,0 +1,63 @@
+package protocol
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSignedMessage(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := &SignedMessagePayload{
+		Sender:    "alice",
+		SenderKey: "abcd1234",
+		Text:      "hello world",
+		Timestamp: 1700000000,
+	}
+	if err := msg.Sign(signer); err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+
+	if err := msg.Verify(signer.PublicKey()); err != nil {
+		t.Errorf("Valid signature rejected: %v", err)
+	}
+
+	msg.Text = "hello w0rld"
+	if err := msg.Verify(signer.PublicKey()); err == nil {
+		t.Errorf("Tampered message accepted")
+	}
+}
+
+func TestHostKeyPayload(t *testing.T) {
+	_, oldPriv, _ := ed25519.GenerateKey(rand.Reader)
+	oldSigner, _ := ssh.NewSignerFromKey(oldPriv)
+	newPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	newKey, _ := ssh.NewPublicKey(newPub)
+
+	msg := &HostKeyPayload{
+		PublicKey: string(ssh.MarshalAuthorizedKey(newKey)),
+		Timestamp: 1700000000,
+	}
+	if err := msg.Sign(oldSigner); err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+
+	if err := msg.Verify(oldSigner.PublicKey()); err != nil {
+		t.Errorf("Valid rotation rejected: %v", err)
+	}
+	if err := msg.Verify(newKey); err == nil {
+		t.Errorf("Rotation verified against the wrong key")
+	}
+
+	msg.Timestamp++
+	if err := msg.Verify(oldSigner.PublicKey()); err == nil {
+		t.Errorf("Tampered rotation accepted")
+	}
+}
@@ -0,0 +1,69 @@
+// Package sshcompress compresses the output of an SSH session with deflate.
+// golang.org/x/crypto/ssh only implements the "none" compression method,
+// and compressing the connection underneath SSH would only see ciphertext,
+// so a room compresses a session's output inside the channel instead, once
+// the client asked for it with a channel request.
+package sshcompress
+
+import (
+	"compress/flate"
+	"io"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// RequestType is the session channel request a client sends, before
+// pty-req, to have the session's output compressed
+const RequestType = "compress@unn"
+
+// Writer deflates everything written to it. Every write is flushed, so
+// interactive output is not held back.
+type Writer struct {
+	mu sync.Mutex
+	w  *flate.Writer
+}
+
+// NewWriter compresses into w. BestSpeed keeps the CPU cost low; terminal
+// output compresses well even at this level.
+func NewWriter(w io.Writer) *Writer {
+	fw, _ := flate.NewWriter(w, flate.BestSpeed)
+	return &Writer{w: fw}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n, err := w.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, w.w.Flush()
+}
+
+// NewReader decompresses what a Writer produced
+func NewReader(r io.Reader) io.ReadCloser {
+	return flate.NewReader(r)
+}
+
+// channel compresses the data written to an SSH channel. Reads, stderr and
+// requests pass through unchanged.
+type channel struct {
+	ssh.Channel
+	w *Writer
+}
+
+// NewChannel wraps ch so its output is compressed
+func NewChannel(ch ssh.Channel) ssh.Channel {
+	return &channel{Channel: ch, w: NewWriter(ch)}
+}
+
+func (c *channel) Write(p []byte) (int, error) {
+	return c.w.Write(p)
+}
+
+// Request asks the server to compress the session's output and reports
+// whether it agreed. It must be sent before the pty or shell request.
+func Request(session *ssh.Session) (bool, error) {
+	return session.SendRequest(RequestType, true, nil)
+}
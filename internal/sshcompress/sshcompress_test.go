@@ -0,0 +1,34 @@
+package sshcompress
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	var wire bytes.Buffer
+	w := NewWriter(&wire)
+	r := NewReader(&wire)
+	defer r.Close()
+
+	screen := strings.Repeat("\x1b[38;5;15m<alice> hello again\x1b[0m\r\n", 50)
+	for _, part := range []string{screen[:100], screen[100:]} {
+		if _, err := io.WriteString(w, part); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if wire.Len() >= len(screen)/4 {
+		t.Errorf("Expected repetitive output to compress well, %d bytes became %d", len(screen), wire.Len())
+	}
+
+	// Each write is flushed, so everything written so far can be read
+	got := make([]byte, len(screen))
+	if _, err := io.ReadFull(r, got); err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != screen {
+		t.Error("Expected the output to arrive unchanged")
+	}
+}
@@ -0,0 +1,100 @@
+package sshserver
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// bandwidthSampleInterval is how often the current throughput is measured
+var bandwidthSampleInterval = 5 * time.Second
+
+// bandwidthMeter counts the bytes visitors' connections carry. Everything
+// goes over SSH (chat, the TUI, doors and file transfers), so counting at
+// the connection covers all of it.
+type bandwidthMeter struct {
+	sent     atomic.Int64
+	received atomic.Int64
+
+	mu           sync.Mutex
+	lastAt       time.Time
+	lastSent     int64
+	lastReceived int64
+	sendRate     float64 // bytes per second over the last sample
+	receiveRate  float64
+}
+
+func newBandwidthMeter() *bandwidthMeter {
+	return &bandwidthMeter{lastAt: time.Now()}
+}
+
+// sample updates the current rates from the bytes counted since the last sample
+func (m *bandwidthMeter) sample(now time.Time) {
+	sent, received := m.sent.Load(), m.received.Load()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if elapsed := now.Sub(m.lastAt).Seconds(); elapsed > 0 {
+		m.sendRate = float64(sent-m.lastSent) / elapsed
+		m.receiveRate = float64(received-m.lastReceived) / elapsed
+	}
+	m.lastAt, m.lastSent, m.lastReceived = now, sent, received
+}
+
+// String reports the current rates and the totals since the room started
+func (m *bandwidthMeter) String() string {
+	m.mu.Lock()
+	sendRate, receiveRate := m.sendRate, m.receiveRate
+	m.mu.Unlock()
+	return fmt.Sprintf("Bandwidth: up %s/s (%s total), down %s/s (%s total)",
+		formatSize(int64(sendRate)), formatSize(m.sent.Load()),
+		formatSize(int64(receiveRate)), formatSize(m.received.Load()))
+}
+
+// countingConn counts the bytes read from and written to a connection
+type countingConn struct {
+	net.Conn
+	meter *bandwidthMeter
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.meter.received.Add(int64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.meter.sent.Add(int64(n))
+	return n, err
+}
+
+// SetBandwidthLog logs the room's throughput every interval (0 disables it)
+func (s *Server) SetBandwidthLog(interval time.Duration) {
+	s.mu.Lock()
+	s.bandwidthLog = interval
+	s.mu.Unlock()
+}
+
+// bandwidthLoop samples the meter and writes the periodic log line until the
+// server is stopped
+func (s *Server) bandwidthLoop() {
+	ticker := time.NewTicker(bandwidthSampleInterval)
+	defer ticker.Stop()
+	lastLog := time.Now()
+	for now := range ticker.C {
+		s.mu.RLock()
+		stopped, interval := s.stopped, s.bandwidthLog
+		s.mu.RUnlock()
+		if stopped {
+			return
+		}
+		s.bandwidth.sample(now)
+		if interval > 0 && now.Sub(lastLog) >= interval {
+			log.Print(s.bandwidth)
+			lastLog = now
+		}
+	}
+}
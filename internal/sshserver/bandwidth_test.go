@@ -0,0 +1,61 @@
+package sshserver
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBandwidthMeter(t *testing.T) {
+	t.Run("counts a transfer", func(t *testing.T) {
+		m := newBandwidthMeter()
+		server, client := net.Pipe()
+		defer client.Close()
+		conn := &countingConn{Conn: server, meter: m}
+
+		const size = 100000
+		go func() {
+			conn.Write(make([]byte, size))
+			conn.Close()
+		}()
+		if _, err := io.Copy(io.Discard, client); err != nil {
+			t.Fatal(err)
+		}
+		if got := m.sent.Load(); got != size {
+			t.Errorf("Expected %d bytes sent, got %d", size, got)
+		}
+
+		server2, client2 := net.Pipe()
+		defer server2.Close()
+		conn2 := &countingConn{Conn: server2, meter: m}
+		go client2.Write([]byte("hello"))
+		buf := make([]byte, 5)
+		if _, err := io.ReadFull(conn2, buf); err != nil {
+			t.Fatal(err)
+		}
+		if got := m.received.Load(); got != 5 {
+			t.Errorf("Expected 5 bytes received, got %d", got)
+		}
+	})
+
+	t.Run("rate over the last sample", func(t *testing.T) {
+		m := newBandwidthMeter()
+		start := m.lastAt
+		m.sent.Add(20 * 1024)
+		m.sample(start.Add(10 * time.Second))
+		if m.sendRate != 2048 {
+			t.Errorf("Expected 2048 B/s, got %v", m.sendRate)
+		}
+		out := m.String()
+		if !strings.Contains(out, "up 2.0 KB/s (20.0 KB total)") {
+			t.Errorf("Unexpected report: %q", out)
+		}
+
+		m.sample(start.Add(20 * time.Second))
+		if m.sendRate != 0 {
+			t.Errorf("Expected rate to drop to 0 when idle, got %v", m.sendRate)
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package sshserver
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"github.com/mevdschee/underground-node-network/internal/ui/bridge"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestGetBanner(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	s.bannerPath = filepath.Join(tmpDir, "room.asc")
+
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	sshPub, _ := ssh.NewPublicKey(pub)
+	ch := &captureChannel{}
+	alice := &Person{Username: "alice", ChatUI: ui.NewChatUI(nil), PubKey: sshPub, UNNAware: true,
+		Bus: bridge.NewSSHBus(bridge.NewInputBridge(ch), 80, 24)}
+	s.people["alice"] = alice
+
+	lastMessage := func() string {
+		msgs := alice.ChatUI.GetMessages()
+		return msgs[len(msgs)-1].Text
+	}
+
+	t.Run("no banner", func(t *testing.T) {
+		s.handleInternalCommand(alice, "/get-banner")
+		if got := lastMessage(); got != "Error: this room has no banner" {
+			t.Errorf("Expected a no-banner error, got %q", got)
+		}
+		if strings.Contains(ch.String(), "transfer_block") {
+			t.Error("Expected nothing to be sent")
+		}
+	})
+
+	t.Run("sends the banner file", func(t *testing.T) {
+		os.WriteFile(s.bannerPath, []byte(" /\\_/\\\n( o.o )\n"), 0644)
+		s.handleInternalCommand(alice, "/get-banner")
+		if got := lastMessage(); got != "Sent testroom.asc to your downloads." {
+			t.Errorf("Expected a confirmation, got %q", got)
+		}
+		out := ch.String()
+		if !strings.Contains(out, "transfer_block") || !strings.Contains(out, "testroom.asc") {
+			t.Errorf("Expected the banner to be sent as testroom.asc, got %q", out)
+		}
+	})
+
+	t.Run("respects the download permission", func(t *testing.T) {
+		s.SetPermissions(PermNobody, PermEveryone)
+		defer s.SetPermissions(PermEveryone, PermEveryone)
+		s.handleInternalCommand(alice, "/get-banner")
+		if got := lastMessage(); got != "Error: downloads are disabled in this room" {
+			t.Errorf("Expected a downloads denial, got %q", got)
+		}
+	})
+}
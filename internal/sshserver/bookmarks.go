@@ -0,0 +1,120 @@
+package sshserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// maxBookmarks caps how many files one person can bookmark
+const maxBookmarks = 50
+
+// bookmarksPath returns the file holding everyone's bookmarks, kept next to
+// the host key
+func (s *Server) bookmarksPath() string {
+	return filepath.Join(filepath.Dir(s.hostKeyPath), s.roomName+".bookmarks")
+}
+
+// loadBookmarks reads the persisted bookmarks, if any
+func (s *Server) loadBookmarks() {
+	data, err := os.ReadFile(s.bookmarksPath())
+	if err != nil {
+		return
+	}
+	bookmarks := make(map[string][]string)
+	if err := json.Unmarshal(data, &bookmarks); err != nil {
+		log.Printf("Failed to read bookmarks: %v", err)
+		return
+	}
+	s.bookmarks = bookmarks
+}
+
+// saveBookmarksLocked persists the bookmarks; the caller holds s.mu
+func (s *Server) saveBookmarksLocked() error {
+	data, err := json.MarshalIndent(s.bookmarks, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.bookmarksPath(), append(data, '\n'), 0600); err != nil {
+		log.Printf("Failed to save bookmarks: %v", err)
+		return err
+	}
+	return nil
+}
+
+// addBookmark remembers a file for a pubkey hash and returns its number
+func (s *Server) addBookmark(pubHash, name string) (int, error) {
+	name = path.Clean("/" + name)[1:]
+	fullPath, err := s.resolveFile(name)
+	if err != nil {
+		return 0, err
+	}
+	if info, err := os.Stat(fullPath); err != nil || info.IsDir() {
+		return 0, fmt.Errorf("file not found: %s", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := s.bookmarks[pubHash]
+	for i, existing := range list {
+		if existing == name {
+			return i + 1, nil
+		}
+	}
+	if len(list) >= maxBookmarks {
+		return 0, fmt.Errorf("you already have %d bookmarks", maxBookmarks)
+	}
+	if s.bookmarks == nil {
+		s.bookmarks = make(map[string][]string)
+	}
+	s.bookmarks[pubHash] = append(list, name)
+	return len(list) + 1, s.saveBookmarksLocked()
+}
+
+// listBookmarks returns a pubkey hash's bookmarks, dropping files that no
+// longer exist so the numbers always refer to something downloadable
+func (s *Server) listBookmarks(pubHash string) []string {
+	s.mu.RLock()
+	list := append([]string(nil), s.bookmarks[pubHash]...)
+	s.mu.RUnlock()
+
+	var kept []string
+	for _, name := range list {
+		fullPath, err := s.resolveFile(name)
+		if err != nil {
+			continue
+		}
+		if info, err := os.Stat(fullPath); err != nil || info.IsDir() {
+			continue
+		}
+		kept = append(kept, name)
+	}
+
+	if len(kept) != len(list) {
+		s.mu.Lock()
+		if len(kept) == 0 {
+			delete(s.bookmarks, pubHash)
+		} else {
+			s.bookmarks[pubHash] = kept
+		}
+		s.saveBookmarksLocked()
+		s.mu.Unlock()
+	}
+	return kept
+}
+
+// getBookmark returns the name and path of the n-th bookmark (1-based)
+func (s *Server) getBookmark(pubHash string, n int) (string, string, error) {
+	list := s.listBookmarks(pubHash)
+	if n < 1 || n > len(list) {
+		return "", "", fmt.Errorf("no bookmark %d, you have %d", n, len(list))
+	}
+	fullPath, err := s.resolveFile(list[n-1])
+	if err != nil {
+		return "", "", err
+	}
+	return list[n-1], fullPath, nil
+}
@@ -0,0 +1,98 @@
+package sshserver
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+)
+
+func TestBookmarks(t *testing.T) {
+	tmpDir := t.TempDir()
+	filesDir := filepath.Join(tmpDir, "files")
+	os.MkdirAll(filepath.Join(filesDir, "docs"), 0755)
+	os.WriteFile(filepath.Join(filesDir, "a.txt"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(filesDir, "docs", "b.txt"), []byte("b"), 0644)
+
+	newRoom := func() *Server {
+		s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+		if err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+		s.SetFileRoots([]string{filesDir})
+		return s
+	}
+	s := newRoom()
+
+	t.Run("add", func(t *testing.T) {
+		if n, err := s.addBookmark("alice", "a.txt"); err != nil || n != 1 {
+			t.Fatalf("Expected bookmark 1, got %d, %v", n, err)
+		}
+		if n, err := s.addBookmark("alice", "/docs/b.txt"); err != nil || n != 2 {
+			t.Fatalf("Expected bookmark 2, got %d, %v", n, err)
+		}
+		if n, err := s.addBookmark("alice", "a.txt"); err != nil || n != 1 {
+			t.Errorf("Expected a duplicate to keep its number, got %d, %v", n, err)
+		}
+		if _, err := s.addBookmark("alice", "missing.txt"); err == nil {
+			t.Error("Expected a missing file to be rejected")
+		}
+		if _, err := s.addBookmark("alice", "docs"); err == nil {
+			t.Error("Expected a directory to be rejected")
+		}
+		if _, err := s.addBookmark("alice", "../host_key"); err == nil {
+			t.Error("Expected a path outside the file root to be rejected")
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		list := s.listBookmarks("alice")
+		if strings.Join(list, ",") != "a.txt,docs/b.txt" {
+			t.Errorf("Unexpected bookmarks %v", list)
+		}
+		if list := s.listBookmarks("bob"); len(list) != 0 {
+			t.Errorf("Expected bob to have no bookmarks, got %v", list)
+		}
+		name, fullPath, err := s.getBookmark("alice", 2)
+		if err != nil || name != "docs/b.txt" || fullPath != filepath.Join(filesDir, "docs", "b.txt") {
+			t.Errorf("Unexpected bookmark 2: %s, %s, %v", name, fullPath, err)
+		}
+		if _, _, err := s.getBookmark("alice", 3); err == nil {
+			t.Error("Expected an error for a bookmark that does not exist")
+		}
+	})
+
+	t.Run("persist", func(t *testing.T) {
+		if list := newRoom().listBookmarks("alice"); len(list) != 2 {
+			t.Errorf("Expected bookmarks to survive a restart, got %v", list)
+		}
+	})
+
+	t.Run("prune", func(t *testing.T) {
+		os.Remove(filepath.Join(filesDir, "a.txt"))
+		list := s.listBookmarks("alice")
+		if strings.Join(list, ",") != "docs/b.txt" {
+			t.Errorf("Expected the removed file to be pruned, got %v", list)
+		}
+		if list := newRoom().listBookmarks("alice"); len(list) != 1 {
+			t.Errorf("Expected the pruned list to be saved, got %v", list)
+		}
+	})
+
+	t.Run("commands", func(t *testing.T) {
+		p := &Person{Username: "carol", ChatUI: ui.NewChatUI(nil)}
+		s.people["carol"] = p
+		s.handleInternalCommand(p, "/bookmark docs/b.txt")
+		s.handleInternalCommand(p, "/bookmarks")
+		var texts []string
+		for _, m := range p.ChatUI.GetMessages() {
+			texts = append(texts, m.Text)
+		}
+		if !strings.Contains(strings.Join(texts, "\n"), "1. docs/b.txt") {
+			t.Errorf("Expected the bookmark to be listed, got %v", texts)
+		}
+	})
+}
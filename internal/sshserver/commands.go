@@ -4,14 +4,48 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"path"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/gdamore/tcell/v2"
 	"github.com/mevdschee/underground-node-network/internal/ui"
 	"github.com/mevdschee/underground-node-network/internal/ui/bridge"
+	"github.com/mevdschee/underground-node-network/internal/ui/common"
 	"golang.org/x/crypto/ssh"
 )
 
+// commandPrefix returns the prefix that marks chat input as a command
+func (s *Server) commandPrefix() string {
+	if s.cmdPrefix == "" {
+		return "/"
+	}
+	return s.cmdPrefix
+}
+
+const (
+	// defaultLogLines is how many lines /log shows by default
+	defaultLogLines = 20
+	// maxLogLines caps the n of /log
+	maxLogLines = 200
+)
+
+// cmdMention matches a "/command" at the start of text or after a space or
+// parenthesis, so paths like <user/hash> are left alone
+var cmdMention = regexp.MustCompile(`(^|[ (\n])/([a-z])`)
+
+// withCmdPrefix rewrites the "/command" mentions in help and usage text to
+// the configured command prefix
+func (s *Server) withCmdPrefix(text string) string {
+	prefix := s.commandPrefix()
+	if prefix == "/" {
+		return text
+	}
+	return cmdMention.ReplaceAllString(text, "${1}"+strings.ReplaceAll(prefix, "$", "$$")+"${2}")
+}
+
 func (s *Server) handleCommand(channel ssh.Channel, sessionID string, input string) chan struct{} {
 	s.mu.RLock()
 	p := s.people[sessionID]
@@ -25,13 +59,14 @@ func (s *Server) handleCommand(channel ssh.Channel, sessionID string, input stri
 		return nil
 	}
 
-	if !strings.HasPrefix(input, "/") {
+	prefix := s.commandPrefix()
+	if !strings.HasPrefix(input, prefix) {
 		// Regular chat message
-		s.Broadcast(username, input)
+		s.sendChat(p, input)
 		return nil
 	}
 
-	cmd := strings.TrimPrefix(input, "/")
+	cmd := strings.TrimPrefix(input, prefix)
 	parts := strings.Fields(cmd)
 	if len(parts) == 0 {
 		return nil
@@ -41,7 +76,7 @@ func (s *Server) handleCommand(channel ssh.Channel, sessionID string, input stri
 	doorName := ""
 	if command == "open" {
 		if len(parts) < 2 {
-			fmt.Fprint(channel, "\rUsage: /open <door>\r\n")
+			fmt.Fprintf(channel, "\rUsage: %sopen <door>\r\n", prefix)
 			return nil
 		}
 		doorName = parts[1]
@@ -51,9 +86,17 @@ func (s *Server) handleCommand(channel ssh.Channel, sessionID string, input stri
 
 	if doorName != "" {
 		if _, ok := s.doorManager.Get(doorName); ok {
+			if err := s.checkDoor(p, doorName); err != nil {
+				fmt.Fprintf(channel, "\r[Cannot open %s: %v]\r\n", doorName, err)
+				return nil
+			}
+			if s.doorManager.Busy(doorName) {
+				fmt.Fprintf(channel, "\r[Door %s is busy, try later]\r\n", doorName)
+				return nil
+			}
 			fmt.Fprintf(channel, "\r[Opening door: %s]\r\n", doorName)
 			// Notification
-			s.broadcastWithHistory(p.PubKey, fmt.Sprintf("* %s started door: %s", username, doorName), ui.MsgSystem)
+			s.broadcastNotice(p.PubKey, fmt.Sprintf("* %s started door: %s", username, doorName))
 
 			done := make(chan struct{})
 			go func() {
@@ -88,10 +131,10 @@ func (s *Server) handleCommand(channel ssh.Channel, sessionID string, input stri
 }
 
 func (s *Server) handleInternalCommand(p *Person, cmd string) bool {
-	if strings.HasPrefix(cmd, "/") {
+	if prefix := s.commandPrefix(); strings.HasPrefix(cmd, prefix) {
 		log.Printf("Internal command from %s: %s", p.Username, cmd)
 		// Echo the command in the chat history
-		parts := strings.SplitN(strings.TrimPrefix(cmd, "/"), " ", 2)
+		parts := strings.SplitN(strings.TrimPrefix(cmd, prefix), " ", 2)
 		command := parts[0]
 		pubHash := s.getPubKeyHash(p.PubKey)
 
@@ -108,23 +151,73 @@ func (s *Server) handleInternalCommand(p *Person, cmd string) bool {
 		switch command {
 		case "help":
 			addMessage("--- Available Commands ---", ui.MsgServer)
-			addMessage("/help         - Show this help", ui.MsgServer)
-			addMessage("/people       - List people in room", ui.MsgServer)
-			addMessage("/doors        - List available doors", ui.MsgServer)
-			addMessage("/clear        - Clear your chat history", ui.MsgServer)
-			addMessage("/open <door>  - Open a door (launch program)", ui.MsgServer)
-			addMessage("/quit [msg]   - Leave the room", ui.MsgServer)
+			addMessage(s.withCmdPrefix("/help         - Show this help"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/people       - List people in room"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/doors        - List available doors"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/stats        - Show room statistics"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/clear        - Clear your chat history"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/open <door>  - Open a door (launch program)"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/files [dir]  - List downloadable files"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/geturl <file> - Show manual download details"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/manifest [csv] - Download a list of all files with checksums"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/head <file> [n] - Show the first n lines of a text file"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/tail <file> [n] - Show the last n lines of a text file"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/bookmark <file> - Remember a file for later"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/bookmarks    - List your bookmarked files"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/get-bookmark <n> - Download a bookmarked file"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/get-banner   - Download the room's banner art"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/whoami       - Show your identity"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/info <person> - Show when someone joined, their transport and idle time"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/motd         - Show the message of the day"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/lockstatus   - Show whether the room is locked"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/perms        - Show who may download and open doors"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/color <name> - Set your nick color (reset to clear)"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/markdown on|off - Show *bold*, _italic_ and `code` in chat"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/poll         - Show the current poll"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/vote <n>     - Vote for option n in the poll"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/send <user> <file> - Offer a file to someone"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/accept       - Accept the file offered to you"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/edit <n> <text> - Edit your n-th latest message"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/delete <n>   - Delete your n-th latest message"), ui.MsgServer)
+			addMessage(s.withCmdPrefix("/quit [msg]   - Leave the room"), ui.MsgServer)
 			addMessage("Ctrl+C        - Exit room", ui.MsgServer)
 
+			if s.isModerator(p.PubKey) {
+				addMessage("--- Moderator Commands ---", ui.MsgServer)
+				addMessage(s.withCmdPrefix("/kick <person> [reason]    - Kick a person"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/kickban <person> [reason] - Kick and ban a person"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/unban <person>            - Unban a person"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/banlist                   - List banned people"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/kickall [reason]          - Kick everyone"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/approve <person>          - Show a new user's first message"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/reject <person>           - Drop a new user's first message"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/ops <message>             - Message only the operator and moderators"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/delete <person> <n>       - Delete a person's n-th latest message"), ui.MsgServer)
+			}
 			if s.isOperator(p.PubKey) {
 				addMessage("--- Operator Commands ---", ui.MsgServer)
-				addMessage("/kick <person> [reason]    - Kick a person", ui.MsgServer)
-				addMessage("/kickban <person> [reason] - Kick and ban a person", ui.MsgServer)
-				addMessage("/unban <person>            - Unban a person", ui.MsgServer)
-				addMessage("/banlist                   - List banned people", ui.MsgServer)
-				addMessage("/lock <key>                - Lock the room", ui.MsgServer)
-				addMessage("/unlock                    - Unlock the room", ui.MsgServer)
-				addMessage("/kickall [reason]          - Kick everyone", ui.MsgServer)
+				addMessage(s.withCmdPrefix("/lock [key]                - Lock the room, or show its key"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/unlock                    - Unlock the room"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/reconnect-all [reason]    - Make everyone reconnect"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/rotate-key                - Replace the room host key"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/motd set <text> | clear   - Set the message of the day"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/announce <text>           - Announce something to everyone"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/schedule <delay> <cmd>    - Run a command later, e.g. 10m /kickall"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/scheduled                 - List scheduled commands"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/grant <person> <duration> - Make someone a moderator for a while"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/deop <person>             - End someone's moderator grant"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/roster [csv]              - List everyone who ever joined"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/unschedule <n>            - Cancel scheduled command n"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/quiet on|off              - Hide leave/door/whisper notices"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/perms downloads|doors <p> - Allow on, verified-only or off"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/snapshot save <name>      - Save room state next to the host key"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/mv <old> <new>            - Rename a file"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/rm <file>                 - Remove a file"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/bandwidth                 - Show upload and download usage"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/doorstats                 - Show runs, runtimes and errors per door"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/log [n]                   - Show the last n lines of the room log"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/poll <q> | <a> | <b> ...  - Start a poll"), ui.MsgServer)
+				addMessage(s.withCmdPrefix("/poll close                - End the poll"), ui.MsgServer)
 			}
 			return true
 		case "people":
@@ -132,7 +225,7 @@ func (s *Server) handleInternalCommand(p *Person, cmd string) bool {
 			people := make([]string, 0, len(s.people))
 			for _, person := range s.people {
 				prefix := ""
-				if s.operatorPubKey != nil && string(person.PubKey.Marshal()) == string(s.operatorPubKey.Marshal()) {
+				if s.isModerator(person.PubKey) {
 					prefix = "@"
 				}
 				hash := s.getPubKeyHash(person.PubKey)
@@ -144,26 +237,65 @@ func (s *Server) handleInternalCommand(p *Person, cmd string) bool {
 			s.mu.RUnlock()
 			addMessage("People:", ui.MsgServer)
 			for _, personStr := range people {
-				addMessage("• "+personStr, ui.MsgServer)
+				addMessage(common.Glyphs.Bullet+" "+personStr, ui.MsgServer)
+			}
+			return true
+		case "stats":
+			addMessage("--- Room Statistics ---", ui.MsgServer)
+			for _, line := range s.roomStats(time.Now()) {
+				addMessage(line, ui.MsgServer)
 			}
 			return true
 		case "me":
 			if len(parts) < 2 {
-				addMessage("Usage: /me <action>", ui.MsgServer)
+				addMessage(s.withCmdPrefix("Usage: /me <action>"), ui.MsgServer)
 				return true
 			}
-			action := strings.TrimSpace(parts[1])
-			chatMsg := fmt.Sprintf("* %s %s", p.Username, action)
-			s.broadcastWithHistory(p.PubKey, chatMsg, ui.MsgAction)
+			s.sendAction(p, strings.TrimSpace(parts[1]))
+			return true
+		case "edit":
+			args := []string{}
+			if len(parts) > 1 {
+				args = strings.SplitN(strings.TrimSpace(parts[1]), " ", 2)
+			}
+			if len(args) < 2 || strings.TrimSpace(args[1]) == "" {
+				addMessage(s.withCmdPrefix("Usage: /edit <n> <text> (1 = your latest message)"), ui.MsgServer)
+				return true
+			}
+			if err := s.editMessage(p, args[0], strings.TrimSpace(args[1])); err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+			}
+			return true
+		case "delete":
+			args := []string{}
+			if len(parts) > 1 {
+				args = strings.Fields(parts[1])
+			}
+			target := p
+			switch {
+			case len(args) == 1:
+			case len(args) == 2 && s.isModerator(p.PubKey):
+				if target = s.findPerson(args[0]); target == nil {
+					addMessage("User not found.", ui.MsgServer)
+					return true
+				}
+				args = args[1:]
+			default:
+				addMessage(s.withCmdPrefix("Usage: /delete <n> (1 = your latest message)"), ui.MsgServer)
+				return true
+			}
+			if err := s.deleteMessage(target, args[0]); err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+			}
 			return true
 		case "whisper":
 			if len(parts) < 2 {
-				addMessage("Usage: /whisper <user> <message>", ui.MsgServer)
+				addMessage(s.withCmdPrefix("Usage: /whisper <user> <message>"), ui.MsgServer)
 				return true
 			}
 			msgParts := strings.SplitN(parts[1], " ", 2)
 			if len(msgParts) < 2 {
-				addMessage("Usage: /whisper <user> <message>", ui.MsgServer)
+				addMessage(s.withCmdPrefix("Usage: /whisper <user> <message>"), ui.MsgServer)
 				return true
 			}
 			targetName := strings.TrimSpace(msgParts[0])
@@ -199,22 +331,24 @@ func (s *Server) handleInternalCommand(p *Person, cmd string) bool {
 			// Broadcast whisper event (the fact, not the content)
 			bystanderMsg := fmt.Sprintf("* %s is secretly whispering with %s", p.Username, targetName)
 			s.mu.Lock()
-			for _, person := range s.people {
-				if person.Username != p.Username && person.Username != targetName {
-					person.ChatUI.AddMessage(bystanderMsg, ui.MsgSystem)
-					h := s.getPubKeyHash(person.PubKey)
-					s.addMessageToHistory(h, ui.Message{Text: bystanderMsg, Type: ui.MsgSystem})
+			if !s.quietMode {
+				for _, person := range s.people {
+					if person.Username != p.Username && person.Username != targetName {
+						person.ChatUI.AddMessage(bystanderMsg, ui.MsgSystem)
+						h := s.getPubKeyHash(person.PubKey)
+						s.addMessageToHistory(h, ui.Message{Text: bystanderMsg, Type: ui.MsgSystem})
+					}
 				}
 			}
 			s.mu.Unlock()
 			return true
 		case "kick":
-			if !s.isOperator(p.PubKey) {
+			if !s.isModerator(p.PubKey) {
 				addMessage("You do not have operator privileges.", ui.MsgServer)
 				return true
 			}
 			if len(parts) < 2 {
-				addMessage("Usage: /kick <user/hash> [reason]", ui.MsgServer)
+				addMessage(s.withCmdPrefix("Usage: /kick <user/hash> [reason]"), ui.MsgServer)
 				return true
 			}
 			kickParts := strings.SplitN(parts[1], " ", 2)
@@ -239,6 +373,10 @@ func (s *Server) handleInternalCommand(p *Person, cmd string) bool {
 				addMessage("User not found.", ui.MsgServer)
 				return true
 			}
+			if s.isOperator(targetPerson.PubKey) {
+				addMessage("The room operator cannot be kicked.", ui.MsgServer)
+				return true
+			}
 
 			s.Broadcast("Server", fmt.Sprintf("*** %s was kicked by @%s (%s) ***", targetPerson.Username, p.Username, reason))
 			s.SendOSC(targetPerson, "popup", map[string]interface{}{
@@ -250,12 +388,12 @@ func (s *Server) handleInternalCommand(p *Person, cmd string) bool {
 			targetPerson.Conn.Close()
 			return true
 		case "kickban":
-			if !s.isOperator(p.PubKey) {
+			if !s.isModerator(p.PubKey) {
 				addMessage("You do not have operator privileges.", ui.MsgServer)
 				return true
 			}
 			if len(parts) < 2 {
-				addMessage("Usage: /kickban <user/hash> [reason]", ui.MsgServer)
+				addMessage(s.withCmdPrefix("Usage: /kickban <user/hash> [reason]"), ui.MsgServer)
 				return true
 			}
 			banParts := strings.SplitN(parts[1], " ", 2)
@@ -276,7 +414,14 @@ func (s *Server) handleInternalCommand(p *Person, cmd string) bool {
 					break
 				}
 			}
-			if targetPerson != nil {
+			if targetPerson == nil {
+				targetHash = targetID
+			}
+			// Offline bans match a hash prefix, so refuse one covering the operator
+			if s.operatorPubKey != nil && strings.HasPrefix(s.getPubKeyHash(s.operatorPubKey), targetHash) {
+				s.mu.Unlock()
+				addMessage("The room operator cannot be banned.", ui.MsgServer)
+			} else if targetPerson != nil {
 				s.bannedHashes[targetHash] = reason
 				s.mu.Unlock()
 				s.Broadcast("Server", fmt.Sprintf("*** %s was banned by @%s (%s) ***", targetPerson.Username, p.Username, reason))
@@ -300,12 +445,12 @@ func (s *Server) handleInternalCommand(p *Person, cmd string) bool {
 			}
 			return true
 		case "unban":
-			if !s.isOperator(p.PubKey) {
+			if !s.isModerator(p.PubKey) {
 				addMessage("You do not have operator privileges.", ui.MsgServer)
 				return true
 			}
 			if len(parts) < 2 {
-				addMessage("Usage: /unban <hash>", ui.MsgServer)
+				addMessage(s.withCmdPrefix("Usage: /unban <hash>"), ui.MsgServer)
 				return true
 			}
 			hash := strings.TrimSpace(parts[1])
@@ -326,7 +471,7 @@ func (s *Server) handleInternalCommand(p *Person, cmd string) bool {
 			}
 			return true
 		case "banlist":
-			if !s.isOperator(p.PubKey) {
+			if !s.isModerator(p.PubKey) {
 				addMessage("You do not have operator privileges.", ui.MsgServer)
 				return true
 			}
@@ -343,7 +488,14 @@ func (s *Server) handleInternalCommand(p *Person, cmd string) bool {
 				return true
 			}
 			if len(parts) < 2 {
-				addMessage("Usage: /lock <key>", ui.MsgServer)
+				s.mu.RLock()
+				key := s.roomLockKey
+				s.mu.RUnlock()
+				if key == "" {
+					addMessage(s.withCmdPrefix("The room is unlocked. Usage: /lock <key>"), ui.MsgServer)
+				} else {
+					addMessage(fmt.Sprintf("The room key is: %s", key), ui.MsgServer)
+				}
 				return true
 			}
 			key := strings.TrimSpace(parts[1])
@@ -352,6 +504,9 @@ func (s *Server) handleInternalCommand(p *Person, cmd string) bool {
 			s.mu.Unlock()
 			s.Broadcast("Server", fmt.Sprintf("*** @%s locked the room ***", p.Username))
 			return true
+		case "lockstatus":
+			addMessage(s.lockStatus(), ui.MsgServer)
+			return true
 		case "unlock":
 			if !s.isOperator(p.PubKey) {
 				addMessage("You do not have operator privileges.", ui.MsgServer)
@@ -363,7 +518,7 @@ func (s *Server) handleInternalCommand(p *Person, cmd string) bool {
 			s.Broadcast("Server", fmt.Sprintf("*** @%s unlocked the room ***", p.Username))
 			return true
 		case "kickall":
-			if !s.isOperator(p.PubKey) {
+			if !s.isModerator(p.PubKey) {
 				addMessage("You do not have operator privileges.", ui.MsgServer)
 				return true
 			}
@@ -375,12 +530,449 @@ func (s *Server) handleInternalCommand(p *Person, cmd string) bool {
 
 			s.mu.Lock()
 			for _, person := range s.people {
-				if !s.isOperator(person.PubKey) {
+				if !s.isModerator(person.PubKey) {
 					person.Conn.Close()
 				}
 			}
 			s.mu.Unlock()
 			return true
+		case "approve", "reject":
+			if !s.isModerator(p.PubKey) {
+				addMessage("You do not have operator privileges.", ui.MsgServer)
+				return true
+			}
+			if len(parts) < 2 {
+				addMessage(s.withCmdPrefix(fmt.Sprintf("Usage: /%s <person>", command)), ui.MsgServer)
+				return true
+			}
+			target := s.findPerson(strings.TrimSpace(parts[1]))
+			if target == nil {
+				addMessage(fmt.Sprintf("Error: user not found: %s", strings.TrimSpace(parts[1])), ui.MsgServer)
+				return true
+			}
+			if err := s.approveFirstMessage(target, command == "approve"); err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+			} else if command == "reject" {
+				addMessage(fmt.Sprintf("Rejected the first message of %s.", target.Username), ui.MsgServer)
+			}
+			return true
+		case "reconnect-all":
+			if !s.isOperator(p.PubKey) {
+				addMessage("You do not have operator privileges.", ui.MsgServer)
+				return true
+			}
+			reason := "Room settings changed."
+			if len(parts) > 1 {
+				reason = strings.TrimSpace(parts[1])
+			}
+			n := s.reconnectAll(reason)
+			addMessage(fmt.Sprintf("Asked %d people to reconnect.", n), ui.MsgServer)
+			return true
+		case "bandwidth":
+			if !s.isOperator(p.PubKey) {
+				addMessage("You do not have operator privileges.", ui.MsgServer)
+				return true
+			}
+			addMessage(s.bandwidth.String(), ui.MsgServer)
+			return true
+		case "doorstats":
+			if !s.isOperator(p.PubKey) {
+				addMessage("You do not have operator privileges.", ui.MsgServer)
+				return true
+			}
+			stats := s.doorManager.Stats()
+			if len(stats) == 0 {
+				addMessage("This room has no doors.", ui.MsgServer)
+				return true
+			}
+			addMessage("Door statistics since startup:", ui.MsgServer)
+			for _, st := range stats {
+				line := fmt.Sprintf("%s %s: %d runs", common.Glyphs.Bullet, st.Name, st.Runs)
+				if st.Runs > 0 {
+					line += fmt.Sprintf(", avg %s, last %s (%s ago)", st.Average().Round(time.Second),
+						st.LastTime.Round(time.Second), time.Since(st.LastRun).Round(time.Second))
+				}
+				if st.LastError != "" {
+					line += ", last error: " + st.LastError
+				}
+				addMessage(line, ui.MsgServer)
+			}
+			return true
+		case "quiet":
+			if !s.isOperator(p.PubKey) {
+				addMessage("You do not have operator privileges.", ui.MsgServer)
+				return true
+			}
+			arg := ""
+			if len(parts) > 1 {
+				arg = strings.ToLower(strings.TrimSpace(parts[1]))
+			}
+			var quiet bool
+			switch arg {
+			case "on":
+				quiet = true
+			case "off":
+				quiet = false
+			default:
+				s.mu.RLock()
+				state := "off"
+				if s.quietMode {
+					state = "on"
+				}
+				s.mu.RUnlock()
+				addMessage(s.withCmdPrefix(fmt.Sprintf("Quiet mode is %s. Usage: /quiet on|off", state)), ui.MsgServer)
+				return true
+			}
+			s.mu.Lock()
+			changed := s.quietMode != quiet
+			s.quietMode = quiet
+			s.mu.Unlock()
+			if !changed {
+				addMessage(fmt.Sprintf("Quiet mode is already %s.", arg), ui.MsgServer)
+				return true
+			}
+			if quiet {
+				s.broadcastWithHistory(nil, fmt.Sprintf("*** @%s turned on quiet mode: leave, door and whisper notices are hidden ***", p.Username), ui.MsgSystem)
+			} else {
+				s.broadcastWithHistory(nil, fmt.Sprintf("*** @%s turned off quiet mode ***", p.Username), ui.MsgSystem)
+			}
+			return true
+		case "perms":
+			args := []string{}
+			if len(parts) > 1 {
+				args = strings.Fields(strings.ToLower(parts[1]))
+			}
+			if len(args) == 0 {
+				s.mu.RLock()
+				msg := fmt.Sprintf("Downloads: %s, doors: %s.", s.downloadPerm, s.doorPerm)
+				s.mu.RUnlock()
+				addMessage(msg, ui.MsgServer)
+				return true
+			}
+			if !s.isOperator(p.PubKey) {
+				addMessage("You do not have operator privileges.", ui.MsgServer)
+				return true
+			}
+			if len(args) != 2 || (args[0] != "downloads" && args[0] != "doors") {
+				addMessage(s.withCmdPrefix("Usage: /perms downloads|doors on|verified-only|off"), ui.MsgServer)
+				return true
+			}
+			perm, err := ParsePermission(args[1])
+			if err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+				return true
+			}
+			s.mu.Lock()
+			if args[0] == "downloads" {
+				s.downloadPerm = perm
+			} else {
+				s.doorPerm = perm
+			}
+			s.mu.Unlock()
+			s.broadcastWithHistory(nil, fmt.Sprintf("*** @%s set %s to %s ***", p.Username, args[0], perm), ui.MsgSystem)
+			return true
+		case "poll":
+			arg := ""
+			if len(parts) > 1 {
+				arg = strings.TrimSpace(parts[1])
+			}
+			if arg == "" {
+				s.mu.RLock()
+				current := s.poll
+				msg := "No poll is running."
+				if current != nil {
+					msg = current.results("Poll")
+				}
+				s.mu.RUnlock()
+				addMessage(msg, ui.MsgSystem)
+				return true
+			}
+			if !s.isOperator(p.PubKey) {
+				addMessage("You do not have operator privileges.", ui.MsgServer)
+				return true
+			}
+			if arg == "close" {
+				s.mu.Lock()
+				closed := s.poll
+				s.poll = nil
+				var msg string
+				if closed != nil {
+					msg = closed.results("Poll closed")
+				}
+				s.mu.Unlock()
+				if closed == nil {
+					addMessage("No poll is running.", ui.MsgServer)
+					return true
+				}
+				s.broadcastWithHistory(nil, msg, ui.MsgSystem)
+				return true
+			}
+			np, err := parsePoll(arg)
+			if err != nil {
+				addMessage(s.withCmdPrefix("Usage: /poll <question> | <option> | <option> ..."), ui.MsgServer)
+				return true
+			}
+			s.mu.Lock()
+			s.poll = np
+			msg := np.results("Poll by @"+p.Username) + s.withCmdPrefix("\nVote with /vote <n>")
+			s.mu.Unlock()
+			s.broadcastWithHistory(nil, msg, ui.MsgSystem)
+			return true
+		case "vote":
+			if len(parts) < 2 {
+				addMessage(s.withCmdPrefix("Usage: /vote <n>"), ui.MsgServer)
+				return true
+			}
+			choice, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+			if err != nil {
+				addMessage(s.withCmdPrefix("Usage: /vote <n>"), ui.MsgServer)
+				return true
+			}
+			s.mu.Lock()
+			if s.poll == nil {
+				s.mu.Unlock()
+				addMessage("No poll is running.", ui.MsgServer)
+				return true
+			}
+			if err := s.poll.vote(pubHash, choice); err != nil {
+				s.mu.Unlock()
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+				return true
+			}
+			msg := s.poll.results("Poll")
+			s.mu.Unlock()
+			s.broadcastWithHistory(nil, msg, ui.MsgSystem)
+			return true
+		case "snapshot":
+			if !s.isOperator(p.PubKey) {
+				addMessage("You do not have operator privileges.", ui.MsgServer)
+				return true
+			}
+			arg := ""
+			if len(parts) > 1 {
+				arg = strings.TrimSpace(parts[1])
+			}
+			sub, name, _ := strings.Cut(arg, " ")
+			name = strings.TrimSpace(name)
+			if sub != "save" || name == "" {
+				addMessage(s.withCmdPrefix("Usage: /snapshot save <name>"), ui.MsgServer)
+				return true
+			}
+			path, err := s.snapshotPath(name)
+			if err == nil {
+				err = s.SaveSnapshot(path)
+			}
+			if err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+				return true
+			}
+			log.Printf("%s saved a snapshot to %s", p.Username, path)
+			addMessage(fmt.Sprintf("Room state saved as %s next to the room's host key. Restore it with -restore.", name), ui.MsgServer)
+			return true
+		case "motd":
+			arg := ""
+			if len(parts) > 1 {
+				arg = strings.TrimSpace(parts[1])
+			}
+			if arg == "" {
+				if motd := s.GetMOTD(); motd != "" {
+					addMessage("MOTD: "+motd, ui.MsgServer)
+				} else {
+					addMessage("No message of the day is set.", ui.MsgServer)
+				}
+				return true
+			}
+			if !s.isOperator(p.PubKey) {
+				addMessage("You do not have operator privileges.", ui.MsgServer)
+				return true
+			}
+			sub, text, _ := strings.Cut(arg, " ")
+			switch sub {
+			case "set":
+				if strings.TrimSpace(text) == "" {
+					addMessage(s.withCmdPrefix("Usage: /motd set <text>"), ui.MsgServer)
+					return true
+				}
+				if err := s.SetMOTD(text); err != nil {
+					addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+					return true
+				}
+				addMessage("MOTD set: "+s.GetMOTD(), ui.MsgServer)
+			case "clear":
+				if err := s.SetMOTD(""); err != nil {
+					addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+					return true
+				}
+				addMessage("MOTD cleared.", ui.MsgServer)
+			default:
+				addMessage(s.withCmdPrefix("Usage: /motd [set <text> | clear]"), ui.MsgServer)
+			}
+			return true
+		case "announce":
+			if !s.isOperator(p.PubKey) {
+				addMessage("You do not have operator privileges.", ui.MsgServer)
+				return true
+			}
+			if len(parts) < 2 || strings.TrimSpace(parts[1]) == "" {
+				addMessage(s.withCmdPrefix("Usage: /announce <text>"), ui.MsgServer)
+				return true
+			}
+			s.broadcastWithHistory(nil, fmt.Sprintf("*** Announcement from @%s: %s ***", p.Username, strings.TrimSpace(parts[1])), ui.MsgServer)
+			return true
+		case "schedule":
+			if !s.isOperator(p.PubKey) {
+				addMessage("You do not have operator privileges.", ui.MsgServer)
+				return true
+			}
+			var delayArg, action string
+			if len(parts) > 1 {
+				delayArg, action, _ = strings.Cut(strings.TrimSpace(parts[1]), " ")
+			}
+			action = strings.TrimSpace(action)
+			if action == "" {
+				addMessage(s.withCmdPrefix("Usage: /schedule <delay> <command> (e.g. /schedule 10m /announce closing soon)"), ui.MsgServer)
+				return true
+			}
+			delay, err := time.ParseDuration(delayArg)
+			if err != nil {
+				addMessage(fmt.Sprintf("Error: invalid delay: %s", delayArg), ui.MsgServer)
+				return true
+			}
+			a, err := s.schedule(p, delay, action)
+			if err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+				return true
+			}
+			addMessage(fmt.Sprintf("Scheduled #%d in %s: %s", a.ID, delay, a.Cmd), ui.MsgServer)
+			return true
+		case "scheduled":
+			if !s.isOperator(p.PubKey) {
+				addMessage("You do not have operator privileges.", ui.MsgServer)
+				return true
+			}
+			lines := s.scheduledList(time.Now())
+			if len(lines) == 0 {
+				addMessage("Nothing is scheduled.", ui.MsgServer)
+				return true
+			}
+			addMessage("--- Scheduled Commands ---", ui.MsgServer)
+			for _, line := range lines {
+				addMessage(line, ui.MsgServer)
+			}
+			return true
+		case "unschedule":
+			if !s.isOperator(p.PubKey) {
+				addMessage("You do not have operator privileges.", ui.MsgServer)
+				return true
+			}
+			id := 0
+			if len(parts) > 1 {
+				id, _ = strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(parts[1]), "#"))
+			}
+			if id < 1 {
+				addMessage(s.withCmdPrefix("Usage: /unschedule <n>"), ui.MsgServer)
+				return true
+			}
+			if err := s.unschedule(id); err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+				return true
+			}
+			addMessage(fmt.Sprintf("Cancelled scheduled command #%d.", id), ui.MsgServer)
+			return true
+		case "grant", "deop":
+			if !s.isModerator(p.PubKey) {
+				addMessage("You do not have operator privileges.", ui.MsgServer)
+				return true
+			}
+			if !s.isOperator(p.PubKey) {
+				addMessage("Only the room operator can grant or end moderator status.", ui.MsgServer)
+				return true
+			}
+			var targetID, durationArg string
+			if len(parts) > 1 {
+				targetID, durationArg, _ = strings.Cut(strings.TrimSpace(parts[1]), " ")
+			}
+			durationArg = strings.TrimSpace(durationArg)
+			if targetID == "" || (command == "grant" && durationArg == "") {
+				if command == "grant" {
+					addMessage(s.withCmdPrefix("Usage: /grant <user/hash> <duration> (e.g. /grant bob 2h)"), ui.MsgServer)
+				} else {
+					addMessage(s.withCmdPrefix("Usage: /deop <user/hash>"), ui.MsgServer)
+				}
+				return true
+			}
+
+			s.mu.RLock()
+			var target *Person
+			for _, person := range s.people {
+				if person.Username == targetID || strings.HasPrefix(s.getPubKeyHash(person.PubKey), targetID) {
+					target = person
+					break
+				}
+			}
+			s.mu.RUnlock()
+			if target == nil {
+				addMessage("User not found.", ui.MsgServer)
+				return true
+			}
+
+			if command == "deop" {
+				if !s.revokeGrant(s.getPubKeyHash(target.PubKey), nil, "revoked by @"+p.Username) {
+					addMessage(fmt.Sprintf("%s has no moderator grant.", target.Username), ui.MsgServer)
+				}
+				return true
+			}
+			d, err := time.ParseDuration(durationArg)
+			if err != nil {
+				addMessage(fmt.Sprintf("Error: invalid duration: %s", durationArg), ui.MsgServer)
+				return true
+			}
+			if err := s.grant(p, target, d); err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+			}
+			return true
+		case "roster":
+			if !s.isOperator(p.PubKey) {
+				addMessage("You do not have operator privileges.", ui.MsgServer)
+				return true
+			}
+			format := ""
+			if len(parts) > 1 {
+				format = strings.ToLower(strings.TrimSpace(parts[1]))
+			}
+			list := s.rosterList()
+			switch format {
+			case "":
+				addMessage(fmt.Sprintf("--- Roster (%d people) ---", len(list)), ui.MsgServer)
+				for _, e := range list {
+					addMessage(common.Glyphs.Bullet+" "+rosterLine(e), ui.MsgServer)
+				}
+			case "csv":
+				if !p.UNNAware || p.Bus == nil {
+					addMessage("The roster export is sent as a download and needs the UNN client.", ui.MsgServer)
+					return true
+				}
+				data, err := encodeRoster(list)
+				if err != nil {
+					addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+					return true
+				}
+				filename := s.roomName + "-roster.csv"
+				s.sendData(p, filename, data)
+				addMessage(fmt.Sprintf("Sent %s (%d people) to your downloads.", filename, len(list)), ui.MsgServer)
+			default:
+				addMessage(s.withCmdPrefix("Usage: /roster [csv]"), ui.MsgServer)
+			}
+			return true
+		case "rotate-key":
+			if !s.isOperator(p.PubKey) {
+				addMessage("You do not have operator privileges.", ui.MsgServer)
+				return true
+			}
+			if _, err := s.RotateHostKey(); err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+			}
+			return true
 		case "clear":
 			s.mu.Lock()
 			delete(s.histories, pubHash)
@@ -389,15 +981,22 @@ func (s *Server) handleInternalCommand(p *Person, cmd string) bool {
 			return true
 		case "doors":
 			doorList := s.doorManager.List()
+			if len(doorList) == 0 {
+				addMessage("This room has no doors.", ui.MsgServer)
+				if s.isOperator(p.PubKey) {
+					addMessage(s.doorManager.Diagnose(), ui.MsgServer)
+				}
+				return true
+			}
 			addMessage("--- Available doors ---", ui.MsgServer)
 			for _, door := range doorList {
-				addMessage("• "+door, ui.MsgServer)
+				addMessage(common.Glyphs.Bullet+" "+door, ui.MsgServer)
 			}
-			addMessage("Type /open <door> to launch a program.", ui.MsgServer)
+			addMessage(s.withCmdPrefix("Type /open <door> to launch a program."), ui.MsgServer)
 			return true
 		case "open":
 			if len(parts) < 2 {
-				addMessage("Usage: /open <door>", ui.MsgServer)
+				addMessage(s.withCmdPrefix("Usage: /open <door>"), ui.MsgServer)
 				return true
 			}
 			doorName := strings.TrimSpace(parts[1])
@@ -405,8 +1004,374 @@ func (s *Server) handleInternalCommand(p *Person, cmd string) bool {
 				addMessage(fmt.Sprintf("Door not found: %s", doorName), ui.MsgServer)
 				return true
 			}
+			if err := s.checkDoor(p, doorName); err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+				return true
+			}
+			if s.doorManager.Busy(doorName) {
+				addMessage(fmt.Sprintf("Door %s is busy, try later.", doorName), ui.MsgServer)
+				return true
+			}
 			// Door exists, return false to exit TUI and execute it in handleCommand
 			return false
+		case "color":
+			if len(parts) < 2 {
+				addMessage(s.withCmdPrefix("Usage: /color <name|reset> (e.g. red, orange, lightgreen)"), ui.MsgServer)
+				return true
+			}
+			name := strings.ToLower(strings.TrimSpace(parts[1]))
+			if name == "reset" || name == "default" {
+				s.mu.Lock()
+				delete(s.colors, pubHash)
+				s.mu.Unlock()
+				addMessage("Your color was reset.", ui.MsgServer)
+				return true
+			}
+			color, ok := tcell.ColorNames[name]
+			if !ok || color == tcell.ColorBlack {
+				addMessage(fmt.Sprintf("Unknown color: %s", name), ui.MsgServer)
+				return true
+			}
+			s.mu.Lock()
+			s.colors[pubHash] = color
+			s.mu.Unlock()
+			addMessage(fmt.Sprintf("Your color is now %s.", name), ui.MsgServer)
+			return true
+		case "markdown":
+			if len(parts) < 2 {
+				s.mu.RLock()
+				on := s.markdown[pubHash]
+				s.mu.RUnlock()
+				state := "off"
+				if on {
+					state = "on"
+				}
+				addMessage(fmt.Sprintf("Markdown is %s.", state), ui.MsgServer)
+				return true
+			}
+			var on bool
+			switch strings.ToLower(strings.TrimSpace(parts[1])) {
+			case "on":
+				on = true
+			case "off":
+			default:
+				addMessage(s.withCmdPrefix("Usage: /markdown on|off"), ui.MsgServer)
+				return true
+			}
+			s.mu.Lock()
+			if on {
+				s.markdown[pubHash] = true
+			} else {
+				delete(s.markdown, pubHash)
+			}
+			s.mu.Unlock()
+			if p.ChatUI != nil {
+				p.ChatUI.SetMarkdown(on)
+			}
+			if on {
+				addMessage("Markdown is now on: *bold*, _italic_ and `code` are formatted.", ui.MsgServer)
+			} else {
+				addMessage("Markdown is now off.", ui.MsgServer)
+			}
+			return true
+		case "whoami":
+			addMessage(fmt.Sprintf("Username: %s", p.Username), ui.MsgServer)
+			if p.PubKey != nil {
+				addMessage(fmt.Sprintf("Key: %s %s", p.PubKey.Type(), ssh.FingerprintSHA256(p.PubKey)), ui.MsgServer)
+			}
+			addMessage(fmt.Sprintf("Hash: %s", pubHash), ui.MsgServer)
+			s.mu.RLock()
+			isOp := s.isOperator(p.PubKey)
+			s.mu.RUnlock()
+			switch {
+			case isOp:
+				addMessage("Operator: yes", ui.MsgServer)
+			case s.hasGrant(p.PubKey):
+				addMessage("Operator: moderator (granted)", ui.MsgServer)
+			default:
+				addMessage("Operator: no", ui.MsgServer)
+			}
+			return true
+		case "info":
+			if len(parts) < 2 {
+				addMessage(s.withCmdPrefix("Usage: /info <person>"), ui.MsgServer)
+				return true
+			}
+			lines, err := s.personInfo(strings.TrimSpace(parts[1]), time.Now())
+			if err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+				return true
+			}
+			for _, line := range lines {
+				addMessage(line, ui.MsgServer)
+			}
+			return true
+		case "files":
+			folder := ""
+			if len(parts) > 1 {
+				folder = strings.TrimSpace(parts[1])
+			}
+			lines, err := s.listFiles(folder)
+			if err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+				return true
+			}
+			addMessage("--- Files ---", ui.MsgServer)
+			if len(lines) == 0 {
+				addMessage("No files available.", ui.MsgServer)
+			}
+			for _, line := range lines {
+				addMessage(common.Glyphs.Bullet+" "+line, ui.MsgServer)
+			}
+			addMessage(s.withCmdPrefix("Type /open files to download."), ui.MsgServer)
+			return true
+		case "geturl":
+			if len(parts) < 2 {
+				addMessage(s.withCmdPrefix("Usage: /geturl <filename>"), ui.MsgServer)
+				return true
+			}
+			if err := s.checkDownload(p); err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+				return true
+			}
+			line, err := s.fileDownloadLine(strings.TrimSpace(parts[1]))
+			if err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+				return true
+			}
+			addMessage(line, ui.MsgServer)
+			if s.clipboard && p.UNNAware && p.Bus != nil {
+				common.SendClipboard(p.Bus, line)
+				addMessage("Copied to your clipboard.", ui.MsgServer)
+			}
+			return true
+		case "head", "tail":
+			if len(parts) < 2 {
+				addMessage(s.withCmdPrefix(fmt.Sprintf("Usage: /%s <file> [n]", command)), ui.MsgServer)
+				return true
+			}
+			if err := s.checkDownload(p); err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+				return true
+			}
+			name, n, err := parsePreviewArgs(parts[1])
+			var lines []string
+			if err == nil {
+				if command == "head" {
+					lines, err = s.headFile(name, n)
+				} else {
+					lines, err = s.tailFile(name, n)
+				}
+			}
+			if err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+				return true
+			}
+			addMessage(fmt.Sprintf("--- %s (%s %d) ---", name, command, n), ui.MsgServer)
+			for _, line := range lines {
+				addMessage(line, ui.MsgServer)
+			}
+			return true
+		case "ops":
+			if !s.isModerator(p.PubKey) {
+				addMessage("You do not have operator privileges.", ui.MsgServer)
+				return true
+			}
+			if len(parts) < 2 || strings.TrimSpace(parts[1]) == "" {
+				addMessage(s.withCmdPrefix("Usage: /ops <message>"), ui.MsgServer)
+				return true
+			}
+			s.broadcastStaff(p, strings.TrimSpace(parts[1]))
+			return true
+		case "log":
+			if !s.isOperator(p.PubKey) {
+				addMessage("You do not have operator privileges.", ui.MsgServer)
+				return true
+			}
+			if s.logRing == nil {
+				addMessage("The room log is not kept in memory.", ui.MsgServer)
+				return true
+			}
+			n := defaultLogLines
+			if len(parts) > 1 {
+				v, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+				if err != nil || v < 1 {
+					addMessage(s.withCmdPrefix("Usage: /log [n]"), ui.MsgServer)
+					return true
+				}
+				n = min(v, maxLogLines)
+			}
+			lines := s.logRing.Lines(n)
+			addMessage(fmt.Sprintf("--- Last %d log lines ---", len(lines)), ui.MsgServer)
+			for _, line := range lines {
+				addMessage(common.StripANSI(line), ui.MsgServer)
+			}
+			return true
+		case "mv", "rm":
+			if !s.isOperator(p.PubKey) {
+				addMessage("You do not have operator privileges.", ui.MsgServer)
+				return true
+			}
+			var args []string
+			if len(parts) > 1 {
+				args = strings.Fields(parts[1])
+			}
+			if command == "mv" {
+				if len(args) != 2 {
+					addMessage(s.withCmdPrefix("Usage: /mv <old> <new>"), ui.MsgServer)
+					return true
+				}
+				from, to, err := s.moveFile(args[0], args[1])
+				if err != nil {
+					addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+					return true
+				}
+				s.broadcastWithHistory(nil, fmt.Sprintf("*** @%s renamed %s to %s ***", p.Username, from, to), ui.MsgSystem)
+				return true
+			}
+			if len(args) != 1 {
+				addMessage(s.withCmdPrefix("Usage: /rm <file>"), ui.MsgServer)
+				return true
+			}
+			name, err := s.removeFile(args[0])
+			if err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+				return true
+			}
+			s.broadcastWithHistory(nil, fmt.Sprintf("*** @%s removed %s ***", p.Username, name), ui.MsgSystem)
+			return true
+		case "bookmark":
+			if len(parts) < 2 {
+				addMessage(s.withCmdPrefix("Usage: /bookmark <file>"), ui.MsgServer)
+				return true
+			}
+			n, err := s.addBookmark(pubHash, strings.TrimSpace(parts[1]))
+			if err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+				return true
+			}
+			addMessage(s.withCmdPrefix(fmt.Sprintf("Bookmarked as %d, download it with /get-bookmark %d.", n, n)), ui.MsgServer)
+			return true
+		case "bookmarks":
+			list := s.listBookmarks(pubHash)
+			if len(list) == 0 {
+				addMessage(s.withCmdPrefix("No bookmarks. Add one with /bookmark <file>."), ui.MsgServer)
+				return true
+			}
+			addMessage("--- Bookmarks ---", ui.MsgServer)
+			for i, name := range list {
+				addMessage(fmt.Sprintf("%d. %s", i+1, name), ui.MsgServer)
+			}
+			return true
+		case "get-bookmark":
+			n := 0
+			if len(parts) > 1 {
+				n, _ = strconv.Atoi(strings.TrimSpace(parts[1]))
+			}
+			if n < 1 {
+				addMessage(s.withCmdPrefix("Usage: /get-bookmark <n>"), ui.MsgServer)
+				return true
+			}
+			if err := s.checkDownload(p); err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+				return true
+			}
+			name, fullPath, err := s.getBookmark(pubHash, n)
+			if err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+				return true
+			}
+			if !p.UNNAware || p.Bus == nil {
+				addMessage(s.withCmdPrefix(fmt.Sprintf("Downloading needs the UNN client. Use /geturl %s instead.", name)), ui.MsgServer)
+				return true
+			}
+			addMessage(fmt.Sprintf("Sending %s...", name), ui.MsgServer)
+			if err := s.sendFile(p, path.Base(name), fullPath); err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+			}
+			return true
+		case "get-banner":
+			if err := s.checkDownload(p); err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+				return true
+			}
+			if !p.UNNAware || p.Bus == nil {
+				addMessage("Downloading the banner needs the UNN client.", ui.MsgServer)
+				return true
+			}
+			filename, err := s.sendBanner(p)
+			if err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+				return true
+			}
+			addMessage(fmt.Sprintf("Sent %s to your downloads.", filename), ui.MsgServer)
+			return true
+		case "send":
+			args := []string{}
+			if len(parts) > 1 {
+				args = strings.SplitN(strings.TrimSpace(parts[1]), " ", 2)
+			}
+			if len(args) < 2 {
+				addMessage(s.withCmdPrefix("Usage: /send <user> <file>"), ui.MsgServer)
+				return true
+			}
+			file := strings.TrimSpace(args[1])
+			to, err := s.offerFile(p, args[0], file)
+			if err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+				return true
+			}
+			if to.ChatUI != nil {
+				to.ChatUI.AddMessage(s.withCmdPrefix(fmt.Sprintf("%s wants to send you %s, accept with /accept", p.Username, file)), ui.MsgServer)
+			}
+			addMessage(fmt.Sprintf("Offered %s to %s.", file, to.Username), ui.MsgServer)
+			return true
+		case "accept":
+			if err := s.checkDownload(p); err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+				return true
+			}
+			offer, err := s.takeOffer(p)
+			if err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+				return true
+			}
+			if !p.UNNAware || p.Bus == nil {
+				addMessage("Receiving files needs the UNN client.", ui.MsgServer)
+				return true
+			}
+			addMessage(fmt.Sprintf("Receiving %s from %s...", offer.Name, offer.From), ui.MsgServer)
+			if err := s.sendFile(p, path.Base(offer.Name), offer.Path); err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+			}
+			return true
+		case "manifest":
+			format := "json"
+			if len(parts) > 1 {
+				format = strings.ToLower(strings.TrimSpace(parts[1]))
+			}
+			if err := s.checkDownload(p); err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+				return true
+			}
+			if !p.UNNAware || p.Bus == nil {
+				addMessage(s.withCmdPrefix("The manifest is sent as a download and needs the UNN client. Use /files and /geturl instead."), ui.MsgServer)
+				return true
+			}
+			entries, err := s.buildManifest()
+			if err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+				return true
+			}
+			data, err := encodeManifest(entries, format)
+			if err != nil {
+				addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+				return true
+			}
+			filename := fmt.Sprintf("%s-manifest.%s", s.roomName, format)
+			s.sendData(p, filename, data)
+			addMessage(fmt.Sprintf("Sent %s (%d files) to your downloads.", filename, len(entries)), ui.MsgServer)
+			return true
 		case "quit", "exit":
 			if len(parts) > 1 {
 				p.QuitReason = strings.TrimSpace(parts[1])
@@ -416,6 +1381,10 @@ func (s *Server) handleInternalCommand(p *Person, cmd string) bool {
 		default:
 			// Check if this is a valid door
 			if _, ok := s.doorManager.Get(command); ok {
+				if err := s.checkDoor(p, command); err != nil {
+					addMessage(fmt.Sprintf("Error: %v", err), ui.MsgServer)
+					return true
+				}
 				return false // Exit TUI to execute door
 			}
 			addMessage(fmt.Sprintf("Unknown command: %s", command), ui.MsgServer)
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/gdamore/tcell/v2"
 	"github.com/mevdschee/underground-node-network/internal/doors"
 	"github.com/mevdschee/underground-node-network/internal/ui"
 	"golang.org/x/crypto/ssh"
@@ -201,6 +202,85 @@ func TestRoomCommands(t *testing.T) {
 			t.Errorf("handleInternalCommand should return false for valid /open")
 		}
 	})
+
+	t.Run("color", func(t *testing.T) {
+		s.mu.Lock()
+		s.people["alice"] = p
+		s.mu.Unlock()
+
+		s.handleInternalCommand(p, "/color purple")
+		s.mu.RLock()
+		stored := s.colors[s.getPubKeyHash(p.PubKey)]
+		s.mu.RUnlock()
+		if stored != tcell.ColorPurple {
+			t.Fatalf("Chosen color not stored, got %v", stored)
+		}
+
+		s.Broadcast("alice", "colorful")
+		msgs := p.ChatUI.GetMessages()
+		last := msgs[len(msgs)-1]
+		if last.Text != "<alice> colorful" || last.Color != tcell.ColorPurple {
+			t.Errorf("Broadcast message not colored: %+v", last)
+		}
+
+		s.handleInternalCommand(p, "/color notacolor")
+		msgs = p.ChatUI.GetMessages()
+		if !strings.Contains(msgs[len(msgs)-1].Text, "Unknown color") {
+			t.Errorf("Invalid color was not rejected")
+		}
+
+		s.handleInternalCommand(p, "/color reset")
+		s.mu.RLock()
+		_, ok := s.colors[s.getPubKeyHash(p.PubKey)]
+		s.mu.RUnlock()
+		if ok {
+			t.Errorf("Color was not reset")
+		}
+	})
+
+	t.Run("whoami", func(t *testing.T) {
+		s.handleInternalCommand(p, "/whoami")
+		msgs := p.ChatUI.GetMessages()
+		foundKey, foundHash := false, false
+		for _, m := range msgs {
+			if strings.Contains(m.Text, ssh.FingerprintSHA256(p.PubKey)) && m.Type == ui.MsgServer {
+				foundKey = true
+			}
+			if strings.Contains(m.Text, "Hash: "+s.getPubKeyHash(p.PubKey)) {
+				foundHash = true
+			}
+		}
+		if !foundKey || !foundHash {
+			t.Errorf("/whoami didn't show key fingerprint and hash")
+		}
+	})
+
+	t.Run("geturl", func(t *testing.T) {
+		filesDir := filepath.Join(tmpDir, "files")
+		os.MkdirAll(filesDir, 0755)
+		os.WriteFile(filepath.Join(filesDir, "hello.txt"), []byte("hello"), 0644)
+		s.SetFileRoots([]string{filesDir})
+
+		s.handleInternalCommand(p, "/geturl hello.txt")
+		s.handleInternalCommand(p, "/geturl ../host_key")
+		msgs := p.ChatUI.GetMessages()
+		foundLine, foundReject := false, false
+		for _, m := range msgs {
+			// sha256 of "hello"
+			if strings.Contains(m.Text, "hello.txt (5 B) sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824") {
+				foundLine = true
+			}
+			if strings.Contains(m.Text, "invalid filename") {
+				foundReject = true
+			}
+		}
+		if !foundLine {
+			t.Errorf("/geturl didn't show file details")
+		}
+		if !foundReject {
+			t.Errorf("/geturl accepted a path outside the files directory")
+		}
+	})
 }
 
 type mockChannel struct {
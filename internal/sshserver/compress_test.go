@@ -0,0 +1,131 @@
+package sshserver
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/sshtest"
+)
+
+func TestCompression(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	waitForLeave(t, s)
+	s.SetHeadless(true)
+	signer, err := sshtest.NewSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AuthorizeKey(signer.PublicKey(), "alice")
+
+	t.Run("refused when off", func(t *testing.T) {
+		client, err := sshtest.Dial(s, "alice", signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+		if _, err := sshtest.StartCompressedShell(client, 80, 24); err == nil {
+			t.Error("Expected the room to refuse compression")
+		}
+	})
+
+	t.Run("compressed session", func(t *testing.T) {
+		s.SetCompression(true)
+		client, err := sshtest.Dial(s, "alice", signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+		sess, err := sshtest.StartCompressedShell(client, 80, 24)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sess.Send("hello")
+		if err := sess.WaitFor("<alice> hello", 2*time.Second); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("plain clients still work", func(t *testing.T) {
+		client, err := sshtest.Dial(s, "alice", signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+		sess, err := sshtest.StartShell(client, 80, 24)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sess.Send("plain")
+		if err := sess.WaitFor("<alice> plain", 2*time.Second); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// BenchmarkCompression runs a scripted chat session in the full TUI with
+// and without -compress-ssh and reports the bytes on the wire per session
+func BenchmarkCompression(b *testing.B) {
+	for _, compress := range []bool{false, true} {
+		name := "plain"
+		if compress {
+			name = "deflate"
+		}
+		b.Run(name, func(b *testing.B) {
+			tmpDir := b.TempDir()
+			s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+			if err != nil {
+				b.Fatalf("Failed to create server: %v", err)
+			}
+			s.SetCompression(compress)
+
+			start := s.bandwidth.sent.Load() + s.bandwidth.received.Load()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				runScriptedSession(b, s, compress)
+			}
+			b.StopTimer()
+			total := s.bandwidth.sent.Load() + s.bandwidth.received.Load() - start
+			b.ReportMetric(float64(total)/float64(b.N), "wire-bytes/op")
+		})
+	}
+}
+
+// runScriptedSession joins the room with a new key, chats and leaves
+func runScriptedSession(b *testing.B, s *Server, compress bool) {
+	signer, err := sshtest.NewSigner()
+	if err != nil {
+		b.Fatal(err)
+	}
+	s.AuthorizeKey(signer.PublicKey(), "alice")
+	client, err := sshtest.Dial(s, "alice", signer)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer client.Close()
+	start := sshtest.StartShell
+	if compress {
+		start = sshtest.StartCompressedShell
+	}
+	sess, err := start(client, 100, 30)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := sess.WaitFor("Type /help for commands", 5*time.Second); err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		sess.Type(fmt.Sprintf("message number %d about the files in this room\r", i))
+	}
+	if err := sess.WaitFor("message number 19", 5*time.Second); err != nil {
+		b.Fatal(err)
+	}
+	sess.Type("/quit\r")
+	sess.WaitClosed(5 * time.Second)
+}
@@ -0,0 +1,76 @@
+package sshserver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/sshtest"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestEndToEndChat(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "unn-e2e-test-*")
+	defer os.RemoveAll(tmpDir)
+
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	s.SetHeadless(true)
+
+	join := func(name string) (*ssh.Client, *sshtest.Session) {
+		signer, err := sshtest.NewSigner()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.AuthorizeKey(signer.PublicKey(), name)
+		client, err := sshtest.Dial(s, name, signer)
+		if err != nil {
+			t.Fatalf("%s failed to connect: %v", name, err)
+		}
+		sess, err := sshtest.StartShell(client, 80, 24)
+		if err != nil {
+			t.Fatalf("%s failed to start shell: %v", name, err)
+		}
+		if err := sess.WaitFor("*** You joined testroom as "+name+" ***", 2*time.Second); err != nil {
+			t.Fatal(err)
+		}
+		return client, sess
+	}
+
+	// The first person to connect becomes the operator
+	aliceClient, alice := join("alice")
+	defer aliceClient.Close()
+	bobClient, bob := join("bob")
+	defer bobClient.Close()
+
+	t.Run("broadcast reaches both", func(t *testing.T) {
+		if err := alice.Send("hello bob"); err != nil {
+			t.Fatal(err)
+		}
+		if err := bob.WaitFor("<alice> hello bob", 2*time.Second); err != nil {
+			t.Error(err)
+		}
+		if err := alice.WaitFor("<alice> hello bob", 2*time.Second); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("operator kicks", func(t *testing.T) {
+		if err := alice.Send("/kick bob spamming"); err != nil {
+			t.Fatal(err)
+		}
+		if err := alice.WaitFor("bob was kicked by @alice (spamming)", 2*time.Second); err != nil {
+			t.Error(err)
+		}
+		if err := bob.WaitClosed(2 * time.Second); err != nil {
+			t.Error(err)
+		}
+		if err := alice.WaitFor("* bob left the room", 2*time.Second); err != nil {
+			t.Error(err)
+		}
+	})
+}
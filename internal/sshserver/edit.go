@@ -0,0 +1,110 @@
+package sshserver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxSentMessages is how many of a person's recent messages /edit and
+// /delete can reach
+const maxSentMessages = 20
+
+// sentMessage is a chat message a person sent in this session
+type sentMessage struct {
+	ID   int
+	Text string
+}
+
+// recordSent remembers a message the person sent. Caller must hold s.mu.
+func (p *Person) recordSent(id int, text string) {
+	p.sent = append(p.sent, sentMessage{ID: id, Text: text})
+	if len(p.sent) > maxSentMessages {
+		p.sent = p.sent[1:]
+	}
+}
+
+// sentByIndex finds the person's n-th most recent message (1 = latest).
+// Caller must hold s.mu.
+func (p *Person) sentByIndex(arg string) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("message number must be 1 (your latest) or higher")
+	}
+	if n > len(p.sent) {
+		return 0, fmt.Errorf("you have no message %d in this session", n)
+	}
+	return len(p.sent) - n, nil
+}
+
+// editMessage replaces the text of the person's n-th most recent message
+// everywhere it is shown and in everyone's history
+func (s *Server) editMessage(p *Person, n, text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, err := p.sentByIndex(n)
+	if err != nil {
+		return err
+	}
+	p.sent[i].Text = text
+	id := p.sent[i].ID
+	chatMsg := fmt.Sprintf("<%s> %s (edited)", p.Username, text)
+
+	for _, other := range s.people {
+		if other.ChatUI != nil {
+			other.ChatUI.EditMessage(id, chatMsg)
+		}
+	}
+	for _, history := range s.histories {
+		for j := range history {
+			if history[j].ID == id {
+				history[j].Text = chatMsg
+			}
+		}
+	}
+	return nil
+}
+
+// deleteMessage removes the target's n-th most recent message everywhere it
+// is shown and from everyone's history
+func (s *Server) deleteMessage(target *Person, n string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, err := target.sentByIndex(n)
+	if err != nil {
+		return err
+	}
+	id := target.sent[i].ID
+	target.sent = append(target.sent[:i], target.sent[i+1:]...)
+
+	for _, other := range s.people {
+		if other.ChatUI != nil {
+			other.ChatUI.DeleteMessage(id)
+		}
+	}
+	for hash, history := range s.histories {
+		kept := history[:0]
+		for _, m := range history {
+			if m.ID != id {
+				kept = append(kept, m)
+			}
+		}
+		s.histories[hash] = kept
+	}
+	return nil
+}
+
+// findPerson returns the person in the room with the given name or key
+// hash prefix, like /kick
+func (s *Server) findPerson(targetID string) *Person {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, p := range s.people {
+		if p.Username == targetID || strings.HasPrefix(s.getPubKeyHash(p.PubKey), targetID) {
+			return p
+		}
+	}
+	return nil
+}
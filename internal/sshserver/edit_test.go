@@ -0,0 +1,94 @@
+package sshserver
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestEditAndDelete(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	newPerson := func(name string) *Person {
+		pub, _, _ := ed25519.GenerateKey(rand.Reader)
+		sshPub, _ := ssh.NewPublicKey(pub)
+		p := &Person{Username: name, ChatUI: ui.NewChatUI(nil), PubKey: sshPub}
+		s.people[name] = p
+		return p
+	}
+	alice := newPerson("alice")
+	bob := newPerson("bob")
+	s.operatorPubKey = alice.PubKey
+
+	// chat returns the chat lines a person sees and has in history
+	chat := func(p *Person) (shown, history []string) {
+		for _, m := range p.ChatUI.GetMessages() {
+			if m.ID != 0 {
+				shown = append(shown, m.Text)
+			}
+		}
+		s.mu.RLock()
+		for _, m := range s.histories[s.getPubKeyHash(p.PubKey)] {
+			if m.ID != 0 {
+				history = append(history, m.Text)
+			}
+		}
+		s.mu.RUnlock()
+		return shown, history
+	}
+
+	s.Broadcast("bob", "helo")
+	s.Broadcast("bob", "second")
+	s.Broadcast("alice", "hi bob")
+
+	t.Run("edit propagates", func(t *testing.T) {
+		s.handleInternalCommand(bob, "/edit 2 hello")
+		for _, p := range []*Person{alice, bob} {
+			shown, history := chat(p)
+			want := "<bob> hello (edited)"
+			if shown[0] != want || history[0] != want {
+				t.Errorf("%s: expected %q, got %q and %q", p.Username, want, shown[0], history[0])
+			}
+		}
+	})
+
+	t.Run("only own messages", func(t *testing.T) {
+		s.handleInternalCommand(bob, "/edit 3 hijack")
+		shown, _ := chat(alice)
+		if strings.Contains(strings.Join(shown, "\n"), "hijack") {
+			t.Error("Expected bob not to reach beyond his own messages")
+		}
+		s.handleInternalCommand(bob, "/delete alice 1")
+		if shown, _ := chat(bob); len(shown) != 3 {
+			t.Errorf("Expected a non-operator not to delete others' messages, got %q", shown)
+		}
+	})
+
+	t.Run("delete propagates", func(t *testing.T) {
+		s.handleInternalCommand(bob, "/delete 1")
+		for _, p := range []*Person{alice, bob} {
+			shown, history := chat(p)
+			if strings.Contains(strings.Join(shown, "\n"), "second") || strings.Contains(strings.Join(history, "\n"), "second") {
+				t.Errorf("%s: expected deleted message to be gone, got %q / %q", p.Username, shown, history)
+			}
+		}
+	})
+
+	t.Run("operator delete", func(t *testing.T) {
+		s.handleInternalCommand(alice, "/delete bob 1")
+		shown, history := chat(bob)
+		if len(shown) != 1 || len(history) != 1 || shown[0] != "<alice> hi bob" {
+			t.Errorf("Expected only alice's message to remain, got %q / %q", shown, history)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package sshserver
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// resolveManagedFile resolves a name for /mv and /rm like a download, and
+// also refuses the file roots themselves
+func (s *Server) resolveManagedFile(name string) (string, string, error) {
+	clean := path.Clean("/" + strings.TrimPrefix(name, "/"))[1:]
+	fullPath, err := s.resolveFile(name)
+	if err != nil {
+		return "", "", err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, root := range s.fileRoots {
+		if filepath.Clean(fullPath) == filepath.Clean(root) {
+			return "", "", fmt.Errorf("cannot change a file root: %s", name)
+		}
+	}
+	return clean, fullPath, nil
+}
+
+// filesChangedLocked drops cached state for a path that was renamed or
+// removed, so listings and checksums do not show the old file. The caller
+// holds s.mu.
+func (s *Server) filesChangedLocked(fullPath string) {
+	s.fileIdx = nil
+	delete(s.checksums, fullPath)
+}
+
+// moveFile renames a file or directory within the file roots, refusing to
+// overwrite anything, and returns the cleaned names
+func (s *Server) moveFile(oldName, newName string) (string, string, error) {
+	oldName, oldPath, err := s.resolveManagedFile(oldName)
+	if err != nil {
+		return "", "", err
+	}
+	newName, newPath, err := s.resolveManagedFile(newName)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := os.Lstat(oldPath); err != nil {
+		return "", "", fmt.Errorf("file not found: %s", oldName)
+	}
+	if _, err := os.Lstat(newPath); err == nil {
+		return "", "", fmt.Errorf("%s already exists", newName)
+	}
+	if info, err := os.Stat(filepath.Dir(newPath)); err != nil || !info.IsDir() {
+		return "", "", fmt.Errorf("no such folder: %s", path.Dir(newName))
+	}
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return "", "", fmt.Errorf("could not rename %s: %v", oldName, err)
+	}
+
+	s.mu.Lock()
+	s.filesChangedLocked(oldPath)
+	s.mu.Unlock()
+	return oldName, newName, nil
+}
+
+// removeFile deletes a file from the file roots. Directories are refused so
+// a typo cannot take a whole folder with it.
+func (s *Server) removeFile(name string) (string, error) {
+	name, fullPath, err := s.resolveManagedFile(name)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Lstat(fullPath)
+	if err != nil {
+		return "", fmt.Errorf("file not found: %s", name)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a folder", name)
+	}
+	if err := os.Remove(fullPath); err != nil {
+		return "", fmt.Errorf("could not remove %s: %v", name, err)
+	}
+
+	s.mu.Lock()
+	s.filesChangedLocked(fullPath)
+	s.mu.Unlock()
+	return name, nil
+}
@@ -0,0 +1,123 @@
+package sshserver
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestFileManagement(t *testing.T) {
+	tmpDir := t.TempDir()
+	filesDir := filepath.Join(tmpDir, "files")
+	os.MkdirAll(filepath.Join(filesDir, "docs"), 0755)
+	os.WriteFile(filepath.Join(filesDir, "a.txt"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(filesDir, "b.txt"), []byte("b"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "secret.txt"), []byte("secret"), 0644)
+
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	s.SetFileRoots([]string{filesDir})
+	opPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	opKey, _ := ssh.NewPublicKey(opPub)
+	s.operatorPubKey = opKey
+	op := &Person{Username: "op", PubKey: opKey, ChatUI: ui.NewChatUI(nil)}
+	visitor := &Person{Username: "visitor", ChatUI: ui.NewChatUI(nil)}
+	s.people["op"] = op
+	s.people["visitor"] = visitor
+
+	last := func(p *Person) string {
+		msgs := p.ChatUI.GetMessages()
+		return msgs[len(msgs)-1].Text
+	}
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(filesDir, name))
+		return err == nil
+	}
+
+	t.Run("mv renames and notifies everyone", func(t *testing.T) {
+		s.handleInternalCommand(op, "/mv a.txt docs/a.txt")
+		if exists("a.txt") || !exists("docs/a.txt") {
+			t.Fatal("Expected a.txt to be moved into docs")
+		}
+		if got := last(visitor); got != "*** @op renamed a.txt to docs/a.txt ***" {
+			t.Errorf("Unexpected notice %q", got)
+		}
+	})
+
+	t.Run("mv refuses to overwrite", func(t *testing.T) {
+		s.handleInternalCommand(op, "/mv docs/a.txt b.txt")
+		if !strings.Contains(last(op), "already exists") {
+			t.Errorf("Expected overwrite refusal, got %q", last(op))
+		}
+		data, _ := os.ReadFile(filepath.Join(filesDir, "b.txt"))
+		if string(data) != "b" {
+			t.Errorf("Expected b.txt to be untouched, got %q", data)
+		}
+	})
+
+	t.Run("traversal is rejected", func(t *testing.T) {
+		for _, cmd := range []string{"/mv ../secret.txt stolen.txt", "/mv b.txt ../b.txt", "/rm ../secret.txt", "/rm ."} {
+			s.handleInternalCommand(op, cmd)
+			if !strings.HasPrefix(last(op), "Error:") {
+				t.Errorf("Expected %q to fail, got %q", cmd, last(op))
+			}
+		}
+		if _, err := os.Stat(filepath.Join(tmpDir, "secret.txt")); err != nil {
+			t.Error("Expected file outside the root to be untouched")
+		}
+		if !exists("b.txt") || exists("stolen.txt") {
+			t.Error("Expected no files to move")
+		}
+	})
+
+	t.Run("rm removes files but not folders", func(t *testing.T) {
+		s.handleInternalCommand(op, "/rm docs")
+		if !strings.Contains(last(op), "is a folder") || !exists("docs") {
+			t.Errorf("Expected folder removal to be refused, got %q", last(op))
+		}
+		s.handleInternalCommand(op, "/rm b.txt")
+		if exists("b.txt") {
+			t.Fatal("Expected b.txt to be removed")
+		}
+		if got := last(visitor); got != "*** @op removed b.txt ***" {
+			t.Errorf("Unexpected notice %q", got)
+		}
+		s.handleInternalCommand(op, "/rm b.txt")
+		if !strings.Contains(last(op), "file not found") {
+			t.Errorf("Expected missing file error, got %q", last(op))
+		}
+	})
+
+	t.Run("operators only", func(t *testing.T) {
+		s.handleInternalCommand(visitor, "/rm docs/a.txt")
+		if last(visitor) != "You do not have operator privileges." || !exists("docs/a.txt") {
+			t.Errorf("Expected visitor to be refused, got %q", last(visitor))
+		}
+	})
+
+	t.Run("index is refreshed", func(t *testing.T) {
+		s.SetIndexInterval(time.Hour)
+		if idx := s.currentFileIndex(); idx == nil || idx.files != 1 {
+			t.Fatalf("Expected an index with 1 file")
+		}
+		s.handleInternalCommand(op, "/mv docs/a.txt c.txt")
+		idx := s.currentFileIndex()
+		found := false
+		for _, f := range idx.dirs[""] {
+			found = found || f.Name == "c.txt"
+		}
+		if !found {
+			t.Errorf("Expected the index to list c.txt, got %v", idx.dirs[""])
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package sshserver
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SetFileRoots sets the directories served to visitors. A single directory is
+// served flat; multiple directories appear as top-level folders named after
+// their base names, so two directories with the same base name are rejected.
+func (s *Server) SetFileRoots(dirs []string) error {
+	roots := make(map[string]string)
+	if len(dirs) == 1 {
+		roots[""] = dirs[0]
+	} else {
+		for _, dir := range dirs {
+			name := filepath.Base(dir)
+			if other, ok := roots[name]; ok {
+				return fmt.Errorf("%s and %s would both be shown as folder %s", other, dir, name)
+			}
+			roots[name] = dir
+		}
+	}
+	s.mu.Lock()
+	s.fileRoots = roots
+	s.fileIdx = nil
+	s.mu.Unlock()
+	return nil
+}
+
+// resolveFile maps a requested name like "docs/readme.txt" to a path inside
+// one of the file roots, rejecting anything that escapes its root
+func (s *Server) resolveFile(name string) (string, error) {
+	clean := path.Clean(strings.TrimPrefix(name, "/"))
+	if clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("invalid filename: %s", name)
+	}
+
+	s.mu.RLock()
+	root, flat := s.fileRoots[""]
+	rest := clean
+	if !flat {
+		folder, r, _ := strings.Cut(clean, "/")
+		root = s.fileRoots[folder]
+		rest = r
+	}
+	s.mu.RUnlock()
+
+	if root == "" {
+		return "", fmt.Errorf("file not found: %s", name)
+	}
+	if rest == "" {
+		return root, nil
+	}
+
+	full := filepath.Join(root, filepath.FromSlash(rest))
+	rel, err := filepath.Rel(root, full)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid filename: %s", name)
+	}
+	return full, nil
+}
+
+// listFiles returns display lines for a folder ("" for the top level)
+func (s *Server) listFiles(folder string) ([]string, error) {
+	s.mu.RLock()
+	flatRoot, flat := s.fileRoots[""]
+	var folders []string
+	if !flat {
+		for name := range s.fileRoots {
+			folders = append(folders, name)
+		}
+	}
+	s.mu.RUnlock()
+
+	if folder == "" && !flat {
+		sort.Strings(folders)
+		lines := make([]string, 0, len(folders))
+		for _, name := range folders {
+			lines = append(lines, name+"/")
+		}
+		return lines, nil
+	}
+
+	dir, key := flatRoot, ""
+	if folder != "" {
+		var err error
+		if dir, err = s.resolveFile(folder); err != nil {
+			return nil, err
+		}
+		key = path.Clean(strings.Trim(folder, "/"))
+	}
+	if idx := s.currentFileIndex(); idx != nil {
+		if entries, ok := idx.dirs[key]; ok {
+			lines := make([]string, 0, len(entries))
+			for _, e := range entries {
+				lines = append(lines, listingLine(path.Base(e.Name), e.Info))
+			}
+			return lines, nil
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("folder not found: %s", folder)
+	}
+
+	var lines []string
+	for _, e := range entries {
+		info, err := os.Stat(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		lines = append(lines, listingLine(e.Name(), info))
+	}
+	return lines, nil
+}
+
+// listingLine formats one entry of a folder listing
+func listingLine(name string, info fs.FileInfo) string {
+	if info.IsDir() {
+		return name + "/"
+	}
+	return fmt.Sprintf("%-30s %10s", name, formatSize(info.Size()))
+}
+
+// fileDownloadLine returns a single copyable line describing a file in the
+// room's files: name, size, SHA-256 and the room host key fingerprint.
+// There is no one-shot file server, so the transfer itself goes through the files door.
+func (s *Server) fileDownloadLine(filename string) (string, error) {
+	fullPath, err := s.resolveFile(filename)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(fullPath)
+	if err != nil || info.IsDir() {
+		return "", fmt.Errorf("file not found: %s", filename)
+	}
+
+	sum, err := s.fileChecksum(fullPath, info)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %s", filename)
+	}
+
+	return fmt.Sprintf("%s (%s) sha256:%s via %sopen files; %s",
+		path.Clean(filename), formatSize(info.Size()), sum, s.commandPrefix(), s.calculateHostKeyFingerprint()), nil
+}
@@ -0,0 +1,81 @@
+package sshserver
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+)
+
+func TestMultipleFileRoots(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "unn-files-test-*")
+	defer os.RemoveAll(tmpDir)
+
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	software := filepath.Join(tmpDir, "software")
+	docs := filepath.Join(tmpDir, "docs")
+	os.MkdirAll(software, 0755)
+	os.MkdirAll(docs, 0755)
+	os.WriteFile(filepath.Join(software, "tool.bin"), []byte("binary"), 0644)
+	os.WriteFile(filepath.Join(docs, "readme.txt"), []byte("hello"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "secret.txt"), []byte("secret"), 0644)
+
+	s.SetFileRoots([]string{software, docs})
+
+	t.Run("top level lists roots", func(t *testing.T) {
+		lines, err := s.listFiles("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(lines, ",") != "docs/,software/" {
+			t.Errorf("Unexpected top level listing: %v", lines)
+		}
+	})
+
+	t.Run("folder listing", func(t *testing.T) {
+		lines, err := s.listFiles("docs")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lines) != 1 || !strings.HasPrefix(lines[0], "readme.txt") {
+			t.Errorf("Unexpected docs listing: %v", lines)
+		}
+	})
+
+	t.Run("download across roots", func(t *testing.T) {
+		for _, name := range []string{"docs/readme.txt", "software/tool.bin"} {
+			line, err := s.fileDownloadLine(name)
+			if err != nil {
+				t.Errorf("Failed to resolve %s: %v", name, err)
+				continue
+			}
+			if !strings.HasPrefix(line, name+" ") {
+				t.Errorf("Unexpected line for %s: %s", name, line)
+			}
+		}
+	})
+
+	t.Run("traversal rejected", func(t *testing.T) {
+		for _, name := range []string{"docs/../secret.txt", "docs/../../secret.txt", "../secret.txt", "readme.txt", "nope/readme.txt"} {
+			if _, err := s.fileDownloadLine(name); err == nil {
+				t.Errorf("Expected %s to be rejected", name)
+			}
+		}
+	})
+	t.Run("duplicate folder names rejected", func(t *testing.T) {
+		otherDocs := filepath.Join(tmpDir, "other", "docs")
+		os.MkdirAll(otherDocs, 0755)
+		if err := s.SetFileRoots([]string{software, docs, otherDocs}); err == nil {
+			t.Error("Expected two roots named docs to be rejected")
+		}
+		if _, err := s.fileDownloadLine("docs/readme.txt"); err != nil {
+			t.Errorf("Expected the previous roots to stay in place, got %v", err)
+		}
+	})
+}
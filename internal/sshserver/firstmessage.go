@@ -0,0 +1,221 @@
+package sshserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mevdschee/underground-node-network/internal/ui"
+)
+
+// FirstMessageGate decides what happens to the first chat message of a key
+// the room has not seen chat before, to deter drive-by spam bots
+type FirstMessageGate int
+
+const (
+	FirstMessageOpen      FirstMessageGate = iota // broadcast it like any other
+	FirstMessageChallenge                         // hold it until a small sum is answered
+	FirstMessageApprove                           // hold it until an operator approves it
+)
+
+// ParseFirstMessageGate maps a flag value ("off", "challenge" or "approve")
+// to a FirstMessageGate
+func ParseFirstMessageGate(name string) (FirstMessageGate, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "off":
+		return FirstMessageOpen, nil
+	case "challenge":
+		return FirstMessageChallenge, nil
+	case "approve":
+		return FirstMessageApprove, nil
+	}
+	return FirstMessageOpen, fmt.Errorf("unknown first message gate: %s", name)
+}
+
+// SetFirstMessageGate sets how the first message of a new key is handled
+func (s *Server) SetFirstMessageGate(gate FirstMessageGate) {
+	s.mu.Lock()
+	s.firstMsgGate = gate
+	s.mu.Unlock()
+}
+
+// heldMessage is a first message waiting to be broadcast
+type heldMessage struct {
+	Text   string
+	Action bool   // sent with /me
+	Answer string // expected challenge answer, "" when waiting for approval
+}
+
+// knownKeysPath returns the file holding the keys whose chat is no longer
+// gated, kept next to the host key
+func (s *Server) knownKeysPath() string {
+	return filepath.Join(filepath.Dir(s.hostKeyPath), s.roomName+".known_keys")
+}
+
+// loadKnownKeys reads the persisted known keys, if any
+func (s *Server) loadKnownKeys() {
+	data, err := os.ReadFile(s.knownKeysPath())
+	if err != nil {
+		return
+	}
+	var hashes []string
+	if err := json.Unmarshal(data, &hashes); err != nil {
+		log.Printf("Failed to read known keys: %v", err)
+		return
+	}
+	for _, hash := range hashes {
+		s.knownKeys[hash] = true
+	}
+}
+
+// saveKnownKeysLocked persists the known keys; the caller holds s.mu
+func (s *Server) saveKnownKeysLocked() error {
+	hashes := make([]string, 0, len(s.knownKeys))
+	for hash := range s.knownKeys {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+	data, err := json.MarshalIndent(hashes, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.knownKeysPath(), append(data, '\n'), 0600); err != nil {
+		log.Printf("Failed to save known keys: %v", err)
+		return err
+	}
+	return nil
+}
+
+// sendChat broadcasts a chat message, unless it is held by the first
+// message gate
+func (s *Server) sendChat(p *Person, msg string) {
+	if s.holdFirstMessage(p, msg, false) {
+		return
+	}
+	s.Broadcast(p.Username, msg)
+}
+
+// sendAction broadcasts a /me action, unless it is held by the first
+// message gate
+func (s *Server) sendAction(p *Person, action string) {
+	if s.holdFirstMessage(p, action, true) {
+		return
+	}
+	s.broadcastWithHistory(p.PubKey, fmt.Sprintf("* %s %s", p.Username, action), ui.MsgAction)
+}
+
+// releaseHeld broadcasts a held message as chat or as an action
+func (s *Server) releaseHeld(p *Person, held *heldMessage) {
+	if held.Action {
+		s.broadcastWithHistory(p.PubKey, fmt.Sprintf("* %s %s", p.Username, held.Text), ui.MsgAction)
+		return
+	}
+	s.Broadcast(p.Username, held.Text)
+}
+
+// holdFirstMessage applies the first message gate and reports whether the
+// message (or action) was held, or was an answer to a challenge, instead of sent
+func (s *Server) holdFirstMessage(p *Person, msg string, action bool) bool {
+	hash := s.getPubKeyHash(p.PubKey)
+
+	s.mu.Lock()
+	if s.firstMsgGate == FirstMessageOpen || s.knownKeys[hash] || s.hasChattedLocked(hash) || s.isModerator(p.PubKey) {
+		s.mu.Unlock()
+		return false
+	}
+	held, waiting := s.held[hash]
+	var reply string
+	var release *heldMessage
+	var operators []*Person
+
+	switch {
+	case waiting && held.Answer == "":
+		reply = "Your first message is still waiting for the operator's approval."
+	case waiting:
+		if strings.TrimSpace(msg) == held.Answer {
+			delete(s.held, hash)
+			s.knownKeys[hash] = true
+			s.saveKnownKeysLocked()
+			release = held
+		} else {
+			held.Answer, reply = newChallenge()
+			reply = "Wrong answer. " + reply
+		}
+	case s.firstMsgGate == FirstMessageChallenge:
+		answer, question := newChallenge()
+		s.held[hash] = &heldMessage{Text: msg, Action: action, Answer: answer}
+		reply = "New here? " + question
+	default:
+		s.held[hash] = &heldMessage{Text: msg, Action: action}
+		reply = "Your first message in this room will be shown once the operator approves it."
+		for _, other := range s.people {
+			if s.isModerator(other.PubKey) {
+				operators = append(operators, other)
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	if release != nil {
+		s.releaseHeld(p, release)
+		return true
+	}
+	if p.ChatUI != nil {
+		p.ChatUI.AddMessage(reply, ui.MsgServer)
+	}
+	for _, op := range operators {
+		if op.ChatUI != nil {
+			op.ChatUI.AddMessage(s.withCmdPrefix(fmt.Sprintf("New user %s wants to say: %s (/approve %s or /reject %s)", p.Username, msg, p.Username, p.Username)), ui.MsgServer)
+		}
+	}
+	return true
+}
+
+// hasChattedLocked reports whether the history of a key holds chat it sent
+// before, so returning visitors are not gated as new; the caller holds s.mu
+func (s *Server) hasChattedLocked(hash string) bool {
+	for _, m := range s.histories[hash] {
+		if m.Type == ui.MsgSelf {
+			return true
+		}
+	}
+	return false
+}
+
+// newChallenge returns the answer and question of a small sum
+func newChallenge() (answer, question string) {
+	a, b := rand.Intn(9)+1, rand.Intn(9)+1
+	return strconv.Itoa(a + b), fmt.Sprintf("What is %d + %d? Type the answer to send your message.", a, b)
+}
+
+// approveFirstMessage broadcasts a held first message, or drops it when
+// approve is false. Approved keys are not gated again.
+func (s *Server) approveFirstMessage(target *Person, approve bool) error {
+	hash := s.getPubKeyHash(target.PubKey)
+	s.mu.Lock()
+	held, ok := s.held[hash]
+	if ok {
+		delete(s.held, hash)
+		if approve {
+			s.knownKeys[hash] = true
+			s.saveKnownKeysLocked()
+		}
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%s has no message waiting for approval", target.Username)
+	}
+	if approve {
+		s.releaseHeld(target, held)
+	} else if target.ChatUI != nil {
+		target.ChatUI.AddMessage("Your first message was not approved.", ui.MsgServer)
+	}
+	return nil
+}
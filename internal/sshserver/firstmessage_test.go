@@ -0,0 +1,184 @@
+package sshserver
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestFirstMessageGate(t *testing.T) {
+	newRoom := func(gate FirstMessageGate) (*Server, map[string]*Person) {
+		tmpDir := t.TempDir()
+		s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+		if err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+		s.SetFirstMessageGate(gate)
+		people := make(map[string]*Person)
+		for _, name := range []string{"op", "newbie", "other"} {
+			pub, _, _ := ed25519.GenerateKey(rand.Reader)
+			sshPub, _ := ssh.NewPublicKey(pub)
+			people[name] = &Person{Username: name, ChatUI: ui.NewChatUI(nil), PubKey: sshPub}
+			s.people[name] = people[name]
+		}
+		s.operatorPubKey = people["op"].PubKey
+		return s, people
+	}
+	sawChat := func(p *Person, text string) bool {
+		for _, m := range p.ChatUI.GetMessages() {
+			if m.Text == "<newbie> "+text {
+				return true
+			}
+		}
+		return false
+	}
+	lastMessage := func(p *Person) string {
+		msgs := p.ChatUI.GetMessages()
+		return msgs[len(msgs)-1].Text
+	}
+
+	t.Run("off", func(t *testing.T) {
+		s, people := newRoom(FirstMessageOpen)
+		s.sendChat(people["newbie"], "hello")
+		if !sawChat(people["other"], "hello") {
+			t.Error("Expected the message to be broadcast without a gate")
+		}
+	})
+
+	t.Run("challenge", func(t *testing.T) {
+		s, people := newRoom(FirstMessageChallenge)
+		s.sendChat(people["newbie"], "buy cheap stuff")
+		if sawChat(people["other"], "buy cheap stuff") {
+			t.Fatal("Expected the first message to be held")
+		}
+		if !strings.Contains(lastMessage(people["newbie"]), "What is") {
+			t.Errorf("Expected a challenge, got %q", lastMessage(people["newbie"]))
+		}
+
+		s.sendChat(people["newbie"], "wrong")
+		if !strings.Contains(lastMessage(people["newbie"]), "Wrong answer") {
+			t.Errorf("Expected a new challenge, got %q", lastMessage(people["newbie"]))
+		}
+
+		hash := s.getPubKeyHash(people["newbie"].PubKey)
+		s.sendChat(people["newbie"], s.held[hash].Answer)
+		if !sawChat(people["other"], "buy cheap stuff") {
+			t.Error("Expected the held message to be broadcast after the right answer")
+		}
+		s.sendChat(people["newbie"], "second")
+		if !sawChat(people["other"], "second") {
+			t.Error("Expected later messages not to be gated")
+		}
+	})
+
+	t.Run("approve", func(t *testing.T) {
+		s, people := newRoom(FirstMessageApprove)
+		s.sendChat(people["op"], "welcome")
+		if lastMessage(people["other"]) != "<op> welcome" {
+			t.Error("Expected the operator not to be gated")
+		}
+
+		s.sendChat(people["newbie"], "hi all")
+		if sawChat(people["other"], "hi all") {
+			t.Fatal("Expected the first message to wait for approval")
+		}
+		if !strings.Contains(lastMessage(people["op"]), "New user newbie wants to say: hi all") {
+			t.Errorf("Expected the operator to be asked, got %q", lastMessage(people["op"]))
+		}
+
+		s.handleInternalCommand(people["other"], "/approve newbie")
+		if sawChat(people["other"], "hi all") {
+			t.Error("Expected only operators to approve")
+		}
+		s.handleInternalCommand(people["op"], "/approve newbie")
+		if !sawChat(people["other"], "hi all") {
+			t.Error("Expected the approved message to be broadcast")
+		}
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		s, people := newRoom(FirstMessageApprove)
+		s.sendChat(people["newbie"], "spam")
+		s.handleInternalCommand(people["op"], "/reject newbie")
+		if sawChat(people["other"], "spam") {
+			t.Error("Expected the rejected message not to be broadcast")
+		}
+		s.sendChat(people["newbie"], "sorry")
+		if sawChat(people["other"], "sorry") {
+			t.Error("Expected a rejected key to stay gated")
+		}
+	})
+
+	t.Run("actions", func(t *testing.T) {
+		s, people := newRoom(FirstMessageApprove)
+		s.handleInternalCommand(people["newbie"], "/me sells cheap stuff")
+		if len(people["other"].ChatUI.GetMessages()) != 0 {
+			t.Fatal("Expected a first /me to wait for approval")
+		}
+		s.handleInternalCommand(people["op"], "/approve newbie")
+		if lastMessage(people["other"]) != "* newbie sells cheap stuff" {
+			t.Errorf("Expected the approved action to be broadcast, got %q", lastMessage(people["other"]))
+		}
+	})
+
+	t.Run("known keys survive a restart", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		hostKey := filepath.Join(tmpDir, "host_key")
+		s, err := NewServer("127.0.0.1:0", hostKey, "testroom", doors.NewManager(tmpDir))
+		if err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+		pub, _, _ := ed25519.GenerateKey(rand.Reader)
+		sshPub, _ := ssh.NewPublicKey(pub)
+		newbie := &Person{Username: "newbie", ChatUI: ui.NewChatUI(nil), PubKey: sshPub}
+		s.people["newbie"] = newbie
+		s.SetFirstMessageGate(FirstMessageChallenge)
+		s.sendChat(newbie, "hello")
+		s.sendChat(newbie, s.held[s.getPubKeyHash(sshPub)].Answer)
+
+		s, err = NewServer("127.0.0.1:0", hostKey, "testroom", doors.NewManager(tmpDir))
+		if err != nil {
+			t.Fatalf("Failed to restart server: %v", err)
+		}
+		s.SetFirstMessageGate(FirstMessageChallenge)
+		newbie = &Person{Username: "newbie", ChatUI: ui.NewChatUI(nil), PubKey: sshPub}
+		s.people["newbie"] = newbie
+		s.sendChat(newbie, "back again")
+		if lastMessage(newbie) != "<newbie> back again" {
+			t.Errorf("Expected a known key not to be gated after a restart, got %q", lastMessage(newbie))
+		}
+	})
+
+	t.Run("returning visitors with chat history", func(t *testing.T) {
+		s, people := newRoom(FirstMessageChallenge)
+		hash := s.getPubKeyHash(people["newbie"].PubKey)
+		s.addMessageToHistory(hash, ui.Message{Text: "* op left the room", Type: ui.MsgSystem})
+		s.sendChat(people["newbie"], "seen the room before")
+		if sawChat(people["other"], "seen the room before") {
+			t.Fatal("Expected history without own chat to still be gated")
+		}
+
+		s, people = newRoom(FirstMessageChallenge)
+		hash = s.getPubKeyHash(people["newbie"].PubKey)
+		s.addMessageToHistory(hash, ui.Message{Text: "<newbie> hi from last time", Type: ui.MsgSelf})
+		s.sendChat(people["newbie"], "hello again")
+		if !sawChat(people["other"], "hello again") {
+			t.Error("Expected someone who chatted before not to be gated")
+		}
+	})
+
+	t.Run("parse", func(t *testing.T) {
+		if g, err := ParseFirstMessageGate("Challenge"); err != nil || g != FirstMessageChallenge {
+			t.Errorf("Unexpected %v, %v", g, err)
+		}
+		if _, err := ParseFirstMessageGate("captcha"); err == nil {
+			t.Error("Expected an unknown gate to be rejected")
+		}
+	})
+}
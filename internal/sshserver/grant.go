@@ -0,0 +1,91 @@
+package sshserver
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"golang.org/x/crypto/ssh"
+)
+
+// operatorGrant is a temporary moderator status given with /grant: kicking,
+// banning and approving, but none of the operator's room settings. It ends
+// when it expires, when the session it was given to leaves or with /deop.
+type operatorGrant struct {
+	Username  string
+	SessionID string
+	Expires   time.Time
+	timer     *time.Timer
+}
+
+// hasGrant reports whether pubKey holds a temporary moderator grant
+func (s *Server) hasGrant(pubKey ssh.PublicKey) bool {
+	s.grantsMu.Lock()
+	defer s.grantsMu.Unlock()
+	return s.grants[s.getPubKeyHash(pubKey)] != nil
+}
+
+// grant makes target a moderator for d, replacing an earlier grant
+func (s *Server) grant(by, target *Person, d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("the duration must be positive")
+	}
+	if s.isOperator(target.PubKey) {
+		return fmt.Errorf("%s is the room operator", target.Username)
+	}
+	hash := s.getPubKeyHash(target.PubKey)
+	g := &operatorGrant{Username: target.Username, SessionID: target.SessionID, Expires: time.Now().Add(d)}
+
+	s.grantsMu.Lock()
+	if old := s.grants[hash]; old != nil {
+		old.timer.Stop()
+	}
+	g.timer = time.AfterFunc(d, func() { s.revokeGrant(hash, g, "the grant expired") })
+	s.grants[hash] = g
+	s.grantsMu.Unlock()
+
+	log.Printf("%s granted moderator status to %s for %s", by.Username, target.Username, d)
+	s.broadcastWithHistory(nil, fmt.Sprintf("*** @%s made %s a moderator for %s ***", by.Username, target.Username, d), ui.MsgServer)
+	return nil
+}
+
+// revokeGrant ends the grant of the key with the given hash, if it is still
+// g (nil matches any grant), and tells everyone why. It reports whether
+// there was a grant to end.
+func (s *Server) revokeGrant(hash string, g *operatorGrant, reason string) bool {
+	s.grantsMu.Lock()
+	current := s.grants[hash]
+	if current == nil || (g != nil && current != g) {
+		s.grantsMu.Unlock()
+		return false
+	}
+	current.timer.Stop()
+	delete(s.grants, hash)
+	s.grantsMu.Unlock()
+
+	log.Printf("Moderator grant of %s ended: %s", current.Username, reason)
+	s.broadcastWithHistory(nil, fmt.Sprintf("*** %s is no longer a moderator: %s ***", current.Username, reason), ui.MsgServer)
+	return true
+}
+
+// revokeGrantOnLeave ends the grant given to p's session when it leaves
+func (s *Server) revokeGrantOnLeave(p *Person) {
+	hash := s.getPubKeyHash(p.PubKey)
+	s.grantsMu.Lock()
+	g := s.grants[hash]
+	s.grantsMu.Unlock()
+	if g != nil && g.SessionID == p.SessionID {
+		s.revokeGrant(hash, g, "they left the room")
+	}
+}
+
+// stopGrants ends all grants without notices
+func (s *Server) stopGrants() {
+	s.grantsMu.Lock()
+	defer s.grantsMu.Unlock()
+	for _, g := range s.grants {
+		g.timer.Stop()
+	}
+	s.grants = make(map[string]*operatorGrant)
+}
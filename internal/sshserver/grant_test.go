@@ -0,0 +1,162 @@
+package sshserver
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/sshtest"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestGrant(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer s.Stop()
+	newKey := func() ssh.PublicKey {
+		pub, _, _ := ed25519.GenerateKey(rand.Reader)
+		key, _ := ssh.NewPublicKey(pub)
+		return key
+	}
+	op := &Person{Username: "op", SessionID: "op", PubKey: newKey(), ChatUI: ui.NewChatUI(nil)}
+	bob := &Person{Username: "bob", SessionID: "bob", PubKey: newKey(), ChatUI: ui.NewChatUI(nil)}
+	s.operatorPubKey = op.PubKey
+	s.people[op.SessionID] = op
+	s.people[bob.SessionID] = bob
+
+	lastMessage := func(p *Person) string {
+		msgs := p.ChatUI.GetMessages()
+		return msgs[len(msgs)-1].Text
+	}
+	waitFor := func(p *Person, text string) bool {
+		for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			for _, m := range p.ChatUI.GetMessages() {
+				if m.Text == text {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	t.Run("operator only", func(t *testing.T) {
+		s.handleInternalCommand(bob, "/grant bob 1h")
+		if got := lastMessage(bob); got != "You do not have operator privileges." {
+			t.Errorf("Expected a denial, got %q", got)
+		}
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		for cmd, want := range map[string]string{
+			"/grant bob":      "Usage: /grant <user/hash> <duration> (e.g. /grant bob 2h)",
+			"/grant bob soon": "Error: invalid duration: soon",
+			"/grant bob -1m":  "Error: the duration must be positive",
+			"/grant carol 1h": "User not found.",
+			"/grant op 1h":    "Error: op is the room operator",
+			"/deop bob":       "bob has no moderator grant.",
+			"/deop":           "Usage: /deop <user/hash>",
+		} {
+			s.handleInternalCommand(op, cmd)
+			if got := lastMessage(op); got != want {
+				t.Errorf("%s: expected %q, got %q", cmd, want, got)
+			}
+		}
+	})
+
+	t.Run("grant expires", func(t *testing.T) {
+		s.handleInternalCommand(op, "/grant bob 100ms")
+		if !waitFor(bob, "*** @op made bob a moderator for 100ms ***") {
+			t.Fatal("Expected the grant to be announced")
+		}
+		if !s.isModerator(bob.PubKey) || s.isOperator(bob.PubKey) {
+			t.Fatal("Expected bob to be a moderator")
+		}
+		s.handleInternalCommand(bob, "/grant op 1h")
+		if got := lastMessage(bob); got != "Only the room operator can grant or end moderator status." {
+			t.Errorf("Expected granted operators not to grant, got %q", got)
+		}
+		if !waitFor(op, "*** bob is no longer a moderator: the grant expired ***") {
+			t.Fatal("Expected the expiry to be announced")
+		}
+		if s.isModerator(bob.PubKey) {
+			t.Error("Expected bob to no longer be a moderator")
+		}
+	})
+
+	t.Run("moderators cannot act on the operator", func(t *testing.T) {
+		s.handleInternalCommand(op, "/grant bob 1h")
+		defer s.handleInternalCommand(op, "/deop bob")
+		opHash := s.getPubKeyHash(op.PubKey)
+		for cmd, want := range map[string]string{
+			"/lock secret":           "You do not have operator privileges.",
+			"/rotate-key":            "You do not have operator privileges.",
+			"/perms downloads off":   "You do not have operator privileges.",
+			"/kick op":               "The room operator cannot be kicked.",
+			"/kickban op":            "The room operator cannot be banned.",
+			"/kickban " + opHash[:8]: "The room operator cannot be banned.",
+			"/banlist":               "--- Banned Users ---",
+		} {
+			s.handleInternalCommand(bob, cmd)
+			if got := lastMessage(bob); got != want {
+				t.Errorf("%s: expected %q, got %q", cmd, want, got)
+			}
+		}
+		if len(s.bannedHashes) != 0 || s.roomLockKey != "" {
+			t.Errorf("Expected no bans and no lock, got %v and %q", s.bannedHashes, s.roomLockKey)
+		}
+	})
+
+	t.Run("deop cancels the grant", func(t *testing.T) {
+		op.ChatUI.ClearMessages()
+		s.handleInternalCommand(op, "/grant bob 100ms")
+		s.handleInternalCommand(op, "/deop bob")
+		if !waitFor(bob, "*** bob is no longer a moderator: revoked by @op ***") {
+			t.Fatal("Expected the revocation to be announced")
+		}
+		if s.isModerator(bob.PubKey) {
+			t.Error("Expected bob to no longer be a moderator")
+		}
+		time.Sleep(200 * time.Millisecond)
+		if waitFor(op, "*** bob is no longer a moderator: the grant expired ***") {
+			t.Error("Expected the timer to be cancelled")
+		}
+	})
+
+	t.Run("leaving ends the grant", func(t *testing.T) {
+		s.SetHeadless(true)
+		signer, err := sshtest.NewSigner()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.AuthorizeKey(signer.PublicKey(), "alice")
+		client, err := sshtest.Dial(s, "alice", signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sess, err := sshtest.StartShell(client, 80, 24)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sess.WaitFor("Type /help for commands", 2*time.Second); err != nil {
+			t.Fatal(err)
+		}
+		s.handleInternalCommand(op, "/grant alice 1h")
+		if !s.isModerator(signer.PublicKey()) {
+			t.Fatal("Expected alice to be a moderator")
+		}
+		client.Close()
+		if !waitFor(op, "*** alice is no longer a moderator: they left the room ***") {
+			t.Fatal("Expected the grant to end when alice left")
+		}
+		if s.isModerator(signer.PublicKey()) {
+			t.Error("Expected alice to no longer be a moderator")
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package sshserver
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/protocol"
+	"github.com/mevdschee/underground-node-network/internal/ui/common"
+	"golang.org/x/crypto/ssh"
+)
+
+// SetOnHostKeyChange sets the function called after /rotate-key with the new
+// public key
+func (s *Server) SetOnHostKeyChange(fn func(ssh.PublicKey)) {
+	s.mu.Lock()
+	s.onHostKeyChange = fn
+	s.mu.Unlock()
+}
+
+// RotateHostKey replaces the room host key with a freshly generated one. The
+// old key is kept next to the new one with an ".old" suffix. Connected people
+// are told the new fingerprint, and UNN-aware clients receive the new key
+// signed by the old one. New connections are served with the new key.
+func (s *Server) RotateHostKey() (ssh.PublicKey, error) {
+	s.mu.Lock()
+	oldKey := s.hostKey
+	path := s.hostKeyPath
+
+	if err := os.Rename(path, path+".old"); err != nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("failed to back up host key: %w", err)
+	}
+	os.Rename(path+".pub", path+".pub.old")
+
+	newKey, err := generateHostKey(path)
+	if err != nil {
+		os.Rename(path+".old", path)
+		os.Rename(path+".pub.old", path+".pub")
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	s.hostKey = newKey
+	s.config = s.newSSHConfig(newKey)
+
+	announce := protocol.HostKeyPayload{
+		PublicKey: string(ssh.MarshalAuthorizedKey(newKey.PublicKey())),
+		Timestamp: time.Now().Unix(),
+	}
+	if err := announce.Sign(oldKey); err != nil {
+		log.Printf("Failed to sign host key rotation: %v", err)
+	}
+	for _, p := range s.people {
+		if p.UNNAware && p.Bus != nil && announce.Signature != "" {
+			common.SendOSC(p.Bus, "host_key", map[string]interface{}{
+				"public_key": announce.PublicKey,
+				"timestamp":  announce.Timestamp,
+				"signature":  announce.Signature,
+			})
+		}
+	}
+	onChange := s.onHostKeyChange
+	s.mu.Unlock()
+
+	log.Printf("Host key rotated, new fingerprint %s", ssh.FingerprintSHA256(newKey.PublicKey()))
+	s.Broadcast("Server", fmt.Sprintf("*** Room host key rotated. New fingerprint: %s ***", ssh.FingerprintSHA256(newKey.PublicKey())))
+
+	if onChange != nil {
+		onChange(newKey.PublicKey())
+	}
+	return newKey.PublicKey(), nil
+}
@@ -0,0 +1,64 @@
+package sshserver
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestRotateHostKey(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "unn-hostkey-test-*")
+	defer os.RemoveAll(tmpDir)
+
+	hostKeyPath := filepath.Join(tmpDir, "host_key")
+	s, err := NewServer("127.0.0.1:0", hostKeyPath, "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	oldKey := s.GetHostKey().PublicKey()
+
+	var announced ssh.PublicKey
+	s.SetOnHostKeyChange(func(pub ssh.PublicKey) { announced = pub })
+
+	newKey, err := s.RotateHostKey()
+	if err != nil {
+		t.Fatalf("RotateHostKey failed: %v", err)
+	}
+
+	t.Run("new key in use", func(t *testing.T) {
+		if bytes.Equal(newKey.Marshal(), oldKey.Marshal()) {
+			t.Errorf("Host key did not change")
+		}
+		if !bytes.Equal(s.GetHostKey().PublicKey().Marshal(), newKey.Marshal()) {
+			t.Errorf("Server still uses the old host key")
+		}
+		if announced == nil || !bytes.Equal(announced.Marshal(), newKey.Marshal()) {
+			t.Errorf("The host key change callback was not called with the new key")
+		}
+	})
+
+	t.Run("old key kept", func(t *testing.T) {
+		keyBytes, err := os.ReadFile(hostKeyPath + ".old")
+		if err != nil {
+			t.Fatalf("Old host key not kept: %v", err)
+		}
+		signer, err := ssh.ParsePrivateKey(keyBytes)
+		if err != nil || !bytes.Equal(signer.PublicKey().Marshal(), oldKey.Marshal()) {
+			t.Errorf("Backup does not contain the old host key")
+		}
+	})
+
+	t.Run("survives restart", func(t *testing.T) {
+		s2, err := NewServer("127.0.0.1:0", hostKeyPath, "testroom", doors.NewManager(tmpDir))
+		if err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+		if !bytes.Equal(s2.GetHostKey().PublicKey().Marshal(), newKey.Marshal()) {
+			t.Errorf("Restarted server did not load the rotated key")
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package sshserver
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// indexedFile is one entry of the file index
+type indexedFile struct {
+	Name    string // display path, as accepted by resolveFile
+	Path    string // path on disk
+	Info    fs.FileInfo
+	Regular bool // a regular file rather than a symlink or device
+}
+
+// fileIndex is an in-memory listing of the file roots, so directories with
+// thousands of files are not read from disk for every /files and /manifest
+type fileIndex struct {
+	builtAt time.Time
+	dirs    map[string][]indexedFile // display folder ("" for the top level) -> entries by name
+	files   int
+}
+
+// SetIndexInterval keeps an index of the file roots that is rebuilt every
+// interval (0 reads the disk on every request). Changes show up in listings
+// within one interval.
+func (s *Server) SetIndexInterval(d time.Duration) {
+	s.mu.Lock()
+	s.indexInterval = d
+	s.fileIdx = nil
+	start := d > 0 && !s.indexing
+	s.indexing = s.indexing || start
+	s.mu.Unlock()
+	if start {
+		go s.indexLoop()
+	}
+}
+
+// buildFileIndex walks every file root. Symlinks are listed with their
+// target's size and type like a directory read shows them, but not followed.
+func buildFileIndex(roots map[string]string) *fileIndex {
+	idx := &fileIndex{builtAt: time.Now(), dirs: make(map[string][]indexedFile)}
+	for folder, root := range roots {
+		filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if p == root {
+				idx.dirs[folder] = []indexedFile{}
+				return nil
+			}
+			info, err := os.Stat(p)
+			if err != nil {
+				return nil
+			}
+			rel, _ := filepath.Rel(root, p)
+			name := path.Join(folder, filepath.ToSlash(rel))
+			parent := path.Dir(name)
+			if parent == "." {
+				parent = ""
+			}
+			idx.dirs[parent] = append(idx.dirs[parent], indexedFile{Name: name, Path: p, Info: info, Regular: d.Type().IsRegular()})
+			if d.IsDir() {
+				idx.dirs[name] = []indexedFile{}
+			} else {
+				idx.files++
+			}
+			return nil
+		})
+	}
+	return idx
+}
+
+// currentFileIndex returns an up-to-date index, or nil if indexing is off
+func (s *Server) currentFileIndex() *fileIndex {
+	s.mu.RLock()
+	interval, idx := s.indexInterval, s.fileIdx
+	s.mu.RUnlock()
+	if interval <= 0 {
+		return nil
+	}
+	if idx != nil && time.Since(idx.builtAt) < interval {
+		return idx
+	}
+	return s.refreshFileIndex()
+}
+
+// refreshFileIndex rebuilds the index. Concurrent callers share one walk.
+func (s *Server) refreshFileIndex() *fileIndex {
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+
+	s.mu.RLock()
+	idx, interval := s.fileIdx, s.indexInterval
+	roots := make(map[string]string, len(s.fileRoots))
+	for name, dir := range s.fileRoots {
+		roots[name] = dir
+	}
+	s.mu.RUnlock()
+	// Another caller may have rebuilt it while we waited
+	if idx != nil && time.Since(idx.builtAt) < interval/2 {
+		return idx
+	}
+
+	started := time.Now()
+	idx = buildFileIndex(roots)
+	if elapsed := time.Since(started); elapsed > time.Second {
+		log.Printf("Indexed %d files in %v", idx.files, elapsed.Round(time.Millisecond))
+	}
+
+	s.mu.Lock()
+	s.fileIdx = idx
+	s.mu.Unlock()
+	return idx
+}
+
+// indexLoop rebuilds the index in the background so requests rarely wait
+// for a walk, until the server is stopped or indexing is turned off
+func (s *Server) indexLoop() {
+	for {
+		s.mu.Lock()
+		stopped, interval := s.stopped, s.indexInterval
+		if stopped || interval <= 0 {
+			s.indexing = false
+			s.mu.Unlock()
+			return
+		}
+		s.mu.Unlock()
+		s.refreshFileIndex()
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,103 @@
+package sshserver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+)
+
+func newIndexTestServer(t testing.TB, files int) (*Server, string) {
+	tmpDir := t.TempDir()
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	root := filepath.Join(tmpDir, "files")
+	os.MkdirAll(filepath.Join(root, "sub"), 0755)
+	for i := 0; i < files; i++ {
+		os.WriteFile(filepath.Join(root, fmt.Sprintf("file%05d.txt", i)), []byte("data"), 0644)
+	}
+	os.WriteFile(filepath.Join(root, "sub", "nested.txt"), []byte("nested"), 0644)
+	s.SetFileRoots([]string{root})
+	return s, root
+}
+
+func TestFileIndex(t *testing.T) {
+	s, root := newIndexTestServer(t, 3)
+
+	diskListing, err := s.listFiles("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	diskManifest, _ := s.buildManifest()
+
+	s.SetIndexInterval(time.Hour)
+	defer s.SetIndexInterval(0)
+
+	t.Run("served like the disk", func(t *testing.T) {
+		listing, err := s.listFiles("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(listing, diskListing) {
+			t.Errorf("Index listing %v differs from disk listing %v", listing, diskListing)
+		}
+		sub, _ := s.listFiles("sub")
+		if len(sub) != 1 || !strings.HasPrefix(sub[0], "nested.txt") {
+			t.Errorf("Unexpected sub listing: %v", sub)
+		}
+		manifest, _ := s.buildManifest()
+		if !reflect.DeepEqual(manifest, diskManifest) {
+			t.Errorf("Index manifest %v differs from disk manifest %v", manifest, diskManifest)
+		}
+	})
+
+	t.Run("served from memory until refreshed", func(t *testing.T) {
+		os.WriteFile(filepath.Join(root, "late.txt"), []byte("late"), 0644)
+		listing, _ := s.listFiles("")
+		if strings.Contains(strings.Join(listing, "\n"), "late.txt") {
+			t.Error("Expected the listing to come from the index")
+		}
+
+		s.mu.Lock()
+		s.fileIdx.builtAt = time.Time{} // as if the interval passed
+		s.mu.Unlock()
+		listing, _ = s.listFiles("")
+		if !strings.Contains(strings.Join(listing, "\n"), "late.txt") {
+			t.Errorf("Expected a refreshed index to list the new file, got %v", listing)
+		}
+	})
+
+	t.Run("new roots invalidate", func(t *testing.T) {
+		other := t.TempDir()
+		os.WriteFile(filepath.Join(other, "other.txt"), []byte("x"), 0644)
+		s.SetFileRoots([]string{other})
+		listing, _ := s.listFiles("")
+		if len(listing) != 1 || !strings.HasPrefix(listing[0], "other.txt") {
+			t.Errorf("Expected the new root to be indexed, got %v", listing)
+		}
+	})
+}
+
+func BenchmarkListFiles(b *testing.B) {
+	for _, interval := range []time.Duration{0, time.Hour} {
+		b.Run(fmt.Sprintf("index=%v", interval), func(b *testing.B) {
+			s, _ := newIndexTestServer(b, 5000)
+			s.SetIndexInterval(interval)
+			defer s.SetIndexInterval(0)
+			s.listFiles("")
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := s.listFiles(""); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package sshserver
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// transportName describes how a connection reached the room. Rooms serve
+// SSH over p2pquic streams, which report a UDP remote address.
+func transportName(addr net.Addr) string {
+	if addr == nil {
+		return "unknown"
+	}
+	switch addr.Network() {
+	case "udp", "udp4", "udp6":
+		return "p2p direct (QUIC)"
+	case "tcp", "tcp4", "tcp6":
+		return "direct SSH (TCP)"
+	}
+	return addr.Network()
+}
+
+// touch records activity of p for the idle time shown by /info
+func (s *Server) touch(p *Person) {
+	s.mu.Lock()
+	p.lastActive = time.Now()
+	s.mu.Unlock()
+}
+
+// personInfo returns the /info lines for the person matching target, by
+// name or pubkey hash prefix
+func (s *Server) personInfo(target string, now time.Time) ([]string, error) {
+	p := s.findPerson(target)
+	if p == nil {
+		return nil, fmt.Errorf("person not found: %s", target)
+	}
+	s.mu.RLock()
+	lastActive := p.lastActive
+	s.mu.RUnlock()
+	return []string{
+		fmt.Sprintf("--- Info for %s ---", p.Username),
+		fmt.Sprintf("Joined: %s (%s ago)", p.JoinedAt.Format("15:04:05"), now.Sub(p.JoinedAt).Round(time.Second)),
+		fmt.Sprintf("Transport: %s", p.Transport),
+		fmt.Sprintf("Idle: %s", now.Sub(lastActive).Round(time.Second)),
+	}, nil
+}
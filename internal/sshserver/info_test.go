@@ -0,0 +1,80 @@
+package sshserver
+
+import (
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+)
+
+func TestPersonInfo(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	bob := &Person{
+		Username:   "bob",
+		ChatUI:     ui.NewChatUI(nil),
+		JoinedAt:   now.Add(-90 * time.Second),
+		Transport:  transportName(&net.UDPAddr{IP: net.IPv4(203, 0, 113, 7), Port: 4000}),
+		lastActive: now.Add(-30 * time.Second),
+	}
+	alice := &Person{Username: "alice", ChatUI: ui.NewChatUI(nil)}
+	s.people["bob"] = bob
+	s.people["alice"] = alice
+
+	t.Run("join and idle time", func(t *testing.T) {
+		lines, err := s.personInfo("bob", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{
+			"--- Info for bob ---",
+			"Joined: 11:58:30 (1m30s ago)",
+			"Transport: p2p direct (QUIC)",
+			"Idle: 30s",
+		}
+		if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+			t.Errorf("Expected %q, got %q", want, lines)
+		}
+	})
+
+	t.Run("activity resets idle time", func(t *testing.T) {
+		s.touch(bob)
+		lines, _ := s.personInfo("bob", time.Now())
+		if lines[3] != "Idle: 0s" {
+			t.Errorf("Expected no idle time after activity, got %q", lines[3])
+		}
+	})
+
+	t.Run("command replies to the requester", func(t *testing.T) {
+		s.handleInternalCommand(alice, "/info bob")
+		msgs := alice.ChatUI.GetMessages()
+		if last := msgs[len(msgs)-1].Text; !strings.HasPrefix(last, "Idle: ") {
+			t.Errorf("Expected the info lines, got %q", last)
+		}
+		if n := len(bob.ChatUI.GetMessages()); n != 0 {
+			t.Errorf("Expected nothing to be sent to bob, got %d messages", n)
+		}
+		s.handleInternalCommand(alice, "/info nobody")
+		msgs = alice.ChatUI.GetMessages()
+		if last := msgs[len(msgs)-1].Text; last != "Error: person not found: nobody" {
+			t.Errorf("Expected a not found error, got %q", last)
+		}
+	})
+
+	t.Run("transport names", func(t *testing.T) {
+		if got := transportName(&net.TCPAddr{}); got != "direct SSH (TCP)" {
+			t.Errorf("Expected direct SSH (TCP), got %q", got)
+		}
+		if got := transportName(nil); got != "unknown" {
+			t.Errorf("Expected unknown, got %q", got)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package sshserver
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/keystrength"
+	"github.com/mevdschee/underground-node-network/internal/sshtest"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestKeyPolicy(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	waitForLeave(t, s)
+	s.SetHeadless(true)
+
+	priv, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	weak, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AuthorizeKey(weak.PublicKey(), "alice")
+
+	t.Run("refuse", func(t *testing.T) {
+		s.SetKeyPolicy(keystrength.Policy{Action: keystrength.Refuse, MinRSABits: 2048})
+		if client, err := sshtest.Dial(s, "alice", weak); err == nil {
+			client.Close()
+			t.Error("Expected the weak key to be refused")
+		}
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		s.SetKeyPolicy(keystrength.Policy{Action: keystrength.Warn, MinRSABits: 2048})
+		client, err := sshtest.Dial(s, "alice", weak)
+		if err != nil {
+			t.Fatalf("Expected the weak key to be accepted, got %v", err)
+		}
+		defer client.Close()
+		sess, err := sshtest.StartShell(client, 120, 24)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sess.WaitFor("Weak key: your RSA key has 1024 bits", 2*time.Second); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// waitForLeave registers a cleanup that waits until everyone has left s, so
+// sessions are done with the temp dir before it is removed
+func waitForLeave(t *testing.T, s *Server) {
+	t.Cleanup(func() {
+		for deadline := time.Now().Add(2 * time.Second); len(s.GetPeople()) > 0 && time.Now().Before(deadline); {
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+}
@@ -0,0 +1,204 @@
+package sshserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/mevdschee/p2pquic-go/pkg/p2pquic"
+	"github.com/mevdschee/underground-node-network/internal/nat"
+	"github.com/quic-go/quic-go"
+)
+
+// Delays between attempts to recreate a failed QUIC listener
+var (
+	rebindDelay    = 1 * time.Second
+	maxRebindDelay = 30 * time.Second
+)
+
+// SetRebind sets whether a failed QUIC listener is recreated (on by default)
+func (s *Server) SetRebind(enabled bool) {
+	s.mu.Lock()
+	s.rebind = enabled
+	s.mu.Unlock()
+}
+
+// SetOnRebind sets the function called with the new port after the QUIC
+// listener was recreated because its socket failed
+func (s *Server) SetOnRebind(fn func(port int)) {
+	s.mu.Lock()
+	s.onRebind = fn
+	s.mu.Unlock()
+}
+
+// SetQUICTuning sets the QUIC transport parameters of the listener, from
+// the next Start or rebind on
+func (s *Server) SetQUICTuning(t nat.QUICTuning) {
+	s.mu.Lock()
+	s.quicTuning = t
+	s.mu.Unlock()
+}
+
+// listenP2P binds a p2pquic peer to port (0 for random) and listens for
+// QUIC on its socket. p2pquic's own Listen has fixed settings, so the room
+// runs its listener with the tuned config.
+func (s *Server) listenP2P(port int) (*p2pquic.Peer, *quic.Listener, error) {
+	config := p2pquic.Config{
+		PeerID:       s.roomName,
+		LocalPort:    port,
+		SignalingURL: "",    // Using SSH-based signaling
+		EnableSTUN:   false, // Disabled - using server-reflexive IPv4 from entrypoint
+	}
+
+	p2pPeer, err := p2pquic.NewPeer(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create p2pquic peer: %w", err)
+	}
+
+	// Bind the socket - this will bind to an actual port
+	if err := p2pPeer.Bind(); err != nil {
+		return nil, nil, fmt.Errorf("failed to bind p2pquic socket: %w", err)
+	}
+
+	s.mu.Lock()
+	if s.quicTLS == nil {
+		if s.quicTLS, err = newQUICTLSConfig(); err != nil {
+			s.mu.Unlock()
+			p2pPeer.Close()
+			return nil, nil, err
+		}
+	}
+	tlsConf, tuning := s.quicTLS, s.quicTuning
+	s.mu.Unlock()
+
+	ln, err := quic.Listen(p2pPeer.GetUDPConn(), tlsConf, tuning.Config())
+	if err != nil {
+		p2pPeer.Close()
+		return nil, nil, fmt.Errorf("failed to start QUIC listener: %w", err)
+	}
+	return p2pPeer, ln, nil
+}
+
+// ListenTCP serves SSH over plain TCP as well, on the bind address and the
+// port of the QUIC socket, for rooms with a public IP or a forwarded port.
+// Call it after Start.
+func (s *Server) ListenTCP() error {
+	host, _, err := net.SplitHostPort(s.address)
+	if err != nil {
+		return fmt.Errorf("invalid address %s: %w", s.address, err)
+	}
+	ln, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(s.GetPort())))
+	if err != nil {
+		return fmt.Errorf("failed to start TCP listener: %w", err)
+	}
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		ln.Close()
+		return fmt.Errorf("server stopped")
+	}
+	s.tcpLn = ln
+	s.mu.Unlock()
+
+	log.Printf("SSH server listening on TCP %s", ln.Addr())
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.handleConnection(conn)
+		}
+	}()
+	return nil
+}
+
+// newQUICTLSConfig creates the self-signed certificate of the QUIC
+// listener. Clients do not verify it: the room is verified by its SSH host
+// key. The config is kept across rebinds, so session tickets stay valid.
+func newQUICTLSConfig() (*tls.Config, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(10, 0, 0),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create QUIC certificate: %w", err)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		NextProtos:   []string{"p2pquic"},
+	}, nil
+}
+
+// rebindP2P replaces a peer whose socket failed, for instance when the
+// network interface went down. It keeps the same port so the advertised
+// candidates stay valid, falling back to the configured port (random if 0)
+// after a few failed attempts, and retries until it succeeds or the server
+// is stopped.
+func (s *Server) rebindP2P(old *p2pquic.Peer, oldLn *quic.Listener) {
+	port := old.GetActualPort()
+	oldLn.Close()
+	old.Close()
+
+	delay := rebindDelay
+	for attempt := 1; ; attempt++ {
+		s.mu.RLock()
+		stopped := s.stopped
+		s.mu.RUnlock()
+		if stopped {
+			return
+		}
+
+		p2pPeer, ln, err := s.listenP2P(port)
+		if err == nil {
+			s.mu.Lock()
+			if s.stopped {
+				s.mu.Unlock()
+				ln.Close()
+				p2pPeer.Close()
+				return
+			}
+			s.p2pPeer, s.quicLn = p2pPeer, ln
+			cb := s.onRebind
+			s.mu.Unlock()
+
+			log.Printf("QUIC listener rebound on port %d", p2pPeer.GetActualPort())
+			go s.acceptLoop(p2pPeer, ln)
+			if cb != nil {
+				cb(p2pPeer.GetActualPort())
+			}
+			return
+		}
+
+		log.Printf("Rebind attempt %d on port %d failed: %v", attempt, port, err)
+		if attempt == 3 {
+			port = s.configuredPort()
+		}
+		time.Sleep(delay)
+		delay = min(delay*2, maxRebindDelay)
+	}
+}
+
+// configuredPort returns the port from the listen address (0 for random)
+func (s *Server) configuredPort() int {
+	_, portStr, err := net.SplitHostPort(s.address)
+	if err != nil {
+		return 0
+	}
+	port, _ := strconv.Atoi(portStr)
+	return port
+}
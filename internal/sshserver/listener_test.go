@@ -0,0 +1,162 @@
+package sshserver
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/nat"
+	"github.com/quic-go/quic-go"
+)
+
+func TestListenerRebind(t *testing.T) {
+	old := rebindDelay
+	rebindDelay = 10 * time.Millisecond
+	defer func() { rebindDelay = old }()
+
+	newServer := func(t *testing.T) *Server {
+		tmpDir := t.TempDir()
+		s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+		if err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+		if err := s.Start(); err != nil {
+			t.Fatalf("Failed to start server: %v", err)
+		}
+		t.Cleanup(func() { s.Stop() })
+		return s
+	}
+
+	t.Run("socket error triggers rebind", func(t *testing.T) {
+		s := newServer(t)
+		rebound := make(chan int, 1)
+		s.SetOnRebind(func(port int) { rebound <- port })
+
+		port := s.GetPort()
+		dead := s.GetUDPConn()
+		dead.Close()
+
+		select {
+		case got := <-rebound:
+			if got != port {
+				t.Errorf("Expected to rebind on port %d, got %d", port, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Listener was not rebound after its socket failed")
+		}
+		if s.GetUDPConn() == dead {
+			t.Errorf("Expected a new UDP socket after rebinding")
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		s := newServer(t)
+		s.SetRebind(false)
+		rebound := make(chan int, 1)
+		s.SetOnRebind(func(port int) { rebound <- port })
+
+		s.GetUDPConn().Close()
+		select {
+		case <-rebound:
+			t.Error("Listener was rebound although rebinding is disabled")
+		case <-time.After(300 * time.Millisecond):
+		}
+	})
+
+	t.Run("not after stop", func(t *testing.T) {
+		s := newServer(t)
+		rebound := make(chan int, 1)
+		s.SetOnRebind(func(port int) { rebound <- port })
+
+		s.Stop()
+		select {
+		case <-rebound:
+			t.Error("Listener was rebound after Stop")
+		case <-time.After(300 * time.Millisecond):
+		}
+	})
+}
+
+func TestListenerQUICTuning(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	const window = 2 << 20
+	s.SetQUICTuning(nat.QUICTuning{MaxIdleTimeout: time.Minute, KeepAlivePeriod: 10 * time.Second, StreamWindow: window})
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	t.Cleanup(func() { s.Stop() })
+
+	tlsConf := &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"p2pquic"}}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := quic.DialAddr(ctx, fmt.Sprintf("127.0.0.1:%d", s.GetPort()), tlsConf, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial the room: %v", err)
+	}
+	defer conn.CloseWithError(0, "")
+
+	// The room takes the first stream for SSH and never reads the second,
+	// so writes to it only stop when the room's stream window is full
+	if _, err := conn.OpenStream(); err != nil {
+		t.Fatalf("Failed to open SSH stream: %v", err)
+	}
+	stream, err := conn.OpenStream()
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	stream.SetWriteDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 16<<10)
+	written := 0
+	for written < 2*window {
+		n, err := stream.Write(buf)
+		written += n
+		if err != nil {
+			break
+		}
+	}
+	if written < window || written >= 2*window {
+		t.Errorf("Expected the room to accept about %d unread bytes, got %d", window, written)
+	}
+}
+
+func TestListenTCP(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	if err := s.ListenTCP(); err != nil {
+		t.Fatalf("Failed to listen on TCP: %v", err)
+	}
+	addr := fmt.Sprintf("127.0.0.1:%d", s.GetPort())
+
+	conn, err := net.DialTimeout("tcp", addr, 2*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to dial the TCP listener: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	banner := make([]byte, 8)
+	if _, err := io.ReadFull(conn, banner); err != nil || string(banner) != "SSH-2.0-" {
+		t.Errorf("Expected an SSH banner over TCP, got %q, %v", banner, err)
+	}
+	conn.Close()
+
+	s.Stop()
+	if conn, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+		conn.Close()
+		t.Error("Expected the TCP listener to close with the server")
+	}
+}
@@ -0,0 +1,81 @@
+package sshserver
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"github.com/mevdschee/underground-node-network/internal/ui/common"
+	"github.com/mevdschee/underground-node-network/internal/ui/password"
+	"golang.org/x/crypto/ssh"
+)
+
+// maxLockAttempts is how many room keys a visitor may try before being
+// disconnected
+const maxLockAttempts = 3
+
+// lockStatus describes whether the room is locked, without revealing the key
+func (s *Server) lockStatus() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.roomLockKey == "" {
+		return "The room is unlocked."
+	}
+	return "The room is locked, new visitors need the room key."
+}
+
+// promptLockKey asks a visitor for the room key, allowing maxLockAttempts
+// tries, and reports whether they may enter. Cancelling ends the prompt.
+func (s *Server) promptLockKey(p *Person, channel ssh.Channel, lockKey string) bool {
+	// Use a local screen variable to avoid conflict with long-running ChatUI screen
+	scr, err := common.NewScreen(p.Bus)
+	if err != nil {
+		scr = nil
+	}
+
+	ok := false
+	errMsg := ""
+	for attempt := 1; attempt <= maxLockAttempts; attempt++ {
+		entered := readLockKey(p, scr, errMsg)
+		if entered == lockKey {
+			ok = true
+			break
+		}
+		if entered == "" {
+			break
+		}
+		if left := maxLockAttempts - attempt; left == 1 {
+			errMsg = "Incorrect key, 1 attempt left"
+		} else {
+			errMsg = fmt.Sprintf("Incorrect key, %d attempts left", left)
+		}
+	}
+
+	if scr != nil {
+		scr.Fini()
+	}
+	if !ok {
+		fmt.Fprintf(channel, "\r\n*** INCORRECT ROOM KEY ***\r\n\r\n")
+	}
+	return ok
+}
+
+// readLockKey reads one room key, on the screen if there is one and as a
+// plain line otherwise
+func readLockKey(p *Person, scr tcell.Screen, errMsg string) string {
+	if scr != nil {
+		pwdUI := password.NewPasswordUI(scr)
+		pwdUI.Error = errMsg
+		return pwdUI.Run()
+	}
+	if errMsg != "" {
+		fmt.Fprintf(p.Bus, "%s\r\n", errMsg)
+	}
+	var entered string
+	fmt.Fprint(p.Bus, "Room key: ")
+	if scanner := ui.LineReader(p.Bus, false); scanner.Scan() {
+		entered = scanner.Text()
+	}
+	fmt.Fprint(p.Bus, "\r\n")
+	return entered
+}
@@ -0,0 +1,67 @@
+package sshserver
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestLockStatus(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	opPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	opKey, _ := ssh.NewPublicKey(opPub)
+	s.operatorPubKey = opKey
+	op := &Person{Username: "op", PubKey: opKey, ChatUI: ui.NewChatUI(nil)}
+	visitor := &Person{Username: "visitor", ChatUI: ui.NewChatUI(nil)}
+	s.people["op"] = op
+	s.people["visitor"] = visitor
+
+	last := func(p *Person) string {
+		msgs := p.ChatUI.GetMessages()
+		return msgs[len(msgs)-1].Text
+	}
+
+	t.Run("unlocked", func(t *testing.T) {
+		s.handleInternalCommand(visitor, "/lockstatus")
+		if got := last(visitor); got != "The room is unlocked." {
+			t.Errorf("Unexpected status %q", got)
+		}
+		s.handleInternalCommand(op, "/lock")
+		if got := last(op); got != "The room is unlocked. Usage: /lock <key>" {
+			t.Errorf("Unexpected /lock reply %q", got)
+		}
+	})
+
+	t.Run("locked", func(t *testing.T) {
+		s.handleInternalCommand(op, "/lock sesame")
+		s.handleInternalCommand(visitor, "/lockstatus")
+		if got := last(visitor); got != "The room is locked, new visitors need the room key." {
+			t.Errorf("Unexpected status %q", got)
+		}
+		s.handleInternalCommand(op, "/lock")
+		if got := last(op); got != "The room key is: sesame" {
+			t.Errorf("Expected the operator to see the key, got %q", got)
+		}
+		s.handleInternalCommand(visitor, "/lock")
+		if got := last(visitor); got != "You do not have operator privileges." {
+			t.Errorf("Expected visitors not to see the key, got %q", got)
+		}
+	})
+
+	t.Run("unlocked again", func(t *testing.T) {
+		s.handleInternalCommand(op, "/unlock")
+		s.handleInternalCommand(visitor, "/lockstatus")
+		if got := last(visitor); got != "The room is unlocked." {
+			t.Errorf("Unexpected status %q", got)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package sshserver
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"path/filepath"
+	"testing"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/logfile"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestLogCommand(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	opPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	opKey, _ := ssh.NewPublicKey(opPub)
+	s.operatorPubKey = opKey
+	op := &Person{Username: "op", PubKey: opKey, ChatUI: ui.NewChatUI(nil)}
+	visitor := &Person{Username: "visitor", ChatUI: ui.NewChatUI(nil)}
+
+	last := func(p *Person, n int) []string {
+		msgs := p.ChatUI.GetMessages()
+		var out []string
+		for _, m := range msgs[len(msgs)-n:] {
+			out = append(out, m.Text)
+		}
+		return out
+	}
+
+	t.Run("not kept", func(t *testing.T) {
+		s.handleInternalCommand(op, "/log")
+		if got := last(op, 1)[0]; got != "The room log is not kept in memory." {
+			t.Errorf("Unexpected reply %q", got)
+		}
+	})
+
+	ring := logfile.NewRing(100)
+	s.SetLogRing(ring)
+	logger := log.New(ring, "", 0)
+	for i := 1; i <= 30; i++ {
+		logger.Printf("event %d", i)
+	}
+
+	t.Run("recent lines", func(t *testing.T) {
+		s.handleInternalCommand(op, "/log 3")
+		got := last(op, 4)
+		want := []string{"--- Last 3 log lines ---", "event 28", "event 29", "event 30"}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	})
+
+	t.Run("default count", func(t *testing.T) {
+		s.handleInternalCommand(op, "/log")
+		got := last(op, defaultLogLines+1)
+		if got[0] != fmt.Sprintf("--- Last %d log lines ---", defaultLogLines) || got[1] != "event 11" {
+			t.Errorf("Unexpected output %q", got[:2])
+		}
+	})
+
+	t.Run("operators only", func(t *testing.T) {
+		s.handleInternalCommand(visitor, "/log")
+		if got := last(visitor, 1)[0]; got != "You do not have operator privileges." {
+			t.Errorf("Expected visitor to be refused, got %q", got)
+		}
+	})
+}
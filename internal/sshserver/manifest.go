@@ -0,0 +1,170 @@
+package sshserver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// manifestEntry describes one downloadable file in the room
+type manifestEntry struct {
+	Name     string    `json:"name"` // as accepted by /geturl and the files door
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	SHA256   string    `json:"sha256"`
+}
+
+// cachedChecksum is a file hash that stays valid while size and mtime match
+type cachedChecksum struct {
+	size    int64
+	modTime time.Time
+	sum     string
+}
+
+// fileChecksum returns the SHA-256 of a file, hashing it only when it is new
+// or has changed since the last call
+func (s *Server) fileChecksum(fullPath string, info fs.FileInfo) (string, error) {
+	s.mu.RLock()
+	c, ok := s.checksums[fullPath]
+	s.mu.RUnlock()
+	if ok && c.size == info.Size() && c.modTime.Equal(info.ModTime()) {
+		return c.sum, nil
+	}
+
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	s.mu.Lock()
+	if s.checksums == nil {
+		s.checksums = make(map[string]cachedChecksum)
+	}
+	s.checksums[fullPath] = cachedChecksum{size: info.Size(), modTime: info.ModTime(), sum: sum}
+	s.mu.Unlock()
+	return sum, nil
+}
+
+// buildManifest lists every file under the file roots, sorted by name
+func (s *Server) buildManifest() ([]manifestEntry, error) {
+	if idx := s.currentFileIndex(); idx != nil {
+		var entries []manifestEntry
+		for _, dir := range idx.dirs {
+			for _, f := range dir {
+				if !f.Regular {
+					continue
+				}
+				sum, err := s.fileChecksum(f.Path, f.Info)
+				if err != nil {
+					continue
+				}
+				entries = append(entries, manifestEntry{
+					Name:     f.Name,
+					Size:     f.Info.Size(),
+					Modified: f.Info.ModTime().UTC().Truncate(time.Second),
+					SHA256:   sum,
+				})
+			}
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+		return entries, nil
+	}
+
+	s.mu.RLock()
+	roots := make(map[string]string, len(s.fileRoots))
+	for name, dir := range s.fileRoots {
+		roots[name] = dir
+	}
+	s.mu.RUnlock()
+
+	var entries []manifestEntry
+	for folder, root := range roots {
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if p == root {
+					return err
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			sum, err := s.fileChecksum(p, info)
+			if err != nil {
+				return nil
+			}
+			rel, _ := filepath.Rel(root, p)
+			entries = append(entries, manifestEntry{
+				Name:     path.Join(folder, filepath.ToSlash(rel)),
+				Size:     info.Size(),
+				Modified: info.ModTime().UTC().Truncate(time.Second),
+				SHA256:   sum,
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cannot read files: %w", err)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+// encodeManifest renders the manifest as "json" or "csv"
+func encodeManifest(entries []manifestEntry, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		if entries == nil {
+			entries = []manifestEntry{}
+		}
+		data, err := json.MarshalIndent(entries, "", "  ")
+		return append(data, '\n'), err
+	case "csv":
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write([]string{"name", "size", "modified", "sha256"})
+		for _, e := range entries {
+			w.Write([]string{e.Name, strconv.FormatInt(e.Size, 10), e.Modified.Format(time.RFC3339), e.SHA256})
+		}
+		w.Flush()
+		return buf.Bytes(), w.Error()
+	}
+	return nil, fmt.Errorf("unknown format: %s (use json or csv)", format)
+}
+
+// sendData transfers generated content to a UNN-aware client as a download,
+// using the same OSC blocks as the files door
+func (s *Server) sendData(p *Person, filename string, data []byte) {
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	count := (len(data) + transferBlockSize - 1) / transferBlockSize
+	if count == 0 {
+		count = 1
+	}
+	transferID := newTransferID(filename)
+	for i := 0; i < count; i++ {
+		end := min((i+1)*transferBlockSize, len(data))
+		s.sendTransferBlock(p, filename, transferID, count, i, checksum, data[i*transferBlockSize:end])
+	}
+}
@@ -0,0 +1,107 @@
+package sshserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+)
+
+func TestManifest(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	files := filepath.Join(tmpDir, "files")
+	os.MkdirAll(filepath.Join(files, "sub"), 0755)
+	os.WriteFile(filepath.Join(files, "a.txt"), []byte("hello"), 0644)
+	os.WriteFile(filepath.Join(files, "sub", "b.bin"), []byte("world!"), 0644)
+	s.SetFileRoots([]string{files})
+
+	sha := func(s string) string {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+
+	t.Run("lists files recursively with checksums", func(t *testing.T) {
+		entries, err := s.buildManifest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 2 || entries[0].Name != "a.txt" || entries[1].Name != "sub/b.bin" {
+			t.Fatalf("Unexpected entries: %+v", entries)
+		}
+		if entries[0].Size != 5 || entries[0].SHA256 != sha("hello") {
+			t.Errorf("Wrong size or checksum: %+v", entries[0])
+		}
+	})
+
+	t.Run("checksums are cached until the file changes", func(t *testing.T) {
+		path := filepath.Join(files, "a.txt")
+		info, _ := os.Stat(path)
+		// Same size and mtime: the cached hash is reused
+		os.WriteFile(path, []byte("HELLO"), 0644)
+		os.Chtimes(path, info.ModTime(), info.ModTime())
+		entries, _ := s.buildManifest()
+		if entries[0].SHA256 != sha("hello") {
+			t.Errorf("Expected the cached checksum, got %s", entries[0].SHA256)
+		}
+		// A new mtime triggers a rehash
+		later := info.ModTime().Add(time.Minute)
+		os.Chtimes(path, later, later)
+		entries, _ = s.buildManifest()
+		if entries[0].SHA256 != sha("HELLO") {
+			t.Errorf("Expected a fresh checksum after the change, got %s", entries[0].SHA256)
+		}
+	})
+
+	t.Run("multiple roots are prefixed with their folder", func(t *testing.T) {
+		other := filepath.Join(tmpDir, "music")
+		os.MkdirAll(other, 0755)
+		os.WriteFile(filepath.Join(other, "song.mp3"), []byte("la"), 0644)
+		s.SetFileRoots([]string{files, other})
+		defer s.SetFileRoots([]string{files})
+
+		entries, _ := s.buildManifest()
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+		if got := strings.Join(names, ","); got != "files/a.txt,files/sub/b.bin,music/song.mp3" {
+			t.Errorf("Unexpected names: %s", got)
+		}
+	})
+
+	t.Run("json and csv", func(t *testing.T) {
+		entries, _ := s.buildManifest()
+		data, err := encodeManifest(entries, "json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded []manifestEntry
+		if err := json.Unmarshal(data, &decoded); err != nil || len(decoded) != 2 {
+			t.Errorf("Invalid JSON manifest: %v %s", err, data)
+		}
+
+		data, err = encodeManifest(entries, "csv")
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(lines) != 3 || lines[0] != "name,size,modified,sha256" || !strings.HasPrefix(lines[2], "sub/b.bin,6,") {
+			t.Errorf("Unexpected CSV manifest:\n%s", data)
+		}
+
+		if _, err := encodeManifest(entries, "xml"); err == nil {
+			t.Errorf("Expected an error for an unknown format")
+		}
+	})
+}
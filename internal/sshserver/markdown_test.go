@@ -0,0 +1,49 @@
+package sshserver
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/sshtest"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+)
+
+func TestMarkdownCommand(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	signer, err := sshtest.NewSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := &Person{Username: "alice", SessionID: "s1", PubKey: signer.PublicKey(), ChatUI: ui.NewChatUI(nil)}
+	s.people["alice"] = alice
+	hash := s.getPubKeyHash(alice.PubKey)
+
+	lastMessage := func(cmd string) string {
+		s.handleInternalCommand(alice, cmd)
+		msgs := alice.ChatUI.GetMessages()
+		return msgs[len(msgs)-1].Text
+	}
+
+	if got := lastMessage("/markdown"); got != "Markdown is off." {
+		t.Errorf("Expected markdown to be off by default, got %q", got)
+	}
+	lastMessage("/markdown on")
+	if !s.markdown[hash] {
+		t.Error("Expected markdown to be remembered for the key")
+	}
+	if got := lastMessage("/markdown"); got != "Markdown is on." {
+		t.Errorf("Expected markdown to be on, got %q", got)
+	}
+	if got := lastMessage("/markdown maybe"); got != "Usage: /markdown on|off" {
+		t.Errorf("Expected usage, got %q", got)
+	}
+	lastMessage("/markdown off")
+	if s.markdown[hash] {
+		t.Error("Expected markdown to be forgotten when turned off")
+	}
+}
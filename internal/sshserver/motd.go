@@ -0,0 +1,51 @@
+package sshserver
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// motdPath returns the file holding this room's message of the day, kept
+// next to the host key
+func (s *Server) motdPath() string {
+	return filepath.Join(filepath.Dir(s.hostKeyPath), s.roomName+".motd")
+}
+
+// loadMOTD reads the persisted message of the day, if any
+func (s *Server) loadMOTD() {
+	data, err := os.ReadFile(s.motdPath())
+	if err != nil {
+		return
+	}
+	s.motd = strings.TrimSpace(string(data))
+}
+
+// SetMOTD sets and persists the message of the day ("" clears it)
+func (s *Server) SetMOTD(text string) error {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\n", " "))
+
+	s.mu.Lock()
+	s.motd = text
+	s.mu.Unlock()
+
+	if text == "" {
+		if err := os.Remove(s.motdPath()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.WriteFile(s.motdPath(), []byte(text+"\n"), 0600); err != nil {
+		log.Printf("Failed to save MOTD: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetMOTD returns the message of the day, or "" if none is set
+func (s *Server) GetMOTD() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.motd
+}
@@ -0,0 +1,81 @@
+package sshserver
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestMOTD(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "unn-motd-test-*")
+	defer os.RemoveAll(tmpDir)
+
+	hostKeyPath := filepath.Join(tmpDir, "host_key")
+	s, err := NewServer("127.0.0.1:0", hostKeyPath, "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	sshPub, _ := ssh.NewPublicKey(pub)
+	p := &Person{Username: "alice", ChatUI: ui.NewChatUI(nil), PubKey: sshPub}
+
+	lastMessage := func() string {
+		msgs := p.ChatUI.GetMessages()
+		return msgs[len(msgs)-1].Text
+	}
+
+	t.Run("operator only", func(t *testing.T) {
+		s.handleInternalCommand(p, "/motd set Maintenance at 2am UTC")
+		if s.GetMOTD() != "" {
+			t.Errorf("Non-operator was able to set the MOTD")
+		}
+	})
+
+	t.Run("set and get", func(t *testing.T) {
+		s.mu.Lock()
+		s.operatorPubKey = sshPub
+		s.mu.Unlock()
+
+		s.handleInternalCommand(p, "/motd set Maintenance at 2am UTC")
+		if got := s.GetMOTD(); got != "Maintenance at 2am UTC" {
+			t.Errorf("Expected MOTD to be set, got %q", got)
+		}
+		s.handleInternalCommand(p, "/motd")
+		if !strings.Contains(lastMessage(), "Maintenance at 2am UTC") {
+			t.Errorf("/motd didn't show the MOTD, got %q", lastMessage())
+		}
+	})
+
+	t.Run("persists across rebuild", func(t *testing.T) {
+		s2, err := NewServer("127.0.0.1:0", hostKeyPath, "testroom", doors.NewManager(tmpDir))
+		if err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+		if got := s2.GetMOTD(); got != "Maintenance at 2am UTC" {
+			t.Errorf("Expected persisted MOTD, got %q", got)
+		}
+		other, _ := NewServer("127.0.0.1:0", hostKeyPath, "otherroom", doors.NewManager(tmpDir))
+		if other.GetMOTD() != "" {
+			t.Errorf("MOTD leaked into another room")
+		}
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		s.handleInternalCommand(p, "/motd clear")
+		if s.GetMOTD() != "" {
+			t.Errorf("Expected MOTD to be cleared")
+		}
+		s2, _ := NewServer("127.0.0.1:0", hostKeyPath, "testroom", doors.NewManager(tmpDir))
+		if s2.GetMOTD() != "" {
+			t.Errorf("Cleared MOTD came back after rebuild")
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package sshserver
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestStaffMessages(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	opPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	opKey, _ := ssh.NewPublicKey(opPub)
+	visitorPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	visitorKey, _ := ssh.NewPublicKey(visitorPub)
+	s.operatorPubKey = opKey
+	op := &Person{Username: "op", PubKey: opKey, ChatUI: ui.NewChatUI(nil)}
+	op2 := &Person{Username: "op-laptop", PubKey: opKey, ChatUI: ui.NewChatUI(nil)}
+	visitor := &Person{Username: "visitor", PubKey: visitorKey, ChatUI: ui.NewChatUI(nil)}
+	s.people["op"] = op
+	s.people["op-laptop"] = op2
+	s.people["visitor"] = visitor
+
+	countStaff := func(msgs []ui.Message) int {
+		n := 0
+		for _, m := range msgs {
+			if m.Type == ui.MsgStaff {
+				n++
+			}
+		}
+		return n
+	}
+
+	t.Run("only operators receive it", func(t *testing.T) {
+		s.handleInternalCommand(op, "/ops watch the new user")
+		for _, p := range []*Person{op, op2} {
+			msgs := p.ChatUI.GetMessages()
+			last := msgs[len(msgs)-1]
+			if last.Text != "[ops] <op> watch the new user" || last.Type != ui.MsgStaff {
+				t.Errorf("Expected %s to get the staff message, got %+v", p.Username, last)
+			}
+		}
+		if n := countStaff(visitor.ChatUI.GetMessages()); n != 0 {
+			t.Errorf("Expected the visitor to get nothing, got %d staff messages", n)
+		}
+	})
+
+	t.Run("stored in operator history only", func(t *testing.T) {
+		if n := countStaff(s.histories[s.getPubKeyHash(opKey)]); n != 1 {
+			t.Errorf("Expected 1 staff message in the operator history, got %d", n)
+		}
+		if n := countStaff(s.histories[s.getPubKeyHash(visitorKey)]); n != 0 {
+			t.Errorf("Expected no staff message in the visitor history, got %d", n)
+		}
+	})
+
+	t.Run("visitors cannot send", func(t *testing.T) {
+		before := countStaff(op.ChatUI.GetMessages())
+		s.handleInternalCommand(visitor, "/ops hello staff")
+		msgs := visitor.ChatUI.GetMessages()
+		if msgs[len(msgs)-1].Text != "You do not have operator privileges." {
+			t.Errorf("Expected the visitor to be refused, got %q", msgs[len(msgs)-1].Text)
+		}
+		if countStaff(op.ChatUI.GetMessages()) != before {
+			t.Error("Expected nothing to reach the operators")
+		}
+	})
+}
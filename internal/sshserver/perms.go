@@ -0,0 +1,101 @@
+package sshserver
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// filesDoor is the door that serves the room's files, so opening it counts
+// as downloading
+const filesDoor = "files"
+
+// Permission says which visitors may use a room feature. Operators are never
+// restricted.
+type Permission int
+
+const (
+	PermEveryone Permission = iota
+	PermVerified
+	PermNobody
+)
+
+func (p Permission) String() string {
+	switch p {
+	case PermVerified:
+		return "verified-only"
+	case PermNobody:
+		return "off"
+	default:
+		return "on"
+	}
+}
+
+// ParsePermission parses "on", "verified-only" or "off"
+func ParsePermission(s string) (Permission, error) {
+	switch s {
+	case "on":
+		return PermEveryone, nil
+	case "verified-only":
+		return PermVerified, nil
+	case "off":
+		return PermNobody, nil
+	}
+	return PermEveryone, fmt.Errorf("unknown permission %q (use on, verified-only or off)", s)
+}
+
+// SetPermissions sets who may download files and who may open doors
+func (s *Server) SetPermissions(downloads, doors Permission) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.downloadPerm = downloads
+	s.doorPerm = doors
+}
+
+// VerifyKey marks a key as belonging to a verified UNN identity. The
+// entrypoint says so in the punch offer.
+func (s *Server) VerifyKey(pubKey ssh.PublicKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.verifiedKeys[string(pubKey.Marshal())] = true
+}
+
+// checkPermission returns why p may not use feature, or nil if they may
+func (s *Server) checkPermission(p *Person, feature string, perm Permission) error {
+	if perm == PermEveryone || s.isOperator(p.PubKey) {
+		return nil
+	}
+	if perm == PermNobody {
+		return fmt.Errorf("%s are disabled in this room", feature)
+	}
+	s.mu.RLock()
+	verified := p.PubKey != nil && s.verifiedKeys[string(p.PubKey.Marshal())]
+	s.mu.RUnlock()
+	if !verified {
+		return fmt.Errorf("%s are limited to verified users in this room", feature)
+	}
+	return nil
+}
+
+// checkDownload returns why p may not download files, or nil if they may
+func (s *Server) checkDownload(p *Person) error {
+	s.mu.RLock()
+	perm := s.downloadPerm
+	s.mu.RUnlock()
+	return s.checkPermission(p, "downloads", perm)
+}
+
+// checkDoor returns why p may not open door, or nil if they may. The files
+// door also needs download permission.
+func (s *Server) checkDoor(p *Person, door string) error {
+	s.mu.RLock()
+	perm := s.doorPerm
+	s.mu.RUnlock()
+	if err := s.checkPermission(p, "doors", perm); err != nil {
+		return err
+	}
+	if door == filesDoor {
+		return s.checkDownload(p)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package sshserver
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"golang.org/x/crypto/ssh"
+)
+
+type recordChannel struct {
+	mockChannel
+	buf bytes.Buffer
+}
+
+func (c *recordChannel) Write(p []byte) (int, error) {
+	return c.buf.Write(p)
+}
+
+func TestPermissions(t *testing.T) {
+	tmpDir := t.TempDir()
+	dm := doors.NewManager(tmpDir)
+	for _, name := range []string{"testdoor", filesDoor} {
+		os.WriteFile(filepath.Join(tmpDir, name), []byte("#!/bin/sh\necho door-output"), 0755)
+	}
+	dm.Scan()
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", dm)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	newKey := func() ssh.PublicKey {
+		pub, _, _ := ed25519.GenerateKey(rand.Reader)
+		key, _ := ssh.NewPublicKey(pub)
+		return key
+	}
+	opKey, verifiedKey, guestKey := newKey(), newKey(), newKey()
+	s.operatorPubKey = opKey
+	s.VerifyKey(verifiedKey)
+	op := &Person{Username: "op", SessionID: "op", PubKey: opKey, ChatUI: ui.NewChatUI(nil)}
+	verified := &Person{Username: "verified", SessionID: "verified", PubKey: verifiedKey, ChatUI: ui.NewChatUI(nil)}
+	guest := &Person{Username: "guest", SessionID: "guest", PubKey: guestKey, ChatUI: ui.NewChatUI(nil)}
+	for _, p := range []*Person{op, verified, guest} {
+		s.people[p.SessionID] = p
+	}
+
+	lastMessage := func(p *Person) string {
+		msgs := p.ChatUI.GetMessages()
+		return msgs[len(msgs)-1].Text
+	}
+
+	t.Run("parse", func(t *testing.T) {
+		for _, name := range []string{"on", "verified-only", "off"} {
+			perm, err := ParsePermission(name)
+			if err != nil || perm.String() != name {
+				t.Errorf("ParsePermission(%q) = %v, %v", name, perm, err)
+			}
+		}
+		if _, err := ParsePermission("maybe"); err == nil {
+			t.Error("Expected an error for an unknown permission")
+		}
+	})
+
+	t.Run("only operators can set", func(t *testing.T) {
+		s.handleInternalCommand(guest, "/perms downloads off")
+		if got := lastMessage(guest); got != "You do not have operator privileges." {
+			t.Errorf("Expected a privileges error, got %q", got)
+		}
+		s.handleInternalCommand(guest, "/perms")
+		if got := lastMessage(guest); got != "Downloads: on, doors: on." {
+			t.Errorf("Expected the permissions to be shown, got %q", got)
+		}
+		s.handleInternalCommand(op, "/perms downloads sometimes")
+		if got := lastMessage(op); !strings.Contains(got, "unknown permission") {
+			t.Errorf("Expected an unknown permission error, got %q", got)
+		}
+	})
+
+	t.Run("downloads off", func(t *testing.T) {
+		s.handleInternalCommand(op, "/perms downloads off")
+		if got := lastMessage(guest); got != "*** @op set downloads to off ***" {
+			t.Errorf("Expected the change to be announced, got %q", got)
+		}
+		for _, cmd := range []string{"/geturl a.txt", "/get-bookmark 1", "/accept", "/manifest", "/head a.txt", "/tail a.txt"} {
+			for _, p := range []*Person{verified, guest} {
+				s.handleInternalCommand(p, cmd)
+				if got := lastMessage(p); got != "Error: downloads are disabled in this room" {
+					t.Errorf("%s %s: expected a denial, got %q", p.Username, cmd, got)
+				}
+			}
+			s.handleInternalCommand(op, cmd)
+			if got := lastMessage(op); strings.Contains(got, "disabled") {
+				t.Errorf("Operator %s was denied: %q", cmd, got)
+			}
+		}
+	})
+
+	t.Run("downloads verified-only", func(t *testing.T) {
+		s.handleInternalCommand(op, "/perms downloads verified-only")
+		s.handleInternalCommand(guest, "/geturl a.txt")
+		if got := lastMessage(guest); got != "Error: downloads are limited to verified users in this room" {
+			t.Errorf("Expected the guest to be denied, got %q", got)
+		}
+		s.handleInternalCommand(verified, "/geturl a.txt")
+		if got := lastMessage(verified); strings.Contains(got, "verified users") {
+			t.Errorf("Expected the verified user to pass, got %q", got)
+		}
+	})
+
+	t.Run("doors verified-only", func(t *testing.T) {
+		s.handleInternalCommand(op, "/perms doors verified-only")
+		for _, cmd := range []string{"/open testdoor", "/testdoor"} {
+			if !s.handleInternalCommand(guest, cmd) {
+				t.Errorf("%s: expected the guest to stay in the chat", cmd)
+			}
+			if got := lastMessage(guest); got != "Error: doors are limited to verified users in this room" {
+				t.Errorf("%s: expected a denial, got %q", cmd, got)
+			}
+			if s.handleInternalCommand(verified, cmd) {
+				t.Errorf("%s: expected the verified user to open the door", cmd)
+			}
+		}
+
+		ch := &recordChannel{}
+		if done := s.handleCommand(ch, guest.SessionID, "/open testdoor"); done != nil {
+			t.Error("Expected no door to run for the guest")
+		}
+		if !strings.Contains(ch.buf.String(), "[Cannot open testdoor: doors are limited to verified users in this room]") {
+			t.Errorf("Expected a denial on the channel, got %q", ch.buf.String())
+		}
+	})
+
+	t.Run("files door needs downloads", func(t *testing.T) {
+		s.SetPermissions(PermNobody, PermEveryone)
+		if !s.handleInternalCommand(verified, "/open files") {
+			t.Error("Expected the files door to stay closed")
+		}
+		if got := lastMessage(verified); got != "Error: downloads are disabled in this room" {
+			t.Errorf("Expected a downloads denial, got %q", got)
+		}
+		if s.handleInternalCommand(verified, "/open testdoor") {
+			t.Error("Expected other doors to open")
+		}
+	})
+
+	t.Run("snapshot", func(t *testing.T) {
+		s.SetPermissions(PermVerified, PermNobody)
+		path := filepath.Join(tmpDir, "snap.json")
+		if err := s.SaveSnapshot(path); err != nil {
+			t.Fatal(err)
+		}
+		s2, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key2"), "testroom", dm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s2.RestoreSnapshot(path); err != nil {
+			t.Fatal(err)
+		}
+		if s2.downloadPerm != PermVerified || s2.doorPerm != PermNobody {
+			t.Errorf("Expected verified-only/off after restore, got %s/%s", s2.downloadPerm, s2.doorPerm)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package sshserver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// poll is a room vote started by the operator with /poll
+type poll struct {
+	question string
+	options  []string
+	votes    map[string]int // pubkey hash -> chosen option index
+}
+
+// parsePoll reads "question | option | option ..." with at least two options
+func parsePoll(arg string) (*poll, error) {
+	fields := strings.Split(arg, "|")
+	var parts []string
+	for _, f := range fields {
+		if f = strings.TrimSpace(f); f != "" {
+			parts = append(parts, f)
+		}
+	}
+	if len(parts) < 3 {
+		return nil, fmt.Errorf("a poll needs a question and at least two options")
+	}
+	return &poll{question: parts[0], options: parts[1:], votes: make(map[string]int)}, nil
+}
+
+// vote records the voter's choice (1-based). Everyone gets one vote.
+func (p *poll) vote(pubHash string, choice int) error {
+	if choice < 1 || choice > len(p.options) {
+		return fmt.Errorf("choose an option from 1 to %d", len(p.options))
+	}
+	if _, ok := p.votes[pubHash]; ok {
+		return fmt.Errorf("you already voted")
+	}
+	p.votes[pubHash] = choice - 1
+	return nil
+}
+
+// tally counts the votes per option
+func (p *poll) tally() []int {
+	counts := make([]int, len(p.options))
+	for _, i := range p.votes {
+		counts[i]++
+	}
+	return counts
+}
+
+// results renders the poll and its current tally as one multi-line message
+func (p *poll) results(title string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*** %s: %s ***", title, p.question)
+	for i, n := range p.tally() {
+		fmt.Fprintf(&b, "\n  %d. %s - %d", i+1, p.options[i], n)
+	}
+	return b.String()
+}
@@ -0,0 +1,99 @@
+package sshserver
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestPoll(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	newPerson := func(name string) *Person {
+		pub, _, _ := ed25519.GenerateKey(rand.Reader)
+		sshPub, _ := ssh.NewPublicKey(pub)
+		p := &Person{Username: name, ChatUI: ui.NewChatUI(nil), PubKey: sshPub}
+		s.people[name] = p
+		return p
+	}
+	alice := newPerson("alice")
+	bob := newPerson("bob")
+	carol := newPerson("carol")
+	s.operatorPubKey = alice.PubKey
+
+	lastMessage := func(p *Person) string {
+		msgs := p.ChatUI.GetMessages()
+		return msgs[len(msgs)-1].Text
+	}
+
+	t.Run("operator only", func(t *testing.T) {
+		s.handleInternalCommand(bob, "/poll Lunch? | pizza | sushi")
+		if s.poll != nil {
+			t.Fatalf("Non-operator was able to start a poll")
+		}
+	})
+
+	t.Run("start", func(t *testing.T) {
+		s.handleInternalCommand(alice, "/poll Lunch? | pizza | sushi | tacos")
+		if s.poll == nil || len(s.poll.options) != 3 {
+			t.Fatalf("Poll not started: %+v", s.poll)
+		}
+		if msg := lastMessage(carol); !strings.Contains(msg, "Lunch?") || !strings.Contains(msg, "3. tacos - 0") {
+			t.Errorf("Poll not shown to the room: %q", msg)
+		}
+	})
+
+	t.Run("tally", func(t *testing.T) {
+		s.handleInternalCommand(bob, "/vote 2")
+		s.handleInternalCommand(carol, "/vote 2")
+		s.handleInternalCommand(alice, "/vote 1")
+		if got := s.poll.tally(); got[0] != 1 || got[1] != 2 || got[2] != 0 {
+			t.Errorf("Unexpected tally: %v", got)
+		}
+		if msg := lastMessage(bob); !strings.Contains(msg, "2. sushi - 2") {
+			t.Errorf("Live results not broadcast: %q", msg)
+		}
+	})
+
+	t.Run("duplicate vote", func(t *testing.T) {
+		s.handleInternalCommand(bob, "/vote 1")
+		if got := s.poll.tally(); got[0] != 1 || got[1] != 2 {
+			t.Errorf("Duplicate vote changed the tally: %v", got)
+		}
+		if msg := lastMessage(bob); !strings.Contains(msg, "already voted") {
+			t.Errorf("Expected duplicate vote error, got %q", msg)
+		}
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		dave := newPerson("dave")
+		s.handleInternalCommand(dave, "/vote 9")
+		if msg := lastMessage(dave); !strings.Contains(msg, "from 1 to 3") {
+			t.Errorf("Expected range error, got %q", msg)
+		}
+	})
+
+	t.Run("close", func(t *testing.T) {
+		s.handleInternalCommand(alice, "/poll close")
+		if s.poll != nil {
+			t.Fatalf("Poll still running after close")
+		}
+		if msg := lastMessage(carol); !strings.Contains(msg, "Poll closed") || !strings.Contains(msg, "2. sushi - 2") {
+			t.Errorf("Final tally not shown: %q", msg)
+		}
+		s.handleInternalCommand(bob, "/vote 1")
+		if msg := lastMessage(bob); msg != "No poll is running." {
+			t.Errorf("Expected no poll, got %q", msg)
+		}
+	})
+}
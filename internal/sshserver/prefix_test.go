@@ -0,0 +1,63 @@
+package sshserver
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestCommandPrefix(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	t.Run("rejects empty and spaced prefixes", func(t *testing.T) {
+		for _, bad := range []string{"", "! "} {
+			if err := s.SetCommandPrefix(bad); err == nil {
+				t.Errorf("Expected %q to be rejected", bad)
+			}
+		}
+	})
+
+	if err := s.SetCommandPrefix("!"); err != nil {
+		t.Fatal(err)
+	}
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	sshPub, _ := ssh.NewPublicKey(pub)
+	p := &Person{Username: "alice", ChatUI: ui.NewChatUI(nil), PubKey: sshPub}
+
+	t.Run("custom prefix runs commands", func(t *testing.T) {
+		if !s.handleInternalCommand(p, "!help") {
+			t.Fatal("Expected !help to be handled")
+		}
+		var help []string
+		for _, m := range p.ChatUI.GetMessages() {
+			help = append(help, m.Text)
+		}
+		out := strings.Join(help, "\n")
+		if !strings.Contains(out, "!vote <n>") || strings.Contains(out, "/vote") {
+			t.Errorf("Expected help to use the ! prefix:\n%s", out)
+		}
+	})
+
+	t.Run("slash is chat", func(t *testing.T) {
+		if s.handleInternalCommand(p, "/help") {
+			t.Error("Expected /help not to be a command with the ! prefix")
+		}
+	})
+
+	t.Run("usage text keeps paths", func(t *testing.T) {
+		got := s.withCmdPrefix("Usage: /kick <user/hash> [reason]")
+		if got != "Usage: !kick <user/hash> [reason]" {
+			t.Errorf("Unexpected rewrite: %q", got)
+		}
+	})
+}
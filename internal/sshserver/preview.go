@@ -0,0 +1,145 @@
+package sshserver
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mevdschee/underground-node-network/internal/ui/common"
+)
+
+const (
+	// defaultPreviewLines is how many lines /head and /tail show by default
+	defaultPreviewLines = 10
+	// maxPreviewLines caps the n of /head and /tail
+	maxPreviewLines = 100
+	// previewSniffSize is how much of a file is checked for binary content
+	previewSniffSize = 8192
+	// tailChunkSize is how much /tail reads per step backwards from the end
+	tailChunkSize = 4096
+)
+
+// parsePreviewArgs splits "<file> [n]" into the file name and line count
+func parsePreviewArgs(args string) (string, int, error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return "", 0, fmt.Errorf("no file given")
+	}
+	n := defaultPreviewLines
+	if len(fields) > 1 {
+		if v, err := strconv.Atoi(fields[len(fields)-1]); err == nil {
+			if v < 1 {
+				return "", 0, fmt.Errorf("line count must be at least 1")
+			}
+			n = min(v, maxPreviewLines)
+			fields = fields[:len(fields)-1]
+		}
+	}
+	return strings.Join(fields, " "), n, nil
+}
+
+// openTextFile opens a room file for previewing, refusing directories and
+// files that do not look like text
+func (s *Server) openTextFile(name string) (*os.File, int64, error) {
+	fullPath, err := s.resolveFile(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return nil, 0, fmt.Errorf("file not found: %s", name)
+	}
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		f.Close()
+		return nil, 0, fmt.Errorf("file not found: %s", name)
+	}
+
+	sniff := make([]byte, previewSniffSize)
+	n, _ := io.ReadFull(f, sniff)
+	if looksBinary(sniff[:n], int64(n) < info.Size()) {
+		f.Close()
+		return nil, 0, fmt.Errorf("%s is not a text file", name)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, info.Size(), nil
+}
+
+// looksBinary reports whether data has NUL bytes or is not UTF-8. When the
+// data was cut off, a rune split at the end is not held against it.
+func looksBinary(data []byte, truncated bool) bool {
+	if bytes.IndexByte(data, 0) >= 0 {
+		return true
+	}
+	if truncated {
+		for i := 0; i < utf8.UTFMax && len(data) > 0 && !utf8.Valid(data); i++ {
+			data = data[:len(data)-1]
+		}
+	}
+	return !utf8.Valid(data)
+}
+
+// headFile returns the first n lines of a text file
+func (s *Server) headFile(name string, n int) ([]string, error) {
+	f, _, err := s.openTextFile(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for len(lines) < n && scanner.Scan() {
+		lines = append(lines, previewLine(scanner.Text()))
+	}
+	return lines, scanner.Err()
+}
+
+// tailFile returns the last n lines of a text file, reading backwards from
+// the end so large logs are not scanned from the start
+func (s *Server) tailFile(name string, n int) ([]string, error) {
+	f, size, err := s.openTextFile(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if size == 0 {
+		return nil, nil
+	}
+
+	var buf []byte
+	offset := size
+	for offset > 0 && bytes.Count(bytes.TrimSuffix(buf, []byte("\n")), []byte("\n")) < n {
+		step := min(int64(tailChunkSize), offset)
+		offset -= step
+		chunk := make([]byte, step)
+		if _, err := f.ReadAt(chunk, offset); err != nil && err != io.EOF {
+			return nil, err
+		}
+		buf = append(chunk, buf...)
+	}
+
+	all := strings.Split(strings.TrimSuffix(string(buf), "\n"), "\n")
+	if len(all) > n {
+		all = all[len(all)-n:]
+	}
+	lines := make([]string, 0, len(all))
+	for _, line := range all {
+		lines = append(lines, previewLine(line))
+	}
+	return lines, nil
+}
+
+// previewLine makes a line of a file safe to show in the chat pane
+func previewLine(line string) string {
+	return common.StripANSI(strings.TrimSuffix(line, "\r"))
+}
@@ -0,0 +1,117 @@
+package sshserver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+)
+
+func TestPreviewFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	filesDir := filepath.Join(tmpDir, "files")
+	os.MkdirAll(filesDir, 0755)
+
+	var log strings.Builder
+	for i := 1; i <= 2000; i++ {
+		fmt.Fprintf(&log, "line %d\r\n", i)
+	}
+	os.WriteFile(filepath.Join(filesDir, "app.log"), []byte(log.String()), 0644)
+	os.WriteFile(filepath.Join(filesDir, "short.txt"), []byte("one\ntwo"), 0644)
+	os.WriteFile(filepath.Join(filesDir, "evil.txt"), []byte("\x1b]0;pwned\x07hi\x1b[2J\n"), 0644)
+	os.WriteFile(filepath.Join(filesDir, "empty.txt"), nil, 0644)
+	os.WriteFile(filepath.Join(filesDir, "image.png"), []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), 0644)
+	os.WriteFile(filepath.Join(filesDir, "latin1.txt"), []byte("caf\xe9\n"), 0644)
+
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	s.SetFileRoots([]string{filesDir})
+
+	t.Run("head", func(t *testing.T) {
+		lines, err := s.headFile("app.log", 3)
+		if err != nil || strings.Join(lines, ",") != "line 1,line 2,line 3" {
+			t.Errorf("Unexpected head %v, %v", lines, err)
+		}
+	})
+
+	t.Run("tail", func(t *testing.T) {
+		lines, err := s.tailFile("app.log", 3)
+		if err != nil || strings.Join(lines, ",") != "line 1998,line 1999,line 2000" {
+			t.Errorf("Unexpected tail %v, %v", lines, err)
+		}
+		lines, err = s.tailFile("app.log", 1000)
+		if err != nil || len(lines) != 1000 || lines[0] != "line 1001" {
+			t.Errorf("Unexpected long tail: %d lines starting with %q, %v", len(lines), lines[0], err)
+		}
+	})
+
+	t.Run("short file", func(t *testing.T) {
+		if lines, _ := s.tailFile("short.txt", 10); strings.Join(lines, ",") != "one,two" {
+			t.Errorf("Unexpected tail %v", lines)
+		}
+		if lines, _ := s.headFile("short.txt", 10); strings.Join(lines, ",") != "one,two" {
+			t.Errorf("Unexpected head %v", lines)
+		}
+		if lines, err := s.tailFile("empty.txt", 10); err != nil || len(lines) != 0 {
+			t.Errorf("Expected no lines for an empty file, got %v, %v", lines, err)
+		}
+	})
+
+	t.Run("escape sequences are stripped", func(t *testing.T) {
+		if lines, _ := s.headFile("evil.txt", 1); len(lines) != 1 || lines[0] != "hi" {
+			t.Errorf("Expected escapes to be stripped, got %q", lines)
+		}
+	})
+
+	t.Run("binary files are refused", func(t *testing.T) {
+		for _, name := range []string{"image.png", "latin1.txt"} {
+			if _, err := s.headFile(name, 5); err == nil || !strings.Contains(err.Error(), "not a text file") {
+				t.Errorf("Expected head of %s to be refused, got %v", name, err)
+			}
+			if _, err := s.tailFile(name, 5); err == nil {
+				t.Errorf("Expected tail of %s to be refused", name)
+			}
+		}
+	})
+
+	t.Run("paths outside the root are refused", func(t *testing.T) {
+		if _, err := s.headFile("../host_key", 5); err == nil {
+			t.Error("Expected a path outside the file root to be refused")
+		}
+	})
+
+	t.Run("arguments", func(t *testing.T) {
+		if name, n, err := parsePreviewArgs("my notes.txt 5"); err != nil || name != "my notes.txt" || n != 5 {
+			t.Errorf("Unexpected %q, %d, %v", name, n, err)
+		}
+		if _, n, _ := parsePreviewArgs("app.log 100000"); n != maxPreviewLines {
+			t.Errorf("Expected n to be capped at %d, got %d", maxPreviewLines, n)
+		}
+		if _, n, _ := parsePreviewArgs("app.log"); n != defaultPreviewLines {
+			t.Errorf("Expected the default of %d lines, got %d", defaultPreviewLines, n)
+		}
+		if _, _, err := parsePreviewArgs("app.log 0"); err == nil {
+			t.Error("Expected n = 0 to be rejected")
+		}
+	})
+
+	t.Run("command", func(t *testing.T) {
+		p := &Person{Username: "alice", ChatUI: ui.NewChatUI(nil)}
+		s.people["alice"] = p
+		s.handleInternalCommand(p, "/tail app.log 2")
+		msgs := p.ChatUI.GetMessages()
+		var texts []string
+		for _, m := range msgs[len(msgs)-3:] {
+			texts = append(texts, m.Text)
+		}
+		if strings.Join(texts, "|") != "--- app.log (tail 2) ---|line 1999|line 2000" {
+			t.Errorf("Unexpected output %q", texts)
+		}
+	})
+}
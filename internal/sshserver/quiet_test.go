@@ -0,0 +1,85 @@
+package sshserver
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/sshtest"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestQuietMode(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "unn-quiet-test-*")
+	defer os.RemoveAll(tmpDir)
+
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	s.SetHeadless(true)
+
+	join := func(name string) (*ssh.Client, *sshtest.Session) {
+		signer, err := sshtest.NewSigner()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.AuthorizeKey(signer.PublicKey(), name)
+		client, err := sshtest.Dial(s, name, signer)
+		if err != nil {
+			t.Fatalf("%s failed to connect: %v", name, err)
+		}
+		sess, err := sshtest.StartShell(client, 80, 24)
+		if err != nil {
+			t.Fatalf("%s failed to start shell: %v", name, err)
+		}
+		if err := sess.WaitFor("*** You joined testroom as "+name+" ***", 2*time.Second); err != nil {
+			t.Fatal(err)
+		}
+		return client, sess
+	}
+
+	// The first person to connect becomes the operator
+	aliceClient, alice := join("alice")
+	defer aliceClient.Close()
+	bobClient, bob := join("bob")
+	defer bobClient.Close()
+	carolClient, carol := join("carol")
+	defer carolClient.Close()
+
+	t.Run("toggle notice", func(t *testing.T) {
+		bob.Send("/quiet on")
+		if err := bob.WaitFor("You do not have operator privileges.", 2*time.Second); err != nil {
+			t.Error(err)
+		}
+		alice.Send("/quiet on")
+		if err := bob.WaitFor("@alice turned on quiet mode", 2*time.Second); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("leave suppressed", func(t *testing.T) {
+		carol.Send("/quit")
+		if err := carol.WaitClosed(2 * time.Second); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(200 * time.Millisecond)
+		if strings.Contains(alice.Output(), "carol left the room") {
+			t.Errorf("Leave notice shown while quiet mode is on")
+		}
+	})
+
+	t.Run("leave shown again", func(t *testing.T) {
+		alice.Send("/quiet off")
+		if err := bob.WaitFor("@alice turned off quiet mode", 2*time.Second); err != nil {
+			t.Error(err)
+		}
+		bob.Send("/quit")
+		if err := alice.WaitFor("* bob left the room", 2*time.Second); err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package sshserver
+
+import (
+	"github.com/mevdschee/underground-node-network/internal/ui"
+)
+
+// reconnectAll asks everyone but the operators to reconnect and closes their
+// connections, so they come back under the room's new settings (such as a
+// rotated host key). UNN clients rejoin the room by themselves; others are
+// told to. It returns how many people were disconnected.
+func (s *Server) reconnectAll(reason string) int {
+	s.mu.RLock()
+	var targets []*Person
+	for _, person := range s.people {
+		if !s.isModerator(person.PubKey) {
+			targets = append(targets, person)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, person := range targets {
+		if person.UNNAware && person.Bus != nil {
+			s.SendOSC(person, "reconnect", map[string]interface{}{
+				"room_name": s.roomName,
+				"reason":    reason,
+			})
+		} else if person.ChatUI != nil {
+			person.ChatUI.AddMessage("*** The room is restarting connections, please join again ***", ui.MsgSystem)
+		}
+		if person.Conn != nil {
+			person.Conn.Close()
+		}
+	}
+	return len(targets)
+}
@@ -0,0 +1,75 @@
+package sshserver
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"github.com/mevdschee/underground-node-network/internal/ui/bridge"
+	"golang.org/x/crypto/ssh"
+)
+
+// closeCountingConn records whether the room closed the connection
+type closeCountingConn struct {
+	ssh.Conn
+	closed bool
+}
+
+func (c *closeCountingConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestReconnectAll(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	conns := make(map[string]*closeCountingConn)
+	channels := make(map[string]*captureChannel)
+	for _, name := range []string{"op", "alice", "bob"} {
+		pub, _, _ := ed25519.GenerateKey(rand.Reader)
+		sshPub, _ := ssh.NewPublicKey(pub)
+		conns[name] = &closeCountingConn{}
+		channels[name] = &captureChannel{}
+		s.people[name] = &Person{Username: name, ChatUI: ui.NewChatUI(nil), PubKey: sshPub, Conn: conns[name],
+			UNNAware: name != "bob", Bus: bridge.NewSSHBus(bridge.NewInputBridge(channels[name]), 80, 24)}
+	}
+	op := s.people["op"]
+	s.operatorPubKey = op.PubKey
+
+	t.Run("operator only", func(t *testing.T) {
+		s.handleInternalCommand(s.people["alice"], "/reconnect-all")
+		if conns["bob"].closed {
+			t.Error("Expected a non-operator not to be able to reconnect everyone")
+		}
+	})
+
+	t.Run("messages and closes everyone but operators", func(t *testing.T) {
+		s.handleInternalCommand(op, "/reconnect-all new host key")
+
+		if !conns["alice"].closed || !conns["bob"].closed {
+			t.Error("Expected all non-operator connections to be closed")
+		}
+		if conns["op"].closed {
+			t.Error("Expected the operator to stay connected")
+		}
+		if out := channels["alice"].String(); !strings.Contains(out, `"action":"reconnect"`) || !strings.Contains(out, "new host key") {
+			t.Errorf("Expected alice's client to get the reconnect OSC, got %q", out)
+		}
+		msgs := s.people["bob"].ChatUI.GetMessages()
+		if !strings.Contains(msgs[len(msgs)-1].Text, "please join again") {
+			t.Errorf("Expected bob to be told to join again, got %q", msgs[len(msgs)-1].Text)
+		}
+		msgs = op.ChatUI.GetMessages()
+		if !strings.Contains(msgs[len(msgs)-1].Text, "Asked 2 people to reconnect") {
+			t.Errorf("Unexpected operator feedback %q", msgs[len(msgs)-1].Text)
+		}
+	})
+}
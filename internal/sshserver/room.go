@@ -5,9 +5,14 @@ import (
 	"encoding/base64"
 	"fmt"
 	"log"
+	"os"
 	"strings"
+	"time"
 
+	"github.com/gdamore/tcell/v2"
+	"github.com/mevdschee/underground-node-network/internal/protocol"
 	"github.com/mevdschee/underground-node-network/internal/ui"
+	"github.com/mevdschee/underground-node-network/internal/ui/banner"
 	"github.com/mevdschee/underground-node-network/internal/ui/common"
 	"golang.org/x/crypto/ssh"
 )
@@ -18,8 +23,20 @@ func (s *Server) Broadcast(sender, message string) {
 	defer s.mu.Unlock()
 
 	chatMsg := fmt.Sprintf("<%s> %s", sender, message)
+	s.chatCount++
+	id := s.chatCount
+	signed := s.signMessage(sender, message)
+	color := s.senderColor(sender)
 
 	for _, p := range s.people {
+		if p.Username == sender {
+			p.recordSent(id, message)
+		}
+
+		if signed != nil && p.UNNAware && p.Bus != nil {
+			common.SendOSC(p.Bus, "signed_message", signed)
+		}
+
 		msgType := ui.MsgChat
 		if p.Username == sender {
 			msgType = ui.MsgSelf
@@ -27,12 +44,57 @@ func (s *Server) Broadcast(sender, message string) {
 
 		// Add to UI if available
 		if p.ChatUI != nil {
-			p.ChatUI.AddMessage(chatMsg, msgType)
+			p.ChatUI.AddChatMessage(id, chatMsg, msgType, color)
 		}
 
 		// Add to history (Security: only because they are connected now)
 		pubHash := s.getPubKeyHash(p.PubKey)
-		s.addMessageToHistory(pubHash, ui.Message{Text: chatMsg, Type: msgType})
+		s.addMessageToHistory(pubHash, ui.Message{Text: chatMsg, Type: msgType, Color: color, ID: id})
+	}
+}
+
+// senderColor returns the chosen nick color of the named sender. Caller must hold s.mu.
+func (s *Server) senderColor(sender string) tcell.Color {
+	for _, p := range s.people {
+		if p.Username == sender {
+			return s.colors[s.getPubKeyHash(p.PubKey)]
+		}
+	}
+	return tcell.ColorDefault
+}
+
+// signMessage returns the OSC params for a signed chat message, or nil if
+// signing is disabled. Caller must hold s.mu.
+func (s *Server) signMessage(sender, message string) map[string]interface{} {
+	if !s.signMessages {
+		return nil
+	}
+
+	senderKey := ""
+	for _, p := range s.people {
+		if p.Username == sender {
+			senderKey = s.getPubKeyHash(p.PubKey)
+			break
+		}
+	}
+
+	payload := protocol.SignedMessagePayload{
+		Sender:    sender,
+		SenderKey: senderKey,
+		Text:      message,
+		Timestamp: time.Now().Unix(),
+	}
+	if err := payload.Sign(s.hostKey); err != nil {
+		log.Printf("Failed to sign message: %v", err)
+		return nil
+	}
+
+	return map[string]interface{}{
+		"sender":     payload.Sender,
+		"sender_key": payload.SenderKey,
+		"text":       payload.Text,
+		"timestamp":  payload.Timestamp,
+		"signature":  payload.Signature,
 	}
 }
 
@@ -54,6 +116,39 @@ func (s *Server) broadcastWithHistory(senderPubKey ssh.PublicKey, chatMsg string
 	}
 }
 
+// broadcastNotice sends a leave or door notice to everyone, unless an
+// operator turned on quiet mode
+func (s *Server) broadcastNotice(senderPubKey ssh.PublicKey, msg string) {
+	s.mu.RLock()
+	quiet := s.quietMode
+	s.mu.RUnlock()
+	if quiet {
+		return
+	}
+	s.broadcastWithHistory(senderPubKey, msg, ui.MsgSystem)
+}
+
+// broadcastStaff sends a message from a moderator to every moderator in the
+// room, and keeps it out of everyone else's chat and history
+func (s *Server) broadcastStaff(from *Person, msg string) {
+	text := fmt.Sprintf("[ops] <%s> %s", from.Username, msg)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := make(map[string]bool) // sessions sharing a key share a history
+	for _, p := range s.people {
+		if !s.isModerator(p.PubKey) {
+			continue
+		}
+		if p.ChatUI != nil {
+			p.ChatUI.AddMessage(text, ui.MsgStaff)
+		}
+		if pubHash := s.getPubKeyHash(p.PubKey); !stored[pubHash] {
+			stored[pubHash] = true
+			s.addMessageToHistory(pubHash, ui.Message{Text: text, Type: ui.MsgStaff})
+		}
+	}
+}
+
 func (s *Server) isOperator(pubKey ssh.PublicKey) bool {
 	if pubKey == nil || s.operatorPubKey == nil {
 		return false
@@ -61,6 +156,15 @@ func (s *Server) isOperator(pubKey ssh.PublicKey) bool {
 	return string(pubKey.Marshal()) == string(s.operatorPubKey.Marshal())
 }
 
+// isModerator reports whether pubKey may moderate the room: the operator,
+// or someone holding a /grant
+func (s *Server) isModerator(pubKey ssh.PublicKey) bool {
+	if pubKey == nil {
+		return false
+	}
+	return s.isOperator(pubKey) || s.hasGrant(pubKey)
+}
+
 func (s *Server) getPubKeyHash(pubKey ssh.PublicKey) string {
 	if pubKey == nil {
 		return "anonymous"
@@ -78,6 +182,35 @@ func (s *Server) addMessageToHistory(pubHash string, msg ui.Message) {
 	s.histories[pubHash] = history
 }
 
+// markRead records how far the person has read, for the welcome-back
+// message on their next visit, and how far they had scrolled up when
+// scroll restoring is on. Caller must hold s.mu.
+func (s *Server) markRead(p *Person) {
+	pubHash := s.getPubKeyHash(p.PubKey)
+	s.readMarks[pubHash] = s.chatCount
+	if s.restoreScroll && p.ChatUI != nil {
+		s.scrollMarks[pubHash] = len(p.ChatUI.GetMessages()) - p.ChatUI.LastSeen()
+	}
+}
+
+// unreadCount returns how many chat messages were broadcast since the user
+// last left the room, and whether they were here before. Caller must hold s.mu.
+func (s *Server) unreadCount(pubHash string) (int, bool) {
+	mark, ok := s.readMarks[pubHash]
+	if !ok {
+		return 0, false
+	}
+	return s.chatCount - mark, true
+}
+
+func welcomeBack(username string, unread int) string {
+	noun := "messages"
+	if unread == 1 {
+		noun = "message"
+	}
+	return fmt.Sprintf("*** Welcome back, %s — %d new %s since you left ***", username, unread, noun)
+}
+
 func (s *Server) addCommandToHistory(pubHash string, cmd string) {
 	history := s.cmdHistories[pubHash]
 	// Avoid duplicate consecutive commands
@@ -108,13 +241,36 @@ func (s *Server) HandleOSC(p *Person, action string, params map[string]interface
 }
 
 func (s *Server) calculateHostKeyFingerprint() string {
-	pubKey := s.hostKey.PublicKey()
+	pubKey := s.GetHostKey().PublicKey()
 	algo := strings.ToUpper(strings.TrimPrefix(pubKey.Type(), "ssh-"))
 	hash := sha256.Sum256(pubKey.Marshal())
 	fingerprint := "SHA256:" + base64.StdEncoding.WithPadding(base64.NoPadding).EncodeToString(hash[:])
 	return fmt.Sprintf("%s key fingerprint is %s.", algo, fingerprint)
 }
 
+// GetBanner returns the lines of the room banner (room.asc), or nil if there is none
+func (s *Server) GetBanner() []string {
+	lines, err := banner.Load(s.bannerPath, s.bannerMaxLines, s.bannerMaxBytes, s.bannerEncoding)
+	if err != nil {
+		return nil
+	}
+	return lines
+}
+
+// sendBanner sends the room banner file as a download, unconverted and
+// without the line and size caps applied when it is shown
+func (s *Server) sendBanner(p *Person) (string, error) {
+	info, err := os.Stat(s.bannerPath)
+	if err != nil || !info.Mode().IsRegular() {
+		return "", fmt.Errorf("this room has no banner")
+	}
+	filename := fmt.Sprintf("%s.asc", s.roomName)
+	if err := s.sendFile(p, filename, s.bannerPath); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
+
 func formatSize(b int64) string {
 	const unit = 1024
 	if b < unit {
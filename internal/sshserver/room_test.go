@@ -0,0 +1,101 @@
+package sshserver
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/sshtest"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestUnreadCount(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "unn-unread-test-*")
+	defer os.RemoveAll(tmpDir)
+
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	pubAlice, _, _ := ed25519.GenerateKey(rand.Reader)
+	sshAlice, _ := ssh.NewPublicKey(pubAlice)
+	alice := &Person{SessionID: "alice-1", Username: "alice", ChatUI: ui.NewChatUI(nil), PubKey: sshAlice}
+	bob := &Person{SessionID: "bob-1", Username: "bob", ChatUI: ui.NewChatUI(nil)}
+	aliceHash := s.getPubKeyHash(sshAlice)
+
+	s.people[alice.SessionID] = alice
+	s.people[bob.SessionID] = bob
+
+	if _, returning := s.unreadCount(aliceHash); returning {
+		t.Errorf("First visit reported as returning")
+	}
+
+	s.Broadcast("bob", "before alice leaves")
+
+	// Alice leaves, bob keeps talking
+	s.mu.Lock()
+	delete(s.people, alice.SessionID)
+	s.markRead(alice)
+	s.mu.Unlock()
+
+	s.Broadcast("bob", "one")
+	s.Broadcast("bob", "two")
+	s.Broadcast("bob", "three")
+
+	s.mu.Lock()
+	unread, returning := s.unreadCount(aliceHash)
+	s.mu.Unlock()
+	if !returning {
+		t.Fatalf("Expected alice to be a returning user")
+	}
+	if unread != 3 {
+		t.Errorf("Expected 3 unread messages, got %d", unread)
+	}
+
+	if got := welcomeBack("alice", unread); !strings.Contains(got, "Welcome back, alice") || !strings.Contains(got, "3 new messages") {
+		t.Errorf("Unexpected welcome back message: %q", got)
+	}
+	if got := welcomeBack("alice", 1); !strings.Contains(got, "1 new message since") {
+		t.Errorf("Expected singular message, got %q", got)
+	}
+}
+
+func TestAutoOperator(t *testing.T) {
+	for _, auto := range []bool{true, false} {
+		tmpDir := t.TempDir()
+		s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+		if err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+		waitForLeave(t, s)
+		s.SetHeadless(true)
+		s.SetAutoOperator(auto)
+		signer, err := sshtest.NewSigner()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.AuthorizeKey(signer.PublicKey(), "alice")
+		client, err := sshtest.Dial(s, "alice", signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sess, err := sshtest.StartShell(client, 80, 24)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sess.WaitFor("Type /help for commands", 2*time.Second); err != nil {
+			t.Fatal(err)
+		}
+		if got := s.isOperator(signer.PublicKey()); got != auto {
+			t.Errorf("SetAutoOperator(%v): expected the first key to be operator=%v, got %v", auto, auto, got)
+		}
+		client.Close()
+	}
+}
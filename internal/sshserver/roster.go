@@ -0,0 +1,152 @@
+package sshserver
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// rosterEntry is what the room remembers about a key that ever connected
+type rosterEntry struct {
+	Hash      string    `json:"-"`
+	Username  string    `json:"username"` // name of the latest session
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Operator  bool      `json:"-"`
+	Present   bool      `json:"-"`
+}
+
+// rosterPath returns the file holding the roster, kept next to the host key
+func (s *Server) rosterPath() string {
+	return filepath.Join(filepath.Dir(s.hostKeyPath), s.roomName+".roster")
+}
+
+// loadRoster reads the persisted roster, if any
+func (s *Server) loadRoster() {
+	data, err := os.ReadFile(s.rosterPath())
+	if err != nil {
+		return
+	}
+	roster := make(map[string]*rosterEntry)
+	if err := json.Unmarshal(data, &roster); err != nil {
+		log.Printf("Failed to read roster: %v", err)
+		return
+	}
+	s.roster = roster
+}
+
+// rosterSaveDelay batches roster writes: joins and leaves within this long
+// of the first unsaved change are written to disk together
+var rosterSaveDelay = 5 * time.Second
+
+// saveRoster writes a pending roster change to disk now. The file is
+// written outside s.mu, so joins and leaves do not wait for the disk.
+func (s *Server) saveRoster() error {
+	s.rosterSaveMu.Lock()
+	defer s.rosterSaveMu.Unlock()
+
+	s.mu.Lock()
+	if s.rosterSave == nil {
+		s.mu.Unlock()
+		return nil
+	}
+	s.rosterSave.Stop()
+	s.rosterSave = nil
+	data, err := json.MarshalIndent(s.roster, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.rosterPath(), append(data, '\n'), 0600); err != nil {
+		log.Printf("Failed to save roster: %v", err)
+		return err
+	}
+	return nil
+}
+
+// seenLocked records that p is in the room at now, on joining and on
+// leaving, and schedules a roster write; the caller holds s.mu
+func (s *Server) seenLocked(p *Person, now time.Time) {
+	if p.PubKey == nil {
+		return
+	}
+	hash := s.getPubKeyHash(p.PubKey)
+	e := s.roster[hash]
+	if e == nil {
+		e = &rosterEntry{FirstSeen: now}
+		s.roster[hash] = e
+	}
+	e.Username = p.Username
+	e.LastSeen = now
+	if s.rosterSave == nil {
+		s.rosterSave = time.AfterFunc(rosterSaveDelay, func() { s.saveRoster() })
+	}
+}
+
+// rosterList returns everyone who ever connected, most recently seen first
+func (s *Server) rosterList() []rosterEntry {
+	s.mu.RLock()
+	present := make(map[string]bool, len(s.people))
+	for _, p := range s.people {
+		present[s.getPubKeyHash(p.PubKey)] = true
+	}
+	operatorHash := ""
+	if s.operatorPubKey != nil {
+		operatorHash = s.getPubKeyHash(s.operatorPubKey)
+	}
+	list := make([]rosterEntry, 0, len(s.roster))
+	for hash, e := range s.roster {
+		entry := *e
+		entry.Hash = hash
+		entry.Present = present[hash]
+		entry.Operator = hash == operatorHash
+		list = append(list, entry)
+	}
+	s.mu.RUnlock()
+
+	s.grantsMu.Lock()
+	for i := range list {
+		list[i].Operator = list[i].Operator || s.grants[list[i].Hash] != nil
+	}
+	s.grantsMu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].LastSeen.Equal(list[j].LastSeen) {
+			return list[i].LastSeen.After(list[j].LastSeen)
+		}
+		return list[i].Hash < list[j].Hash
+	})
+	return list
+}
+
+// rosterLine describes one roster entry for the chat
+func rosterLine(e rosterEntry) string {
+	name := e.Username
+	if e.Operator {
+		name = "@" + name
+	}
+	lastSeen := e.LastSeen.Format("2006-01-02 15:04")
+	if e.Present {
+		lastSeen = "now"
+	}
+	return fmt.Sprintf("%s (%s) first seen %s, last seen %s", name, e.Hash[:8], e.FirstSeen.Format("2006-01-02 15:04"), lastSeen)
+}
+
+// encodeRoster renders the roster as a CSV download
+func encodeRoster(list []rosterEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"username", "hash", "first_seen", "last_seen", "operator", "present"})
+	for _, e := range list {
+		w.Write([]string{e.Username, e.Hash, e.FirstSeen.Format(time.RFC3339), e.LastSeen.Format(time.RFC3339),
+			fmt.Sprint(e.Operator), fmt.Sprint(e.Present)})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
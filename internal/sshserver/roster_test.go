@@ -0,0 +1,130 @@
+package sshserver
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/sshtest"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"github.com/mevdschee/underground-node-network/internal/ui/bridge"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestRoster(t *testing.T) {
+	tmpDir := t.TempDir()
+	hostKeyPath := filepath.Join(tmpDir, "host_key")
+	s, err := NewServer("127.0.0.1:0", hostKeyPath, "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer s.Stop()
+	s.SetHeadless(true)
+
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	opKey, _ := ssh.NewPublicKey(pub)
+	ch := &captureChannel{}
+	op := &Person{Username: "op", SessionID: "op", PubKey: opKey, ChatUI: ui.NewChatUI(nil), UNNAware: true,
+		Bus: bridge.NewSSHBus(bridge.NewInputBridge(ch), 80, 24)}
+	s.operatorPubKey = opKey
+	s.mu.Lock()
+	s.people[op.SessionID] = op
+	s.seenLocked(op, time.Now().Add(-time.Hour))
+	s.mu.Unlock()
+
+	// bob visits and leaves again
+	signer, err := sshtest.NewSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AuthorizeKey(signer.PublicKey(), "bob")
+	client, err := sshtest.Dial(s, "bob", signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := sshtest.StartShell(client, 80, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sess.WaitFor("Type /help for commands", 2*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+	for deadline := time.Now().Add(2 * time.Second); len(s.GetPeople()) > 1 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	bobHash := s.getPubKeyHash(signer.PublicKey())[:8]
+	opHash := s.getPubKeyHash(opKey)[:8]
+
+	roster := func(p *Person) []string {
+		before := len(p.ChatUI.GetMessages())
+		s.handleInternalCommand(p, "/roster")
+		var lines []string
+		for _, m := range p.ChatUI.GetMessages()[before+1:] {
+			lines = append(lines, m.Text)
+		}
+		return lines
+	}
+
+	t.Run("includes people who left", func(t *testing.T) {
+		lines := roster(op)
+		if len(lines) != 3 || lines[0] != "--- Roster (2 people) ---" {
+			t.Fatalf("Expected a roster of 2 people, got %q", lines)
+		}
+		if !strings.Contains(lines[1], "bob ("+bobHash+") first seen ") || strings.HasSuffix(lines[1], "last seen now") {
+			t.Errorf("Expected bob first, seen before, got %q", lines[1])
+		}
+		if !strings.Contains(lines[2], "@op ("+opHash+")") || !strings.HasSuffix(lines[2], "last seen now") {
+			t.Errorf("Expected the operator to be present, got %q", lines[2])
+		}
+	})
+
+	t.Run("operator only", func(t *testing.T) {
+		visitor := &Person{Username: "eve", SessionID: "eve", ChatUI: ui.NewChatUI(nil)}
+		s.handleInternalCommand(visitor, "/roster")
+		msgs := visitor.ChatUI.GetMessages()
+		if got := msgs[len(msgs)-1].Text; got != "You do not have operator privileges." {
+			t.Errorf("Expected a denial, got %q", got)
+		}
+	})
+
+	t.Run("csv export", func(t *testing.T) {
+		s.handleInternalCommand(op, "/roster csv")
+		msgs := op.ChatUI.GetMessages()
+		if got := msgs[len(msgs)-1].Text; got != "Sent testroom-roster.csv (2 people) to your downloads." {
+			t.Errorf("Expected a confirmation, got %q", got)
+		}
+		if out := ch.String(); !strings.Contains(out, "transfer_block") || !strings.Contains(out, "testroom-roster.csv") {
+			t.Errorf("Expected the roster to be sent, got %q", out)
+		}
+	})
+
+	t.Run("writes are batched", func(t *testing.T) {
+		if _, err := os.Stat(s.rosterPath()); err == nil {
+			t.Fatal("Expected joins and leaves not to be written right away")
+		}
+		if err := s.saveRoster(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(s.rosterPath()); err != nil {
+			t.Errorf("Expected the pending changes to be written, got %v", err)
+		}
+	})
+
+	t.Run("persists across restarts", func(t *testing.T) {
+		s2, err := NewServer("127.0.0.1:0", hostKeyPath, "testroom", doors.NewManager(tmpDir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s2.Stop()
+		list := s2.rosterList()
+		if len(list) != 2 || list[0].Username != "bob" || list[1].Username != "op" {
+			t.Errorf("Expected bob and op to be remembered, got %+v", list)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package sshserver
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// scheduledAction is a command an operator queued with /schedule. It runs
+// as that operator, through the same path as typed commands.
+type scheduledAction struct {
+	ID    int
+	Cmd   string
+	By    *Person
+	Due   time.Time
+	timer *time.Timer
+}
+
+// schedule queues cmd to run as p after delay. Scheduling commands cannot
+// be scheduled themselves.
+func (s *Server) schedule(p *Person, delay time.Duration, cmd string) (*scheduledAction, error) {
+	if delay <= 0 {
+		return nil, fmt.Errorf("the delay must be positive")
+	}
+	prefix := s.commandPrefix()
+	if !strings.HasPrefix(cmd, prefix) {
+		return nil, fmt.Errorf("the action must be a command starting with %s", prefix)
+	}
+	switch name, _, _ := strings.Cut(strings.TrimPrefix(cmd, prefix), " "); name {
+	case "", "schedule", "scheduled", "unschedule":
+		return nil, fmt.Errorf("cannot schedule %s", cmd)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scheduleSeq++
+	a := &scheduledAction{ID: s.scheduleSeq, Cmd: cmd, By: p, Due: time.Now().Add(delay)}
+	a.timer = time.AfterFunc(delay, func() { s.runScheduled(a) })
+	s.schedules = append(s.schedules, a)
+	return a, nil
+}
+
+// runScheduled runs a due action unless it was cancelled meanwhile
+func (s *Server) runScheduled(a *scheduledAction) {
+	if !s.removeSchedule(a.ID) {
+		return
+	}
+	log.Printf("Running action #%d scheduled by %s: %s", a.ID, a.By.Username, a.Cmd)
+	if !s.handleInternalCommand(a.By, a.Cmd) {
+		// Doors need a terminal, which a timer does not have
+		log.Printf("Scheduled action #%d is not a room command, skipped", a.ID)
+	}
+}
+
+// unschedule cancels the pending action with the given id
+func (s *Server) unschedule(id int) error {
+	if !s.removeSchedule(id) {
+		return fmt.Errorf("no scheduled action #%d", id)
+	}
+	return nil
+}
+
+// removeSchedule stops and removes the action with the given id and reports
+// whether it was still pending
+func (s *Server) removeSchedule(id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, a := range s.schedules {
+		if a.ID == id {
+			a.timer.Stop()
+			s.schedules = append(s.schedules[:i], s.schedules[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// scheduledList describes the pending actions, soonest first
+func (s *Server) scheduledList(now time.Time) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	pending := make([]*scheduledAction, len(s.schedules))
+	copy(pending, s.schedules)
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Due.Before(pending[j].Due) })
+	lines := make([]string, 0, len(pending))
+	for _, a := range pending {
+		left := a.Due.Sub(now).Round(time.Second)
+		lines = append(lines, fmt.Sprintf("#%d in %s: %s (by @%s)", a.ID, max(left, 0), a.Cmd, a.By.Username))
+	}
+	return lines
+}
+
+// stopSchedules cancels all pending actions
+func (s *Server) stopSchedules() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range s.schedules {
+		a.timer.Stop()
+	}
+	s.schedules = nil
+}
@@ -0,0 +1,115 @@
+package sshserver
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSchedule(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer s.Stop()
+	newKey := func() ssh.PublicKey {
+		pub, _, _ := ed25519.GenerateKey(rand.Reader)
+		key, _ := ssh.NewPublicKey(pub)
+		return key
+	}
+	op := &Person{Username: "op", SessionID: "op", PubKey: newKey(), ChatUI: ui.NewChatUI(nil)}
+	bob := &Person{Username: "bob", SessionID: "bob", PubKey: newKey(), ChatUI: ui.NewChatUI(nil)}
+	s.operatorPubKey = op.PubKey
+	s.people[op.SessionID] = op
+	s.people[bob.SessionID] = bob
+
+	lastMessage := func(p *Person) string {
+		msgs := p.ChatUI.GetMessages()
+		return msgs[len(msgs)-1].Text
+	}
+	waitFor := func(p *Person, text string) bool {
+		for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			for _, m := range p.ChatUI.GetMessages() {
+				if m.Text == text {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	t.Run("operator only", func(t *testing.T) {
+		s.handleInternalCommand(bob, "/schedule 1m /announce hi")
+		if got := lastMessage(bob); got != "You do not have operator privileges." {
+			t.Errorf("Expected a denial, got %q", got)
+		}
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		for cmd, want := range map[string]string{
+			"/schedule 10m":               "Usage: /schedule <delay> <command> (e.g. /schedule 10m /announce closing soon)",
+			"/schedule soon /announce hi": "Error: invalid delay: soon",
+			"/schedule -1m /announce hi":  "Error: the delay must be positive",
+			"/schedule 1m hello":          "Error: the action must be a command starting with /",
+			"/schedule 1m /unschedule 1":  "Error: cannot schedule /unschedule 1",
+		} {
+			s.handleInternalCommand(op, cmd)
+			if got := lastMessage(op); got != want {
+				t.Errorf("%s: expected %q, got %q", cmd, want, got)
+			}
+		}
+	})
+
+	t.Run("runs through the command path", func(t *testing.T) {
+		s.handleInternalCommand(op, "/schedule 50ms /announce closing soon")
+		if got := lastMessage(op); !strings.HasPrefix(got, "Scheduled #1 in 50ms: /announce closing soon") {
+			t.Errorf("Expected a confirmation, got %q", got)
+		}
+		if !waitFor(bob, "*** Announcement from @op: closing soon ***") {
+			t.Error("Expected the announcement to reach bob")
+		}
+		if lines := s.scheduledList(time.Now()); len(lines) != 0 {
+			t.Errorf("Expected the action to be removed after running, got %q", lines)
+		}
+	})
+
+	t.Run("list and cancel", func(t *testing.T) {
+		s.handleInternalCommand(op, "/schedule 1h /kickall maintenance")
+		s.handleInternalCommand(op, "/schedule 10m /announce closing soon")
+		s.handleInternalCommand(op, "/scheduled")
+		msgs := op.ChatUI.GetMessages()
+		got := []string{msgs[len(msgs)-2].Text, msgs[len(msgs)-1].Text}
+		if !strings.HasPrefix(got[0], "#3 in ") || !strings.HasSuffix(got[0], ": /announce closing soon (by @op)") ||
+			!strings.HasPrefix(got[1], "#2 in ") || !strings.HasSuffix(got[1], ": /kickall maintenance (by @op)") {
+			t.Errorf("Expected both actions, soonest first, got %q", got)
+		}
+
+		s.handleInternalCommand(op, "/unschedule 2")
+		if got := lastMessage(op); got != "Cancelled scheduled command #2." {
+			t.Errorf("Expected a cancellation, got %q", got)
+		}
+		s.handleInternalCommand(op, "/unschedule 2")
+		if got := lastMessage(op); got != "Error: no scheduled action #2" {
+			t.Errorf("Expected an error for a cancelled action, got %q", got)
+		}
+		if lines := s.scheduledList(time.Now()); len(lines) != 1 || !strings.HasPrefix(lines[0], "#3 ") {
+			t.Errorf("Expected only #3 to be left, got %q", lines)
+		}
+	})
+
+	t.Run("stop cancels everything", func(t *testing.T) {
+		s.stopSchedules()
+		s.handleInternalCommand(op, "/scheduled")
+		if got := lastMessage(op); got != "Nothing is scheduled." {
+			t.Errorf("Expected an empty list, got %q", got)
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package sshserver
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/ui"
+)
+
+// offerTTL is how long a /send offer waits for /accept by default
+var offerTTL = 2 * time.Minute
+
+// fileOffer is a room file one person offered to another with /send
+type fileOffer struct {
+	From     string
+	FromHash string // pubkey hash of the sender, to tell them when it expires
+	Name     string // as given to /send, used as the download name
+	Path     string
+	Expires  time.Time
+	timer    *time.Timer // expires the offer if it is never accepted
+}
+
+// SetOfferTimeout sets how long a /send offer waits for /accept before both
+// people are told it expired (0 = the default of 2 minutes)
+func (s *Server) SetOfferTimeout(d time.Duration) {
+	s.mu.Lock()
+	s.offerTimeout = d
+	s.mu.Unlock()
+}
+
+// offerFile offers a room file to the person named target. Only the target's
+// key can accept it, and the transfer goes to their session alone.
+func (s *Server) offerFile(from *Person, target, name string) (*Person, error) {
+	to := s.findPerson(target)
+	if to == nil {
+		return nil, fmt.Errorf("user not found: %s", target)
+	}
+	if to == from {
+		return nil, fmt.Errorf("you cannot send a file to yourself")
+	}
+	if !to.UNNAware || to.Bus == nil {
+		return nil, fmt.Errorf("%s needs the UNN client to receive files", to.Username)
+	}
+
+	fullPath, err := s.resolveFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(fullPath); err != nil || info.IsDir() {
+		return nil, fmt.Errorf("file not found: %s", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ttl := s.offerTimeout
+	if ttl <= 0 {
+		ttl = offerTTL
+	}
+	hash := s.getPubKeyHash(to.PubKey)
+	if old := s.offers[hash]; old != nil {
+		old.timer.Stop()
+	}
+	offer := &fileOffer{
+		From:     from.Username,
+		FromHash: s.getPubKeyHash(from.PubKey),
+		Name:     path.Clean(strings.TrimPrefix(name, "/")),
+		Path:     fullPath,
+		Expires:  time.Now().Add(ttl),
+	}
+	offer.timer = time.AfterFunc(ttl, func() { s.expireOffer(hash, offer) })
+	s.offers[hash] = offer
+	return to, nil
+}
+
+// expireOffer drops an offer that was not accepted in time and tells both
+// people the file was not transferred, also in their history in case they
+// are reconnecting
+func (s *Server) expireOffer(hash string, offer *fileOffer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.offers[hash] != offer {
+		return // accepted or replaced meanwhile
+	}
+	delete(s.offers, hash)
+
+	notices := map[string]string{
+		hash:           fmt.Sprintf("The offer of %s from %s expired, the file was not transferred.", offer.Name, offer.From),
+		offer.FromHash: fmt.Sprintf("Your offer of %s was not accepted in time, the file was not transferred.", offer.Name),
+	}
+	for pubHash, text := range notices {
+		s.addMessageToHistory(pubHash, ui.Message{Text: text, Type: ui.MsgServer})
+	}
+	for _, p := range s.people {
+		if text, ok := notices[s.getPubKeyHash(p.PubKey)]; ok && p.ChatUI != nil {
+			p.ChatUI.AddMessage(text, ui.MsgServer)
+		}
+	}
+}
+
+// stopOffers cancels the expiry timers of all pending offers
+func (s *Server) stopOffers() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for hash, offer := range s.offers {
+		offer.timer.Stop()
+		delete(s.offers, hash)
+	}
+}
+
+// takeOffer returns and removes the pending offer for the person
+func (s *Server) takeOffer(p *Person) (*fileOffer, error) {
+	hash := s.getPubKeyHash(p.PubKey)
+	s.mu.Lock()
+	offer := s.offers[hash]
+	delete(s.offers, hash)
+	s.mu.Unlock()
+	if offer != nil {
+		offer.timer.Stop()
+	}
+
+	if offer == nil {
+		return nil, fmt.Errorf("nobody has offered you a file")
+	}
+	if time.Now().After(offer.Expires) {
+		return nil, fmt.Errorf("the offer of %s from %s expired", offer.Name, offer.From)
+	}
+	return offer, nil
+}
+
+// sendFile streams a file to a UNN-aware client as a download, using the
+// same OSC blocks as the files door
+func (s *Server) sendFile(p *Person, filename, fullPath string) error {
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	checksum, err := s.fileChecksum(fullPath, info)
+	if err != nil {
+		return err
+	}
+
+	count := int((info.Size() + transferBlockSize - 1) / transferBlockSize)
+	if count == 0 {
+		count = 1
+	}
+	transferID := newTransferID(filename)
+	buf := make([]byte, transferBlockSize)
+	for i := 0; i < count; i++ {
+		n, err := io.ReadFull(f, buf)
+		if err != nil && err != io.ErrUnexpectedEOF && !(err == io.EOF && info.Size() == 0) {
+			return fmt.Errorf("failed to read %s: %w", filename, err)
+		}
+		s.sendTransferBlock(p, filename, transferID, count, i, checksum, buf[:n])
+	}
+	return nil
+}
+
+// transferBlockSize is the payload size of one transfer_block OSC
+const transferBlockSize = 8192
+
+func newTransferID(filename string) string {
+	id := sha256.Sum256([]byte(time.Now().String() + filename))
+	return hex.EncodeToString(id[:])[:16]
+}
+
+func (s *Server) sendTransferBlock(p *Person, filename, id string, count, index int, checksum string, data []byte) {
+	s.SendOSC(p, "transfer_block", map[string]interface{}{
+		"filename": filename,
+		"id":       id,
+		"count":    count,
+		"index":    index,
+		"checksum": checksum,
+		"data":     base64.StdEncoding.EncodeToString(data),
+	})
+}
@@ -0,0 +1,171 @@
+package sshserver
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"github.com/mevdschee/underground-node-network/internal/ui/bridge"
+	"golang.org/x/crypto/ssh"
+)
+
+// captureChannel records what the room writes to a session
+type captureChannel struct {
+	mockChannel
+	mu  sync.Mutex
+	out bytes.Buffer
+}
+
+func (c *captureChannel) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.out.Write(p)
+}
+
+func (c *captureChannel) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.out.String()
+}
+
+func TestSendFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	filesDir := filepath.Join(tmpDir, "files")
+	os.Mkdir(filesDir, 0755)
+	os.WriteFile(filepath.Join(filesDir, "notes.txt"), []byte("hello"), 0644)
+
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	s.SetFileRoots([]string{filesDir})
+
+	channels := make(map[string]*captureChannel)
+	newPerson := func(name string, unnAware bool) *Person {
+		pub, _, _ := ed25519.GenerateKey(rand.Reader)
+		sshPub, _ := ssh.NewPublicKey(pub)
+		ch := &captureChannel{}
+		channels[name] = ch
+		p := &Person{Username: name, ChatUI: ui.NewChatUI(nil), PubKey: sshPub, UNNAware: unnAware,
+			Bus: bridge.NewSSHBus(bridge.NewInputBridge(ch), 80, 24)}
+		s.people[name] = p
+		return p
+	}
+	alice := newPerson("alice", true)
+	bob := newPerson("bob", true)
+	carol := newPerson("carol", false)
+
+	lastMessage := func(p *Person) string {
+		msgs := p.ChatUI.GetMessages()
+		return msgs[len(msgs)-1].Text
+	}
+
+	t.Run("offer", func(t *testing.T) {
+		s.handleInternalCommand(alice, "/send bob notes.txt")
+		if got := lastMessage(bob); !strings.Contains(got, "alice wants to send you notes.txt, accept with /accept") {
+			t.Errorf("Expected bob to be prompted, got %q", got)
+		}
+		if strings.Contains(channels["bob"].String(), "transfer_block") {
+			t.Error("Expected nothing to be sent before /accept")
+		}
+	})
+
+	t.Run("only the target can accept", func(t *testing.T) {
+		s.handleInternalCommand(carol, "/accept")
+		if got := lastMessage(carol); !strings.Contains(got, "nobody has offered you a file") {
+			t.Errorf("Expected carol to have no offer, got %q", got)
+		}
+		s.handleInternalCommand(alice, "/accept")
+		if strings.Contains(channels["alice"].String(), "transfer_block") {
+			t.Error("Expected the sender not to receive the file")
+		}
+	})
+
+	t.Run("accept sends to the target only", func(t *testing.T) {
+		s.handleInternalCommand(bob, "/accept")
+		out := channels["bob"].String()
+		if !strings.Contains(out, "transfer_block") || !strings.Contains(out, "notes.txt") {
+			t.Errorf("Expected bob to receive the file, got %q", out)
+		}
+		if strings.Contains(channels["carol"].String(), "transfer_block") {
+			t.Error("Expected the file not to be broadcast")
+		}
+		s.handleInternalCommand(bob, "/accept")
+		if got := lastMessage(bob); !strings.Contains(got, "nobody has offered you a file") {
+			t.Errorf("Expected the offer to be used up, got %q", got)
+		}
+	})
+
+	t.Run("needs the UNN client", func(t *testing.T) {
+		s.handleInternalCommand(alice, "/send carol notes.txt")
+		if got := lastMessage(alice); !strings.Contains(got, "needs the UNN client") {
+			t.Errorf("Expected a refusal, got %q", got)
+		}
+	})
+
+	t.Run("expires", func(t *testing.T) {
+		s.SetOfferTimeout(20 * time.Millisecond)
+		defer s.SetOfferTimeout(0)
+		s.handleInternalCommand(alice, "/send bob notes.txt")
+		deadline := time.Now().Add(2 * time.Second)
+		for !strings.Contains(lastMessage(bob), "expired") && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if got := lastMessage(bob); got != "The offer of notes.txt from alice expired, the file was not transferred." {
+			t.Fatalf("Expected bob to be told the offer expired, got %q", got)
+		}
+		if got := lastMessage(alice); got != "Your offer of notes.txt was not accepted in time, the file was not transferred." {
+			t.Errorf("Expected alice to be told the file was not sent, got %q", got)
+		}
+		s.mu.RLock()
+		pending := len(s.offers)
+		s.mu.RUnlock()
+		if pending != 0 {
+			t.Errorf("Expected the expired offer to be cleaned up, got %d pending", pending)
+		}
+		s.handleInternalCommand(bob, "/accept")
+		if got := lastMessage(bob); !strings.Contains(got, "nobody has offered you a file") {
+			t.Errorf("Expected nothing left to accept, got %q", got)
+		}
+		history := s.histories[s.getPubKeyHash(bob.PubKey)]
+		found := false
+		for _, m := range history {
+			found = found || strings.Contains(m.Text, "expired, the file was not transferred")
+		}
+		if !found {
+			t.Error("Expected the expiry notice in bob's history for a reconnect")
+		}
+	})
+
+	t.Run("accepted offers do not expire", func(t *testing.T) {
+		s.SetOfferTimeout(50 * time.Millisecond)
+		defer s.SetOfferTimeout(0)
+		s.handleInternalCommand(alice, "/send bob notes.txt")
+		s.handleInternalCommand(bob, "/accept")
+		time.Sleep(100 * time.Millisecond)
+		if got := lastMessage(alice); strings.Contains(got, "not accepted in time") {
+			t.Errorf("Expected no expiry notice after /accept, got %q", got)
+		}
+	})
+
+	t.Run("stop cancels pending offers", func(t *testing.T) {
+		s.SetOfferTimeout(time.Hour)
+		defer s.SetOfferTimeout(0)
+		s.handleInternalCommand(alice, "/send bob notes.txt")
+		s.Stop()
+		s.mu.RLock()
+		pending := len(s.offers)
+		s.mu.RUnlock()
+		if pending != 0 {
+			t.Errorf("Expected Stop to drop pending offers, got %d", pending)
+		}
+	})
+}
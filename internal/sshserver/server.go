@@ -3,6 +3,7 @@ package sshserver
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/base64"
 	"fmt"
 	"io"
@@ -17,11 +18,16 @@ import (
 	"github.com/gdamore/tcell/v2"
 	"github.com/mevdschee/p2pquic-go/pkg/p2pquic"
 	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/keystrength"
+	"github.com/mevdschee/underground-node-network/internal/logfile"
 	"github.com/mevdschee/underground-node-network/internal/nat"
+	"github.com/mevdschee/underground-node-network/internal/protocol"
+	"github.com/mevdschee/underground-node-network/internal/sshcompress"
 	"github.com/mevdschee/underground-node-network/internal/ui"
+	"github.com/mevdschee/underground-node-network/internal/ui/banner"
 	"github.com/mevdschee/underground-node-network/internal/ui/bridge"
 	"github.com/mevdschee/underground-node-network/internal/ui/common"
-	"github.com/mevdschee/underground-node-network/internal/ui/password"
+	"github.com/quic-go/quic-go"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -35,6 +41,13 @@ type Person struct {
 	Bridge     *bridge.InputBridge
 	PubKey     ssh.PublicKey // The specific key used for auth
 	QuitReason string
+	UNNAware   bool      // Connected with the UNN client (understands OSC 31337)
+	JoinedAt   time.Time // When this session connected
+	Transport  string    // How the session reached the room, e.g. "p2p direct (QUIC)"
+	KeyWarning string    // Set when the key is weaker than -min-key-strength allows
+
+	sent       []sentMessage // recent chat messages, newest last, guarded by Server.mu
+	lastActive time.Time     // last chat, command or keystroke, guarded by Server.mu
 }
 
 type Server struct {
@@ -45,15 +58,78 @@ type Server struct {
 	people         map[string]*Person
 	authorizedKeys map[string]string // Marshaled pubkey -> verified username
 	hostKey        ssh.Signer
+	hostKeyPath    string
 	mu             sync.RWMutex
-	p2pPeer        *p2pquic.Peer // p2pquic peer for connections
+	p2pPeer        *p2pquic.Peer  // p2pquic peer for connections
+	quicLn         *quic.Listener // QUIC listener on the peer's socket
+	quicTLS        *tls.Config    // certificate and session tickets of quicLn
+	quicTuning     nat.QUICTuning // QUIC transport parameters of quicLn
+	tcpLn          net.Listener   // optional plain TCP listener, see ListenTCP
 	headless       bool
-	histories      map[string][]ui.Message // keyed by pubkey hash (hex)
-	cmdHistories   map[string][]string     // keyed by pubkey hash (hex)
-	bannedHashes   map[string]string       // hash -> reason
+	histories      map[string][]ui.Message   // keyed by pubkey hash (hex)
+	cmdHistories   map[string][]string       // keyed by pubkey hash (hex)
+	bannedHashes   map[string]string         // hash -> reason
+	typing         map[string]time.Time      // session ID -> typing indicator expiry
+	fileRoots      map[string]string         // folder name -> directory ("" for a single flat root)
+	signMessages   bool                      // Sign chat messages for UNN-aware clients
+	clipboard      bool                      // Copy /geturl output to UNN-aware clients' clipboards
+	colors         map[string]tcell.Color    // pubkey hash -> chosen nick color
+	markdown       map[string]bool           // pubkey hash -> renders markdown in chat
+	chatCount      int                       // chat messages broadcast so far
+	startedAt      time.Time                 // when the room was created, for /stats
+	readMarks      map[string]int            // pubkey hash -> chatCount when the user left
+	scrollMarks    map[string]int            // pubkey hash -> messages below the view when the user left
+	restoreScroll  bool                      // restore the scroll position when a user rejoins
+	theme          protocol.ThemePayload     // Room branding pushed on join
+	themeAccent    tcell.Color               // Parsed theme.Accent
+	bannerPath     string                    // room banner, room.asc in the working directory
+	bannerMaxLines int                       // cap on room.asc lines (0 = no limit)
+	bannerMaxBytes int                       // cap on room.asc size (0 = no limit)
+	bannerEncoding banner.Encoding           // character encoding of room.asc
+	motd           string                    // operator-set message of the day
+	quietMode      bool                      // suppress leave/door/whisper notices
+	pasteMode      ui.PasteMode              // handling of multi-line bracketed pastes
+	cmdPrefix      string                    // marks chat input as a command
+	bandwidth      *bandwidthMeter           // bytes carried by visitor connections
+	bandwidthLog   time.Duration             // how often to log throughput (0 = never)
+	offers         map[string]*fileOffer     // pubkey hash of the recipient -> pending /send
+	offerTimeout   time.Duration             // how long a /send offer waits (0 = offerTTL)
+	poll           *poll                     // active /poll, nil if none
+	schedules      []*scheduledAction        // pending /schedule actions, oldest first
+	scheduleSeq    int                       // id of the last /schedule action
+	grants         map[string]*operatorGrant // pubkey hash -> temporary operator from /grant
+	grantsMu       sync.Mutex                // guards grants, as isOperator runs with and without mu
+	checksums      map[string]cachedChecksum // file path -> SHA-256 cached by size and mtime
+	indexInterval  time.Duration             // how often to rebuild the file index (0 = no index)
+	fileIdx        *fileIndex                // in-memory listing of the file roots
+	indexMu        sync.Mutex                // serializes index rebuilds
+	indexing       bool                      // indexLoop is running
+	rebind         bool                      // recreate the QUIC listener if its socket fails
+	autoOperator   bool                      // the first key to join becomes the operator
+	compress       bool                      // compress session output for clients that ask
+	firstMsgGate   FirstMessageGate          // handling of the first message of a new key
+	knownKeys      map[string]bool           // pubkey hashes whose chat is no longer gated
+	held           map[string]*heldMessage   // pubkey hash -> first message held by the gate
+	writeDelay     time.Duration             // how long output is coalesced (0 = unbuffered)
+	bookmarks      map[string][]string       // pubkey hash -> bookmarked file names
+	roster         map[string]*rosterEntry   // pubkey hash -> first and last seen, for /roster
+	rosterSave     *time.Timer               // pending roster write, see seenLocked
+	rosterSaveMu   sync.Mutex                // serializes roster file writes
+	maxUsername    int                       // usernames are cut to this many characters (0 = no limit)
+	logRing        *logfile.Ring             // recent log lines shown by /log (nil = not kept)
+	downloadPerm   Permission                // who may download files
+	doorPerm       Permission                // who may open doors
+	verifiedKeys   map[string]bool           // marshaled pubkeys of verified UNN identities
+	keyPolicy      keystrength.Policy        // how keys weaker than the minimum are handled
+	stopped        bool                      // Stop was called
 	roomLockKey    string
 	operatorPubKey ssh.PublicKey
 	OnPeopleChange func(int)
+	// onHostKeyChange is called after /rotate-key with the new public key
+	onHostKeyChange func(ssh.PublicKey)
+	// onRebind is called with the new port after the QUIC listener was
+	// recreated because its socket failed
+	onRebind func(port int)
 }
 
 func NewServer(address, hostKeyPath, roomName string, doorManager *doors.Manager) (*Server, error) {
@@ -61,13 +137,53 @@ func NewServer(address, hostKeyPath, roomName string, doorManager *doors.Manager
 		address:        address,
 		doorManager:    doorManager,
 		roomName:       roomName,
+		hostKeyPath:    hostKeyPath,
 		people:         make(map[string]*Person),
+		startedAt:      time.Now(),
 		authorizedKeys: make(map[string]string),
+		verifiedKeys:   make(map[string]bool),
 		histories:      make(map[string][]ui.Message),
 		cmdHistories:   make(map[string][]string),
 		bannedHashes:   make(map[string]string),
+		quicTuning:     nat.DefaultQUICTuning,
+		typing:         make(map[string]time.Time),
+		fileRoots:      map[string]string{"": "./room_files"},
+		colors:         make(map[string]tcell.Color),
+		markdown:       make(map[string]bool),
+		readMarks:      make(map[string]int),
+		scrollMarks:    make(map[string]int),
+		bannerPath:     "room.asc",
+		bannerMaxLines: banner.DefaultMaxLines,
+		bannerMaxBytes: banner.DefaultMaxBytes,
+		bannerEncoding: banner.EncodingAuto,
+		cmdPrefix:      "/",
+		bandwidth:      newBandwidthMeter(),
+		offers:         make(map[string]*fileOffer),
+		grants:         make(map[string]*operatorGrant),
+		knownKeys:      make(map[string]bool),
+		held:           make(map[string]*heldMessage),
+		roster:         make(map[string]*rosterEntry),
+		maxUsername:    DefaultMaxUsernameLength,
+		rebind:         true,
+		autoOperator:   true,
 	}
 
+	// Load or generate host key
+	hostKey, err := loadOrGenerateHostKey(hostKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load host key: %w", err)
+	}
+	s.hostKey = hostKey
+	s.config = s.newSSHConfig(hostKey)
+	s.loadMOTD()
+	s.loadBookmarks()
+	s.loadKnownKeys()
+	s.loadRoster()
+	return s, nil
+}
+
+// newSSHConfig builds the SSH server configuration around a host key
+func (s *Server) newSSHConfig(hostKey ssh.Signer) *ssh.ServerConfig {
 	config := &ssh.ServerConfig{
 		NoClientAuth: false,
 	}
@@ -80,27 +196,27 @@ func NewServer(address, hostKeyPath, roomName string, doorManager *doors.Manager
 		if _, ok := s.authorizedKeys[string(marshaled)]; !ok {
 			return nil, fmt.Errorf("public key not authorized for this room")
 		}
+		warning, err := s.keyPolicy.Check(pubKey)
+		if err != nil {
+			log.Printf("Refused key for %s: %v", c.User(), err)
+			return nil, err
+		}
 
 		return &ssh.Permissions{
 			Extensions: map[string]string{
-				"pubkey": base64.StdEncoding.EncodeToString(marshaled),
+				"pubkey":  base64.StdEncoding.EncodeToString(marshaled),
+				"weakkey": warning,
 			},
 		}, nil
 	}
 
-	// Load or generate host key
-	hostKey, err := loadOrGenerateHostKey(hostKeyPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load host key: %w", err)
-	}
 	config.AddHostKey(hostKey)
-	s.hostKey = hostKey
-
-	s.config = config
-	return s, nil
+	return config
 }
 
 func (s *Server) GetHostKey() ssh.Signer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.hostKey
 }
 
@@ -108,15 +224,96 @@ func (s *Server) SetHeadless(headless bool) {
 	s.headless = headless
 }
 
+// SetAutoOperator sets whether the first key to join becomes the operator
+// (on by default). Rooms without an operator cannot be administered.
+func (s *Server) SetAutoOperator(enabled bool) {
+	s.mu.Lock()
+	s.autoOperator = enabled
+	s.mu.Unlock()
+}
+
+// SetCompression lets clients started with -compress-ssh have their
+// session output deflate-compressed. Other clients are served uncompressed.
+func (s *Server) SetCompression(on bool) {
+	s.mu.Lock()
+	s.compress = on
+	s.mu.Unlock()
+}
+
+// SetKeyPolicy sets how keys weaker than the minimum strength are handled:
+// accepted, accepted with a warning, or refused
+func (s *Server) SetKeyPolicy(policy keystrength.Policy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keyPolicy = policy
+}
+
+// SetSignMessages enables signing chat messages with the room host key
+func (s *Server) SetSignMessages(sign bool) {
+	s.signMessages = sign
+}
+
+// SetClipboard enables copying /geturl download details to the clipboard of
+// UNN-aware clients using OSC 52
+func (s *Server) SetClipboard(enabled bool) {
+	s.clipboard = enabled
+}
+
+// SetRestoreScroll makes a rejoining user's chat pane scroll back to where
+// it was when they left, instead of to the newest message
+func (s *Server) SetRestoreScroll(enabled bool) {
+	s.restoreScroll = enabled
+}
+
+// SetLogRing lets operators read the room's recent log lines with /log
+func (s *Server) SetLogRing(r *logfile.Ring) {
+	s.logRing = r
+}
+
+// SetWriteDelay sets how long small writes to a visitor's terminal are
+// collected before being sent as one (0 sends every write right away)
+func (s *Server) SetWriteDelay(d time.Duration) {
+	s.writeDelay = d
+}
+
+// SetPasteMode sets how multi-line pastes into the chat input are handled
+func (s *Server) SetPasteMode(mode ui.PasteMode) {
+	s.pasteMode = mode
+}
+
+// SetCommandPrefix sets what chat input must start with to be a command
+// (default "/"), for communities that use "/" for something else
+func (s *Server) SetCommandPrefix(prefix string) error {
+	if prefix == "" || strings.ContainsAny(prefix, " \t\r\n") {
+		return fmt.Errorf("command prefix must be non-empty and without spaces")
+	}
+	s.cmdPrefix = prefix
+	return nil
+}
+
+// SetBannerLimits caps the size of room.asc (0 means no limit)
+func (s *Server) SetBannerLimits(maxLines, maxBytes int) {
+	s.bannerMaxLines = maxLines
+	s.bannerMaxBytes = maxBytes
+}
+
+// SetBannerEncoding sets the character encoding room.asc is read in
+func (s *Server) SetBannerEncoding(enc banner.Encoding) {
+	s.bannerEncoding = enc
+}
+
 func (s *Server) AuthorizeKey(pubKey ssh.PublicKey, username string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	username = normalizeUsername(username, s.maxUsername)
 	s.authorizedKeys[string(pubKey.Marshal())] = username
 	log.Printf("Authorized key for person: %s", username)
 }
 
 // GetP2PPeer returns the p2pquic peer for configuration
 func (s *Server) GetP2PPeer() *p2pquic.Peer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.p2pPeer
 }
 
@@ -132,24 +329,14 @@ func (s *Server) Start() error {
 		return fmt.Errorf("invalid port in address %s: %w", s.address, err)
 	}
 
-	// Create p2pquic peer with requested port (may be 0 for random)
-	config := p2pquic.Config{
-		PeerID:       s.roomName,
-		LocalPort:    port,
-		SignalingURL: "",    // Using SSH-based signaling
-		EnableSTUN:   false, // Disabled - using server-reflexive IPv4 from entrypoint
-	}
-
-	p2pPeer, err := p2pquic.NewPeer(config)
+	// Listen on the requested port (may be 0 for random)
+	p2pPeer, ln, err := s.listenP2P(port)
 	if err != nil {
-		return fmt.Errorf("failed to create p2pquic peer: %w", err)
-	}
-	s.p2pPeer = p2pPeer
-
-	// Start listening - this will bind to an actual port
-	if err := p2pPeer.Listen(); err != nil {
-		return fmt.Errorf("failed to start p2pquic listener: %w", err)
+		return err
 	}
+	s.mu.Lock()
+	s.p2pPeer, s.quicLn = p2pPeer, ln
+	s.mu.Unlock()
 
 	// Get actual port from the peer's listener
 	actualPort := p2pPeer.GetActualPort()
@@ -159,30 +346,47 @@ func (s *Server) Start() error {
 
 	log.Printf("SSH server listening on %s:%d", strings.Split(s.address, ":")[0], actualPort)
 
-	go s.acceptLoop()
+	go s.acceptLoop(p2pPeer, ln)
+	go s.bandwidthLoop()
 	return nil
 }
 
 // GetPort returns the actual port the server is listening on
 func (s *Server) GetPort() int {
-	if s.p2pPeer == nil {
+	p2pPeer := s.GetP2PPeer()
+	if p2pPeer == nil {
 		return 0
 	}
-	return s.p2pPeer.GetActualPort()
+	return p2pPeer.GetActualPort()
 }
 
 // GetUDPConn returns the underlying UDP connection for hole-punching
 func (s *Server) GetUDPConn() *net.UDPConn {
-	if s.p2pPeer == nil {
+	p2pPeer := s.GetP2PPeer()
+	if p2pPeer == nil {
 		return nil
 	}
-	return s.p2pPeer.GetUDPConn()
+	return p2pPeer.GetUDPConn()
 }
 
 // Stop stops the SSH server
 func (s *Server) Stop() error {
-	if s.p2pPeer != nil {
-		return s.p2pPeer.Close()
+	s.mu.Lock()
+	s.stopped = true
+	p2pPeer, ln, tcpLn := s.p2pPeer, s.quicLn, s.tcpLn
+	s.mu.Unlock()
+	if tcpLn != nil {
+		tcpLn.Close()
+	}
+	s.stopOffers()
+	s.stopSchedules()
+	s.stopGrants()
+	s.saveRoster()
+	if ln != nil {
+		ln.Close()
+	}
+	if p2pPeer != nil {
+		return p2pPeer.Close()
 	}
 	return nil
 }
@@ -221,7 +425,7 @@ func (s *Server) updatePeopleList(p *Person) {
 	names := make([]string, 0, len(s.people))
 	for _, person := range s.people {
 		displayName := person.Username
-		if s.isOperator(person.PubKey) {
+		if s.isModerator(person.PubKey) {
 			displayName = "@" + person.Username
 		}
 		names = append(names, displayName)
@@ -231,11 +435,22 @@ func (s *Server) updatePeopleList(p *Person) {
 	p.ChatUI.SetDoors(s.doorManager.List())
 }
 
-func (s *Server) acceptLoop() {
+func (s *Server) acceptLoop(p2pPeer *p2pquic.Peer, ln *quic.Listener) {
 	for {
-		// Accept QUIC connection via p2pquic
-		quicConn, err := s.p2pPeer.Accept(context.Background())
+		// Accept QUIC connection on the p2pquic peer's socket
+		quicConn, err := ln.Accept(context.Background())
 		if err != nil {
+			s.mu.RLock()
+			stopped, rebind := s.stopped, s.rebind
+			s.mu.RUnlock()
+			if stopped {
+				return
+			}
+			if rebind {
+				log.Printf("QUIC listener failed: %v. Rebinding...", err)
+				go s.rebindP2P(p2pPeer, ln)
+				return
+			}
 			if !strings.Contains(err.Error(), "server closed") && !strings.Contains(err.Error(), "closed") {
 				log.Printf("Failed to accept connection: %v", err)
 			}
@@ -255,10 +470,22 @@ func (s *Server) acceptLoop() {
 	}
 }
 
+// ServeConn runs the SSH protocol on an established connection and blocks
+// until it closes. Start calls it for every accepted QUIC stream.
+func (s *Server) ServeConn(conn net.Conn) {
+	s.handleConnection(conn)
+}
+
 func (s *Server) handleConnection(conn net.Conn) {
-	// handeConnection
+	s.mu.RLock()
+	config := s.config
+	s.mu.RUnlock()
+
+	if s.bandwidth != nil {
+		conn = &countingConn{Conn: conn, meter: s.bandwidth}
+	}
 
-	sshConn, chans, _, err := ssh.NewServerConn(conn, s.config)
+	sshConn, chans, _, err := ssh.NewServerConn(conn, config)
 	if err != nil {
 		if err != io.EOF {
 			log.Printf("Failed SSH handshake: %v", err)
@@ -285,6 +512,8 @@ func (s *Server) handleConnection(conn net.Conn) {
 			}
 		}
 	}
+	// A ban restored from a snapshot or an old prefix never locks out the operator
+	banned = banned && !s.isOperator(pubKey)
 	s.mu.RUnlock()
 
 	if banned {
@@ -295,7 +524,7 @@ func (s *Server) handleConnection(conn net.Conn) {
 
 	// First connection becomes operator if none exists
 	s.mu.Lock()
-	if s.operatorPubKey == nil && pubKey != nil {
+	if s.operatorPubKey == nil && pubKey != nil && s.autoOperator {
 		s.operatorPubKey = pubKey
 		log.Printf("First person %s identified as operator", sshConn.User())
 	}
@@ -309,11 +538,11 @@ func (s *Server) handleConnection(conn net.Conn) {
 		username = mappedName
 	}
 	s.mu.RUnlock()
-	log.Printf("Person connected: %s", username)
-
-	sessionID := fmt.Sprintf("%s-%d", username, time.Now().UnixNano())
 
 	s.mu.Lock()
+	username = s.uniqueUsernameLocked(normalizeUsername(username, s.maxUsername), pubKey)
+	log.Printf("Person connected: %s", username)
+	sessionID := fmt.Sprintf("%s-%d", username, time.Now().UnixNano())
 	// Disconnect old session with same key
 	if pubKey != nil {
 		pubKeyBytes := pubKey.Marshal()
@@ -335,13 +564,21 @@ func (s *Server) handleConnection(conn net.Conn) {
 		}
 	}
 
+	now := time.Now()
 	p := &Person{
-		SessionID: sessionID,
-		Username:  username,
-		Conn:      sshConn,
-		PubKey:    pubKey,
+		SessionID:  sessionID,
+		Username:   username,
+		Conn:       sshConn,
+		PubKey:     pubKey,
+		UNNAware:   strings.HasPrefix(string(sshConn.ClientVersion()), "SSH-2.0-UNN-CLIENT"),
+		JoinedAt:   now,
+		Transport:  transportName(sshConn.RemoteAddr()),
+		KeyWarning: sshConn.Permissions.Extensions["weakkey"],
+
+		lastActive: now,
 	}
 	s.people[sessionID] = p
+	s.seenLocked(p, now)
 	s.mu.Unlock()
 	s.updateAllPeople()
 
@@ -351,6 +588,9 @@ func (s *Server) handleConnection(conn net.Conn) {
 		if current, ok := s.people[sessionID]; ok && current == p {
 			delete(s.people, sessionID)
 		}
+		delete(s.typing, sessionID)
+		s.markRead(p)
+		s.seenLocked(p, time.Now())
 		s.mu.Unlock()
 
 		log.Printf("Person disconnected: %s", username)
@@ -359,7 +599,8 @@ func (s *Server) handleConnection(conn net.Conn) {
 		if reason != "" {
 			msg = fmt.Sprintf("* %s left the room: %s", username, reason)
 		}
-		s.broadcastWithHistory(p.PubKey, msg, ui.MsgSystem)
+		s.broadcastNotice(p.PubKey, msg)
+		s.revokeGrantOnLeave(p)
 
 		s.updateAllPeople()
 	}()
@@ -458,6 +699,15 @@ func (s *Server) handleSession(newChannel ssh.NewChannel, sessionID string) {
 		case "exec":
 			req.Reply(false, nil)
 			return
+		case sshcompress.RequestType:
+			// Must come before pty-req, which starts the output
+			s.mu.RLock()
+			compress := s.compress
+			s.mu.RUnlock()
+			req.Reply(compress, nil)
+			if compress {
+				rawChannel = sshcompress.NewChannel(rawChannel)
+			}
 		case "pty-req":
 			if w, h, ok := common.ParsePtyRequest(req.Payload); ok {
 				initialW, initialH = w, h
@@ -467,6 +717,7 @@ func (s *Server) handleSession(newChannel ssh.NewChannel, sessionID string) {
 			// Interactive session - init TUI and start interaction
 			p.Bridge = bridge.NewInputBridge(rawChannel)
 			p.Bus = bridge.NewSSHBus(p.Bridge, int(initialW), int(initialH))
+			p.Bus.SetWriteDelay(s.writeDelay)
 
 			// Handle remaining requests in background (e.g., resize)
 			go func() {
@@ -532,20 +783,9 @@ func (s *Server) handleInteraction(channel ssh.Channel, sessionID string) {
 	s.mu.RUnlock()
 
 	if lockKey != "" && !isOp && !s.headless {
-		// Use a local screen variable to avoid conflict with long-running ChatUI screen
-		scr, err := tcell.NewTerminfoScreenFromTty(p.Bus)
-		if err == nil {
-			if err := scr.Init(); err == nil {
-				pwdUI := password.NewPasswordUI(
-					scr)
-				entered := pwdUI.Run()
-				scr.Fini()
-				if entered != lockKey {
-					fmt.Fprintf(channel, "\r\n*** INCORRECT ROOM KEY ***\r\n\r\n")
-					p.Conn.Close()
-					return
-				}
-			}
+		if !s.promptLockKey(p, channel, lockKey) {
+			p.Conn.Close()
+			return
 		}
 	}
 
@@ -553,17 +793,32 @@ func (s *Server) handleInteraction(channel ssh.Channel, sessionID string) {
 	chatUI.SetUsername(username)
 	chatUI.SetTitle(fmt.Sprintf("Underground Node Network - Room: %s", s.roomName))
 	chatUI.Headless = s.headless
+	chatUI.PasteMode = s.pasteMode
+	chatUI.CmdPrefix = s.commandPrefix()
 	chatUI.Input = p.Bus
 	p.ChatUI = chatUI
+	s.applyTheme(p, chatUI)
+	s.sendTitle(p)
 
 	pubHash := s.getPubKeyHash(p.PubKey)
+	s.mu.RLock()
+	markdown := s.markdown[pubHash]
+	s.mu.RUnlock()
+	chatUI.SetMarkdown(markdown)
 
 	chatUI.OnSend(func(msg string) {
 		if strings.TrimSpace(msg) == "" {
 			return // Ignore empty messages
 		}
 		s.addCommandToHistory(pubHash, msg)
-		s.Broadcast(username, msg)
+		s.touch(p)
+		s.clearTyping(p)
+		s.sendChat(p, msg)
+	})
+
+	chatUI.OnTyping(func() {
+		s.touch(p)
+		s.setTyping(p)
 	})
 
 	chatUI.OnClose(func() {
@@ -572,6 +827,7 @@ func (s *Server) handleInteraction(channel ssh.Channel, sessionID string) {
 
 	chatUI.OnCmd(func(cmd string) bool {
 		s.addCommandToHistory(pubHash, cmd)
+		s.touch(p)
 		return s.handleInternalCommand(p, cmd)
 	})
 
@@ -579,33 +835,45 @@ func (s *Server) handleInteraction(channel ssh.Channel, sessionID string) {
 	s.mu.Lock()
 	history := s.histories[pubHash]
 	cmdHistory := s.cmdHistories[pubHash]
+	unread, returning := s.unreadCount(pubHash)
+	below := s.scrollMarks[pubHash]
 	s.mu.Unlock()
 
 	if len(cmdHistory) > 0 {
 		chatUI.SetCommandHistory(cmdHistory)
 	}
 
+	if motd := s.GetMOTD(); motd != "" {
+		chatUI.AddMessage("*** MOTD: "+motd+" ***", ui.MsgSystem)
+	}
+
 	if len(history) > 0 {
+		if returning {
+			chatUI.AddMessage(welcomeBack(username, unread), ui.MsgSystem)
+		}
 		for _, m := range history {
-			chatUI.AddMessage(m.Text, m.Type)
+			chatUI.AddChatMessage(m.ID, m.Text, m.Type, m.Color)
+		}
+		if s.restoreScroll && below > 0 {
+			chatUI.RestoreScroll(len(chatUI.GetMessages()) - min(below, len(history)))
 		}
 	} else {
 		// New session welcome message
-		bannerPath := "room.asc"
-		if b, err := os.ReadFile(bannerPath); err == nil {
-			lines := strings.Split(string(b), "\n")
+		if lines := s.GetBanner(); lines != nil {
 			s.mu.Lock()
-			for _, line := range lines {
-				text := strings.TrimRight(line, "\r\n")
+			for _, text := range lines {
 				chatUI.AddMessage(text, ui.MsgServer)
 				s.addMessageToHistory(pubHash, ui.Message{Text: text, Type: ui.MsgServer})
 			}
 			s.mu.Unlock()
 		} else {
 			chatUI.AddMessage(fmt.Sprintf("*** You joined %s as %s ***", s.roomName, username), ui.MsgSystem)
-			chatUI.AddMessage("*** Type /help for commands ***", ui.MsgSystem)
+			chatUI.AddMessage(s.withCmdPrefix("*** Type /help for commands ***"), ui.MsgSystem)
 		}
 	}
+	if p.KeyWarning != "" {
+		chatUI.AddMessage("*** "+p.KeyWarning+" ***", ui.MsgServer)
+	}
 
 	for {
 		// Reset bus and UI for each TUI run
@@ -613,18 +881,16 @@ func (s *Server) handleInteraction(channel ssh.Channel, sessionID string) {
 		chatUI.Reset()
 
 		// Create a fresh screen for each run to avoid "already engaged" errors
-		if !s.headless {
-			screen, err := tcell.NewTerminfoScreenFromTty(p.Bus)
+		if !s.headless && !chatUI.LineMode {
+			screen, err := common.NewScreen(p.Bus)
 			if err != nil {
-				log.Printf("Failed to create screen: %v", err)
-				return
-			}
-
-			if err := screen.Init(); err != nil {
-				log.Printf("Failed to init screen: %v", err)
-				return
+				// Keep the session usable on terminals tcell cannot drive
+				log.Printf("Failed to create screen for %s, using line mode: %v", username, err)
+				chatUI.EnableLineMode()
+				chatUI.AddMessage("*** Terminal not supported, using line mode ***", ui.MsgSystem)
+			} else {
+				chatUI.SetScreen(screen)
 			}
-			chatUI.SetScreen(screen)
 		}
 
 		// Update visitors list
@@ -634,14 +900,17 @@ func (s *Server) handleInteraction(channel ssh.Channel, sessionID string) {
 
 		// Explicitly finalize screen immediately after Run() to restore terminal state
 		s.mu.RLock()
-		if !s.headless && chatUI.GetScreen() != nil {
+		if !s.headless && !chatUI.LineMode && chatUI.GetScreen() != nil {
 			chatUI.GetScreen().Fini()
 		}
 		s.mu.RUnlock()
 
 		if cmd == "" {
 			// Clear screen on manual exit - first reset colors to avoid black background spill
-			fmt.Fprint(p.Bus, "\033[m\033[2J\033[H")
+			if !chatUI.LineMode {
+				fmt.Fprint(p.Bus, "\033[m\033[2J\033[H")
+			}
+			p.Bus.Flush()
 			p.Conn.Close() // Force immediate disconnect
 			return         // User exited
 		}
@@ -0,0 +1,153 @@
+package sshserver
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// roomSnapshot is the operator-managed state of a room, without chat
+// history. It is written as JSON, which is also valid YAML, so snapshots can
+// be read and edited with YAML tooling without adding a YAML dependency.
+type roomSnapshot struct {
+	Room      string            `json:"room"`
+	Title     string            `json:"title,omitempty"`
+	Accent    string            `json:"accent,omitempty"`
+	MOTD      string            `json:"motd,omitempty"`
+	LockKey   string            `json:"lock_key,omitempty"`
+	Quiet     bool              `json:"quiet,omitempty"`
+	Downloads string            `json:"downloads,omitempty"` // on, verified-only or off
+	Doors     string            `json:"doors,omitempty"`     // on, verified-only or off
+	Operator  string            `json:"operator,omitempty"`  // authorized_keys format
+	Bans      map[string]string `json:"bans,omitempty"`      // hash (prefix) -> reason
+}
+
+// snapshotPath returns where /snapshot save writes a snapshot called name:
+// next to the host key, as remote operators must not pick any path the room
+// can write to
+func (s *Server) snapshotPath(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return "", fmt.Errorf("invalid snapshot name: %s (use a plain file name)", name)
+	}
+	return filepath.Join(filepath.Dir(s.hostKeyPath), name), nil
+}
+
+// SaveSnapshot writes the room's theme, MOTD, lock, quiet mode, permissions,
+// operator and bans to path. It never overwrites an existing file.
+func (s *Server) SaveSnapshot(path string) error {
+	s.mu.RLock()
+	snap := roomSnapshot{
+		Room:    s.roomName,
+		Title:   s.theme.Title,
+		Accent:  s.theme.Accent,
+		MOTD:    s.motd,
+		LockKey: s.roomLockKey,
+		Quiet:   s.quietMode,
+		Bans:    make(map[string]string, len(s.bannedHashes)),
+	}
+	if s.downloadPerm != PermEveryone {
+		snap.Downloads = s.downloadPerm.String()
+	}
+	if s.doorPerm != PermEveryone {
+		snap.Doors = s.doorPerm.String()
+	}
+	if s.operatorPubKey != nil {
+		snap.Operator = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(s.operatorPubKey)))
+	}
+	for h, r := range s.bannedHashes {
+		snap.Bans[h] = r
+	}
+	s.mu.RUnlock()
+
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("snapshot %s already exists", filepath.Base(path))
+		}
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// RestoreSnapshot loads a snapshot written by SaveSnapshot. The whole file
+// is validated before anything is applied, so a bad snapshot leaves the
+// room unchanged.
+func (s *Server) RestoreSnapshot(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var snap roomSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("invalid snapshot %s: %w", path, err)
+	}
+
+	var operator ssh.PublicKey
+	if snap.Operator != "" {
+		operator, _, _, _, err = ssh.ParseAuthorizedKey([]byte(snap.Operator))
+		if err != nil {
+			return fmt.Errorf("invalid operator key: %w", err)
+		}
+	}
+	downloads, doors := PermEveryone, PermEveryone
+	if snap.Downloads != "" {
+		if downloads, err = ParsePermission(snap.Downloads); err != nil {
+			return fmt.Errorf("invalid downloads permission: %w", err)
+		}
+	}
+	if snap.Doors != "" {
+		if doors, err = ParsePermission(snap.Doors); err != nil {
+			return fmt.Errorf("invalid doors permission: %w", err)
+		}
+	}
+	for h := range snap.Bans {
+		if _, err := hex.DecodeString(h); err != nil || len(h) < 8 {
+			return fmt.Errorf("invalid ban hash: %q", h)
+		}
+	}
+	if snap.Title != "" || snap.Accent != "" {
+		if err := s.SetTheme(snap.Accent, snap.Title); err != nil {
+			return err
+		}
+	}
+	if snap.Room != "" && snap.Room != s.roomName {
+		log.Printf("Restoring snapshot of room '%s' into '%s'", snap.Room, s.roomName)
+	}
+
+	s.mu.Lock()
+	s.roomLockKey = snap.LockKey
+	s.quietMode = snap.Quiet
+	s.downloadPerm = downloads
+	s.doorPerm = doors
+	if operator != nil {
+		s.operatorPubKey = operator
+	}
+	for h, r := range snap.Bans {
+		s.bannedHashes[h] = r
+	}
+	s.mu.Unlock()
+
+	if err := s.SetMOTD(snap.MOTD); err != nil {
+		return err
+	}
+
+	log.Printf("Restored snapshot %s: title=%q motd=%t locked=%t quiet=%t downloads=%s doors=%s operator=%t bans=%d",
+		path, snap.Title, snap.MOTD != "", snap.LockKey != "", snap.Quiet, downloads, doors, operator != nil, len(snap.Bans))
+	return nil
+}
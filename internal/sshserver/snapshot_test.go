@@ -0,0 +1,136 @@
+package sshserver
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSnapshot(t *testing.T) {
+	tmpDir := t.TempDir()
+	newServer := func(dir string) *Server {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		s, err := NewServer("127.0.0.1:0", filepath.Join(dir, "host_key"), "testroom", doors.NewManager(dir))
+		if err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+		return s
+	}
+
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	sshPub, _ := ssh.NewPublicKey(pub)
+	snapPath := filepath.Join(tmpDir, "a", "room.yaml")
+
+	t.Run("round trip", func(t *testing.T) {
+		s := newServer(filepath.Join(tmpDir, "a"))
+		if err := s.SetTheme("green", "The Vault"); err != nil {
+			t.Fatal(err)
+		}
+		s.SetMOTD("Maintenance at 2am UTC")
+		s.mu.Lock()
+		s.operatorPubKey = sshPub
+		s.roomLockKey = "sesame"
+		s.quietMode = true
+		s.bannedHashes["deadbeefcafe"] = "spam"
+		s.mu.Unlock()
+
+		p := &Person{Username: "alice", ChatUI: ui.NewChatUI(nil), PubKey: sshPub}
+		s.handleInternalCommand(p, "/snapshot save room.yaml")
+		if _, err := os.Stat(snapPath); err != nil {
+			t.Fatalf("Snapshot was not written: %v", err)
+		}
+
+		r := newServer(filepath.Join(tmpDir, "b"))
+		if err := r.RestoreSnapshot(snapPath); err != nil {
+			t.Fatalf("RestoreSnapshot failed: %v", err)
+		}
+		if r.theme.Title != "The Vault" || r.theme.Accent != "green" {
+			t.Errorf("Theme not restored: %+v", r.theme)
+		}
+		if r.GetMOTD() != "Maintenance at 2am UTC" {
+			t.Errorf("MOTD not restored: %q", r.GetMOTD())
+		}
+		if r.roomLockKey != "sesame" || !r.quietMode {
+			t.Errorf("Lock or quiet mode not restored: %q %v", r.roomLockKey, r.quietMode)
+		}
+		if !r.isOperator(sshPub) {
+			t.Errorf("Operator not restored")
+		}
+		if r.bannedHashes["deadbeefcafe"] != "spam" {
+			t.Errorf("Bans not restored: %v", r.bannedHashes)
+		}
+	})
+
+	t.Run("non-operator cannot save", func(t *testing.T) {
+		s := newServer(filepath.Join(tmpDir, "c"))
+		other, _, _ := ed25519.GenerateKey(rand.Reader)
+		otherPub, _ := ssh.NewPublicKey(other)
+		s.mu.Lock()
+		s.operatorPubKey = sshPub
+		s.mu.Unlock()
+		p := &Person{Username: "mallory", ChatUI: ui.NewChatUI(nil), PubKey: otherPub}
+		path := filepath.Join(tmpDir, "c", "mallory.yaml")
+		s.handleInternalCommand(p, "/snapshot save mallory.yaml")
+		if _, err := os.Stat(path); err == nil {
+			t.Errorf("Non-operator was able to save a snapshot")
+		}
+	})
+
+	t.Run("only plain names next to the host key", func(t *testing.T) {
+		dir := filepath.Join(tmpDir, "e")
+		s := newServer(dir)
+		s.operatorPubKey = sshPub
+		p := &Person{Username: "alice", ChatUI: ui.NewChatUI(nil), PubKey: sshPub}
+		lastMessage := func() string {
+			msgs := p.ChatUI.GetMessages()
+			return msgs[len(msgs)-1].Text
+		}
+		outside := filepath.Join(tmpDir, "outside.yaml")
+		for _, name := range []string{outside, "../outside.yaml", "sub/room.yaml", `sub\room.yaml`, ".."} {
+			s.handleInternalCommand(p, "/snapshot save "+name)
+			if got, want := lastMessage(), "Error: invalid snapshot name: "+name+" (use a plain file name)"; got != want {
+				t.Errorf("Expected %q, got %q", want, got)
+			}
+		}
+		if _, err := os.Stat(outside); err == nil {
+			t.Error("Snapshot was written outside the host key directory")
+		}
+
+		s.handleInternalCommand(p, "/snapshot save state.yaml")
+		if got := lastMessage(); got != "Room state saved as state.yaml next to the room's host key. Restore it with -restore." {
+			t.Errorf("Expected a confirmation, got %q", got)
+		}
+		os.WriteFile(filepath.Join(dir, "state.yaml"), []byte("keep"), 0600)
+		s.handleInternalCommand(p, "/snapshot save state.yaml")
+		if got := lastMessage(); got != "Error: snapshot state.yaml already exists" {
+			t.Errorf("Expected an overwrite to be refused, got %q", got)
+		}
+		s.handleInternalCommand(p, "/snapshot save host_key")
+		if data, _ := os.ReadFile(filepath.Join(dir, "state.yaml")); string(data) != "keep" {
+			t.Error("Existing snapshot was overwritten")
+		}
+		if got := lastMessage(); got != "Error: snapshot host_key already exists" {
+			t.Errorf("Expected the host key to be protected, got %q", got)
+		}
+	})
+
+	t.Run("invalid snapshot changes nothing", func(t *testing.T) {
+		s := newServer(filepath.Join(tmpDir, "d"))
+		bad := filepath.Join(tmpDir, "bad.yaml")
+		os.WriteFile(bad, []byte(`{"lock_key": "x", "bans": {"not-hex!": "spam"}}`), 0600)
+		if err := s.RestoreSnapshot(bad); err == nil {
+			t.Errorf("Expected invalid ban hash to be rejected")
+		}
+		if s.roomLockKey != "" {
+			t.Errorf("Invalid snapshot was partially applied")
+		}
+	})
+}
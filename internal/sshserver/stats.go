@@ -0,0 +1,40 @@
+package sshserver
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/ui/common"
+)
+
+// roomStats describes the room for /stats. It only shows counts, so it is
+// safe for every visitor, unlike the operator's /bandwidth and /doorstats.
+func (s *Server) roomStats(now time.Time) []string {
+	s.mu.RLock()
+	people, messages, started := len(s.people), s.chatCount, s.startedAt
+	s.mu.RUnlock()
+
+	bullet := common.Glyphs.Bullet
+	return []string{
+		fmt.Sprintf("%s People: %d", bullet, people),
+		fmt.Sprintf("%s Messages: %d", bullet, messages),
+		fmt.Sprintf("%s Files: %d", bullet, s.countFiles()),
+		fmt.Sprintf("%s Doors: %d", bullet, len(s.doorManager.List())),
+		fmt.Sprintf("%s Uptime: %s", bullet, now.Sub(started).Round(time.Second)),
+	}
+}
+
+// countFiles counts the files under the file roots, from the index when
+// there is one
+func (s *Server) countFiles() int {
+	if idx := s.currentFileIndex(); idx != nil {
+		return idx.files
+	}
+	s.mu.RLock()
+	roots := make(map[string]string, len(s.fileRoots))
+	for name, dir := range s.fileRoots {
+		roots[name] = dir
+	}
+	s.mu.RUnlock()
+	return buildFileIndex(roots).files
+}
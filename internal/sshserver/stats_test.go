@@ -0,0 +1,73 @@
+package sshserver
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"github.com/mevdschee/underground-node-network/internal/ui/common"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestStats(t *testing.T) {
+	tmpDir := t.TempDir()
+	doorDir := filepath.Join(tmpDir, "doors")
+	os.Mkdir(doorDir, 0755)
+	os.WriteFile(filepath.Join(doorDir, "game"), []byte("#!/bin/sh\necho hi"), 0755)
+	dm := doors.NewManager(doorDir)
+	dm.Scan()
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", dm)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer s.Stop()
+	fileDir := filepath.Join(tmpDir, "files")
+	os.MkdirAll(filepath.Join(fileDir, "docs"), 0755)
+	os.WriteFile(filepath.Join(fileDir, "a.txt"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(fileDir, "docs", "b.txt"), []byte("b"), 0644)
+	s.SetFileRoots([]string{fileDir})
+
+	newPerson := func(name string) *Person {
+		pub, _, _ := ed25519.GenerateKey(rand.Reader)
+		key, _ := ssh.NewPublicKey(pub)
+		p := &Person{Username: name, SessionID: name, PubKey: key, ChatUI: ui.NewChatUI(nil)}
+		s.people[p.SessionID] = p
+		return p
+	}
+	alice := newPerson("alice")
+	newPerson("bob")
+	s.Broadcast("alice", "hi")
+	s.Broadcast("bob", "hello")
+	s.Broadcast("alice", "files are up")
+	s.startedAt = time.Now().Add(-90 * time.Minute)
+
+	s.handleInternalCommand(alice, "/stats")
+	msgs := alice.ChatUI.GetMessages()
+	b := common.Glyphs.Bullet
+	want := []string{
+		"--- Room Statistics ---",
+		b + " People: 2",
+		b + " Messages: 3",
+		b + " Files: 2",
+		b + " Doors: 1",
+		b + " Uptime: 1h30m0s",
+	}
+	got := msgs[len(msgs)-len(want):]
+	for i, text := range want {
+		if got[i].Text != text {
+			t.Errorf("Line %d: expected %q, got %q", i, text, got[i].Text)
+		}
+	}
+
+	t.Run("kept in history", func(t *testing.T) {
+		history := s.histories[s.getPubKeyHash(alice.PubKey)]
+		if last := history[len(history)-1].Text; last != want[len(want)-1] {
+			t.Errorf("Expected the stats in the history, got %q", last)
+		}
+	})
+}
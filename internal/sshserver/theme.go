@@ -0,0 +1,53 @@
+package sshserver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mevdschee/underground-node-network/internal/protocol"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+	"github.com/mevdschee/underground-node-network/internal/ui/common"
+)
+
+// SetTheme sets the room branding shown in the chat header: an accent color
+// (a color name or #rrggbb) and a title replacing the default one. Empty
+// values keep the defaults.
+func (s *Server) SetTheme(accent, title string) error {
+	accent = strings.ToLower(strings.TrimSpace(accent))
+	if accent != "" {
+		color := tcell.GetColor(accent)
+		if color == tcell.ColorDefault || color == tcell.ColorBlack {
+			return fmt.Errorf("unknown accent color: %s", accent)
+		}
+		s.themeAccent = color
+	}
+	s.theme = protocol.ThemePayload{Accent: accent, Title: title}
+	return nil
+}
+
+// applyTheme brands the person's chat UI and pushes the theme to UNN-aware
+// clients. Nick colors chosen with /color are not affected.
+func (s *Server) applyTheme(p *Person, chatUI *ui.ChatUI) {
+	if s.theme.Title != "" {
+		chatUI.SetTitle(s.theme.Title)
+	}
+	if s.themeAccent != tcell.ColorDefault {
+		chatUI.SetAccent(s.themeAccent)
+	}
+	if p.UNNAware && p.Bus != nil && (s.theme.Accent != "" || s.theme.Title != "") {
+		common.SendOSC(p.Bus, "theme", map[string]interface{}{
+			"accent": s.theme.Accent,
+			"title":  s.theme.Title,
+		})
+	}
+}
+
+// sendTitle asks UNN-aware clients to name the terminal after the room
+func (s *Server) sendTitle(p *Person) {
+	if p.UNNAware && p.Bus != nil {
+		common.SendOSC(p.Bus, "title", map[string]interface{}{
+			"title": "UNN: room " + s.roomName,
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package sshserver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+)
+
+func TestRoomTheme(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "unn-theme-test-*")
+	defer os.RemoveAll(tmpDir)
+
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	t.Run("invalid accent", func(t *testing.T) {
+		if err := s.SetTheme("notacolor", ""); err == nil {
+			t.Errorf("Expected error for unknown accent color")
+		}
+		if err := s.SetTheme("#zzzzzz", ""); err == nil {
+			t.Errorf("Expected error for malformed hex color")
+		}
+	})
+
+	t.Run("applied to chat header", func(t *testing.T) {
+		if err := s.SetTheme("#ff8800", "Hacker Den"); err != nil {
+			t.Fatalf("SetTheme failed: %v", err)
+		}
+
+		screen := tcell.NewSimulationScreen("")
+		if err := screen.Init(); err != nil {
+			t.Fatal(err)
+		}
+		defer screen.Fini()
+		screen.SetSize(80, 24)
+
+		p := &Person{Username: "alice"}
+		chat := ui.NewChatUI(screen)
+		s.applyTheme(p, chat)
+		chat.Draw()
+
+		title := ""
+		for x := 2; x < 12; x++ {
+			str, style, _ := screen.Get(x, 0)
+			title += str
+			if fg, _, _ := style.Decompose(); x == 2 && fg != tcell.NewHexColor(0xff8800) {
+				t.Errorf("Expected accent color on header, got %v", fg)
+			}
+		}
+		if title != "Hacker Den" {
+			t.Errorf("Expected themed title, got %q", title)
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package sshserver
+
+import (
+	"sort"
+	"time"
+)
+
+// typingTimeout is how long a typing indicator stays visible without a new signal
+const typingTimeout = 5 * time.Second
+
+// setTyping marks a person as typing and refreshes everyone's footer.
+// Only UNN-aware clients take part in typing indicators.
+func (s *Server) setTyping(p *Person) {
+	if !p.UNNAware {
+		return
+	}
+	s.mu.Lock()
+	s.typing[p.SessionID] = time.Now().Add(typingTimeout)
+	s.mu.Unlock()
+	s.updateTyping()
+
+	// Refresh again once the indicator may have expired
+	time.AfterFunc(typingTimeout, s.updateTyping)
+}
+
+// clearTyping removes a person's typing indicator (e.g. after sending)
+func (s *Server) clearTyping(p *Person) {
+	s.mu.Lock()
+	_, ok := s.typing[p.SessionID]
+	delete(s.typing, p.SessionID)
+	s.mu.Unlock()
+	if ok {
+		s.updateTyping()
+	}
+}
+
+// pruneTyping drops expired typing indicators. Caller must hold s.mu.
+func (s *Server) pruneTyping(now time.Time) {
+	for id, expiry := range s.typing {
+		if !now.Before(expiry) {
+			delete(s.typing, id)
+		}
+	}
+}
+
+// typingNames returns the names of people typing, excluding the given session.
+// Caller must hold s.mu.
+func (s *Server) typingNames(excludeID string) []string {
+	names := make([]string, 0, len(s.typing))
+	for id := range s.typing {
+		if id == excludeID {
+			continue
+		}
+		if person, ok := s.people[id]; ok {
+			names = append(names, person.Username)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// updateTyping pushes the current typing state to every connected person
+func (s *Server) updateTyping() {
+	s.mu.Lock()
+	s.pruneTyping(time.Now())
+	updates := make(map[*Person][]string, len(s.people))
+	for _, p := range s.people {
+		if p.ChatUI != nil {
+			updates[p] = s.typingNames(p.SessionID)
+		}
+	}
+	s.mu.Unlock()
+
+	for p, names := range updates {
+		p.ChatUI.SetTyping(names)
+	}
+}
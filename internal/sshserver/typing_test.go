@@ -0,0 +1,76 @@
+package sshserver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"github.com/mevdschee/underground-node-network/internal/ui"
+)
+
+func TestTypingExpiry(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "unn-typing-test-*")
+	defer os.RemoveAll(tmpDir)
+
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	alice := &Person{SessionID: "alice-1", Username: "alice", ChatUI: ui.NewChatUI(nil), UNNAware: true}
+	bob := &Person{SessionID: "bob-1", Username: "bob", ChatUI: ui.NewChatUI(nil)}
+	s.people[alice.SessionID] = alice
+	s.people[bob.SessionID] = bob
+
+	t.Run("non-UNN-aware ignored", func(t *testing.T) {
+		s.setTyping(bob)
+		s.mu.Lock()
+		_, ok := s.typing[bob.SessionID]
+		s.mu.Unlock()
+		if ok {
+			t.Errorf("Typing state recorded for non-UNN-aware client")
+		}
+	})
+
+	t.Run("visible to others only", func(t *testing.T) {
+		s.setTyping(alice)
+		s.mu.Lock()
+		forBob := s.typingNames(bob.SessionID)
+		forAlice := s.typingNames(alice.SessionID)
+		s.mu.Unlock()
+		if len(forBob) != 1 || forBob[0] != "alice" {
+			t.Errorf("Expected bob to see alice typing, got %v", forBob)
+		}
+		if len(forAlice) != 0 {
+			t.Errorf("Expected alice not to see herself typing, got %v", forAlice)
+		}
+	})
+
+	t.Run("expires", func(t *testing.T) {
+		s.mu.Lock()
+		s.pruneTyping(time.Now().Add(typingTimeout - time.Second))
+		before := len(s.typing)
+		s.pruneTyping(time.Now().Add(typingTimeout + time.Second))
+		after := len(s.typing)
+		s.mu.Unlock()
+		if before != 1 {
+			t.Errorf("Typing indicator expired too early")
+		}
+		if after != 0 {
+			t.Errorf("Typing indicator did not expire")
+		}
+	})
+
+	t.Run("cleared on send", func(t *testing.T) {
+		s.setTyping(alice)
+		s.clearTyping(alice)
+		s.mu.Lock()
+		n := len(s.typing)
+		s.mu.Unlock()
+		if n != 0 {
+			t.Errorf("Typing indicator not cleared")
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package sshserver
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/mevdschee/underground-node-network/internal/ui/common"
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultMaxUsernameLength caps usernames shown in the room, matching what
+// the entrypoint allows for UNN usernames
+const DefaultMaxUsernameLength = 20
+
+// SetMaxUsernameLength sets how many characters of a username are kept
+// (0 = no limit)
+func (s *Server) SetMaxUsernameLength(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxUsername = n
+}
+
+// normalizeUsername makes a username safe to render and to address in
+// commands: escape sequences and control characters are removed, spaces
+// become underscores and it is cut to max characters (0 = no limit)
+func normalizeUsername(name string, max int) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsControl(r):
+			return -1
+		case unicode.IsSpace(r):
+			return '_'
+		case !unicode.IsPrint(r):
+			return -1
+		}
+		return r
+	}, common.StripANSI(name))
+	if runes := []rune(name); max > 0 && len(runes) > max {
+		name = string(runes[:max])
+	}
+	if name == "" {
+		return "visitor"
+	}
+	return name
+}
+
+// uniqueUsernameLocked returns name, or name with a number appended when
+// someone with another key is already in the room under that name. The
+// caller holds s.mu.
+func (s *Server) uniqueUsernameLocked(name string, pubKey ssh.PublicKey) string {
+	taken := func(candidate string) bool {
+		for _, p := range s.people {
+			if p.Username != candidate {
+				continue
+			}
+			if pubKey == nil || p.PubKey == nil || !bytes.Equal(p.PubKey.Marshal(), pubKey.Marshal()) {
+				return true
+			}
+		}
+		return false
+	}
+	if !taken(name) {
+		return name
+	}
+	base := []rune(name)
+	for i := 2; ; i++ {
+		suffix := fmt.Sprint(i)
+		if limit := s.maxUsername; limit > 0 && len(base)+len(suffix) > limit {
+			base = base[:max(limit-len(suffix), 0)]
+		}
+		if candidate := string(base) + suffix; !taken(candidate) {
+			return candidate
+		}
+	}
+}
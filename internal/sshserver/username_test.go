@@ -0,0 +1,83 @@
+package sshserver
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/mevdschee/underground-node-network/internal/doors"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestNormalizeUsername(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+		max            int
+	}{
+		{"plain", "alice", "alice", 20},
+		{"escape sequences", "\x1b[31mmallory\x1b[0m\x1b]0;pwned\x07", "mallory", 20},
+		{"control characters", "bo\rb\x00\x08\n", "bob", 20},
+		{"spaces", "john doe", "john_doe", 20},
+		{"too long", "averyveryverylongusernameindeed", "averyveryverylonguse", 20},
+		{"runes", "ééééé", "ééé", 3},
+		{"no limit", "averyveryverylongusernameindeed", "averyveryverylongusernameindeed", 0},
+		{"empty", "\x1b[2J", "visitor", 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeUsername(tt.in, tt.max); got != tt.want {
+				t.Errorf("normalizeUsername(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUniqueUsername(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := NewServer("127.0.0.1:0", filepath.Join(tmpDir, "host_key"), "testroom", doors.NewManager(tmpDir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	newKey := func() ssh.PublicKey {
+		pub, _, _ := ed25519.GenerateKey(rand.Reader)
+		key, _ := ssh.NewPublicKey(pub)
+		return key
+	}
+	aliceKey := newKey()
+	s.people["alice-1"] = &Person{Username: "alice", PubKey: aliceKey}
+
+	t.Run("same key keeps its name", func(t *testing.T) {
+		if got := s.uniqueUsernameLocked("alice", aliceKey); got != "alice" {
+			t.Errorf("Expected alice, got %q", got)
+		}
+	})
+
+	t.Run("other key gets a suffix", func(t *testing.T) {
+		got := s.uniqueUsernameLocked("alice", newKey())
+		if got != "alice2" {
+			t.Errorf("Expected alice2, got %q", got)
+		}
+		s.people["alice-2"] = &Person{Username: got, PubKey: newKey()}
+		if got := s.uniqueUsernameLocked("alice", newKey()); got != "alice3" {
+			t.Errorf("Expected alice3, got %q", got)
+		}
+	})
+
+	t.Run("suffix fits the limit", func(t *testing.T) {
+		s.SetMaxUsernameLength(5)
+		defer s.SetMaxUsernameLength(DefaultMaxUsernameLength)
+		s.people["bobby-1"] = &Person{Username: "bobby", PubKey: newKey()}
+		if got := s.uniqueUsernameLocked("bobby", newKey()); got != "bobb2" {
+			t.Errorf("Expected bobb2, got %q", got)
+		}
+	})
+
+	t.Run("authorized names are normalized", func(t *testing.T) {
+		key := newKey()
+		s.AuthorizeKey(key, "\x1b[1mevil\x1b[0m name")
+		if got := s.authorizedKeys[string(key.Marshal())]; got != "evil_name" {
+			t.Errorf("Expected evil_name, got %q", got)
+		}
+	})
+}
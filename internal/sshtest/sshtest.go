@@ -0,0 +1,164 @@
+// Package sshtest drives SSH servers over in-memory connections so tests can
+// run full handshakes and interactive sessions without opening sockets.
+package sshtest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mevdschee/underground-node-network/internal/sshcompress"
+	"golang.org/x/crypto/ssh"
+)
+
+// ConnServer is implemented by servers that can run SSH on an established
+// connection, such as sshserver.Server.
+type ConnServer interface {
+	ServeConn(conn net.Conn)
+}
+
+// NewSigner returns a fresh Ed25519 key for a test identity
+func NewSigner() (ssh.Signer, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.NewSignerFromKey(priv)
+}
+
+// Dial connects to srv over an in-memory pipe and completes the SSH
+// handshake as user, authenticating with signer. The host key is not checked.
+func Dial(srv ConnServer, user string, signer ssh.Signer) (*ssh.Client, error) {
+	clientSide, serverSide := Pipe()
+	go srv.ServeConn(serverSide)
+
+	config := &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	}
+	conn, chans, reqs, err := ssh.NewClientConn(clientSide, "pipe", config)
+	if err != nil {
+		clientSide.Close()
+		return nil, err
+	}
+	return ssh.NewClient(conn, chans, reqs), nil
+}
+
+// Session is an interactive shell whose output is collected in the background
+type Session struct {
+	*ssh.Session
+	stdin io.WriteCloser
+
+	mu   sync.Mutex
+	out  strings.Builder
+	done chan struct{}
+}
+
+// StartShell requests a PTY of the given size and starts a shell
+func StartShell(client *ssh.Client, width, height int) (*Session, error) {
+	return startShell(client, width, height, false)
+}
+
+// StartCompressedShell is StartShell with the session output compressed,
+// as unn-client -compress-ssh asks for
+func StartCompressedShell(client *ssh.Client, width, height int) (*Session, error) {
+	return startShell(client, width, height, true)
+}
+
+func startShell(client *ssh.Client, width, height int, compress bool) (*Session, error) {
+	sess, err := client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	stdin, err := sess.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	var stdout io.Reader
+	if stdout, err = sess.StdoutPipe(); err != nil {
+		return nil, err
+	}
+	if compress {
+		ok, err := sshcompress.Request(sess)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("server refused compression")
+		}
+		stdout = sshcompress.NewReader(stdout)
+	}
+	if err := sess.RequestPty("xterm", height, width, ssh.TerminalModes{}); err != nil {
+		return nil, err
+	}
+	if err := sess.Shell(); err != nil {
+		return nil, err
+	}
+
+	s := &Session{Session: sess, stdin: stdin, done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		buf := make([]byte, 4096)
+		for {
+			n, err := stdout.Read(buf)
+			if n > 0 {
+				s.mu.Lock()
+				s.out.Write(buf[:n])
+				s.mu.Unlock()
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return s, nil
+}
+
+// Send types a line into the session
+func (s *Session) Send(line string) error {
+	_, err := io.WriteString(s.stdin, line+"\n")
+	return err
+}
+
+// Type sends raw keystrokes, e.g. "hello\r" to press Enter in a full-screen
+// UI, which reads a carriage return rather than a newline
+func (s *Session) Type(keys string) error {
+	_, err := io.WriteString(s.stdin, keys)
+	return err
+}
+
+// Output returns everything the session has printed so far
+func (s *Session) Output() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.out.String()
+}
+
+// WaitFor blocks until the output contains substr or the timeout expires
+func (s *Session) WaitFor(substr string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if strings.Contains(s.Output(), substr) {
+			return nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return fmt.Errorf("timed out waiting for %q, output so far: %q", substr, s.Output())
+}
+
+// WaitClosed blocks until the server ends the session or the timeout expires
+func (s *Session) WaitClosed(timeout time.Duration) error {
+	select {
+	case <-s.done:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("session still open after %v", timeout)
+	}
+}
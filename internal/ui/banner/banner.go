@@ -1,10 +1,67 @@
 package banner
 
 import (
+	"io"
+	"log"
+	"os"
+	"strings"
+
 	"github.com/gdamore/tcell/v2"
 	"github.com/mevdschee/underground-node-network/internal/ui/common"
 )
 
+// Default limits for banner files, generous for ASCII art but small enough
+// that a banner stored in every new user's history stays cheap
+const (
+	DefaultMaxLines = 60
+	DefaultMaxBytes = 16 * 1024
+)
+
+// Load reads a banner file in the given encoding and returns its lines as
+// UTF-8, truncated to at most maxLines lines and maxBytes bytes of the file
+// (0 means no limit). Oversized banners are logged so the operator can trim
+// them.
+func Load(path string, maxLines, maxBytes int, enc Encoding) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if maxBytes > 0 {
+		// Read one extra byte to detect truncation
+		r = io.LimitReader(f, int64(maxBytes)+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	truncated := false
+	if maxBytes > 0 && len(data) > maxBytes {
+		data = data[:maxBytes]
+		// Drop the partial last line
+		if i := strings.LastIndexByte(string(data), '\n'); i >= 0 {
+			data = data[:i]
+		}
+		truncated = true
+	}
+
+	lines := strings.Split(decode(data, enc), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, "\r")
+	}
+	if maxLines > 0 && len(lines) > maxLines {
+		lines = lines[:maxLines]
+		truncated = true
+	}
+	if truncated {
+		log.Printf("Banner %s exceeds %d lines or %d bytes, truncated", path, maxLines, maxBytes)
+	}
+	return lines, nil
+}
+
 // Banner represents a multi-line ANSI art banner
 type Banner struct {
 	Lines []string
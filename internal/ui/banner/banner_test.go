@@ -0,0 +1,109 @@
+package banner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadTruncatesOversizedBanner(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "room.asc")
+
+	var b strings.Builder
+	for i := 0; i < 500; i++ {
+		b.WriteString(strings.Repeat("#", 40) + "\r\n")
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("line cap", func(t *testing.T) {
+		lines, err := Load(path, 10, 0, EncodingAuto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lines) != 10 {
+			t.Errorf("Expected 10 lines, got %d", len(lines))
+		}
+		if strings.HasSuffix(lines[0], "\r") {
+			t.Errorf("Expected carriage returns to be trimmed")
+		}
+	})
+
+	t.Run("byte cap", func(t *testing.T) {
+		lines, err := Load(path, 0, 100, EncodingAuto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		total := 0
+		for _, l := range lines {
+			total += len(l) + 1
+			if len(l) != 40 {
+				t.Errorf("Expected only whole lines, got %q", l)
+			}
+		}
+		if total > 100 {
+			t.Errorf("Expected at most 100 bytes, got %d", total)
+		}
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		lines, err := Load(path, 0, 0, EncodingAuto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 500 lines plus the empty string after the final newline
+		if len(lines) != 501 {
+			t.Errorf("Expected 501 lines, got %d", len(lines))
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := Load(filepath.Join(t.TempDir(), "nope.asc"), 10, 10, EncodingAuto); err == nil {
+			t.Errorf("Expected error for missing banner")
+		}
+	})
+}
+
+func TestCP437Banner(t *testing.T) {
+	// ╔═╗ ░▒▓█ over a red ANSI escape, then a SAUCE-style trailer
+	raw := []byte{0x1b, '[', '3', '1', 'm', 0xc9, 0xcd, 0xbb, ' ', 0xb0, 0xb1, 0xb2, 0xdb, '\r', '\n', 0x82, 't', 0xe9, 0x1a, 'S', 'A', 'U', 'C', 'E'}
+	path := filepath.Join(t.TempDir(), "room.asc")
+	if err := os.WriteFile(path, raw, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, enc := range []Encoding{EncodingCP437, EncodingAuto} {
+		t.Run(string(enc), func(t *testing.T) {
+			lines, err := Load(path, 0, 0, enc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []string{"\x1b[31m╔═╗ ░▒▓█", "étΘ"}
+			if strings.Join(lines, "|") != strings.Join(want, "|") {
+				t.Errorf("Expected %q, got %q", want, lines)
+			}
+		})
+	}
+
+	t.Run("utf-8 is left alone", func(t *testing.T) {
+		utf := filepath.Join(t.TempDir(), "room.asc")
+		if err := os.WriteFile(utf, []byte("╔═╗ café"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		lines, err := Load(utf, 0, 0, EncodingAuto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lines[0] != "╔═╗ café" {
+			t.Errorf("Expected UTF-8 banner unchanged, got %q", lines[0])
+		}
+	})
+
+	t.Run("unknown encoding", func(t *testing.T) {
+		if _, err := ParseEncoding("latin1"); err == nil {
+			t.Error("Expected an error for an unsupported encoding")
+		}
+	})
+}
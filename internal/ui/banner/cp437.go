@@ -0,0 +1,74 @@
+package banner
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Encoding is the character encoding of a banner file
+type Encoding string
+
+const (
+	// EncodingAuto keeps valid UTF-8 and reads anything else as CP437
+	EncodingAuto  Encoding = "auto"
+	EncodingUTF8  Encoding = "utf-8"
+	EncodingCP437 Encoding = "cp437"
+)
+
+// ParseEncoding validates an encoding name from a flag
+func ParseEncoding(name string) (Encoding, error) {
+	switch strings.ToLower(name) {
+	case "", "auto":
+		return EncodingAuto, nil
+	case "utf-8", "utf8":
+		return EncodingUTF8, nil
+	case "cp437", "ibm437", "dos":
+		return EncodingCP437, nil
+	}
+	return "", fmt.Errorf("unknown banner encoding %q (use auto, utf-8 or cp437)", name)
+}
+
+// cp437High maps CP437 bytes 0x80-0xFF to Unicode
+var cp437High = [128]rune{
+	'Ç', 'ü', 'é', 'â', 'ä', 'à', 'å', 'ç', 'ê', 'ë', 'è', 'ï', 'î', 'ì', 'Ä', 'Å',
+	'É', 'æ', 'Æ', 'ô', 'ö', 'ò', 'û', 'ù', 'ÿ', 'Ö', 'Ü', '¢', '£', '¥', '₧', 'ƒ',
+	'á', 'í', 'ó', 'ú', 'ñ', 'Ñ', 'ª', 'º', '¿', '⌐', '¬', '½', '¼', '¡', '«', '»',
+	'░', '▒', '▓', '│', '┤', '╡', '╢', '╖', '╕', '╣', '║', '╗', '╝', '╜', '╛', '┐',
+	'└', '┴', '┬', '├', '─', '┼', '╞', '╟', '╚', '╔', '╩', '╦', '╠', '═', '╬', '╧',
+	'╨', '╤', '╥', '╙', '╘', '╒', '╓', '╫', '╪', '┘', '┌', '█', '▄', '▌', '▐', '▀',
+	'α', 'ß', 'Γ', 'π', 'Σ', 'σ', 'µ', 'τ', 'Φ', 'Θ', 'Ω', 'δ', '∞', 'φ', 'ε', '∩',
+	'≡', '±', '≥', '≤', '⌠', '⌡', '÷', '≈', '°', '∙', '·', '√', 'ⁿ', '²', '■', ' ',
+}
+
+// decodeCP437 converts CP437 text to UTF-8. Bytes below 0x80 are kept as is,
+// so ANSI escape sequences and line endings survive. Everything from the DOS
+// end-of-file marker (Ctrl-Z) on, such as a SAUCE record, is dropped.
+func decodeCP437(data []byte) string {
+	var b strings.Builder
+	b.Grow(len(data))
+	for _, c := range data {
+		if c == 0x1a {
+			break
+		}
+		if c < 0x80 {
+			b.WriteByte(c)
+		} else {
+			b.WriteRune(cp437High[c-0x80])
+		}
+	}
+	return b.String()
+}
+
+// decode converts banner bytes in the given encoding to a UTF-8 string
+func decode(data []byte, enc Encoding) string {
+	switch enc {
+	case EncodingCP437:
+		return decodeCP437(data)
+	case EncodingAuto:
+		if !utf8.Valid(data) {
+			return decodeCP437(data)
+		}
+	}
+	return string(data)
+}
@@ -1,12 +1,22 @@
 package bridge
 
 import (
+	"bytes"
 	"io"
 	"sync"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 )
 
+// maxWriteBuffer is how many buffered output bytes are sent at once even
+// before the write delay has passed; a full screen redraw is usually larger.
+const maxWriteBuffer = 4096
+
+// oscStart marks writes that carry an OSC sequence for the client, which are
+// sent right away so actions like teleports are never held back.
+var oscStart = []byte("\033]")
+
 // SSHBus implements tcell.Tty for an ssh.Channel by reading from an InputBridge
 type SSHBus struct {
 	bridge   *InputBridge
@@ -16,6 +26,11 @@ type SSHBus struct {
 	mu       sync.Mutex
 	cb       func()
 	doneChan chan struct{}
+
+	wmu   sync.Mutex
+	wbuf  []byte
+	delay time.Duration
+	timer *time.Timer
 }
 
 func NewSSHBus(bridge *InputBridge, width, height int) *SSHBus {
@@ -28,10 +43,24 @@ func NewSSHBus(bridge *InputBridge, width, height int) *SSHBus {
 	}
 }
 
+// SetWriteDelay enables output coalescing: small writes are collected for at
+// most delay and then sent as one, so the many tiny writes of the UIs and
+// prompts do not each become an SSH packet. Zero writes straight through.
+func (b *SSHBus) SetWriteDelay(delay time.Duration) {
+	b.wmu.Lock()
+	defer b.wmu.Unlock()
+	b.delay = delay
+	if delay <= 0 {
+		b.flushLocked()
+	}
+}
+
 func (b *SSHBus) Read(p []byte) (int, error) {
 	if len(p) == 0 {
 		return 0, nil
 	}
+	// Whoever reads is waiting for an answer to what was written before
+	b.Flush()
 	select {
 	case <-b.doneChan:
 		return 0, io.EOF
@@ -74,7 +103,45 @@ func (b *SSHBus) Read(p []byte) (int, error) {
 }
 
 func (b *SSHBus) Write(p []byte) (int, error) {
-	return b.bridge.channel.Write(p)
+	b.wmu.Lock()
+	defer b.wmu.Unlock()
+	if b.delay <= 0 {
+		if err := b.flushLocked(); err != nil {
+			return 0, err
+		}
+		return b.bridge.channel.Write(p)
+	}
+	b.wbuf = append(b.wbuf, p...)
+	if len(b.wbuf) >= maxWriteBuffer || bytes.Contains(p, oscStart) {
+		if err := b.flushLocked(); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.delay, func() { b.Flush() })
+	}
+	return len(p), nil
+}
+
+// Flush sends any buffered output to the channel.
+func (b *SSHBus) Flush() error {
+	b.wmu.Lock()
+	defer b.wmu.Unlock()
+	return b.flushLocked()
+}
+
+func (b *SSHBus) flushLocked() error {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if len(b.wbuf) == 0 {
+		return nil
+	}
+	_, err := b.bridge.channel.Write(b.wbuf)
+	b.wbuf = b.wbuf[:0]
+	return err
 }
 
 func (b *SSHBus) Close() error {
@@ -83,6 +150,7 @@ func (b *SSHBus) Close() error {
 }
 
 func (b *SSHBus) SignalExit() {
+	b.Flush()
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	select {
@@ -93,6 +161,7 @@ func (b *SSHBus) SignalExit() {
 }
 
 func (b *SSHBus) Reset() {
+	b.Flush()
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	select {
@@ -108,8 +177,8 @@ func (b *SSHBus) ForceClose() error {
 }
 
 func (b *SSHBus) Start() error { return nil }
-func (b *SSHBus) Stop() error  { return nil }
-func (b *SSHBus) Drain() error { return nil }
+func (b *SSHBus) Stop() error  { return b.Flush() }
+func (b *SSHBus) Drain() error { return b.Flush() }
 
 func (b *SSHBus) WindowSize() (tcell.WindowSize, error) {
 	b.mu.Lock()
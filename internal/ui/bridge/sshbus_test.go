@@ -0,0 +1,151 @@
+package bridge
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingChannel is an ssh.Channel that records every write
+type countingChannel struct {
+	mu     sync.Mutex
+	out    bytes.Buffer
+	writes int
+	closed chan struct{}
+}
+
+func newCountingChannel() *countingChannel {
+	return &countingChannel{closed: make(chan struct{})}
+}
+
+func (c *countingChannel) Read(p []byte) (int, error) {
+	<-c.closed
+	return 0, io.EOF
+}
+
+func (c *countingChannel) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writes++
+	return c.out.Write(p)
+}
+
+func (c *countingChannel) Close() error {
+	select {
+	case <-c.closed:
+	default:
+		close(c.closed)
+	}
+	return nil
+}
+
+func (c *countingChannel) CloseWrite() error { return nil }
+func (c *countingChannel) SendRequest(string, bool, []byte) (bool, error) {
+	return false, nil
+}
+func (c *countingChannel) Stderr() io.ReadWriter { return nil }
+
+func (c *countingChannel) stats() (int, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.writes, c.out.String()
+}
+
+func TestSSHBusWriteCoalescing(t *testing.T) {
+	newBus := func(delay time.Duration) (*SSHBus, *countingChannel) {
+		ch := newCountingChannel()
+		t.Cleanup(func() { ch.Close() })
+		bus := NewSSHBus(NewInputBridge(ch), 80, 24)
+		bus.SetWriteDelay(delay)
+		return bus, ch
+	}
+
+	t.Run("unbuffered", func(t *testing.T) {
+		bus, ch := newBus(0)
+		for i := 0; i < 10; i++ {
+			fmt.Fprintf(bus, "line %d\r\n", i)
+		}
+		if writes, _ := ch.stats(); writes != 10 {
+			t.Errorf("Expected 10 writes, got %d", writes)
+		}
+	})
+
+	t.Run("coalesced", func(t *testing.T) {
+		bus, ch := newBus(time.Hour)
+		for i := 0; i < 10; i++ {
+			fmt.Fprintf(bus, "line %d\r\n", i)
+		}
+		if writes, _ := ch.stats(); writes != 0 {
+			t.Errorf("Expected writes to be held, got %d", writes)
+		}
+		bus.Flush()
+		writes, out := ch.stats()
+		if writes != 1 {
+			t.Errorf("Expected 1 write after Flush, got %d", writes)
+		}
+		if !bytes.HasPrefix([]byte(out), []byte("line 0\r\n")) || !bytes.HasSuffix([]byte(out), []byte("line 9\r\n")) {
+			t.Errorf("Unexpected output %q", out)
+		}
+	})
+
+	t.Run("delay", func(t *testing.T) {
+		bus, ch := newBus(10 * time.Millisecond)
+		fmt.Fprint(bus, "a")
+		fmt.Fprint(bus, "b")
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			if writes, out := ch.stats(); writes == 1 && out == "ab" {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		writes, out := ch.stats()
+		t.Errorf("Expected one write of \"ab\" after the delay, got %d writes of %q", writes, out)
+	})
+
+	t.Run("osc", func(t *testing.T) {
+		bus, ch := newBus(time.Hour)
+		fmt.Fprint(bus, "text")
+		fmt.Fprint(bus, "\033]31337;{\"action\":\"teleport\"}\007")
+		if writes, out := ch.stats(); writes != 1 || out != "text\033]31337;{\"action\":\"teleport\"}\007" {
+			t.Errorf("Expected an OSC write to flush right away, got %d writes of %q", writes, out)
+		}
+	})
+
+	t.Run("large", func(t *testing.T) {
+		bus, ch := newBus(time.Hour)
+		bus.Write(make([]byte, maxWriteBuffer))
+		if writes, _ := ch.stats(); writes != 1 {
+			t.Errorf("Expected a full buffer to flush, got %d writes", writes)
+		}
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		bus, ch := newBus(time.Hour)
+		fmt.Fprint(bus, "bye")
+		bus.Stop()
+		if writes, _ := ch.stats(); writes != 1 {
+			t.Errorf("Expected Stop to flush, got %d writes", writes)
+		}
+	})
+}
+
+func BenchmarkSSHBusWrites(b *testing.B) {
+	for _, delay := range []time.Duration{0, time.Hour} {
+		b.Run(fmt.Sprintf("delay=%v", delay), func(b *testing.B) {
+			ch := newCountingChannel()
+			defer ch.Close()
+			bus := NewSSHBus(NewInputBridge(ch), 80, 24)
+			bus.SetWriteDelay(delay)
+			for i := 0; i < b.N; i++ {
+				fmt.Fprintf(bus, "\033[%d;1H%s", i%24+1, "progress")
+			}
+			bus.Flush()
+			writes, _ := ch.stats()
+			b.ReportMetric(float64(writes)/float64(b.N), "packets/op")
+		})
+	}
+}
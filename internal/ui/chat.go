@@ -1,11 +1,11 @@
 package ui
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/mevdschee/underground-node-network/internal/ui/common"
@@ -24,19 +24,51 @@ type ChatUI struct {
 	mu        sync.Mutex
 	username  string
 	title     string
+	accent    tcell.Color // Header color, set by the room theme
 	onSend    func(string)
 	onExit    func()
 	onClose   func()
 	onCmd     func(string) bool
+	onTyping  func()
 	drawChan  chan struct{}
 	closeChan chan struct{}
 
-	success   bool
-	firstDraw bool
-	Headless  bool
-	Input     io.ReadWriter
+	success    bool
+	firstDraw  bool
+	typing     []string
+	lastTyping time.Time
+	pasting    bool            // inside a bracketed paste
+	pasteBuf   strings.Builder // text pasted so far
+	Headless   bool
+	LineMode   bool // Plain line-based I/O when no terminal screen is available
+	PasteMode  PasteMode
+	CmdPrefix  string // Marks input as a command, "/" when empty
+	Input      io.ReadWriter
 }
 
+// PasteMode controls what happens to a bracketed paste that spans several lines.
+// Single-line pastes always go into the input field.
+type PasteMode int
+
+const (
+	PasteMessage PasteMode = iota // send as one multi-line chat message, never as a command
+	PasteReject                   // refuse the paste with a warning
+)
+
+// ParsePasteMode maps a flag value ("message" or "reject") to a PasteMode
+func ParsePasteMode(name string) (PasteMode, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "message":
+		return PasteMessage, nil
+	case "reject":
+		return PasteReject, nil
+	}
+	return PasteMessage, fmt.Errorf("unknown paste mode: %s", name)
+}
+
+// typingThrottle limits how often OnTyping fires while the user keeps typing
+const typingThrottle = 2 * time.Second
+
 func NewChatUI(screen tcell.Screen) *ChatUI {
 	return &ChatUI{
 		screen:        screen,
@@ -51,7 +83,7 @@ func NewChatUI(screen tcell.Screen) *ChatUI {
 
 func (ui *ChatUI) SetUsername(name string) {
 	ui.mu.Lock()
-	ui.username = name
+	ui.username = common.StripANSI(name)
 	screen := ui.screen
 	ui.mu.Unlock()
 	if screen != nil {
@@ -61,7 +93,31 @@ func (ui *ChatUI) SetUsername(name string) {
 
 func (ui *ChatUI) SetTitle(title string) {
 	ui.mu.Lock()
-	ui.title = title
+	ui.title = common.StripANSI(title)
+	screen := ui.screen
+	ui.mu.Unlock()
+	if screen != nil {
+		screen.PostEvent(&tcell.EventInterrupt{})
+	}
+}
+
+// SetAccent sets the header color, or restores the default with ColorDefault
+func (ui *ChatUI) SetAccent(color tcell.Color) {
+	ui.mu.Lock()
+	ui.accent = color
+	screen := ui.screen
+	ui.mu.Unlock()
+	if screen != nil {
+		screen.PostEvent(&tcell.EventInterrupt{})
+	}
+}
+
+// SetMarkdown turns rendering of *bold*, _italic_ and `code` in chat
+// messages on or off
+func (ui *ChatUI) SetMarkdown(on bool) {
+	ui.mu.Lock()
+	ui.logs.Markdown = on
+	ui.logs.PhysicalLines = nil // rewrap on the next draw
 	screen := ui.screen
 	ui.mu.Unlock()
 	if screen != nil {
@@ -103,6 +159,37 @@ func (ui *ChatUI) OnCmd(cb func(string) bool) {
 	ui.mu.Unlock()
 }
 
+// OnTyping registers a callback fired (throttled) while the input line is non-empty
+func (ui *ChatUI) OnTyping(cb func()) {
+	ui.mu.Lock()
+	ui.onTyping = cb
+	ui.mu.Unlock()
+}
+
+// SetTyping sets the names shown in the "is typing" footer
+func (ui *ChatUI) SetTyping(names []string) {
+	ui.mu.Lock()
+	ui.typing = names
+	screen := ui.screen
+	ui.mu.Unlock()
+
+	if screen != nil {
+		screen.PostEvent(&tcell.EventInterrupt{})
+	}
+}
+
+func (ui *ChatUI) notifyTyping() {
+	ui.mu.Lock()
+	cb := ui.onTyping
+	if cb == nil || ui.cmdInput.Value == "" || time.Since(ui.lastTyping) < typingThrottle {
+		ui.mu.Unlock()
+		return
+	}
+	ui.lastTyping = time.Now()
+	ui.mu.Unlock()
+	cb()
+}
+
 func (ui *ChatUI) SetCommandHistory(history []string) {
 	ui.mu.Lock()
 	defer ui.mu.Unlock()
@@ -146,7 +233,9 @@ func (ui *ChatUI) GetMessages() []log.Message {
 
 func (ui *ChatUI) ClearMessages() {
 	ui.mu.Lock()
+	markdown := ui.logs.Markdown
 	ui.logs = log.NewLogView()
+	ui.logs.Markdown = markdown
 	ui.mu.Unlock()
 	if ui.screen != nil {
 		ui.screen.PostEvent(&tcell.EventInterrupt{})
@@ -175,6 +264,21 @@ func (ui *ChatUI) Close(success bool) {
 	}
 }
 
+// EnableLineMode switches the UI to plain line-based I/O and prints the
+// messages received so far, for terminals that tcell cannot drive.
+func (ui *ChatUI) EnableLineMode() {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	ui.LineMode = true
+	ui.screen = nil
+	if ui.Input == nil {
+		return
+	}
+	for _, m := range ui.logs.Messages {
+		fmt.Fprintf(ui.Input, "%s\r\n", m.Text)
+	}
+}
+
 func (ui *ChatUI) Reset() {
 	ui.mu.Lock()
 	defer ui.mu.Unlock()
@@ -183,14 +287,40 @@ func (ui *ChatUI) Reset() {
 	ui.logs.ScrollOffset = 0
 }
 
+// LastSeen returns how many messages had been drawn up to the bottom of the
+// chat pane, for restoring the scroll position after a reconnect
+func (ui *ChatUI) LastSeen() int {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	return ui.logs.LastSeen()
+}
+
+// RestoreScroll scrolls back to a LastSeen position on the next draw. It
+// survives Reset, so it can be set while replaying history.
+func (ui *ChatUI) RestoreScroll(seen int) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	ui.logs.RestoreScroll(seen)
+}
+
+// isCommand reports whether input starts with the command prefix
+func (ui *ChatUI) isCommand(input string) bool {
+	prefix := ui.CmdPrefix
+	if prefix == "" {
+		prefix = "/"
+	}
+	return strings.HasPrefix(input, prefix)
+}
+
 func (ui *ChatUI) Run() string {
-	if ui.Headless {
+	if ui.Headless || ui.LineMode {
+		cmdChan := make(chan string, 1)
 		if ui.Input != nil {
 			go func() {
-				scanner := bufio.NewScanner(ui.Input)
+				scanner := LineReader(ui.Input, ui.LineMode)
 				for scanner.Scan() {
 					msg := scanner.Text()
-					if strings.HasPrefix(msg, "/") {
+					if ui.isCommand(msg) {
 						handled := false
 						ui.mu.Lock()
 						onCmd := ui.onCmd
@@ -199,6 +329,7 @@ func (ui *ChatUI) Run() string {
 							handled = onCmd(msg)
 						}
 						if !handled {
+							cmdChan <- msg
 							ui.Close(true)
 							return
 						}
@@ -210,10 +341,16 @@ func (ui *ChatUI) Run() string {
 			}()
 		}
 		<-ui.closeChan
-		return "" // Headless command return not supported in this simplified refactor
+		select {
+		case cmd := <-cmdChan:
+			return cmd
+		default:
+			return ""
+		}
 	}
 
 	// Initial setup
+	ui.screen.EnablePaste()
 	blackStyle := tcell.StyleDefault.Background(tcell.ColorBlack).Foreground(tcell.ColorWhite)
 	ui.screen.SetStyle(blackStyle)
 	ui.screen.Sync()
@@ -233,31 +370,43 @@ func (ui *ChatUI) Run() string {
 			ui.screen.Sync()
 		case *tcell.EventInterrupt:
 			// Just redraw
+		case *tcell.EventPaste:
+			if ev.Start() {
+				ui.pasting = true
+				ui.pasteBuf.Reset()
+			} else if ui.pasting {
+				ui.pasting = false
+				ui.handlePaste(ui.pasteBuf.String())
+			}
 		case *tcell.EventKey:
 			if ev.Key() == tcell.KeyCtrlC {
 				ui.Close(false)
 				return ""
 			}
 
-			if ev.Key() == tcell.KeyPgUp {
-				ui.mu.Lock()
-				ui.logs.ScrollOffset += 10
-				ui.mu.Unlock()
+			if ui.pasting {
+				// Collect pasted keys literally so Enter can't submit lines one by one
+				switch ev.Key() {
+				case tcell.KeyRune:
+					ui.pasteBuf.WriteRune(ev.Rune())
+				case tcell.KeyEnter, tcell.KeyLF:
+					ui.pasteBuf.WriteByte('\n')
+				case tcell.KeyTab:
+					ui.pasteBuf.WriteByte(' ')
+				}
 				continue
 			}
-			if ev.Key() == tcell.KeyPgDn {
-				ui.mu.Lock()
-				ui.logs.ScrollOffset -= 10
-				if ui.logs.ScrollOffset < 0 {
-					ui.logs.ScrollOffset = 0
-				}
-				ui.mu.Unlock()
+
+			ui.mu.Lock()
+			scrolled := ui.logs.HandleKey(ev)
+			ui.mu.Unlock()
+			if scrolled {
 				continue
 			}
 
 			submitted, val := ui.cmdInput.HandleKey(ev)
 			if submitted {
-				if strings.HasPrefix(val, "/") {
+				if ui.isCommand(val) {
 					handled := false
 					ui.mu.Lock()
 					onCmd := ui.onCmd
@@ -272,6 +421,11 @@ func (ui *ChatUI) Run() string {
 				} else if ui.onSend != nil {
 					ui.onSend(val)
 				}
+				ui.mu.Lock()
+				ui.lastTyping = time.Time{}
+				ui.mu.Unlock()
+			} else {
+				ui.notifyTyping()
 			}
 		}
 
@@ -283,7 +437,52 @@ func (ui *ChatUI) Run() string {
 	}
 }
 
+// handlePaste deals with the text of a completed bracketed paste. One line
+// is inserted at the cursor; several lines are sent as a single message or
+// refused, depending on PasteMode.
+func (ui *ChatUI) handlePaste(text string) {
+	text = strings.Trim(text, "\n")
+	if text == "" {
+		return
+	}
+	if !strings.Contains(text, "\n") {
+		ui.cmdInput.Insert(text)
+		ui.notifyTyping()
+		return
+	}
+
+	if ui.PasteMode == PasteReject {
+		for i, line := range strings.Split(text, "\n") {
+			if ui.isCommand(strings.TrimSpace(line)) {
+				ui.AddMessage(fmt.Sprintf("Paste rejected: line %d looks like a command.", i+1), MsgServer)
+				return
+			}
+		}
+		ui.AddMessage("Paste rejected: multi-line pastes are disabled here.", MsgServer)
+		return
+	}
+
+	if ui.onSend != nil {
+		ui.onSend(text)
+	}
+	ui.mu.Lock()
+	ui.lastTyping = time.Time{}
+	ui.mu.Unlock()
+}
+
 func (ui *ChatUI) AddMessage(msg string, msgType MessageType) {
+	ui.AddColoredMessage(msg, msgType, tcell.ColorDefault)
+}
+
+// AddColoredMessage adds a message shown in the sender's chosen color
+func (ui *ChatUI) AddColoredMessage(msg string, msgType MessageType, color tcell.Color) {
+	ui.AddChatMessage(0, msg, msgType, color)
+}
+
+// AddChatMessage adds a message that can later be changed with EditMessage
+// and DeleteMessage by its id
+func (ui *ChatUI) AddChatMessage(id int, msg string, msgType MessageType, color tcell.Color) {
+	msg = common.StripANSI(msg)
 	ui.mu.Lock()
 	defer ui.mu.Unlock()
 
@@ -303,12 +502,16 @@ func (ui *ChatUI) AddMessage(msg string, msgType MessageType) {
 		lt = log.MsgAction
 	case MsgWhisper:
 		lt = log.MsgWhisper
+	case MsgStaff:
+		lt = log.MsgStaff
 	default:
 		lt = log.MsgChat
 	}
 
-	ui.logs.AddMessage(msg, lt)
-	if ui.Headless && ui.Input != nil {
+	ui.logs.Add(log.Message{Text: msg, Type: lt, Color: color, ID: id})
+	if ui.LineMode && ui.Input != nil {
+		fmt.Fprintf(ui.Input, "%s\r\n", strings.ReplaceAll(msg, "\n", "\r\n"))
+	} else if ui.Headless && ui.Input != nil {
 		fmt.Fprintf(ui.Input, "%s\n", msg)
 	}
 	if ui.screen != nil {
@@ -316,6 +519,35 @@ func (ui *ChatUI) AddMessage(msg string, msgType MessageType) {
 	}
 }
 
+// EditMessage replaces the text of the message with the given id. Line-based
+// sessions cannot rewrite what they printed, so they get the new text as a
+// new line.
+func (ui *ChatUI) EditMessage(id int, msg string) {
+	msg = common.StripANSI(msg)
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	if !ui.logs.Update(id, msg) {
+		return
+	}
+	if ui.LineMode && ui.Input != nil {
+		fmt.Fprintf(ui.Input, "%s\r\n", strings.ReplaceAll(msg, "\n", "\r\n"))
+	} else if ui.Headless && ui.Input != nil {
+		fmt.Fprintf(ui.Input, "%s\n", msg)
+	}
+	if ui.screen != nil {
+		ui.screen.PostEvent(&tcell.EventInterrupt{})
+	}
+}
+
+// DeleteMessage removes the message with the given id
+func (ui *ChatUI) DeleteMessage(id int) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	if ui.logs.Remove(id) && ui.screen != nil {
+		ui.screen.PostEvent(&tcell.EventInterrupt{})
+	}
+}
+
 func (ui *ChatUI) Draw() {
 	if ui.screen == nil {
 		return
@@ -324,10 +556,13 @@ func (ui *ChatUI) Draw() {
 	defer ui.mu.Unlock()
 
 	s := ui.screen
-	w, h := s.Size()
+	w, h := common.ClampSize(s.Size())
 
 	blackStyle := tcell.StyleDefault.Background(tcell.ColorBlack).Foreground(tcell.ColorWhite)
 	headerStyle := blackStyle.Foreground(tcell.ColorLightCyan).Bold(true)
+	if ui.accent != tcell.ColorDefault {
+		headerStyle = headerStyle.Foreground(ui.accent)
+	}
 	sepStyle := blackStyle.Foreground(tcell.ColorDimGray)
 
 	s.Clear()
@@ -351,8 +586,8 @@ func (ui *ChatUI) Draw() {
 
 	// 1. Draw horizontal separators
 	for x := 0; x < w; x++ {
-		s.SetContent(x, 1, '─', nil, sepStyle)
-		s.SetContent(x, h-2, '─', nil, sepStyle)
+		s.SetContent(x, 1, common.Glyphs.Horizontal, nil, sepStyle)
+		s.SetContent(x, h-2, common.Glyphs.Horizontal, nil, sepStyle)
 	}
 
 	// 2. Draw Sidebar
@@ -383,18 +618,12 @@ func (ui *ChatUI) Draw() {
 		}
 
 		// Add a separator between doors and people
-		s.SetContent(mainW, 2+doorsH, '│', nil, sepStyle)
+		s.SetContent(mainW, 2+doorsH, common.Glyphs.Vertical, nil, sepStyle)
 	}
 
 	// 3. Draw Logs
 	logH := h - 4
 	if logH > 0 {
-		if ui.logs.ScrollOffset > len(ui.logs.PhysicalLines)-logH {
-			ui.logs.ScrollOffset = len(ui.logs.PhysicalLines) - logH
-		}
-		if ui.logs.ScrollOffset < 0 {
-			ui.logs.ScrollOffset = 0
-		}
 		logW := w - 2
 		if sidebarW > 0 {
 			logW = mainW - 1
@@ -404,12 +633,36 @@ func (ui *ChatUI) Draw() {
 
 	// 4. Draw Connectors (last to ensure they aren't overwritten)
 	if sidebarW > 0 {
-		s.SetContent(mainW, 1, '┬', nil, sepStyle)
-		s.SetContent(mainW, h-2, '┴', nil, sepStyle)
+		s.SetContent(mainW, 1, common.Glyphs.TeeDown, nil, sepStyle)
+		s.SetContent(mainW, h-2, common.Glyphs.TeeUp, nil, sepStyle)
 	}
 
-	// 5. Draw Input
+	// 5. Draw typing indicator in the footer separator
+	if footer := typingText(ui.typing); footer != "" {
+		text := " " + footer + " "
+		textW := len([]rune(text))
+		if textW > mainW-4 {
+			textW = mainW - 4
+		}
+		common.DrawText(s, 2, h-2, text, textW, sepStyle.Italic(true))
+	}
+
+	// 6. Draw Input
 	ui.cmdInput.Draw(s, 1, h-1, w-2, blackStyle, blackStyle.Foreground(tcell.ColorGreen))
 
 	s.Show()
 }
+
+// typingText formats the footer line for the given typing people
+func typingText(names []string) string {
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf("%s is typing%s", names[0], common.Glyphs.Ellipsis)
+	case 2:
+		return fmt.Sprintf("%s and %s are typing%s", names[0], names[1], common.Glyphs.Ellipsis)
+	default:
+		return "several people are typing" + common.Glyphs.Ellipsis
+	}
+}
@@ -0,0 +1,92 @@
+package common
+
+import (
+	"os"
+	"strings"
+)
+
+// GlyphSet holds the characters the UIs draw their frames with
+type GlyphSet struct {
+	Horizontal rune // thin separator lines
+	Vertical   rune
+	TeeDown    rune // where a vertical separator meets the top line
+	TeeUp      rune // where it meets the bottom line
+
+	// Heavy border around popups and forms
+	BorderTopLeft     rune
+	BorderTopRight    rune
+	BorderBottomLeft  rune
+	BorderBottomRight rune
+	BorderHorizontal  rune
+	BorderVertical    rune
+
+	// Block characters for the client's message boxes
+	BlockTopLeft     string
+	BlockTopRight    string
+	BlockBottomLeft  string
+	BlockBottomRight string
+	BlockTop         string
+	BlockBottom      string
+	BlockLeft        string
+	BlockRight       string
+	Shadow           string
+	ShadowBottom     string
+
+	IconInfo    string
+	IconError   string
+	IconWarning string
+
+	Bullet   string
+	Pointer  string
+	Ellipsis string
+	Favorite string
+}
+
+// UnicodeGlyphs uses box-drawing and block characters
+var UnicodeGlyphs = GlyphSet{
+	Horizontal: '─', Vertical: '│', TeeDown: '┬', TeeUp: '┴',
+	BorderTopLeft: '┏', BorderTopRight: '┓', BorderBottomLeft: '┗', BorderBottomRight: '┛',
+	BorderHorizontal: '━', BorderVertical: '┃',
+	BlockTopLeft: "▛", BlockTopRight: "▜", BlockBottomLeft: "▙", BlockBottomRight: "▟",
+	BlockTop: "▀", BlockBottom: "▄", BlockLeft: "▌", BlockRight: "▐",
+	Shadow: "█", ShadowBottom: "▀",
+	IconInfo: "ⓘ", IconError: "✖", IconWarning: "⚠",
+	Bullet: "•", Pointer: "▶", Ellipsis: "…", Favorite: "★",
+}
+
+// ASCIIGlyphs draws everything with plain ASCII for terminals that lack the
+// Unicode glyphs
+var ASCIIGlyphs = GlyphSet{
+	Horizontal: '-', Vertical: '|', TeeDown: '+', TeeUp: '+',
+	BorderTopLeft: '+', BorderTopRight: '+', BorderBottomLeft: '+', BorderBottomRight: '+',
+	BorderHorizontal: '=', BorderVertical: '|',
+	BlockTopLeft: "+", BlockTopRight: "+", BlockBottomLeft: "+", BlockBottomRight: "+",
+	BlockTop: "-", BlockBottom: "-", BlockLeft: "|", BlockRight: "|",
+	Shadow: "#", ShadowBottom: "#",
+	IconInfo: "i", IconError: "x", IconWarning: "!",
+	Bullet: "*", Pointer: ">", Ellipsis: "...", Favorite: "*",
+}
+
+// Glyphs is the glyph set in use, chosen once at startup with SetASCII
+var Glyphs = UnicodeGlyphs
+
+// SetASCII switches every UI in the process to ASCII-only glyphs
+func SetASCII(ascii bool) {
+	if ascii {
+		Glyphs = ASCIIGlyphs
+	} else {
+		Glyphs = UnicodeGlyphs
+	}
+}
+
+// LocaleIsUTF8 reports whether the locale environment announces UTF-8. An
+// unset locale counts as UTF-8, as most terminals today are.
+func LocaleIsUTF8() bool {
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if v := os.Getenv(name); v != "" {
+			v = strings.ToLower(v)
+			return strings.Contains(v, "utf-8") || strings.Contains(v, "utf8")
+		}
+	}
+	return true
+}
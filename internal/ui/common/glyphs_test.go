@@ -0,0 +1,60 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestASCIIGlyphs(t *testing.T) {
+	SetASCII(true)
+	t.Cleanup(func() { SetASCII(false) })
+
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(10, 5)
+
+	t.Run("border", func(t *testing.T) {
+		DrawBorder(screen, 0, 0, 6, 4, tcell.StyleDefault)
+		want := []string{
+			"+====+",
+			"|    |",
+			"|    |",
+			"+====+",
+		}
+		for y, row := range want {
+			for x, r := range row {
+				if r == ' ' {
+					continue
+				}
+				got, _, _, _ := screen.GetContent(x, y)
+				if got != r {
+					t.Errorf("At %d,%d expected %q, got %q", x, y, r, got)
+				}
+				if got > 127 {
+					t.Errorf("At %d,%d found non-ASCII %q", x, y, got)
+				}
+			}
+		}
+	})
+
+	t.Run("truncation", func(t *testing.T) {
+		if got := TruncateString("underground", 8); got != "under..." {
+			t.Errorf("Expected ASCII ellipsis, got %q", got)
+		}
+		if got := TruncateString("underground", 2); got != ".." {
+			t.Errorf("Expected a clipped ellipsis, got %q", got)
+		}
+	})
+
+	t.Run("back to unicode", func(t *testing.T) {
+		SetASCII(false)
+		if got := TruncateString("underground", 8); got != "undergr…" {
+			t.Errorf("Expected Unicode ellipsis, got %q", got)
+		}
+		SetASCII(true)
+	})
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -22,3 +23,10 @@ func SendOSC(w io.Writer, action string, params map[string]interface{}) error {
 	_, err = fmt.Fprintf(w, "\x1b]31337;%s\x07", string(jsonData))
 	return err
 }
+
+// SendClipboard sends an OSC 52 sequence asking the terminal to put text on
+// the system clipboard
+func SendClipboard(w io.Writer, text string) error {
+	_, err := fmt.Fprintf(w, "\x1b]52;c;%s\x07", base64.StdEncoding.EncodeToString([]byte(text)))
+	return err
+}
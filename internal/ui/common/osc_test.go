@@ -0,0 +1,16 @@
+package common
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSendClipboard(t *testing.T) {
+	var buf bytes.Buffer
+	if err := SendClipboard(&buf, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "\x1b]52;c;aGVsbG8=\x07"; got != want {
+		t.Errorf("SendClipboard wrote %q, want %q", got, want)
+	}
+}
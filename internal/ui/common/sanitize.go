@@ -0,0 +1,104 @@
+package common
+
+import (
+	"strings"
+	"unicode"
+)
+
+// StripANSI removes terminal escape sequences and control characters from
+// untrusted text before it is rendered. It understands CSI (ESC [ ... final),
+// OSC/DCS/APC/PM/SOS strings (terminated by BEL or ESC \), two-byte escapes
+// and their 8-bit C1 forms. Tabs become spaces; newlines are kept so
+// multi-line messages still wrap.
+func StripANSI(s string) string {
+	if isPlain(s) {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == 0x1b:
+			i = skipEscape(runes, i+1)
+		case r == 0x9b: // C1 CSI
+			i = skipCSI(runes, i+1)
+		case r == 0x90 || r == 0x9d || r == 0x98 || r == 0x9e || r == 0x9f: // C1 DCS, OSC, SOS, PM, APC
+			i = skipString(runes, i+1)
+		case r == '\n':
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteRune(' ')
+		case unicode.IsControl(r):
+			// Drop C0/C1 controls, including BEL, backspace and carriage return
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// isPlain reports whether s has no control characters at all, which is the
+// common case for chat and lets StripANSI return without allocating.
+func isPlain(s string) bool {
+	for _, r := range s {
+		if unicode.IsControl(r) && r != '\n' {
+			return false
+		}
+	}
+	return true
+}
+
+// skipEscape skips the sequence following an ESC at runes[i-1] and returns
+// the index of its last rune.
+func skipEscape(runes []rune, i int) int {
+	if i >= len(runes) {
+		return i - 1
+	}
+	switch runes[i] {
+	case '[':
+		return skipCSI(runes, i+1)
+	case ']', 'P', 'X', '^', '_':
+		return skipString(runes, i+1)
+	}
+	// Intermediate bytes (e.g. ESC ( B) followed by one final byte
+	for i < len(runes) && runes[i] >= 0x20 && runes[i] <= 0x2f {
+		i++
+	}
+	if i >= len(runes) {
+		return len(runes) - 1
+	}
+	return i
+}
+
+// skipCSI skips parameter and intermediate bytes up to and including the
+// final byte of a control sequence.
+func skipCSI(runes []rune, i int) int {
+	for ; i < len(runes); i++ {
+		if runes[i] >= 0x40 && runes[i] <= 0x7e {
+			return i
+		}
+		if runes[i] < 0x20 || runes[i] > 0x3f {
+			// Malformed sequence, stop before the offending rune
+			return i - 1
+		}
+	}
+	return len(runes) - 1
+}
+
+// skipString skips a control string up to and including its BEL or ST
+// terminator. An unterminated string swallows the rest of the text.
+func skipString(runes []rune, i int) int {
+	for ; i < len(runes); i++ {
+		switch runes[i] {
+		case 0x07, 0x9c:
+			return i
+		case 0x1b:
+			if i+1 < len(runes) && runes[i+1] == '\\' {
+				return i + 1
+			}
+		}
+	}
+	return len(runes) - 1
+}
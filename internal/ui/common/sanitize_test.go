@@ -0,0 +1,67 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestStripANSI(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "hello world", "hello world"},
+		{"unicode", "héllo ✓ 世界", "héllo ✓ 世界"},
+		{"color", "\x1b[1;31mred\x1b[0m text", "red text"},
+		{"cursor move", "a\x1b[10;20Hb\x1b[2Jc", "abc"},
+		{"private mode", "\x1b[?1049hx\x1b[?25l", "x"},
+		{"osc title bel", "\x1b]0;pwned\x07after", "after"},
+		{"osc st", "\x1b]31337;{\"action\":\"popup\"}\x1b\\after", "after"},
+		{"unterminated osc", "before\x1b]0;never ends", "before"},
+		{"charset", "\x1b(Bx", "x"},
+		{"reset", "\x1bcx", "x"},
+		{"c1 csi", "a\u009b31mb", "ab"},
+		{"c1 osc", "a\u009d0;t\u009cb", "ab"},
+		{"controls", "a\rb\bc\x07d\x00e", "abcde"},
+		{"tab and newline", "a\tb\nc", "a b\nc"},
+		{"trailing esc", "abc\x1b", "abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripANSI(tt.in); got != tt.want {
+				t.Errorf("StripANSI(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDrawTextSkipsControls(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(10, 1)
+
+	DrawText(screen, 0, 0, "a\x1bb\x07c", 10, tcell.StyleDefault)
+	screen.Show()
+
+	cells, _, _ := screen.GetContents()
+	got := ""
+	for _, c := range cells[:3] {
+		got += string(c.Runes)
+	}
+	if got != "abc" {
+		t.Errorf("Expected control characters to be skipped, got %q", got)
+	}
+}
+
+func TestWrapTextMultiLine(t *testing.T) {
+	got := WrapText("<alice> first\nsecond", 40)
+	want := []string{"<alice> first", "  second"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("WrapText = %q, want %q", got, want)
+	}
+}
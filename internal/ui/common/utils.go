@@ -3,11 +3,44 @@ package common
 import (
 	"encoding/binary"
 	"strings"
+	"unicode"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/uniseg"
 )
 
+// Minimum usable terminal size for layout math
+const (
+	MinWidth  = 20
+	MinHeight = 5
+)
+
+// ClampSize raises a reported terminal size to the minimum usable size.
+// Some clients briefly report 0x0 during SSH negotiation.
+func ClampSize(w, h int) (int, int) {
+	if w < MinWidth {
+		w = MinWidth
+	}
+	if h < MinHeight {
+		h = MinHeight
+	}
+	return w, h
+}
+
+// NewScreen creates and initializes a terminfo screen on the given tty. It
+// fails when the client's TERM has no usable terminfo entry, in which case
+// callers fall back to line mode.
+func NewScreen(tty tcell.Tty) (tcell.Screen, error) {
+	screen, err := tcell.NewTerminfoScreenFromTty(tty)
+	if err != nil {
+		return nil, err
+	}
+	if err := screen.Init(); err != nil {
+		return nil, err
+	}
+	return screen, nil
+}
+
 // DrawText renders a string at (x, y) on the screen, respecting visual width and grapheme clusters.
 func DrawText(s tcell.Screen, x, y int, text string, width int, style tcell.Style) {
 	if s == nil {
@@ -17,6 +50,10 @@ func DrawText(s tcell.Screen, x, y int, text string, width int, style tcell.Styl
 	posX := 0
 	for gr.Next() {
 		str := gr.Str()
+		if r := []rune(str)[0]; unicode.IsControl(r) {
+			// Never hand control characters to the terminal
+			continue
+		}
 		w := uniseg.StringWidth(str)
 		if posX+w > width {
 			break
@@ -39,8 +76,14 @@ func TruncateString(s string, limit int) string {
 	if limit <= 0 {
 		return ""
 	}
-	if limit == 1 {
-		return "…"
+	ellipsis := Glyphs.Ellipsis
+	ew := uniseg.StringWidth(ellipsis)
+	if limit == ew {
+		return ellipsis
+	}
+	if limit < ew {
+		// Only the ASCII "..." is wider than one cell
+		return ellipsis[:limit]
 	}
 
 	res := ""
@@ -48,13 +91,13 @@ func TruncateString(s string, limit int) string {
 	gr := uniseg.NewGraphemes(s)
 	for gr.Next() {
 		w := uniseg.StringWidth(gr.Str())
-		if width+w > limit-1 {
-			return res + "…"
+		if width+w > limit-ew {
+			return res + ellipsis
 		}
 		res += gr.Str()
 		width += w
 	}
-	return res + "…"
+	return res + ellipsis
 }
 
 // ParsePtyRequest parses the width and height from an SSH pty-req payload
@@ -89,6 +132,15 @@ func WrapText(s string, width int) []string {
 		return []string{s}
 	}
 
+	// Multi-line messages: later lines are indented like wrapped ones
+	if first, rest, ok := strings.Cut(s, "\n"); ok {
+		lines := WrapText(first, width)
+		for _, part := range strings.Split(rest, "\n") {
+			lines = append(lines, WrapText("  "+part, width)...)
+		}
+		return lines
+	}
+
 	var lines []string
 	remaining := s
 	first := true
@@ -185,16 +237,16 @@ func FillRegion(s tcell.Screen, x, y, w, h int, r rune, style tcell.Style) {
 }
 
 func DrawBorder(s tcell.Screen, x, y, w, h int, style tcell.Style) {
-	s.SetContent(x, y, '┏', nil, style)
-	s.SetContent(x+w-1, y, '┓', nil, style)
-	s.SetContent(x, y+h-1, '┗', nil, style)
-	s.SetContent(x+w-1, y+h-1, '┛', nil, style)
+	s.SetContent(x, y, Glyphs.BorderTopLeft, nil, style)
+	s.SetContent(x+w-1, y, Glyphs.BorderTopRight, nil, style)
+	s.SetContent(x, y+h-1, Glyphs.BorderBottomLeft, nil, style)
+	s.SetContent(x+w-1, y+h-1, Glyphs.BorderBottomRight, nil, style)
 	for lx := x + 1; lx < x+w-1; lx++ {
-		s.SetContent(lx, y, '━', nil, style)
-		s.SetContent(lx, y+h-1, '━', nil, style)
+		s.SetContent(lx, y, Glyphs.BorderHorizontal, nil, style)
+		s.SetContent(lx, y+h-1, Glyphs.BorderHorizontal, nil, style)
 	}
 	for ly := y + 1; ly < y+h-1; ly++ {
-		s.SetContent(x, ly, '┃', nil, style)
-		s.SetContent(x+w-1, ly, '┃', nil, style)
+		s.SetContent(x, ly, Glyphs.BorderVertical, nil, style)
+		s.SetContent(x+w-1, ly, Glyphs.BorderVertical, nil, style)
 	}
 }
@@ -1,7 +1,6 @@
 package ui
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	stdlog "log"
@@ -25,7 +24,9 @@ type EntryUI struct {
 	registration  *form.Form
 	passwordInput *password.PasswordEntry
 
-	roomsData []RoomInfo
+	roomsData    []RoomInfo
+	roomFocus    bool   // Tab moved keyboard focus to the room list
+	selectedRoom string // name of the highlighted room
 
 	prompt         string
 	promptChan     chan string
@@ -45,6 +46,7 @@ type EntryUI struct {
 	closeChan      chan struct{}
 	success        bool
 	Headless       bool
+	LineMode       bool // Plain line-based I/O when no terminal screen is available
 	Input          io.ReadWriter
 
 	// SCROLLING
@@ -96,7 +98,14 @@ func (ui *EntryUI) GetCommandHistory() []string {
 func (ui *EntryUI) SetChatHistory(messages []Message) {
 	ui.mu.Lock()
 	defer ui.mu.Unlock()
-	ui.logs.Messages = append([]Message{}, messages...)
+	ui.logs.Messages = make([]Message, len(messages))
+	for i, m := range messages {
+		m.Text = common.StripANSI(m.Text)
+		ui.logs.Messages[i] = m
+		if ui.LineMode && ui.Input != nil {
+			fmt.Fprintf(ui.Input, "%s\r\n", m.Text)
+		}
+	}
 	if ui.screen != nil {
 		ui.screen.PostEvent(&tcell.EventInterrupt{})
 	}
@@ -141,16 +150,91 @@ func (ui *EntryUI) SetRooms(rooms []RoomInfo) {
 
 	var items []string
 	for _, r := range rooms {
-		items = append(items, fmt.Sprintf("%s (%d)", r.Name, r.PeopleCount))
+		item := fmt.Sprintf("%s (%d)", r.Name, r.PeopleCount)
+		if r.Favorite {
+			item = common.Glyphs.Favorite + " " + item
+		}
+		items = append(items, item)
 	}
 	ui.roomsDataSpec = sidebar.NewSidebar("Rooms:", 25)
 	ui.roomsDataSpec.SetItems(items)
+	ui.selectRoom(ui.roomIndex(ui.selectedRoom))
 
 	if ui.screen != nil {
 		ui.screen.PostEvent(&tcell.EventInterrupt{})
 	}
 }
 
+// roomIndex returns the position of the named room in the list, or 0 if
+// it is gone. Caller must hold ui.mu.
+func (ui *EntryUI) roomIndex(name string) int {
+	for i, r := range ui.roomsData {
+		if r.Name == name {
+			return i
+		}
+	}
+	return 0
+}
+
+// selectRoom highlights the room at index i, clamped to the list. Caller
+// must hold ui.mu.
+func (ui *EntryUI) selectRoom(i int) {
+	if len(ui.roomsData) == 0 {
+		ui.selectedRoom = ""
+		ui.roomFocus = false
+		if ui.roomsDataSpec != nil {
+			ui.roomsDataSpec.Selected = -1
+			ui.roomsDataSpec.Focused = false
+		}
+		return
+	}
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(ui.roomsData) {
+		i = len(ui.roomsData) - 1
+	}
+	ui.selectedRoom = ui.roomsData[i].Name
+	ui.roomsDataSpec.Selected = i
+	ui.roomsDataSpec.Focused = ui.roomFocus
+}
+
+// handleRoomKey moves the room selection while the list has focus and
+// returns the command to run, if any. Caller must hold ui.mu.
+func (ui *EntryUI) handleRoomKey(ev *tcell.EventKey) (cmd string, handled bool) {
+	i := ui.roomIndex(ui.selectedRoom)
+	switch ev.Key() {
+	case tcell.KeyUp:
+		ui.selectRoom(i - 1)
+	case tcell.KeyDown:
+		ui.selectRoom(i + 1)
+	case tcell.KeyHome:
+		ui.selectRoom(0)
+	case tcell.KeyEnd:
+		ui.selectRoom(len(ui.roomsData) - 1)
+	case tcell.KeyEnter:
+		ui.setRoomFocus(false)
+		return "/join " + ui.selectedRoom, true
+	case tcell.KeyEscape:
+		ui.setRoomFocus(false)
+	default:
+		return "", false
+	}
+	return "", true
+}
+
+// setRoomFocus moves keyboard focus between the input and the room list.
+// Caller must hold ui.mu.
+func (ui *EntryUI) setRoomFocus(focus bool) {
+	if len(ui.roomsData) == 0 {
+		focus = false
+	}
+	ui.roomFocus = focus
+	if ui.roomsDataSpec != nil {
+		ui.roomsDataSpec.Focused = focus
+	}
+}
+
 func (ui *EntryUI) SetBanner(lines []string) {
 	ui.mu.Lock()
 	defer ui.mu.Unlock()
@@ -164,6 +248,7 @@ func (ui *EntryUI) SetBanner(lines []string) {
 }
 
 func (ui *EntryUI) ShowMessage(msg string, msgType MessageType) {
+	msg = common.StripANSI(msg)
 	ui.mu.Lock()
 	defer ui.mu.Unlock()
 
@@ -183,12 +268,16 @@ func (ui *EntryUI) ShowMessage(msg string, msgType MessageType) {
 		lt = log.MsgAction
 	case MsgWhisper:
 		lt = log.MsgWhisper
+	case MsgStaff:
+		lt = log.MsgStaff
 	default:
 		lt = log.MsgChat
 	}
 
 	ui.logs.AddMessage(msg, lt)
-	if ui.Headless && ui.Input != nil {
+	if ui.LineMode && ui.Input != nil {
+		fmt.Fprintf(ui.Input, "%s\r\n", msg)
+	} else if ui.Headless && ui.Input != nil {
 		fmt.Fprintf(ui.Input, "%s\n", msg)
 	}
 	if ui.screen != nil {
@@ -198,7 +287,7 @@ func (ui *EntryUI) ShowMessage(msg string, msgType MessageType) {
 
 func (ui *EntryUI) SetUsername(username string) {
 	ui.mu.Lock()
-	ui.username = username
+	ui.username = common.StripANSI(username)
 	screen := ui.screen
 	ui.mu.Unlock()
 	if screen != nil {
@@ -220,6 +309,10 @@ func (ui *EntryUI) Prompt(q string) string {
 }
 
 func (ui *EntryUI) PromptForm(fields []form.FormField) []string {
+	if ui.LineMode {
+		return ui.promptFormLines(fields)
+	}
+
 	ui.mu.Lock()
 	ui.InFormMode = true
 	//ui.FlushInputUntil = time.Now().Add(600 * time.Millisecond) // Ignore buffered input for 600ms
@@ -240,6 +333,37 @@ func (ui *EntryUI) PromptForm(fields []form.FormField) []string {
 	return res
 }
 
+// promptFormLines asks for each form field on its own line, keeping the
+// current value when the answer is left empty.
+func (ui *EntryUI) promptFormLines(fields []form.FormField) []string {
+	ui.mu.Lock()
+	ui.InFormMode = true
+	ui.mu.Unlock()
+
+	results := make([]string, len(fields))
+	for i, f := range fields {
+		if f.Error != "" {
+			fmt.Fprintf(ui.Input, "%s: %s\r\n", f.Label, f.Error)
+		}
+		fmt.Fprintf(ui.Input, "%s [%s]: ", f.Label, f.Value)
+		select {
+		case answer := <-ui.promptChan:
+			if answer == "" {
+				answer = f.Value
+			}
+			results[i] = answer
+		case <-ui.closeChan:
+			return nil
+		}
+	}
+
+	ui.mu.Lock()
+	ui.InFormMode = false
+	ui.mu.Unlock()
+
+	return results
+}
+
 func (ui *EntryUI) PromptPassword(q string) string {
 	ui.mu.Lock()
 	ui.InFormMode = true
@@ -294,15 +418,24 @@ func (ui *EntryUI) Close(success bool) {
 }
 
 func (ui *EntryUI) Run() bool {
-	if ui.Headless {
+	if ui.Headless || ui.LineMode {
 		if ui.Input != nil {
 			go func() {
-				scanner := bufio.NewScanner(ui.Input)
+				scanner := LineReader(ui.Input, ui.LineMode)
 				for scanner.Scan() {
 					cmd := scanner.Text()
 					ui.mu.Lock()
 					onCmd := ui.onCmd
+					inForm := ui.LineMode && ui.InFormMode
 					ui.mu.Unlock()
+					if inForm {
+						select {
+						case ui.promptChan <- cmd:
+						case <-ui.closeChan:
+							return
+						}
+						continue
+					}
 					if onCmd != nil {
 						onCmd(cmd)
 					}
@@ -370,7 +503,7 @@ func (ui *EntryUI) Draw() {
 	defer ui.mu.Unlock()
 
 	s := ui.screen
-	w, h := s.Size()
+	w, h := common.ClampSize(s.Size())
 
 	blackStyle := tcell.StyleDefault.Background(tcell.ColorBlack).Foreground(tcell.ColorWhite)
 	headerStyle := blackStyle.Foreground(tcell.ColorLightCyan).Bold(true)
@@ -424,8 +557,8 @@ func (ui *EntryUI) Draw() {
 
 	// 1. Draw horizontal separators
 	for x := 0; x < w; x++ {
-		s.SetContent(x, sepY, '─', nil, sepStyle)
-		s.SetContent(x, h-2, '─', nil, sepStyle)
+		s.SetContent(x, sepY, common.Glyphs.Horizontal, nil, sepStyle)
+		s.SetContent(x, h-2, common.Glyphs.Horizontal, nil, sepStyle)
 	}
 
 	// 2. Draw Sidebar
@@ -437,12 +570,6 @@ func (ui *EntryUI) Draw() {
 	// 3. Draw Logs
 	logH := h - 3 - sepY
 	if logH > 0 {
-		if ui.logs.ScrollOffset > len(ui.logs.PhysicalLines)-logH {
-			ui.logs.ScrollOffset = len(ui.logs.PhysicalLines) - logH
-		}
-		if ui.logs.ScrollOffset < 0 {
-			ui.logs.ScrollOffset = 0
-		}
 		logW := w - 2
 		if sidebarW > 0 {
 			logW = mainW - 1
@@ -452,8 +579,8 @@ func (ui *EntryUI) Draw() {
 
 	// 4. Draw Connectors (last to ensure they aren't overwritten)
 	if sidebarW > 0 {
-		s.SetContent(mainW, sepY, '┬', nil, sepStyle)
-		s.SetContent(mainW, h-2, '┴', nil, sepStyle)
+		s.SetContent(mainW, sepY, common.Glyphs.TeeDown, nil, sepStyle)
+		s.SetContent(mainW, h-2, common.Glyphs.TeeUp, nil, sepStyle)
 	}
 
 	// 5. Draw Input
@@ -506,19 +633,27 @@ func (ui *EntryUI) HandleKeyResult(ev *tcell.EventKey) (done bool, success bool)
 		return true, false
 	}
 
-	if ev.Key() == tcell.KeyPgUp {
-		ui.logs.ScrollOffset += 10
+	if ui.logs.HandleKey(ev) {
 		ui.mu.Unlock()
 		return false, false
 	}
-	if ev.Key() == tcell.KeyPgDn {
-		ui.logs.ScrollOffset -= 10
-		if ui.logs.ScrollOffset < 0 {
-			ui.logs.ScrollOffset = 0
-		}
+
+	if ev.Key() == tcell.KeyTab && prompt == "" {
+		ui.setRoomFocus(!ui.roomFocus)
 		ui.mu.Unlock()
 		return false, false
 	}
+	if ui.roomFocus {
+		if cmd, handled := ui.handleRoomKey(ev); handled {
+			ui.mu.Unlock()
+			if cmd != "" && onCmd != nil {
+				onCmd(cmd)
+			}
+			return false, false
+		}
+		// Typing goes to the input, so focus follows
+		ui.setRoomFocus(false)
+	}
 
 	submitted, val := ui.cmdInput.HandleKey(ev)
 	if submitted {
@@ -63,7 +63,7 @@ func (f *Form) Draw(s tcell.Screen, w, h int, style tcell.Style) {
 		fieldY := startY + 2 + (i * 3)
 		label := field.Label
 		if i == f.ActiveIdx {
-			label = fmt.Sprintf("▶ %s", label)
+			label = fmt.Sprintf("%s %s", common.Glyphs.Pointer, label)
 		} else {
 			label = fmt.Sprintf("  %s", label)
 		}
@@ -91,7 +91,8 @@ func (f *Form) Draw(s tcell.Screen, w, h int, style tcell.Style) {
 		}
 	}
 
-	hint := "TAB to move • ENTER to submit • ESC to cancel"
+	bullet := " " + common.Glyphs.Bullet + " "
+	hint := "TAB to move" + bullet + "ENTER to submit" + bullet + "ESC to cancel"
 	hintText := fmt.Sprintf(" %s ", hint)
 	common.DrawText(s, startX+(boxW-len(hintText))/2, startY+boxH-1, hintText, len(hintText), borderStyle)
 }
@@ -40,6 +40,14 @@ func (i *CommandInput) Draw(s tcell.Screen, x, y, w int, style, promptStyle tcel
 	s.ShowCursor(x+promptWidth+visualPos, y)
 }
 
+// Insert adds text at the cursor
+func (i *CommandInput) Insert(text string) {
+	runes := []rune(i.Value)
+	ins := []rune(text)
+	i.Value = string(runes[:i.CursorIdx]) + text + string(runes[i.CursorIdx:])
+	i.CursorIdx += len(ins)
+}
+
 func (i *CommandInput) HandleKey(ev *tcell.EventKey) (bool, string) {
 	switch ev.Key() {
 	case tcell.KeyEnter:
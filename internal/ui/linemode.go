@@ -0,0 +1,97 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// LineReader wraps an interactive terminal stream for line-based (non-TUI)
+// sessions. Terminals without a usable terminfo entry still send raw keys,
+// so it echoes what the user types and applies backspace before a line is
+// handed to the scanner.
+func LineReader(rw io.ReadWriter, echo bool) *bufio.Scanner {
+	var r io.Reader = rw
+	if echo {
+		r = &echoReader{rw: rw}
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Split(scanTerminalLines())
+	return scanner
+}
+
+// echoReader writes every byte it reads back to the terminal, translating
+// carriage returns and backspaces into their visible equivalents. Ctrl-C and
+// Ctrl-D end the input, like they end the TUI.
+type echoReader struct {
+	rw   io.ReadWriter
+	done bool
+}
+
+func (e *echoReader) Read(p []byte) (int, error) {
+	if e.done {
+		return 0, io.EOF
+	}
+	n, err := e.rw.Read(p)
+	if i := bytes.IndexAny(p[:n], "\x03\x04"); i >= 0 {
+		n, err = i, io.EOF
+		e.done = true
+	}
+	if n > 0 {
+		var out bytes.Buffer
+		for _, b := range p[:n] {
+			switch b {
+			case '\r', '\n':
+				out.WriteString("\r\n")
+			case '\b', 0x7f:
+				out.WriteString("\b \b")
+			default:
+				if b >= 0x20 {
+					out.WriteByte(b)
+				}
+			}
+		}
+		e.rw.Write(out.Bytes())
+	}
+	return n, err
+}
+
+// scanTerminalLines returns a bufio.SplitFunc that accepts "\r", "\n" and
+// "\r\n" as line endings and applies backspace characters to the line
+// contents. A bare "\r" ends the line immediately, since interactive
+// terminals send nothing after it until the next key is pressed.
+func scanTerminalLines() bufio.SplitFunc {
+	afterCR := false
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		skip := 0
+		if afterCR && len(data) > 0 && data[0] == '\n' {
+			skip = 1
+		}
+		rest := data[skip:]
+		if i := bytes.IndexAny(rest, "\r\n"); i >= 0 {
+			afterCR = rest[i] == '\r'
+			return skip + i + 1, applyBackspace(rest[:i]), nil
+		}
+		if atEOF && len(rest) > 0 {
+			return len(data), applyBackspace(rest), nil
+		}
+		if len(data) > 0 {
+			afterCR = false
+		}
+		return skip, nil, nil
+	}
+}
+
+func applyBackspace(line []byte) []byte {
+	out := make([]byte, 0, len(line))
+	for _, b := range line {
+		if b == '\b' || b == 0x7f {
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+			continue
+		}
+		out = append(out, b)
+	}
+	return out
+}
@@ -0,0 +1,76 @@
+package ui
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mevdschee/underground-node-network/internal/ui/common"
+)
+
+// fakeTerm feeds scripted keystrokes and records everything written back
+type fakeTerm struct {
+	in  io.Reader
+	mu  sync.Mutex
+	out bytes.Buffer
+}
+
+func (f *fakeTerm) Read(p []byte) (int, error) { return f.in.Read(p) }
+
+func (f *fakeTerm) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.out.Write(p)
+}
+
+func (f *fakeTerm) String() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.out.String()
+}
+
+func TestLineReader(t *testing.T) {
+	term := &fakeTerm{in: strings.NewReader("hello\ra\r\nb\nabx\x7fc\r\x03ignored\r")}
+	scanner := LineReader(term, true)
+
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	want := []string{"hello", "a", "b", "abc"}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected lines %q, got %q", want, lines)
+	}
+	if !strings.Contains(term.String(), "hello\r\n") {
+		t.Errorf("Expected typed input to be echoed, got %q", term.String())
+	}
+}
+
+func TestChatUILineModeFallback(t *testing.T) {
+	t.Setenv("TERM", "dumb")
+	if _, err := common.NewScreen(nil); err == nil {
+		t.Skip("terminfo for TERM=dumb is usable here")
+	}
+
+	term := &fakeTerm{in: strings.NewReader("hi there\r/door\r")}
+	chat := NewChatUI(nil)
+	chat.Input = term
+	chat.AddMessage("*** Welcome ***", MsgSystem)
+	chat.EnableLineMode()
+
+	var sent []string
+	chat.OnSend(func(msg string) { sent = append(sent, msg) })
+	chat.OnCmd(func(cmd string) bool { return false })
+
+	if cmd := chat.Run(); cmd != "/door" {
+		t.Errorf("Expected door command to be returned, got %q", cmd)
+	}
+	if len(sent) != 1 || sent[0] != "hi there" {
+		t.Errorf("Expected one chat message, got %q", sent)
+	}
+	if !strings.Contains(term.String(), "*** Welcome ***\r\n") {
+		t.Errorf("Expected earlier messages to be printed, got %q", term.String())
+	}
+}
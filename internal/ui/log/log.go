@@ -1,8 +1,11 @@
 package log
 
 import (
+	"strings"
+
 	"github.com/gdamore/tcell/v2"
 	"github.com/mevdschee/underground-node-network/internal/ui/common"
+	"github.com/rivo/uniseg"
 )
 
 type MessageType int
@@ -15,28 +18,70 @@ const (
 	MsgSystem
 	MsgAction
 	MsgWhisper
+	MsgStaff // operator-only messages sent with /ops
 )
 
 type Message struct {
-	Text string
-	Type MessageType
+	Text  string
+	Type  MessageType
+	Color tcell.Color // Sender's chosen color for chat messages (ColorDefault = none)
+	ID    int         // Identifies a chat message for edits and deletes (0 = none)
 }
 
-// LogView manages a scrollable feed of messages
+// LogView manages a scrollable feed of messages, wrapped to the width it
+// was last drawn at. ScrollOffset counts lines up from the bottom.
 type LogView struct {
 	Messages      []Message
 	PhysicalLines []Message
 	ScrollOffset  int
 	Width         int
+	Height        int  // visible lines at the last Draw
+	Markdown      bool // render *bold*, _italic_ and `code` in chat messages
 	lastMsgCount  int
+	lineMsg       []int   // index into Messages of each physical line
+	lineRuns      [][]Run // formatted runs of each physical line (nil = plain)
+	restoreSeen   int     // LastSeen to scroll back to on the next Draw
+	restoring     bool
 }
 
+// ScrollStep is how many lines PgUp and PgDn move
+const ScrollStep = 10
+
 func NewLogView() *LogView {
 	return &LogView{}
 }
 
 func (v *LogView) AddMessage(msg string, msgType MessageType) {
-	v.Messages = append(v.Messages, Message{Text: msg, Type: msgType})
+	v.Add(Message{Text: msg, Type: msgType})
+}
+
+func (v *LogView) Add(m Message) {
+	v.Messages = append(v.Messages, m)
+}
+
+// Update replaces the text of the message with the given id and reports
+// whether it was found
+func (v *LogView) Update(id int, text string) bool {
+	for i := range v.Messages {
+		if id != 0 && v.Messages[i].ID == id {
+			v.Messages[i].Text = text
+			v.PhysicalLines = nil // rewrap on the next draw
+			return true
+		}
+	}
+	return false
+}
+
+// Remove deletes the message with the given id and reports whether it was found
+func (v *LogView) Remove(id int) bool {
+	for i := range v.Messages {
+		if id != 0 && v.Messages[i].ID == id {
+			v.Messages = append(v.Messages[:i], v.Messages[i+1:]...)
+			v.PhysicalLines = nil
+			return true
+		}
+	}
+	return false
 }
 
 func (v *LogView) UpdatePhysicalLines(width int) {
@@ -46,12 +91,140 @@ func (v *LogView) UpdatePhysicalLines(width int) {
 	v.Width = width
 	v.lastMsgCount = len(v.Messages)
 	v.PhysicalLines = nil
-	for _, m := range v.Messages {
-		lines := common.WrapText(m.Text, width)
+	v.lineMsg = nil
+	v.lineRuns = nil
+	for i, m := range v.Messages {
+		text := m.Text
+		var formats []Format
+		if v.Markdown && isChatType(m.Type) {
+			text, formats = flattenRuns(ParseMarkdown(m.Text))
+		}
+		lines := common.WrapText(text, width)
+		cursor := 0
 		for _, line := range lines {
-			v.PhysicalLines = append(v.PhysicalLines, Message{Text: line, Type: m.Type})
+			var runs []Run
+			if formats != nil {
+				runs, cursor = lineFormats(line, text, formats, cursor)
+			}
+			v.PhysicalLines = append(v.PhysicalLines, Message{Text: line, Type: m.Type, Color: m.Color, ID: m.ID})
+			v.lineMsg = append(v.lineMsg, i)
+			v.lineRuns = append(v.lineRuns, runs)
+		}
+	}
+}
+
+// isChatType reports whether messages of type t are written by people and
+// may contain markdown
+func isChatType(t MessageType) bool {
+	return t == MsgChat || t == MsgSelf || t == MsgWhisper || t == MsgAction
+}
+
+// flattenRuns joins runs into plain text with the format of every byte
+func flattenRuns(runs []Run) (string, []Format) {
+	var b strings.Builder
+	var formats []Format
+	for _, r := range runs {
+		b.WriteString(r.Text)
+		for range len(r.Text) {
+			formats = append(formats, r.Format)
+		}
+	}
+	if formats == nil {
+		formats = []Format{}
+	}
+	return b.String(), formats
+}
+
+// lineFormats splits a wrapped line of text back into formatted runs. The
+// line's text is searched in text from cursor, as wrapping only drops spaces
+// and adds an indent, which is returned as a plain run. It returns the runs
+// and the cursor after the line, or nil runs if the line was not found.
+func lineFormats(line, text string, formats []Format, cursor int) ([]Run, int) {
+	body := strings.TrimLeft(line, " ")
+	idx := strings.Index(text[cursor:], body)
+	if idx < 0 {
+		return nil, cursor
+	}
+	start := cursor + idx
+	var runs []Run
+	if indent := line[:len(line)-len(body)]; indent != "" {
+		runs = append(runs, Run{Text: indent})
+	}
+	for i := 0; i < len(body); {
+		j := i
+		for j < len(body) && formats[start+j] == formats[start+i] {
+			j++
+		}
+		runs = append(runs, Run{Text: body[i:j], Format: formats[start+i]})
+		i = j
+	}
+	return runs, start + len(body)
+}
+
+// LastSeen returns the number of messages up to and including the one on
+// the bottom line of the last Draw, so len(Messages)-LastSeen() messages were
+// scrolled out of view below it
+func (v *LogView) LastSeen() int {
+	if v.ScrollOffset == 0 || len(v.lineMsg) != len(v.PhysicalLines) {
+		return len(v.Messages)
+	}
+	bottom := len(v.PhysicalLines) - 1 - v.ScrollOffset
+	if bottom < 0 {
+		return 0
+	}
+	return v.lineMsg[bottom] + 1
+}
+
+// RestoreScroll scrolls the next Draw so that message seen-1 is on the
+// bottom line again, as returned by LastSeen before a reconnect
+func (v *LogView) RestoreScroll(seen int) {
+	v.restoreSeen = seen
+	v.restoring = true
+}
+
+// applyRestore turns a pending RestoreScroll into a ScrollOffset once the
+// messages are wrapped
+func (v *LogView) applyRestore() {
+	v.restoring = false
+	v.ScrollOffset = 0
+	for i := len(v.lineMsg) - 1; i >= 0; i-- {
+		if v.lineMsg[i] < v.restoreSeen {
+			v.ScrollOffset = len(v.lineMsg) - 1 - i
+			break
 		}
 	}
+	v.clampScroll()
+}
+
+// ScrollBy moves the view up (positive) or down (negative) by n lines,
+// staying within the scrollback
+func (v *LogView) ScrollBy(n int) {
+	v.ScrollOffset += n
+	v.clampScroll()
+}
+
+// clampScroll keeps ScrollOffset between the newest line and the oldest
+// full page
+func (v *LogView) clampScroll() {
+	if v.Height > 0 && v.ScrollOffset > len(v.PhysicalLines)-v.Height {
+		v.ScrollOffset = len(v.PhysicalLines) - v.Height
+	}
+	if v.ScrollOffset < 0 {
+		v.ScrollOffset = 0
+	}
+}
+
+// HandleKey scrolls on PgUp/PgDn and reports whether the key was used
+func (v *LogView) HandleKey(ev *tcell.EventKey) bool {
+	switch ev.Key() {
+	case tcell.KeyPgUp:
+		v.ScrollBy(ScrollStep)
+		return true
+	case tcell.KeyPgDn:
+		v.ScrollBy(-ScrollStep)
+		return true
+	}
+	return false
 }
 
 func (v *LogView) Draw(s tcell.Screen, x, y, w, h int, baseStyle tcell.Style) {
@@ -60,6 +233,11 @@ func (v *LogView) Draw(s tcell.Screen, x, y, w, h int, baseStyle tcell.Style) {
 	}
 
 	v.UpdatePhysicalLines(w)
+	v.Height = h
+	if v.restoring {
+		v.applyRestore()
+	}
+	v.clampScroll()
 
 	totalLines := len(v.PhysicalLines)
 	if totalLines == 0 {
@@ -86,9 +264,43 @@ func (v *LogView) Draw(s tcell.Screen, x, y, w, h int, baseStyle tcell.Style) {
 			style = style.Foreground(tcell.ColorDarkOrchid)
 		case MsgSelf:
 			style = style.Foreground(tcell.ColorLightSkyBlue)
+		case MsgStaff:
+			style = style.Foreground(tcell.ColorOrangeRed).Bold(true)
 		case MsgChat:
 			style = style.Foreground(tcell.ColorWhite)
 		}
+		if line.Color != tcell.ColorDefault && (line.Type == MsgChat || line.Type == MsgSelf) {
+			style = style.Foreground(line.Color)
+		}
+		if start+i < len(v.lineRuns) && v.lineRuns[start+i] != nil {
+			drawRuns(s, x, y+i, v.lineRuns[start+i], w, style)
+			continue
+		}
 		common.DrawText(s, x, y+i, line.Text, w, style)
 	}
 }
+
+// drawRuns renders formatted runs on one line, clearing the rest of it
+func drawRuns(s tcell.Screen, x, y int, runs []Run, w int, style tcell.Style) {
+	posX := 0
+	for _, r := range runs {
+		runStyle := style
+		if r.Format&FormatBold != 0 {
+			runStyle = runStyle.Bold(true)
+		}
+		if r.Format&FormatItalic != 0 {
+			runStyle = runStyle.Italic(true)
+		}
+		if r.Format&FormatCode != 0 {
+			runStyle = runStyle.Background(tcell.ColorDarkSlateGray)
+		}
+		if posX >= w {
+			return
+		}
+		common.DrawText(s, x+posX, y, r.Text, w-posX, runStyle)
+		posX += uniseg.StringWidth(r.Text)
+	}
+	if posX < w {
+		common.DrawText(s, x+posX, y, "", w-posX, style)
+	}
+}
@@ -0,0 +1,174 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func newScreen(t *testing.T, w, h int) tcell.SimulationScreen {
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(w, h)
+	t.Cleanup(screen.Fini)
+	return screen
+}
+
+func TestLogViewWrapping(t *testing.T) {
+	t.Run("wraps to width", func(t *testing.T) {
+		v := NewLogView()
+		v.AddMessage("one two three four", MsgChat)
+		v.UpdatePhysicalLines(9)
+		if len(v.PhysicalLines) != 3 {
+			t.Fatalf("Expected 3 lines, got %d: %q", len(v.PhysicalLines), v.PhysicalLines)
+		}
+		if v.PhysicalLines[1].Text != "  three" {
+			t.Errorf("Expected indented continuation, got %q", v.PhysicalLines[1].Text)
+		}
+		for _, l := range v.PhysicalLines {
+			if l.Type != MsgChat {
+				t.Errorf("Expected wrapped line to keep type, got %v", l.Type)
+			}
+		}
+	})
+
+	t.Run("rewraps on width change and new messages", func(t *testing.T) {
+		v := NewLogView()
+		v.AddMessage(strings.Repeat("x", 20), MsgChat)
+		v.UpdatePhysicalLines(10)
+		if len(v.PhysicalLines) != 3 {
+			t.Fatalf("Expected 3 lines at width 10, got %d", len(v.PhysicalLines))
+		}
+		v.UpdatePhysicalLines(20)
+		if len(v.PhysicalLines) != 1 {
+			t.Fatalf("Expected 1 line at width 20, got %d", len(v.PhysicalLines))
+		}
+		v.AddMessage("more", MsgSystem)
+		v.UpdatePhysicalLines(20)
+		if len(v.PhysicalLines) != 2 {
+			t.Fatalf("Expected new message to be wrapped, got %d lines", len(v.PhysicalLines))
+		}
+	})
+}
+
+func TestLogViewScrolling(t *testing.T) {
+	screen := newScreen(t, 20, 5)
+	v := NewLogView()
+	for i := 0; i < 30; i++ {
+		v.AddMessage("line", MsgChat)
+	}
+	v.Draw(screen, 0, 0, 20, 5, tcell.StyleDefault)
+
+	t.Run("page up stops at oldest page", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			v.HandleKey(tcell.NewEventKey(tcell.KeyPgUp, 0, tcell.ModNone))
+		}
+		if v.ScrollOffset != 25 {
+			t.Errorf("Expected offset 25, got %d", v.ScrollOffset)
+		}
+	})
+
+	t.Run("page down stops at newest line", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			v.HandleKey(tcell.NewEventKey(tcell.KeyPgDn, 0, tcell.ModNone))
+		}
+		if v.ScrollOffset != 0 {
+			t.Errorf("Expected offset 0, got %d", v.ScrollOffset)
+		}
+	})
+
+	t.Run("other keys are ignored", func(t *testing.T) {
+		if v.HandleKey(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone)) {
+			t.Error("Expected Enter not to be handled")
+		}
+	})
+
+	t.Run("draw clamps after resize", func(t *testing.T) {
+		v.ScrollBy(25)
+		screen.SetSize(20, 10)
+		v.Draw(screen, 0, 0, 20, 10, tcell.StyleDefault)
+		if v.ScrollOffset != 20 {
+			t.Errorf("Expected offset 20 after growing, got %d", v.ScrollOffset)
+		}
+	})
+}
+
+func TestLogViewRestoreScroll(t *testing.T) {
+	newView := func() *LogView {
+		v := NewLogView()
+		for i := 0; i < 50; i++ {
+			v.AddMessage(fmt.Sprintf("message %d", i), MsgChat)
+		}
+		return v
+	}
+
+	t.Run("marker puts last seen message on the bottom line", func(t *testing.T) {
+		screen := newScreen(t, 20, 10)
+		v := newView()
+		v.RestoreScroll(30)
+		v.Draw(screen, 0, 0, 20, 10, tcell.StyleDefault)
+		if v.ScrollOffset != 20 {
+			t.Fatalf("Expected offset 20, got %d", v.ScrollOffset)
+		}
+		if got := v.LastSeen(); got != 30 {
+			t.Errorf("Expected LastSeen 30, got %d", got)
+		}
+	})
+
+	t.Run("wrapped messages count by their last line", func(t *testing.T) {
+		screen := newScreen(t, 10, 5)
+		v := NewLogView()
+		for i := 0; i < 10; i++ {
+			v.AddMessage("aaaa bbbb cccc", MsgChat) // wraps to 2 lines at width 10
+		}
+		v.RestoreScroll(7)
+		v.Draw(screen, 0, 0, 10, 5, tcell.StyleDefault)
+		if v.ScrollOffset != 6 {
+			t.Errorf("Expected offset 6, got %d", v.ScrollOffset)
+		}
+		if got := v.LastSeen(); got != 7 {
+			t.Errorf("Expected LastSeen 7, got %d", got)
+		}
+	})
+
+	t.Run("round trips through LastSeen", func(t *testing.T) {
+		screen := newScreen(t, 20, 10)
+		v := newView()
+		v.Draw(screen, 0, 0, 20, 10, tcell.StyleDefault)
+		v.ScrollBy(12)
+		seen := v.LastSeen()
+
+		replayed := newView()
+		replayed.RestoreScroll(seen)
+		replayed.Draw(screen, 0, 0, 20, 10, tcell.StyleDefault)
+		if replayed.ScrollOffset != 12 {
+			t.Errorf("Expected offset 12 after replay, got %d", replayed.ScrollOffset)
+		}
+	})
+
+	t.Run("marker is clamped to the oldest page", func(t *testing.T) {
+		screen := newScreen(t, 20, 10)
+		v := newView()
+		v.RestoreScroll(3)
+		v.Draw(screen, 0, 0, 20, 10, tcell.StyleDefault)
+		if v.ScrollOffset != 40 {
+			t.Errorf("Expected offset 40, got %d", v.ScrollOffset)
+		}
+	})
+
+	t.Run("restore applies only once", func(t *testing.T) {
+		screen := newScreen(t, 20, 10)
+		v := newView()
+		v.RestoreScroll(30)
+		v.Draw(screen, 0, 0, 20, 10, tcell.StyleDefault)
+		v.ScrollBy(-20)
+		v.Draw(screen, 0, 0, 20, 10, tcell.StyleDefault)
+		if v.ScrollOffset != 0 {
+			t.Errorf("Expected user scrolling to stick, got offset %d", v.ScrollOffset)
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package log
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Format is the inline formatting of a run of text
+type Format int
+
+const (
+	FormatBold Format = 1 << iota
+	FormatItalic
+	FormatCode
+)
+
+// Run is a piece of a message with one formatting
+type Run struct {
+	Text   string
+	Format Format
+}
+
+// ParseMarkdown splits text into runs for *bold*, _italic_ and `code`, with
+// the markers removed. A marker only counts when it has a partner: * and _
+// must start and end at a word boundary around non-space text, so 2*3*4 and
+// snake_case_names stay as they are. Nothing is formatted inside code.
+func ParseMarkdown(text string) []Run {
+	var runs []Run
+	var cur strings.Builder
+	format := Format(0)
+	flush := func() {
+		if cur.Len() > 0 {
+			runs = append(runs, Run{Text: cur.String(), Format: format})
+			cur.Reset()
+		}
+	}
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '`':
+			if end := strings.IndexByte(text[i+1:], '`'); end > 0 {
+				flush()
+				runs = append(runs, Run{Text: text[i+1 : i+1+end], Format: format | FormatCode})
+				i += end + 2
+				continue
+			}
+		case c == '*' || c == '_':
+			flag := FormatBold
+			if c == '_' {
+				flag = FormatItalic
+			}
+			if format&flag != 0 && closesAt(text, i) {
+				flush()
+				format &^= flag
+				i++
+				continue
+			}
+			if format&flag == 0 && opensAt(text, i) && findCloser(text, i) > 0 {
+				flush()
+				format |= flag
+				i++
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		cur.WriteString(text[i : i+size])
+		i += size
+	}
+	flush()
+	return runs
+}
+
+// opensAt reports whether the marker at i can open: it follows the start or
+// a non-word character and is followed by non-space text
+func opensAt(text string, i int) bool {
+	if i > 0 {
+		if r, _ := utf8.DecodeLastRuneInString(text[:i]); isWord(r) {
+			return false
+		}
+	}
+	r, size := utf8.DecodeRuneInString(text[i+1:])
+	return size > 0 && !unicode.IsSpace(r) && r != rune(text[i])
+}
+
+// closesAt reports whether the marker at i can close: it follows non-space
+// text and is followed by the end or a non-word character
+func closesAt(text string, i int) bool {
+	if i == 0 {
+		return false
+	}
+	if r, _ := utf8.DecodeLastRuneInString(text[:i]); unicode.IsSpace(r) {
+		return false
+	}
+	r, size := utf8.DecodeRuneInString(text[i+1:])
+	return size == 0 || !isWord(r)
+}
+
+// findCloser returns the index of the marker that closes the one opening at
+// i, or -1. Code spans in between are skipped.
+func findCloser(text string, i int) int {
+	for j := i + 2; j < len(text); j++ {
+		switch text[j] {
+		case '`':
+			if end := strings.IndexByte(text[j+1:], '`'); end > 0 {
+				j += end + 1
+			}
+		case text[i]:
+			if closesAt(text, j) {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+func isWord(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,74 @@
+package log
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestParseMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []Run
+	}{
+		{"plain", "hello world", []Run{{"hello world", 0}}},
+		{"bold", "a *big* deal", []Run{{"a ", 0}, {"big", FormatBold}, {" deal", 0}}},
+		{"italic", "_really_?", []Run{{"really", FormatItalic}, {"?", 0}}},
+		{"code", "run `go *test*` now", []Run{{"run ", 0}, {"go *test*", FormatCode}, {" now", 0}}},
+		{"nested", "*bold _both_*", []Run{{"bold ", FormatBold}, {"both", FormatBold | FormatItalic}}},
+		{"code inside bold", "*see `x`*", []Run{{"see ", FormatBold}, {"x", FormatBold | FormatCode}}},
+		{"unmatched", "a *b c", []Run{{"a *b c", 0}}},
+		{"inside words", "snake_case_name and 2*3*4", []Run{{"snake_case_name and 2*3*4", 0}}},
+		{"spaces inside markers", "a * b * c", []Run{{"a * b * c", 0}}},
+		{"empty markers", "** and __ and ``", []Run{{"** and __ and ``", 0}}},
+		{"chat prefix", "<bob_x> *hi*", []Run{{"<bob_x> ", 0}, {"hi", FormatBold}}},
+		{"unicode", "*héllo* wörld", []Run{{"héllo", FormatBold}, {" wörld", 0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseMarkdown(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMarkdown(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogViewMarkdown(t *testing.T) {
+	t.Run("off by default", func(t *testing.T) {
+		v := NewLogView()
+		v.AddMessage("<bob> *hi*", MsgChat)
+		v.UpdatePhysicalLines(40)
+		if v.PhysicalLines[0].Text != "<bob> *hi*" {
+			t.Errorf("Expected the markers to stay, got %q", v.PhysicalLines[0].Text)
+		}
+	})
+
+	t.Run("formats chat and keeps formats across wrapping", func(t *testing.T) {
+		v := NewLogView()
+		v.Markdown = true
+		v.AddMessage("<bob> one *two three* four", MsgChat)
+		v.AddMessage("*** bob joined ***", MsgSystem)
+		screen := newScreen(t, 14, 6)
+		v.Draw(screen, 0, 0, 14, 6, tcell.StyleDefault)
+
+		want := []string{"<bob> one two", "  three four", "*** bob"}
+		for i, text := range want {
+			if v.PhysicalLines[i].Text != text {
+				t.Errorf("Line %d: expected %q, got %q", i, text, v.PhysicalLines[i].Text)
+			}
+		}
+		bold := func(x, y int) bool {
+			_, _, style, _ := screen.GetContent(x, y)
+			_, _, attrs := style.Decompose()
+			return attrs&tcell.AttrBold != 0
+		}
+		if bold(6, 0) || !bold(10, 0) || !bold(2, 1) || bold(8, 1) {
+			t.Error("Expected only two and three to be bold")
+		}
+		if bold(13, 0) {
+			t.Error("Expected the rest of the line to be cleared without bold")
+		}
+	})
+}
@@ -1,9 +1,12 @@
 package password
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"sync"
+	"unicode/utf8"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/mevdschee/underground-node-network/internal/ui/common"
@@ -61,7 +64,7 @@ func (p *PasswordEntry) Draw(s tcell.Screen, w, h int, style tcell.Style) {
 	s.ShowCursor(startX+2+visualPos, startY+2)
 
 	// Hints
-	hint := "ENTER to submit • ESC to cancel"
+	hint := "ENTER to submit " + common.Glyphs.Bullet + " ESC to cancel"
 	common.DrawText(s, startX+(boxW-len(hint))/2, startY+boxH-1, fmt.Sprintf(" %s ", hint), len(hint)+2, borderStyle)
 }
 
@@ -102,24 +105,37 @@ func (p *PasswordEntry) HandleKey(ev *tcell.EventKey) (bool, string, bool) {
 	return false, "", false
 }
 
-// PasswordUI is a full-screen prompt for sensitive input (legacy/standalone wrapper)
+// PasswordUI is a full-screen prompt for sensitive input. It runs on any
+// tcell screen, so it works over an SSH channel as well as a local terminal.
 type PasswordUI struct {
 	screen    tcell.Screen
-	input     string
-	Cursor    int
+	entry     *PasswordEntry
+	Title     string // heading above the prompt
+	Error     string // shown below the prompt, e.g. after a wrong key
 	mu        sync.Mutex
 	done      chan string
 	closeChan chan struct{}
 }
 
+// NewPasswordUI returns the room key prompt
 func NewPasswordUI(screen tcell.Screen) *PasswordUI {
+	return NewPasswordPrompt(screen, "--- ROOM SECURED ---", "Enter Key: ")
+}
+
+// NewPasswordPrompt returns a prompt with its own heading and label, e.g.
+// for a key passphrase
+func NewPasswordPrompt(screen tcell.Screen, title, prompt string) *PasswordUI {
 	return &PasswordUI{
 		screen:    screen,
+		entry:     NewPasswordEntry(prompt),
+		Title:     title,
 		done:      make(chan string, 1),
 		closeChan: make(chan struct{}),
 	}
 }
 
+// Run shows the prompt until ENTER, which returns the input, or ESC/Ctrl+C,
+// which return ""
 func (ui *PasswordUI) Run() string {
 	if ui.screen == nil {
 		return ""
@@ -142,53 +158,13 @@ func (ui *PasswordUI) Run() string {
 			}
 			switch ev := ev.(type) {
 			case *tcell.EventKey:
-				if ev.Key() == tcell.KeyCtrlC || ev.Key() == tcell.KeyEscape {
-					ui.done <- ""
+				ui.mu.Lock()
+				done, value, _ := ui.entry.HandleKey(ev)
+				ui.mu.Unlock()
+				if done {
+					ui.done <- value
 					return
 				}
-				switch ev.Key() {
-				case tcell.KeyBackspace, tcell.KeyBackspace2:
-					ui.mu.Lock()
-					runes := []rune(ui.input)
-					if ui.Cursor > 0 {
-						ui.input = string(append(runes[:ui.Cursor-1], runes[ui.Cursor:]...))
-						ui.Cursor--
-					}
-					ui.mu.Unlock()
-				case tcell.KeyDelete:
-					ui.mu.Lock()
-					runes := []rune(ui.input)
-					if ui.Cursor < len(runes) {
-						ui.input = string(append(runes[:ui.Cursor], runes[ui.Cursor+1:]...))
-					}
-					ui.mu.Unlock()
-				case tcell.KeyLeft:
-					ui.mu.Lock()
-					if ui.Cursor > 0 {
-						ui.Cursor--
-					}
-					ui.mu.Unlock()
-				case tcell.KeyRight:
-					ui.mu.Lock()
-					if ui.Cursor < len([]rune(ui.input)) {
-						ui.Cursor++
-					}
-					ui.mu.Unlock()
-				case tcell.KeyHome:
-					ui.mu.Lock()
-					ui.Cursor = 0
-					ui.mu.Unlock()
-				case tcell.KeyEnd:
-					ui.mu.Lock()
-					ui.Cursor = len([]rune(ui.input))
-					ui.mu.Unlock()
-				case tcell.KeyRune:
-					ui.mu.Lock()
-					runes := []rune(ui.input)
-					ui.input = string(append(runes[:ui.Cursor], append([]rune{ev.Rune()}, runes[ui.Cursor:]...)...))
-					ui.Cursor++
-					ui.mu.Unlock()
-				}
 				ui.DrawStandalone()
 			case *tcell.EventResize:
 				ui.screen.Sync()
@@ -205,28 +181,32 @@ func (ui *PasswordUI) DrawStandalone() {
 	ui.mu.Lock()
 	defer ui.mu.Unlock()
 
-	w, h := ui.screen.Size()
+	w, h := common.ClampSize(ui.screen.Size())
 	style := tcell.StyleDefault.Background(tcell.ColorBlack).Foreground(tcell.ColorWhite)
 
 	ui.screen.Fill(' ', style)
 
-	title := "--- ROOM SECURED ---"
-	prompt := "Enter Key: "
-	stars := strings.Repeat("*", len(ui.input))
+	prompt := ui.entry.Prompt
+	stars := strings.Repeat("*", len([]rune(ui.entry.Value)))
 
-	tx := (w - len(title)) / 2
+	tx := (w - len(ui.Title)) / 2
 	px := (w - (len(prompt) + 20)) / 2
 	ty := h/2 - 2
 
-	common.DrawText(ui.screen, tx, ty, title, len(title), style.Bold(true))
-	common.DrawText(ui.screen, px, ty+2, prompt+stars, 30, style)
+	common.DrawText(ui.screen, tx, ty, ui.Title, len(ui.Title), style.Bold(true))
+	common.DrawText(ui.screen, px, ty+2, prompt+stars, len(prompt)+20, style)
 
 	// Cursor
-	prefix := stars[:ui.Cursor]
+	prefix := stars[:ui.entry.Cursor]
 	visualPos := uniseg.StringWidth(prefix)
 	ui.screen.ShowCursor(px+len(prompt)+visualPos, ty+2)
 
-	hint := "ENTER to submit • ESC to cancel"
+	if ui.Error != "" {
+		ex := (w - len(ui.Error)) / 2
+		common.DrawText(ui.screen, ex, ty+3, ui.Error, len(ui.Error), style.Foreground(tcell.ColorRed))
+	}
+
+	hint := "ENTER to submit " + common.Glyphs.Bullet + " ESC to cancel"
 	hx := (w - len(hint)) / 2
 	common.DrawText(ui.screen, hx, ty+4, hint, len(hint), style.Foreground(tcell.ColorLightCyan))
 
@@ -236,3 +216,54 @@ func (ui *PasswordUI) DrawStandalone() {
 func (ui *PasswordUI) Close() {
 	close(ui.closeChan)
 }
+
+// ErrCancelled is returned by ReadLine when the input is cancelled with
+// Ctrl+C or Ctrl+D
+var ErrCancelled = errors.New("input cancelled")
+
+// ReadLine is PasswordUI for streams without a screen, such as a raw local
+// terminal or an SSH channel in line mode. It writes prompt, echoes an
+// asterisk for every character and handles backspace. It reads byte by byte,
+// so input after the line stays in rw for the next reader.
+func ReadLine(rw io.ReadWriter, prompt string) (string, error) {
+	if _, err := io.WriteString(rw, prompt); err != nil {
+		return "", err
+	}
+	var input, pending []byte
+	b := make([]byte, 1)
+	for {
+		if _, err := io.ReadFull(rw, b); err != nil {
+			return "", err
+		}
+		if len(pending) > 0 || b[0] >= utf8.RuneSelf {
+			// Collect the bytes of a multi-byte character
+			pending = append(pending, b[0])
+			if !utf8.FullRune(pending) {
+				continue
+			}
+			if r, _ := utf8.DecodeRune(pending); r != utf8.RuneError {
+				input = append(input, pending...)
+				io.WriteString(rw, "*")
+			}
+			pending = pending[:0]
+			continue
+		}
+		switch c := b[0]; {
+		case c == '\r' || c == '\n':
+			io.WriteString(rw, "\r\n")
+			return string(input), nil
+		case c == 0x03 || c == 0x04:
+			io.WriteString(rw, "\r\n")
+			return "", ErrCancelled
+		case c == '\b' || c == 0x7f:
+			if len(input) > 0 {
+				_, size := utf8.DecodeLastRune(input)
+				input = input[:len(input)-size]
+				io.WriteString(rw, "\b \b")
+			}
+		case c >= 0x20:
+			input = append(input, c)
+			io.WriteString(rw, "*")
+		}
+	}
+}
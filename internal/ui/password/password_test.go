@@ -0,0 +1,97 @@
+package password
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func screenText(s tcell.SimulationScreen) string {
+	cells, w, _ := s.GetContents()
+	var b strings.Builder
+	for i, c := range cells {
+		if i > 0 && i%w == 0 {
+			b.WriteByte('\n')
+		}
+		if len(c.Runes) > 0 {
+			b.WriteRune(c.Runes[0])
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	return b.String()
+}
+
+func TestPasswordUI(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(80, 24)
+
+	pwd := NewPasswordPrompt(screen, "--- KEY LOCKED ---", "Passphrase: ")
+	result := make(chan string, 1)
+	go func() { result <- pwd.Run() }()
+
+	for _, r := range "s3cr" {
+		screen.InjectKey(tcell.KeyRune, r, tcell.ModNone)
+	}
+	screen.InjectKey(tcell.KeyBackspace2, 0, tcell.ModNone)
+	for _, r := range "ret" {
+		screen.InjectKey(tcell.KeyRune, r, tcell.ModNone)
+	}
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+
+	if got := <-result; got != "s3cret" {
+		t.Errorf("Expected s3cret, got %q", got)
+	}
+	text := screenText(screen)
+	if !strings.Contains(text, "--- KEY LOCKED ---") || !strings.Contains(text, "Passphrase: ******") {
+		t.Errorf("Expected the title and a masked value, got:\n%s", text)
+	}
+	if strings.Contains(text, "s3c") {
+		t.Errorf("Input was shown in the clear:\n%s", text)
+	}
+}
+
+func TestReadLine(t *testing.T) {
+	run := func(input string) (got, echo, rest string, err error) {
+		in := strings.NewReader(input)
+		var out bytes.Buffer
+		got, err = ReadLine(struct {
+			io.Reader
+			io.Writer
+		}{in, &out}, "Key: ")
+		left, _ := io.ReadAll(in)
+		return got, out.String(), string(left), err
+	}
+
+	t.Run("masks input and handles backspace", func(t *testing.T) {
+		got, echo, rest, err := run("ab\x7fcé\rnext")
+		if err != nil || got != "acé" {
+			t.Errorf("Expected acé, got %q, %v", got, err)
+		}
+		if echo != "Key: **\b \b**\r\n" {
+			t.Errorf("Unexpected echo %q", echo)
+		}
+		if rest != "next" {
+			t.Errorf("Expected the rest of the input to be left, got %q", rest)
+		}
+	})
+
+	t.Run("ctrl+c cancels", func(t *testing.T) {
+		if _, _, _, err := run("abc\x03"); err != ErrCancelled {
+			t.Errorf("Expected ErrCancelled, got %v", err)
+		}
+	})
+
+	t.Run("end of input", func(t *testing.T) {
+		if _, _, _, err := run("abc"); err != io.EOF {
+			t.Errorf("Expected io.EOF, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// runPaste feeds raw terminal input through tcell's parser into a ChatUI and
+// returns what was sent and which commands ran
+func runPaste(t *testing.T, mode PasteMode, raw string) (*ChatUI, []string, []string) {
+	t.Helper()
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+
+	chat := NewChatUI(screen)
+	chat.PasteMode = mode
+	var sent, cmds []string
+	chat.OnSend(func(msg string) { sent = append(sent, msg) })
+	chat.OnCmd(func(cmd string) bool {
+		cmds = append(cmds, cmd)
+		return true
+	})
+
+	events := make(chan tcell.Event, 256)
+	tcell.NewInputProcessor(events).ScanUTF8([]byte(raw))
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		for len(events) > 0 {
+			screen.PostEventWait(<-events)
+		}
+		screen.PostEventWait(tcell.NewEventKey(tcell.KeyCtrlC, 'c', tcell.ModNone))
+	}()
+	chat.Run()
+	return chat, sent, cmds
+}
+
+func TestBracketedPaste(t *testing.T) {
+	t.Run("multi-line sent as one message", func(t *testing.T) {
+		_, sent, cmds := runPaste(t, PasteMessage, "\x1b[200~hello\r/kick bob\rworld\x1b[201~")
+		if len(sent) != 1 || sent[0] != "hello\n/kick bob\nworld" {
+			t.Errorf("Expected one multi-line message, got %q", sent)
+		}
+		if len(cmds) != 0 {
+			t.Errorf("Pasted line ran as a command: %q", cmds)
+		}
+	})
+
+	t.Run("single line goes to input", func(t *testing.T) {
+		chat, sent, _ := runPaste(t, PasteMessage, "\x1b[200~/quit\x1b[201~")
+		if len(sent) != 0 {
+			t.Errorf("Single-line paste was submitted: %q", sent)
+		}
+		if chat.cmdInput.Value != "/quit" {
+			t.Errorf("Expected paste in the input field, got %q", chat.cmdInput.Value)
+		}
+	})
+
+	t.Run("reject mode warns about commands", func(t *testing.T) {
+		chat, sent, cmds := runPaste(t, PasteReject, "\x1b[200~hello\r/kick bob\x1b[201~")
+		if len(sent) != 0 || len(cmds) != 0 {
+			t.Errorf("Rejected paste was sent: %q %q", sent, cmds)
+		}
+		msgs := chat.GetMessages()
+		if len(msgs) == 0 || msgs[len(msgs)-1].Text != "Paste rejected: line 2 looks like a command." {
+			t.Errorf("Expected a rejection warning, got %v", msgs)
+		}
+	})
+
+	t.Run("typing after paste still submits", func(t *testing.T) {
+		_, sent, _ := runPaste(t, PasteMessage, "\x1b[200~hi\x1b[201~ there\r")
+		if len(sent) != 1 || sent[0] != "hi there" {
+			t.Errorf("Expected pasted text to be editable and sent, got %q", sent)
+		}
+	})
+}
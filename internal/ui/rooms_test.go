@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestEntryUIRoomSelection(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(80, 24)
+
+	ui := NewEntryUI(screen, "alice", "localhost")
+	var cmds []string
+	ui.OnCmd(func(cmd string) { cmds = append(cmds, cmd) })
+	ui.SetRooms([]RoomInfo{{Name: "alpha"}, {Name: "beta"}, {Name: "gamma"}})
+
+	key := func(k tcell.Key) {
+		ui.HandleKeyResult(tcell.NewEventKey(k, 0, tcell.ModNone))
+	}
+
+	t.Run("tab focuses the list", func(t *testing.T) {
+		key(tcell.KeyTab)
+		if !ui.roomFocus || ui.selectedRoom != "alpha" {
+			t.Fatalf("Expected focus on alpha, got focus=%v selected=%q", ui.roomFocus, ui.selectedRoom)
+		}
+	})
+
+	t.Run("arrows move and clamp", func(t *testing.T) {
+		key(tcell.KeyDown)
+		key(tcell.KeyDown)
+		key(tcell.KeyDown)
+		if ui.selectedRoom != "gamma" {
+			t.Errorf("Expected gamma after moving past the end, got %q", ui.selectedRoom)
+		}
+		key(tcell.KeyUp)
+		if ui.selectedRoom != "beta" {
+			t.Errorf("Expected beta, got %q", ui.selectedRoom)
+		}
+		if ui.cmdInput.Value != "" {
+			t.Errorf("Arrows leaked into the input: %q", ui.cmdInput.Value)
+		}
+	})
+
+	t.Run("selection is drawn", func(t *testing.T) {
+		ui.Draw()
+		_, style, _ := screen.Get(57, 4)
+		if _, _, attrs := style.Decompose(); attrs&tcell.AttrReverse == 0 {
+			t.Errorf("Expected the highlighted room to be drawn reversed")
+		}
+	})
+
+	t.Run("selection survives a room list update", func(t *testing.T) {
+		ui.SetRooms([]RoomInfo{{Name: "new"}, {Name: "alpha"}, {Name: "beta"}})
+		if ui.selectedRoom != "beta" {
+			t.Errorf("Expected beta to stay selected, got %q", ui.selectedRoom)
+		}
+	})
+
+	t.Run("enter joins the highlighted room", func(t *testing.T) {
+		key(tcell.KeyEnter)
+		if len(cmds) != 1 || cmds[0] != "/join beta" {
+			t.Fatalf("Expected /join beta, got %v", cmds)
+		}
+		if ui.roomFocus {
+			t.Errorf("Expected focus to return to the input after joining")
+		}
+	})
+
+	t.Run("typing returns focus to the input", func(t *testing.T) {
+		key(tcell.KeyTab)
+		ui.HandleKeyResult(tcell.NewEventKey(tcell.KeyRune, '/', tcell.ModNone))
+		if ui.roomFocus || ui.cmdInput.Value != "/" {
+			t.Errorf("Expected typed text in the input, got focus=%v value=%q", ui.roomFocus, ui.cmdInput.Value)
+		}
+	})
+
+	t.Run("no rooms, no focus", func(t *testing.T) {
+		ui.SetRooms(nil)
+		key(tcell.KeyTab)
+		if ui.roomFocus {
+			t.Errorf("Expected Tab to do nothing without rooms")
+		}
+	})
+}
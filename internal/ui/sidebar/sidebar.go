@@ -7,22 +7,28 @@ import (
 
 // Sidebar represents a vertical information panel
 type Sidebar struct {
-	Title string
-	Items []string
-	Width int
+	Title    string
+	Items    []string
+	Width    int
+	Selected int  // index of the highlighted item, -1 for none
+	Focused  bool // draw the highlight, the sidebar has keyboard focus
 }
 
 // NewSidebar creates a new sidebar with a title and width
 func NewSidebar(title string, width int) *Sidebar {
 	return &Sidebar{
-		Title: title,
-		Width: width,
+		Title:    title,
+		Width:    width,
+		Selected: -1,
 	}
 }
 
 // SetItems updates the items in the sidebar
 func (b *Sidebar) SetItems(items []string) {
-	b.Items = items
+	b.Items = make([]string, len(items))
+	for i, item := range items {
+		b.Items[i] = common.StripANSI(item)
+	}
 }
 
 // Draw renders the sidebar at (x, y) with a specific height
@@ -33,17 +39,26 @@ func (b *Sidebar) Draw(s tcell.Screen, x, y, h int, style, sepStyle tcell.Style)
 
 	// Draw vertical separator
 	for sy := y; sy < y+h; sy++ {
-		s.SetContent(x, sy, '│', nil, sepStyle)
+		s.SetContent(x, sy, common.Glyphs.Vertical, nil, sepStyle)
 	}
 
 	// Draw title
 	common.DrawText(s, x+1, y, " "+b.Title, b.Width-1, style.Bold(true))
 
-	// Draw items
-	for i, item := range b.Items {
-		if i+1 >= h {
+	// Draw items, scrolled so the selection stays visible
+	first := 0
+	if b.Selected >= h-1 {
+		first = b.Selected - (h - 2)
+	}
+	for i := first; i < len(b.Items); i++ {
+		row := i - first
+		if row+1 >= h {
 			break
 		}
-		common.DrawText(s, x+2, y+1+i, item, b.Width-2, style)
+		itemStyle := style
+		if b.Focused && i == b.Selected {
+			itemStyle = style.Reverse(true)
+		}
+		common.DrawText(s, x+2, y+1+row, b.Items[i], b.Width-2, itemStyle)
 	}
 }
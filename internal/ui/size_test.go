@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestDrawZeroSizeThenResize(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+
+	chat := NewChatUI(screen)
+	chat.AddMessage("hello", MsgChat)
+	entry := NewEntryUI(screen, "alice", "localhost")
+
+	// A client reporting 0x0 during negotiation must not break layout
+	screen.SetSize(0, 0)
+	chat.Draw()
+	entry.Draw()
+
+	screen.SetSize(80, 24)
+	chat.Draw()
+	if text := screenText(screen); !strings.Contains(text, "hello") {
+		t.Errorf("Expected the chat message to be drawn after resize, got:\n%s", text)
+	}
+	entry.Draw()
+	if text := screenText(screen); !strings.Contains(text, "Logged in as: alice") {
+		t.Errorf("Expected the entry screen to be drawn after resize, got:\n%s", text)
+	}
+
+	_, w, h := screen.GetContents()
+	if w != 80 || h != 24 {
+		t.Errorf("Expected 80x24 after resize, got %dx%d", w, h)
+	}
+}
+
+// screenText returns the characters on the screen, one line per row
+func screenText(s tcell.SimulationScreen) string {
+	cells, w, _ := s.GetContents()
+	var b strings.Builder
+	for i, c := range cells {
+		if i > 0 && i%w == 0 {
+			b.WriteByte('\n')
+		}
+		if len(c.Runes) > 0 {
+			b.WriteRune(c.Runes[0])
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	return b.String()
+}
@@ -14,6 +14,7 @@ const (
 	MsgSystem  = log.MsgSystem
 	MsgAction  = log.MsgAction
 	MsgWhisper = log.MsgWhisper
+	MsgStaff   = log.MsgStaff
 )
 
 type Message = log.Message
@@ -23,4 +24,5 @@ type RoomInfo struct {
 	Owner       string
 	Doors       []string
 	PeopleCount int
+	Favorite    bool // marked with /favorite by the person viewing the list
 }